@@ -4,12 +4,17 @@ import (
 	"net"
 	"os"
 
+	"github.com/joho/godotenv"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
 	pb "github.com/snailrake/sstu-auth-proto/proto/auth"
+	accountpb "sstu-go-forum-auth-service/api/proto/account"
+	"sstu-go-forum-auth-service/internal/config"
 	"sstu-go-forum-auth-service/internal/handler"
+	"sstu-go-forum-auth-service/internal/storage"
+	usecaseImpl "sstu-go-forum-auth-service/internal/usecase/impl"
 )
 
 var logger zerolog.Logger
@@ -19,13 +24,55 @@ func init() {
 }
 
 func main() { // TODO: вынести обработку в отдельный handler
+	if err := godotenv.Load(); err != nil {
+		logger.Fatal().Err(err).Msg("failed to load .env")
+	}
+	cfg := config.Load()
+	if err := cfg.ValidateRateLimits(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid rate limit config")
+	}
+	if err := cfg.ValidateRequireVerifiedEmail(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid REQUIRE_VERIFIED_EMAIL")
+	}
+
+	// Хранилище нужно для проверки отзыва токенов и операций с аккаунтом
+	store, err := storage.Open(cfg.DatabaseURL, cfg.AutoMigrate)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to open storage")
+	}
+	defer store.Close()
+
 	lis, err := net.Listen("tcp", ":50051")
 	if err != nil {
 		logger.Fatal().Err(err).Msg("failed to listen on port 50051")
 	}
 
-	s := grpc.NewServer()
-	pb.RegisterAuthServiceServer(s, handler.NewGrpcHandler())
+	hasher, err := cfg.PasswordHasher()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid PASSWORD_HASH")
+	}
+	policy, err := cfg.PasswordPolicy()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid password policy")
+	}
+	authUC := usecaseImpl.NewAuthUseCase(store.Auth)
+	authUC.Hasher = hasher
+	authUC.PasswordPolicy = policy
+	grpcHandler := handler.NewGrpcHandler(authUC)
+	grpcHandler.RequireVerifiedEmail = cfg.RequireVerifiedEmail == config.RequireVerifiedEmailForPost
+	limits, err := store.RateLimits(cfg.RateLimitBackend)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid RATE_LIMIT_BACKEND")
+	}
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		handler.RateLimitInterceptor(
+			limits.TokenBucket("grpc_client", cfg.RateLimitGRPC, cfg.RateLimitGRPCWindow), handler.GRPCKeyByClientID),
+		handler.MethodRateLimitInterceptor(handler.GRPCCredentialMethods,
+			limits.TokenBucket("grpc_credentials_ip", cfg.RateLimitCredentialsIP, cfg.RateLimitCredentialsWindow),
+			handler.GRPCKeyByPeer),
+	))
+	pb.RegisterAuthServiceServer(s, grpcHandler)
+	accountpb.RegisterAccountServiceServer(s, grpcHandler)
 	reflection.Register(s)
 
 	logger.Info().Msg("Starting gRPC server on :50051")
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog"
+
+	"sstu-go-forum-auth-service/internal/config"
+	"sstu-go-forum-auth-service/internal/migrations"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/password"
+	"sstu-go-forum-auth-service/internal/storage"
+)
+
+var logger zerolog.Logger
+
+func init() {
+	logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Logger()
+}
+
+const usage = `Usage:
+  authctl migrate up          apply all pending migrations
+  authctl migrate down [N]    revert the last N migrations (default 1)
+  authctl migrate status      show applied and pending migrations
+  authctl usernames normalize
+                              recompute normalized and lookalike keys after the normalization rules change
+  authctl users set-role USERNAME ROLE
+                              change the role of a user (USER or ADMIN); registration always creates USER
+  authctl breached-index IN OUT
+                              build a breached password index from IN (one password or SHA-1 hash per line)`
+
+func main() {
+	_ = godotenv.Load()
+	switch {
+	case len(os.Args) >= 3 && os.Args[1] == "migrate":
+		migrate()
+	case len(os.Args) == 3 && os.Args[1] == "usernames" && os.Args[2] == "normalize":
+		normalizeUsernames()
+	case len(os.Args) == 5 && os.Args[1] == "users" && os.Args[2] == "set-role":
+		setRole(os.Args[3], os.Args[4])
+	case len(os.Args) == 4 && os.Args[1] == "breached-index":
+		breachedIndex(os.Args[2], os.Args[3])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func openSQLStorage() *storage.Storage {
+	store, err := storage.Open(config.Load().DatabaseURL, false)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to open storage")
+	}
+	if store.DB == nil {
+		logger.Fatal().Msg("storage has no schema to migrate")
+	}
+	return store
+}
+
+func migrate() {
+	store := openSQLStorage()
+	defer store.Close()
+	migrator, err := migrations.New(store.DB, store.Dialect)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to load migrations")
+	}
+
+	switch os.Args[2] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			logger.Info().Int("version", m.Version).Str("name", m.Name).Msg("applied")
+		}
+		if err != nil {
+			logger.Fatal().Err(err).Msg("migrate up failed")
+		}
+		if len(applied) == 0 {
+			logger.Info().Msg("no pending migrations")
+		}
+	case "down":
+		steps := 1
+		if len(os.Args) > 3 {
+			if steps, err = strconv.Atoi(os.Args[3]); err != nil || steps < 1 {
+				logger.Fatal().Str("steps", os.Args[3]).Msg("N must be a positive number")
+			}
+		}
+		reverted, err := migrator.Down(steps)
+		for _, m := range reverted {
+			logger.Info().Int("version", m.Version).Str("name", m.Name).Msg("reverted")
+		}
+		if err != nil {
+			logger.Fatal().Err(err).Msg("migrate down failed")
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			logger.Fatal().Err(err).Msg("migrate status failed")
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%06d  %-8s %s\n", s.Version, state, s.Name)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// setRole назначает роль: регистрация всегда создает USER, поэтому первого администратора назначают здесь
+func setRole(username, role string) {
+	if role != "USER" && role != "ADMIN" {
+		logger.Fatal().Str("role", role).Msg("role must be USER or ADMIN")
+	}
+	store := openSQLStorage()
+	defer store.Close()
+
+	user, err := store.Auth.GetUserByUsername(username)
+	if err != nil {
+		logger.Fatal().Err(err).Str("username", username).Msg("failed to find user")
+	}
+	if err := store.Auth.UpdateRole(user.ID, role); err != nil {
+		logger.Fatal().Err(err).Int("userID", user.ID).Msg("failed to change role")
+	}
+	logger.Info().Int("userID", user.ID).Str("username", user.Username).Str("role", role).Msg("role changed")
+}
+
+// breachedIndex собирает индекс утекших паролей; вход — список паролей или выгрузка Have I Been Pwned в формате SHA-1
+func breachedIndex(in, out string) {
+	f, err := os.Open(in)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to open input")
+	}
+	defer f.Close()
+
+	var hashes [][sha1.Size]byte
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if h, ok := password.ParseBreachedLine(scanner.Text()); ok {
+			hashes = append(hashes, h)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		logger.Fatal().Err(err).Msg("failed to read input")
+	}
+
+	tmp := out + ".tmp"
+	w, err := os.Create(tmp)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create index")
+	}
+	if err := password.WriteBreachedIndex(w, hashes); err != nil {
+		w.Close()
+		os.Remove(tmp)
+		logger.Fatal().Err(err).Msg("failed to write index")
+	}
+	if err := w.Close(); err != nil {
+		os.Remove(tmp)
+		logger.Fatal().Err(err).Msg("failed to write index")
+	}
+	if err := os.Rename(tmp, out); err != nil {
+		logger.Fatal().Err(err).Msg("failed to write index")
+	}
+	logger.Info().Int("hashes", len(hashes)).Str("file", out).Msg("breached password index built")
+}
+
+// normalizeUsernames пересчитывает ключи имен после изменения правил model.UsernameKey или model.UsernameSkeleton;
+// при переходе на нормализацию ключи заполняет сама миграция 000014
+func normalizeUsernames() {
+	store := openSQLStorage()
+	defer store.Close()
+
+	type row struct {
+		id                             int
+		username, normalized, skeleton string
+	}
+	rows, err := store.DB.Query("SELECT id, username, username_normalized, COALESCE(username_skeleton, '') FROM users ORDER BY id")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to list users")
+	}
+	var users []row
+	for rows.Next() {
+		var u row
+		if err := rows.Scan(&u.id, &u.username, &u.normalized, &u.skeleton); err != nil {
+			logger.Fatal().Err(err).Msg("failed to list users")
+		}
+		users = append(users, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logger.Fatal().Err(err).Msg("failed to list users")
+	}
+
+	updated, conflicts := 0, 0
+	for _, u := range users {
+		key, skeleton := model.UsernameKey(u.username), model.UsernameSkeleton(u.username)
+		if key == u.normalized && skeleton == u.skeleton {
+			continue
+		}
+		_, err := store.DB.Exec("UPDATE users SET username_normalized = $1, username_skeleton = $2 WHERE id = $3", key, skeleton, u.id)
+		if err != nil {
+			// Чаще всего это два старых пользователя, чьи имена после нормализации совпали: их нужно развести вручную
+			logger.Warn().Err(err).Int("userID", u.id).Str("username", u.username).Msg("failed to normalize username")
+			conflicts++
+			continue
+		}
+		updated++
+	}
+	logger.Info().Int("users", len(users)).Int("updated", updated).Int("failed", conflicts).Msg("usernames normalized")
+	if conflicts > 0 {
+		os.Exit(1)
+	}
+}
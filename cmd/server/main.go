@@ -1,19 +1,25 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"expvar"
 	"log"
 	"net/http"
 	"os"
-	"sstu-go-forum-auth-service/internal/repository/impl"
 
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
 	"github.com/rs/zerolog"
 	httpSwagger "github.com/swaggo/http-swagger"
 
 	_ "sstu-go-forum-auth-service/docs"
+	"sstu-go-forum-auth-service/internal/config"
 	"sstu-go-forum-auth-service/internal/handler"
+	"sstu-go-forum-auth-service/internal/janitor"
+	"sstu-go-forum-auth-service/internal/mailer"
+	"sstu-go-forum-auth-service/internal/migrations"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/storage"
 	usecaseImpl "sstu-go-forum-auth-service/internal/usecase/impl"
 )
 
@@ -26,47 +32,175 @@ func init() {
 // @title API сервиса авторизации
 // @version 1.0
 // @host localhost:8081
-// @BasePath /
+// @BasePath /api/v1
 // @schemes http
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 func main() {
 	if err := godotenv.Load(); err != nil {
 		logger.Fatal().Err(err).Msg("failed to load .env")
 	}
 
-	db, err := sql.Open("postgres", os.Getenv("DATABASE_URL"))
+	cfg := config.Load()
+	if err := cfg.ValidateRateLimits(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid rate limit config")
+	}
+	if err := cfg.ValidateRequireVerifiedEmail(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid REQUIRE_VERIFIED_EMAIL")
+	}
+
+	store, err := storage.Open(cfg.DatabaseURL, cfg.AutoMigrate)
 	if err != nil {
-		logger.Fatal().Err(err).Msg("failed to open database connection")
+		logger.Fatal().Err(err).Msg("failed to open storage")
 	}
-	defer db.Close()
+	defer store.Close()
 
-	if err := db.Ping(); err != nil {
-		logger.Fatal().Err(err).Msg("failed to ping database")
+	var locker janitor.Locker = janitor.LocalLocker{}
+	if store.Dialect == migrations.Postgres {
+		locker = janitor.PostgresLocker{DB: store.DB}
+	}
+	limits, err := store.RateLimits(cfg.RateLimitBackend)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid RATE_LIMIT_BACKEND")
+	}
+	tasks := []janitor.Task{
+		janitor.RefreshTokensTask(store.Auth),
+		janitor.DenylistTask(store.Auth),
+		janitor.PasswordResetTokensTask(store.Auth),
+		janitor.EmailVerificationTokensTask(store.Auth),
+		janitor.MagicLinkTokensTask(store.Auth),
+		janitor.LoginFailuresTask(store.Auth),
+		janitor.WebAuthnSessionsTask(store.Auth),
+	}
+	if limits.DB != nil {
+		tasks = append(tasks, janitor.RateLimitsTask(limits.DB))
 	}
+	go janitor.New(locker, cfg.JanitorInterval, cfg.JanitorBatchSize, tasks...).Run(context.Background())
 
-	repo := impl.NewRepository(db)
-	authUC := usecaseImpl.NewAuthUseCase(repo)
+	hasher, err := cfg.PasswordHasher()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid PASSWORD_HASH")
+	}
+	policy, err := cfg.PasswordPolicy()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid password policy")
+	}
+	mail := mailer.NewAsync(newMailer(cfg), 100)
+	emailUC := usecaseImpl.NewEmailUseCase(store.Auth, mail,
+		ratelimit.NewSlidingWindow(cfg.EmailVerifyAccountLimit, cfg.EmailVerifyWindow),
+		cfg.EmailVerifyURL, cfg.EmailVerifyTTL)
+	emailUC.Hasher = hasher
+	emailHandler := handler.NewEmailHandler(emailUC,
+		ratelimit.NewSlidingWindow(cfg.EmailVerifyIPLimit, cfg.EmailVerifyWindow))
+
+	authUC := usecaseImpl.NewAuthUseCase(store.Auth)
+	authUC.Hasher = hasher
+	authUC.PasswordPolicy = policy
+	authUC.ReservedUsernames = cfg.ReservedUsernames
+	authUC.Verification = emailUC
+	authUC.RequireVerifiedEmail = cfg.RequireVerifiedEmail == config.RequireVerifiedEmailForLogin
+	authUC.Lockout = usecaseImpl.NewLoginLockout(store.Auth, cfg.LoginFreeAttempts, cfg.LoginBackoffBase,
+		cfg.LoginLockoutThreshold, cfg.LoginIPLockoutThreshold, cfg.LoginLockout, cfg.LoginLockoutMax, cfg.LoginFailureWindow)
+	mfaUC := usecaseImpl.NewMFAUseCase(store.Auth, cfg.MFAIssuer, cfg.MFARequiredRoles,
+		ratelimit.NewSlidingWindow(cfg.MFAMaxAttempts, cfg.MFAAttemptWindow))
+	mfaUC.Mailer = mail
+	mfaUC.Hasher = hasher
+	authUC.MFA = mfaUC
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthnRPID,
+		RPDisplayName: cfg.WebAuthnRPName,
+		RPOrigins:     cfg.WebAuthnOrigins,
+	})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid webauthn config")
+	}
+	webAuthnUC := usecaseImpl.NewWebAuthnUseCase(store.Auth, wa, cfg.WebAuthnSessionTTL)
+	webAuthnUC.Hasher = hasher
+	webAuthnUC.RequiredRoles = cfg.MFARequiredRoles
+	authUC.WebAuthn = webAuthnUC
+	magicLinkUC := usecaseImpl.NewMagicLinkUseCase(store.Auth, mail,
+		ratelimit.NewSlidingWindow(cfg.MagicLinkAccountLimit, cfg.MagicLinkWindow),
+		cfg.MagicLinkURL, cfg.MagicLinkTTL)
+	authUC.MagicLink = magicLinkUC
 	authHandler := handler.NewAuthHandler(authUC)
+	mfaHandler := handler.NewMFAHandler(mfaUC, authUC)
+	webAuthnHandler := handler.NewWebAuthnHandler(webAuthnUC, authUC)
+	magicLinkHandler := handler.NewMagicLinkHandler(magicLinkUC, authUC,
+		ratelimit.NewSlidingWindow(cfg.MagicLinkIPLimit, cfg.MagicLinkWindow))
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/register", authHandler.Register)
-	mux.HandleFunc("/login", authHandler.Login)
-	mux.HandleFunc("/refresh", authHandler.Refresh)
-	mux.HandleFunc("/swagger/", httpSwagger.WrapHandler)
+	resetUC := usecaseImpl.NewPasswordResetUseCase(store.Auth, mail,
+		ratelimit.NewSlidingWindow(cfg.PasswordResetAccountLimit, cfg.PasswordResetWindow),
+		cfg.PasswordResetURL, cfg.PasswordResetTTL)
+	resetUC.Hasher = hasher
+	resetUC.PasswordPolicy = policy
+	resetHandler := handler.NewPasswordResetHandler(resetUC,
+		ratelimit.NewSlidingWindow(cfg.PasswordResetIPLimit, cfg.PasswordResetWindow))
+
+	loginIPLimit := limits.TokenBucket("login_ip", cfg.RateLimitLoginIP, cfg.RateLimitLoginWindow)
+	loginUsernameLimit := limits.SlidingWindow("login_username", cfg.RateLimitLoginUsername, cfg.RateLimitLoginWindow)
+	registerIPLimit := limits.SlidingWindow("register_ip", cfg.RateLimitRegisterIP, cfg.RateLimitRegisterWindow)
+	refreshIPLimit := limits.TokenBucket("refresh_ip", cfg.RateLimitRefreshIP, cfg.RateLimitRefreshWindow)
+	credentialsIPLimit := limits.TokenBucket("credentials_ip", cfg.RateLimitCredentialsIP, cfg.RateLimitCredentialsWindow)
+
+	api := &handler.API{
+		Auth:                authHandler,
+		Email:               emailHandler,
+		MFA:                 mfaHandler,
+		WebAuthn:            webAuthnHandler,
+		MagicLink:           magicLinkHandler,
+		Reset:               resetHandler,
+		LoginIPLimit:        loginIPLimit,
+		LoginUsernameLimit:  loginUsernameLimit,
+		RegisterIPLimit:     registerIPLimit,
+		RefreshIPLimit:      refreshIPLimit,
+		CredentialsIPLimit:  credentialsIPLimit,
+		UniformResponseTime: cfg.UniformResponseTime,
+	}
+	corsPolicy, corsRoutes, err := cfg.CORSPolicies()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid CORS config")
+	}
+	routes := api.Routes()
+	for path, policy := range corsRoutes {
+		routes.SetCORS(path, policy)
+	}
+	routes.Mount("GET /swagger/", httpSwagger.WrapHandler)
+
+	srv := handler.Chain(routes,
+		handler.RequestID,
+		handler.AccessLog,
+		handler.Recover,
+		routes.CORS(corsPolicy),
+		handler.BodyLimit(cfg.MaxBodyBytes),
+		handler.Timeout(cfg.RequestTimeout),
+	)
+
+	if cfg.DebugAddr != "off" {
+		debug := http.NewServeMux()
+		debug.Handle("GET /debug/vars", expvar.Handler())
+		go func() {
+			logger.Info().Str("addr", cfg.DebugAddr).Msg("Starting debug server")
+			if err := http.ListenAndServe(cfg.DebugAddr, debug); err != nil {
+				logger.Error().Err(err).Msg("debug server stopped")
+			}
+		}()
+	}
 
 	logger.Info().Msg("Starting server on :8081")
-	log.Fatal(http.ListenAndServe(":8081", withCORS(mux)))
+	log.Fatal(http.ListenAndServe(":8081", srv))
 }
 
-func withCORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug().Str("method", r.Method).Str("url", r.URL.String()).Msg("handling request")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type,Authorization")
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
+func newMailer(cfg config.Config) mailer.Mailer {
+	switch cfg.Mailer {
+	case "smtp":
+		return &mailer.SMTPMailer{Addr: cfg.SMTPAddr, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword, From: cfg.MailFrom}
+	case "file":
+		return &mailer.FileMailer{Dir: cfg.MailDir, From: cfg.MailFrom}
+	case "log":
+		return mailer.LogMailer{}
+	default:
+		logger.Fatal().Str("mailer", cfg.Mailer).Msg("unknown MAILER, use smtp, log or file")
+		return nil
+	}
 }
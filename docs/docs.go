@@ -15,176 +15,1667 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/login": {
+        "/admin/login/unlock": {
             "post": {
-                "description": "Функция для авторизации пользователя",
-                "summary": "Авторизация пользователя",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Сбрасывает счетчик неудачных входов по имени пользователя. Доступно только роли ADMIN",
+                "summary": "Снятие блокировки входа",
                 "parameters": [
                     {
-                        "description": "Данные для авторизации пользователя",
-                        "name": "login_request",
+                        "description": "Имя пользователя",
+                        "name": "unlock_login_request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.LoginRequest"
+                            "$ref": "#/definitions/dto.UnlockLoginRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Ответ с токенами",
+                        "description": "Блокировка снята",
                         "schema": {
-                            "$ref": "#/definitions/model.AuthResponse"
+                            "$ref": "#/definitions/dto.MessageResponse"
                         }
                     },
                     "400": {
                         "description": "Неверный запрос",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/apierror.Problem"
                         }
                     },
                     "401": {
-                        "description": "Неверный логин или пароль",
+                        "description": "Неверный токен",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/apierror.Problem"
                         }
                     }
                 }
             }
         },
-        "/refresh": {
+        "/email/change": {
             "post": {
-                "description": "Функция для обновления токенов пользователя",
-                "summary": "Обновление токена авторизации",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Проверяет текущий пароль, меняет адрес почты и отправляет на него письмо для подтверждения. До подтверждения почта считается неподтвержденной",
+                "summary": "Смена почты",
                 "parameters": [
                     {
-                        "description": "Данные для обновления токена",
-                        "name": "refresh_request",
+                        "description": "Текущий пароль и новый адрес",
+                        "name": "change_email_request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.RefreshRequest"
+                            "$ref": "#/definitions/dto.ChangeEmailRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Ответ с новым токеном",
+                    "202": {
+                        "description": "Почта изменена, письмо отправлено",
                         "schema": {
-                            "$ref": "#/definitions/model.AuthResponse"
+                            "$ref": "#/definitions/dto.MessageResponse"
                         }
                     },
                     "400": {
-                        "description": "Неверный запрос",
+                        "description": "Неверный запрос или адрес (список ошибок полей с кодами)",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/apierror.Problem"
                         }
                     },
                     "401": {
-                        "description": "Неверный токен",
+                        "description": "Неверный токен или текущий пароль",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Адрес уже используется",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
                         }
                     }
                 }
             }
         },
-        "/register": {
+        "/email/verify": {
             "post": {
-                "description": "Функция для регистрации нового пользователя",
-                "summary": "Регистрация нового пользователя",
+                "description": "Подтверждает адрес электронной почты одноразовым токеном из письма. Чтобы claim email_verified появился в access токене, клиент обновляет токены через /refresh",
+                "summary": "Подтверждение почты",
                 "parameters": [
                     {
-                        "description": "Данные пользователя для регистрации",
-                        "name": "user",
+                        "description": "Токен из письма",
+                        "name": "verify_email_request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.User"
+                            "$ref": "#/definitions/dto.VerifyEmailRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Ответ с информацией о регистрации",
+                        "description": "Почта подтверждена",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или токен",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/email/verify/resend": {
+            "post": {
+                "description": "Отправляет новую ссылку для подтверждения почты. Ответ не зависит от того, существует ли аккаунт",
+                "summary": "Повторная отправка письма с подтверждением",
+                "parameters": [
+                    {
+                        "description": "Имя пользователя",
+                        "name": "resend_verification_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ResendVerificationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Запрос принят",
                         "schema": {
-                            "$ref": "#/definitions/model.RegisterResponse"
+                            "$ref": "#/definitions/dto.MessageResponse"
                         }
                     },
                     "400": {
                         "description": "Неверный запрос",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/apierror.Problem"
                         }
                     },
-                    "405": {
-                        "description": "Метод не разрешён",
+                    "429": {
+                        "description": "Слишком много запросов",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/apierror.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "model.AuthResponse": {
-            "type": "object",
-            "properties": {
-                "access_token": {
-                    "type": "string"
-                },
-                "refresh_token": {
-                    "type": "string"
+        },
+        "/locale/change": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Сохраняет язык писем и ответов (ru или en); он важнее Accept-Language. Пустой язык возвращает выбор по Accept-Language. Выданные access токены содержат прежний язык до обновления",
+                "summary": "Смена языка",
+                "parameters": [
+                    {
+                        "description": "Новый язык",
+                        "name": "change_locale_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ChangeLocaleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Язык изменен",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или неподдерживаемый язык",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
                 }
             }
         },
-        "model.LoginRequest": {
-            "type": "object",
-            "properties": {
-                "password": {
-                    "type": "string"
-                },
-                "username": {
-                    "type": "string"
+        "/login": {
+            "post": {
+                "description": "Функция для авторизации пользователя. Если нужен второй фактор, вместо токенов возвращается MFA токен для /login/mfa",
+                "summary": "Авторизация пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные для авторизации пользователя",
+                        "name": "login_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ответ с токенами",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AuthResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Пароль верный, нужен код TOTP",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MFAChallengeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный логин или пароль",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Почта не подтверждена",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Вход временно заблокирован после неудачных попыток или превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
                 }
             }
         },
-        "model.RefreshRequest": {
-            "type": "object",
-            "properties": {
-                "refresh_token": {
-                    "type": "string"
+        "/login/magic-link": {
+            "post": {
+                "description": "Отправляет на почту пользователя одноразовую ссылку для входа и возвращает nonce, который браузер предъявляет вместе с токеном из ссылки. Ответ не зависит от того, существует ли аккаунт",
+                "summary": "Запрос ссылки для входа",
+                "parameters": [
+                    {
+                        "description": "Имя пользователя",
+                        "name": "magic_link_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.MagicLinkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Запрос принят",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MagicLinkResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
                 }
             }
         },
-        "model.RegisterResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
-                },
-                "user_id": {
-                    "type": "integer"
+        "/login/magic-link/finish": {
+            "post": {
+                "description": "Обменивает одноразовый токен из письма и nonce браузера на пару токенов. Если подключен второй фактор, возвращается MFA токен для /login/mfa",
+                "summary": "Вход по ссылке из письма",
+                "parameters": [
+                    {
+                        "description": "Токен из письма и nonce",
+                        "name": "magic_link_login_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.MagicLinkLoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ответ с токенами",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AuthResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Ссылка верна, нужен второй фактор",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MFAChallengeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверная, истекшая или уже использованная ссылка",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
                 }
             }
         },
-        "model.User": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "integer"
-                },
-                "password": {
-                    "type": "string"
-                },
-                "role": {
-                    "type": "string"
-                },
-                "username": {
-                    "type": "string"
+        "/login/mfa": {
+            "post": {
+                "description": "Завершает вход кодом из приложения-аутентификатора, одноразовым кодом восстановления или ответом ключа доступа после /login/mfa/webauthn/begin. Если роль требует 2FA и TOTP привязан через /login/mfa/enroll, первый верный код его подключает",
+                "summary": "Второй шаг входа",
+                "parameters": [
+                    {
+                        "description": "MFA токен и код, код восстановления или ответ ключа доступа",
+                        "name": "mfa_login_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.MFALoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ответ с токенами",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или TOTP не привязан",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный MFA токен или код",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много попыток",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
                 }
             }
+        },
+        "/login/mfa/enroll": {
+            "post": {
+                "description": "Создает секрет TOTP по MFA токену из /login. Вход завершается через /login/mfa первым кодом",
+                "summary": "Привязка TOTP во время входа",
+                "parameters": [
+                    {
+                        "description": "MFA токен",
+                        "name": "mfa_enroll_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.MFAEnrollRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Секрет, otpauth ссылка и коды восстановления",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TOTPEnrollResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный MFA токен",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "TOTP уже подключен",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/login/mfa/webauthn/begin": {
+            "post": {
+                "description": "Возвращает параметры для navigator.credentials.get по ключам пользователя из MFA токена. Ответ браузера отправляется в /login/mfa",
+                "summary": "Ключ доступа как второй фактор",
+                "parameters": [
+                    {
+                        "description": "MFA токен",
+                        "name": "webauthn_mfa_begin_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.WebAuthnMFABeginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Параметры входа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.WebAuthnBeginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или нет ключей",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный MFA токен",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/login/webauthn/begin": {
+            "post": {
+                "description": "Возвращает параметры для navigator.credentials.get без имени пользователя: браузер предложит сохраненные passkey",
+                "summary": "Начало входа по passkey",
+                "responses": {
+                    "200": {
+                        "description": "Параметры входа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.WebAuthnBeginResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/login/webauthn/finish": {
+            "post": {
+                "description": "Проверяет ответ navigator.credentials.get и выдает токены. Ключ проверяет пользователя сам, поэтому второй фактор не запрашивается",
+                "summary": "Вход по passkey",
+                "parameters": [
+                    {
+                        "description": "Ответ браузера",
+                        "name": "webauthn_login_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.WebAuthnLoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ответ с токенами",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный ответ ключа",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Почта не подтверждена",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/mfa/recovery-codes": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Проверяет текущий пароль и выдает новый набор одноразовых кодов восстановления. Прежние коды перестают действовать",
+                "summary": "Новые коды восстановления",
+                "parameters": [
+                    {
+                        "description": "Текущий пароль",
+                        "name": "regenerate_recovery_codes_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.RegenerateRecoveryCodesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Новые коды восстановления",
+                        "schema": {
+                            "$ref": "#/definitions/dto.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или TOTP не подключен",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен или пароль",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/mfa/totp/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Включает второй фактор после проверки первого кода",
+                "summary": "Подтверждение TOTP",
+                "parameters": [
+                    {
+                        "description": "Код из приложения-аутентификатора",
+                        "name": "totp_code_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.TOTPCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Второй фактор включен",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или TOTP не привязан",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен или код",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "TOTP уже подключен",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много попыток",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/mfa/totp/disable": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отключает второй фактор после проверки пароля и кода. Для ролей с обязательной 2FA недоступно",
+                "summary": "Отключение TOTP",
+                "parameters": [
+                    {
+                        "description": "Текущий пароль и код",
+                        "name": "disable_totp_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.DisableTOTPRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Второй фактор отключен",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или TOTP не привязан",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен, пароль или код",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Роль требует 2FA",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много попыток",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/mfa/totp/enroll": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создает новый секрет TOTP после проверки текущего пароля. Второй фактор включается после подтверждения кодом через /mfa/totp/confirm",
+                "summary": "Привязка TOTP",
+                "parameters": [
+                    {
+                        "description": "Текущий пароль",
+                        "name": "totp_enroll_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.TOTPEnrollRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Секрет, otpauth ссылка и коды восстановления",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TOTPEnrollResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен или пароль",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "TOTP уже подключен",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много попыток",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/change": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Проверяет текущий пароль, устанавливает новый, завершает все остальные сессии и выдает новую пару токенов",
+                "summary": "Смена пароля",
+                "parameters": [
+                    {
+                        "description": "Текущий и новый пароль",
+                        "name": "change_password_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ответ с новыми токенами",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или новый пароль не соответствует политике (список ошибок полей с кодами)",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен или текущий пароль",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/forgot": {
+            "post": {
+                "description": "Отправляет на почту пользователя одноразовую ссылку для сброса пароля. Ответ не зависит от того, существует ли аккаунт",
+                "summary": "Запрос сброса пароля",
+                "parameters": [
+                    {
+                        "description": "Имя пользователя",
+                        "name": "forgot_password_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ForgotPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Запрос принят",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/reset": {
+            "post": {
+                "description": "Устанавливает новый пароль по одноразовому токену и завершает все сессии пользователя",
+                "summary": "Сброс пароля",
+                "parameters": [
+                    {
+                        "description": "Токен из письма и новый пароль",
+                        "name": "reset_password_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пароль изменен",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос, токен или новый пароль (список ошибок полей с кодами)",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/refresh": {
+            "post": {
+                "description": "Функция для обновления токенов пользователя",
+                "summary": "Обновление токена авторизации",
+                "parameters": [
+                    {
+                        "description": "Данные для обновления токена",
+                        "name": "refresh_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ответ с новым токеном",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен; auth.token_reused — токен уже обменян, все сессии отозваны",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Почта не подтверждена",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/register": {
+            "post": {
+                "description": "Функция для регистрации нового пользователя. Если locale не указан, он берется из Accept-Language. Имя — от 3 до 32 букв, цифр и символов _ - . (с буквы или цифры); имена сравниваются без учета регистра и формы записи символов (NFKC), зарезервированные и похожие на занятые имена отклоняются. Поле role игнорируется: регистрация всегда создает USER, администраторов назначает authctl users set-role",
+                "summary": "Регистрация нового пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные пользователя для регистрации",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ответ с информацией о регистрации",
+                        "schema": {
+                            "$ref": "#/definitions/dto.RegisterResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или поля (список ошибок полей с кодами)",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "405": {
+                        "description": "Метод не разрешён",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много регистраций с этого адреса, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webauthn/credentials": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "summary": "Список ключей доступа",
+                "responses": {
+                    "200": {
+                        "description": "Ключи пользователя",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.WebAuthnCredential"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webauthn/credentials/delete": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Требует текущий пароль. Последний второй фактор роли, которой он обязателен, удалить нельзя",
+                "summary": "Удаление ключа доступа",
+                "parameters": [
+                    {
+                        "description": "ID ключа и текущий пароль",
+                        "name": "delete_webauthn_credential_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.DeleteWebAuthnCredentialRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ключ удален",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен или пароль",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Второй фактор обязателен для роли",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Ключ не найден",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webauthn/register/begin": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Требует текущий пароль. Возвращает параметры для navigator.credentials.create. Ответ браузера отправляется в /webauthn/register/finish",
+                "summary": "Начало регистрации ключа доступа",
+                "parameters": [
+                    {
+                        "description": "Текущий пароль",
+                        "name": "webauthn_begin_registration_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.WebAuthnBeginRegistrationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Параметры регистрации",
+                        "schema": {
+                            "$ref": "#/definitions/dto.WebAuthnBeginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен или пароль",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webauthn/register/finish": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Проверяет ответ navigator.credentials.create и сохраняет ключ. После этого ключ служит для входа без пароля и вторым фактором",
+                "summary": "Завершение регистрации ключа доступа",
+                "parameters": [
+                    {
+                        "description": "Ответ браузера",
+                        "name": "webauthn_register_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.WebAuthnRegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сохраненный ключ",
+                        "schema": {
+                            "$ref": "#/definitions/model.WebAuthnCredential"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или ответ ключа",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "apierror.FieldViolation": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code — стабильный код ошибки поля: required, length, invalid_characters, invalid_format, invalid_value,\nreserved, confusable, taken или код правила политики паролей (min_length, max_length, character_classes,\nentropy, contains_username, breached)",
+                    "type": "string"
+                },
+                "field": {
+                    "description": "Имя поля в JSON запроса",
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "apierror.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "errors": {
+                    "description": "Ошибки полей, если запрос не прошел проверку",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apierror.FieldViolation"
+                    }
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AuthResponse": {
+            "description": "Структура ответа для авторизации, содержащая access и refresh токены",
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "description": "Токен доступа",
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "description": "Токен для обновления",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ChangeEmailRequest": {
+            "description": "Структура запроса для смены почты с подтверждением текущим паролем",
+            "type": "object",
+            "properties": {
+                "current_password": {
+                    "description": "Текущий пароль",
+                    "type": "string"
+                },
+                "email": {
+                    "description": "Новый адрес электронной почты",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ChangeLocaleRequest": {
+            "description": "Структура запроса для смены языка; пустой язык возвращает выбор по Accept-Language",
+            "type": "object",
+            "properties": {
+                "locale": {
+                    "description": "Язык: ru или en",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ChangePasswordRequest": {
+            "description": "Структура запроса для смены пароля авторизованного пользователя",
+            "type": "object",
+            "properties": {
+                "current_password": {
+                    "description": "Текущий пароль",
+                    "type": "string"
+                },
+                "new_password": {
+                    "description": "Новый пароль",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.DeleteWebAuthnCredentialRequest": {
+            "description": "Структура запроса для удаления ключа доступа",
+            "type": "object",
+            "properties": {
+                "current_password": {
+                    "description": "Текущий пароль",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID ключа из /webauthn/credentials",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.DisableTOTPRequest": {
+            "description": "Структура запроса для отключения второго фактора",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Код из приложения-аутентификатора",
+                    "type": "string"
+                },
+                "current_password": {
+                    "description": "Текущий пароль",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ForgotPasswordRequest": {
+            "description": "Структура запроса для восстановления пароля по имени пользователя",
+            "type": "object",
+            "properties": {
+                "username": {
+                    "description": "Имя пользователя",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.LoginRequest": {
+            "description": "Структура запроса для авторизации пользователя с его данными",
+            "type": "object",
+            "properties": {
+                "password": {
+                    "description": "Пароль пользователя",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "Имя пользователя",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.MFAChallengeResponse": {
+            "description": "Ответ первого шага входа с токеном для подтверждения кодом TOTP",
+            "type": "object",
+            "properties": {
+                "enrollment_required": {
+                    "description": "Роль требует 2FA: сначала привяжите TOTP через /login/mfa/enroll",
+                    "type": "boolean"
+                },
+                "mfa_token": {
+                    "description": "Токен для /login/mfa, действует 5 минут",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.MFAEnrollRequest": {
+            "description": "Структура запроса для привязки TOTP, если роль требует 2FA",
+            "type": "object",
+            "properties": {
+                "mfa_token": {
+                    "description": "Токен из ответа /login",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.MFALoginRequest": {
+            "description": "Структура запроса для завершения входа кодом TOTP",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Код из приложения-аутентификатора",
+                    "type": "string"
+                },
+                "mfa_token": {
+                    "description": "Токен из ответа /login",
+                    "type": "string"
+                },
+                "recovery_code": {
+                    "description": "Код восстановления вместо кода TOTP, если доступа к приложению нет",
+                    "type": "string"
+                },
+                "webauthn_credential": {
+                    "type": "object"
+                },
+                "webauthn_session": {
+                    "description": "Сессия из /login/mfa/webauthn/begin и ответ ключа доступа вместо кода TOTP",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.MagicLinkLoginRequest": {
+            "description": "Структура запроса для обмена токена из письма на пару токенов",
+            "type": "object",
+            "properties": {
+                "nonce": {
+                    "description": "Nonce из ответа /login/magic-link",
+                    "type": "string"
+                },
+                "token": {
+                    "description": "Токен из письма",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.MagicLinkRequest": {
+            "description": "Структура запроса ссылки для входа по имени пользователя",
+            "type": "object",
+            "properties": {
+                "username": {
+                    "description": "Имя пользователя",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.MagicLinkResponse": {
+            "description": "Ответ с nonce, который нужно сохранить до перехода по ссылке",
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "nonce": {
+                    "description": "Предъявляется вместе с токеном из письма: ссылка сработает только в этом браузере",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.MessageResponse": {
+            "description": "Структура ответа с сообщением о результате операции",
+            "type": "object",
+            "properties": {
+                "message": {
+                    "description": "Сообщение о результате",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.RecoveryCodesResponse": {
+            "description": "Новый набор одноразовых кодов восстановления; прежние коды больше не действуют",
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "description": "Коды восстановления; показываются только один раз",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dto.RefreshRequest": {
+            "description": "Структура запроса для обновления токена с новым refresh токеном",
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "description": "Refresh токен",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.RegenerateRecoveryCodesRequest": {
+            "description": "Структура запроса для замены кодов восстановления",
+            "type": "object",
+            "properties": {
+                "current_password": {
+                    "description": "Текущий пароль",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.RegisterResponse": {
+            "description": "Структура ответа при успешной регистрации пользователя",
+            "type": "object",
+            "properties": {
+                "message": {
+                    "description": "Сообщение о статусе регистрации",
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "ID зарегистрированного пользователя",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ResendVerificationRequest": {
+            "description": "Структура запроса для повторной отправки письма по имени пользователя",
+            "type": "object",
+            "properties": {
+                "username": {
+                    "description": "Имя пользователя",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ResetPasswordRequest": {
+            "description": "Структура запроса для сброса пароля одноразовым токеном",
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "description": "Новый пароль",
+                    "type": "string"
+                },
+                "token": {
+                    "description": "Токен из письма",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TOTPCodeRequest": {
+            "description": "Структура запроса с кодом из приложения-аутентификатора",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Код из приложения-аутентификатора",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TOTPEnrollRequest": {
+            "description": "Структура запроса для привязки TOTP из профиля",
+            "type": "object",
+            "properties": {
+                "current_password": {
+                    "description": "Текущий пароль",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TOTPEnrollResponse": {
+            "description": "Секрет TOTP и otpauth ссылка для QR-кода",
+            "type": "object",
+            "properties": {
+                "otpauth_uri": {
+                    "description": "Ссылка otpauth:// для QR-кода",
+                    "type": "string"
+                },
+                "recovery_codes": {
+                    "description": "Одноразовые коды восстановления; показываются только один раз",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "Секрет в base32 для ручного ввода",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.UnlockLoginRequest": {
+            "description": "Структура запроса для снятия блокировки входа по имени пользователя",
+            "type": "object",
+            "properties": {
+                "username": {
+                    "description": "Имя пользователя",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.VerifyEmailRequest": {
+            "description": "Структура запроса для подтверждения адреса электронной почты",
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "Токен из письма",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.WebAuthnBeginRegistrationRequest": {
+            "description": "Структура запроса с текущим паролем для начала регистрации ключа доступа",
+            "type": "object",
+            "properties": {
+                "current_password": {
+                    "description": "Текущий пароль",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.WebAuthnBeginResponse": {
+            "description": "Параметры для navigator.credentials.create или navigator.credentials.get и токен сессии церемонии",
+            "type": "object",
+            "properties": {
+                "options": {
+                    "description": "PublicKeyCredentialCreationOptions или PublicKeyCredentialRequestOptions",
+                    "type": "object"
+                },
+                "session_token": {
+                    "description": "Токен для завершения церемонии",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.WebAuthnLoginRequest": {
+            "description": "Структура запроса с ответом navigator.credentials.get",
+            "type": "object",
+            "properties": {
+                "credential": {
+                    "description": "PublicKeyCredential от браузера",
+                    "type": "object"
+                },
+                "session_token": {
+                    "description": "Токен из /login/webauthn/begin",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.WebAuthnMFABeginRequest": {
+            "description": "Структура запроса с MFA токеном для начала проверки ключом доступа",
+            "type": "object",
+            "properties": {
+                "mfa_token": {
+                    "description": "Токен из ответа /login",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.WebAuthnRegisterRequest": {
+            "description": "Структура запроса с ответом navigator.credentials.create",
+            "type": "object",
+            "properties": {
+                "credential": {
+                    "description": "PublicKeyCredential от браузера",
+                    "type": "object"
+                },
+                "name": {
+                    "description": "Название ключа для списка ключей",
+                    "type": "string"
+                },
+                "session_token": {
+                    "description": "Токен из /webauthn/register/begin",
+                    "type": "string"
+                }
+            }
+        },
+        "model.User": {
+            "description": "Структура пользователя с полями для хранения информации о пользователе",
+            "type": "object",
+            "properties": {
+                "email": {
+                    "description": "Адрес электронной почты для восстановления доступа",
+                    "type": "string"
+                },
+                "email_verified": {
+                    "description": "Подтвержден ли адрес; сбрасывается при смене почты",
+                    "type": "boolean"
+                },
+                "id": {
+                    "description": "ID пользователя",
+                    "type": "integer"
+                },
+                "locale": {
+                    "description": "Язык писем и ответов (ru или en); пустой — по Accept-Language",
+                    "type": "string"
+                },
+                "password": {
+                    "description": "Пароль пользователя",
+                    "type": "string"
+                },
+                "role": {
+                    "description": "Роль пользователя (USER или ADMIN); при регистрации всегда USER",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "Имя пользователя",
+                    "type": "string"
+                }
+            }
+        },
+        "model.WebAuthnCredential": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "transports": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -193,7 +1684,7 @@ const docTemplate = `{
 var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
 	Host:             "localhost:8081",
-	BasePath:         "/",
+	BasePath:         "/api/v1",
 	Schemes:          []string{"http"},
 	Title:            "API сервиса авторизации",
 	Description:      "",
@@ -0,0 +1,146 @@
+// Package apierror отвечает на ошибки в формате application/problem+json (RFC 7807): у каждой ошибки
+// стабильный машинный код, идентификатор запроса и заголовок на языке пользователя, см. i18n.FromRequest
+package apierror
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"sstu-go-forum-auth-service/internal/i18n"
+	"sstu-go-forum-auth-service/internal/model"
+)
+
+const (
+	ContentType     = "application/problem+json"
+	RequestIDHeader = "X-Request-ID"
+	// typePrefix — префикс URI типа проблемы; тип однозначно определяется кодом
+	typePrefix = "urn:sstu-forum:auth:problem:"
+)
+
+// Problem — тело ответа об ошибке. Code не меняется между версиями, поэтому клиентам стоит опираться на него,
+// а не на Title
+type Problem struct {
+	Type      string           `json:"type"`
+	Title     string           `json:"title"`
+	Status    int              `json:"status"`
+	Instance  string           `json:"instance,omitempty"`
+	Code      string           `json:"code"`
+	RequestID string           `json:"request_id"`
+	Errors    []FieldViolation `json:"errors,omitempty"` // Ошибки полей, если запрос не прошел проверку
+}
+
+type FieldViolation struct {
+	Field string `json:"field"` // Имя поля в JSON запроса
+	// Code — стабильный код ошибки поля: required, length, invalid_characters, invalid_format, invalid_value,
+	// reserved, confusable, taken или код правила политики паролей (min_length, max_length, character_classes,
+	// entropy, contains_username, breached)
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Ошибки уровня HTTP, которые возникают до вызова сценария
+var (
+	ErrInvalidRequest   = errors.New("invalid request")
+	ErrNotFound         = errors.New("not found")
+	ErrMethodNotAllowed = errors.New("method not allowed")
+	ErrRequestTooLarge  = errors.New("request body too large")
+	ErrRequestTimeout   = errors.New("request timed out")
+	ErrOriginNotAllowed = errors.New("origin not allowed")
+	ErrMissingToken     = errors.New("missing bearer token")
+	ErrUnauthorized     = errors.New("unauthorized")
+	ErrForbidden        = errors.New("forbidden")
+	ErrRateLimited      = errors.New("too many requests")
+)
+
+type statusError struct {
+	error
+	status int
+}
+
+func (e *statusError) Unwrap() error { return e.error }
+
+// WithStatus меняет HTTP-статус ответа на err, сохраняя код: например, неверный ответ ключа доступа при входе —
+// 401, а при регистрации ключа — 400
+func WithStatus(err error, status int) error {
+	return &statusError{error: err, status: status}
+}
+
+// New собирает описание ошибки err для запроса r; неизвестные ошибки становятся server.internal без подробностей
+func New(w http.ResponseWriter, r *http.Request, err error) Problem {
+	k := lookup(err)
+	locale := i18n.FromRequest(r)
+	p := Problem{
+		Type:      typePrefix + k.code,
+		Title:     i18n.T(locale, "error."+k.code),
+		Status:    k.status,
+		Instance:  r.URL.Path,
+		Code:      k.code,
+		RequestID: RequestID(w, r),
+	}
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		p.Status = statusErr.status
+	}
+	var validationErr *model.ValidationError
+	if errors.As(err, &validationErr) {
+		for _, f := range validationErr.Fields {
+			p.Errors = append(p.Errors, FieldViolation{Field: f.Field, Code: f.Code, Message: f.Message(locale)})
+		}
+	}
+	return p
+}
+
+// Title — описание ошибки err на языке locale, то же, что в поле title ответа HTTP; для gRPC
+func Title(locale string, err error) string {
+	return i18n.T(locale, "error."+lookup(err).code)
+}
+
+// Write отвечает на err; заголовки вроде Retry-After нужно выставить до вызова
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	p := New(w, r, err)
+	if p.Status >= http.StatusInternalServerError {
+		log.Error().Err(err).Str("requestID", p.RequestID).Str("path", r.URL.Path).Msg("Request failed")
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Content-Language", i18n.FromRequest(r))
+	w.Header().Add("Vary", "Accept-Language")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// RequestID возвращает идентификатор запроса и записывает его в заголовок ответа X-Request-ID.
+// Берется уже выставленный заголовок ответа, затем X-Request-ID клиента или прокси, иначе создается новый
+func RequestID(w http.ResponseWriter, r *http.Request) string {
+	if id := w.Header().Get(RequestIDHeader); id != "" {
+		return id
+	}
+	id := r.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		id = NewRequestID()
+	}
+	w.Header().Set(RequestIDHeader, id)
+	return id
+}
+
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID не пускает в заголовки и логи произвольные строки клиента
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/i18n"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/usecase"
+)
+
+func write(t *testing.T, r *http.Request, err error) (*httptest.ResponseRecorder, Problem) {
+	rec := httptest.NewRecorder()
+	Write(rec, r, err)
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	var p Problem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+	assert.Equal(t, rec.Code, p.Status)
+	return rec, p
+}
+
+func TestWrite_MapsErrorsToStableCodes(t *testing.T) {
+	for err, want := range map[error]struct {
+		code   string
+		status int
+	}{
+		usecase.ErrInvalidCredentials:                       {"auth.invalid_credentials", http.StatusUnauthorized},
+		fmt.Errorf("wrapped: %w", usecase.ErrEmailTaken):    {"email.taken", http.StatusConflict},
+		&usecase.LoginLockedError{}:                         {"auth.login_locked", http.StatusTooManyRequests},
+		ErrMethodNotAllowed:                                 {"request.method_not_allowed", http.StatusMethodNotAllowed},
+		WithStatus(usecase.ErrInvalidWebAuthnResponse, 400): {"webauthn.invalid_response", http.StatusBadRequest},
+		errors.New("pq: connection refused"):                {"server.internal", http.StatusInternalServerError},
+	} {
+		_, p := write(t, httptest.NewRequest(http.MethodPost, "/auth/login", nil), err)
+		assert.Equal(t, want.code, p.Code, err.Error())
+		assert.Equal(t, want.status, p.Status, err.Error())
+		assert.Equal(t, typePrefix+want.code, p.Type)
+		assert.Equal(t, "/auth/login", p.Instance)
+	}
+}
+
+func TestWrite_InternalErrorHidesDetails(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Write(rec, httptest.NewRequest(http.MethodGet, "/", nil), errors.New("pq: password authentication failed for user auth"))
+	assert.NotContains(t, rec.Body.String(), "pq:")
+}
+
+func TestWrite_ValidationErrorListsFields(t *testing.T) {
+	err := &model.ValidationError{Fields: []model.FieldError{
+		*model.NewFieldError("username", model.CodeLength, 3, 32),
+		*model.NewFieldError("email", model.CodeInvalidFormat),
+	}}
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Accept-Language", "en")
+	_, p := write(t, r, err)
+	assert.Equal(t, "request.validation_failed", p.Code)
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, []FieldViolation{
+		{Field: "username", Code: model.CodeLength, Message: "username must be 3 to 32 characters"},
+		{Field: "email", Code: model.CodeInvalidFormat, Message: "email is invalid"},
+	}, p.Errors)
+
+	err.Err = usecase.ErrInvalidNewPassword
+	_, p = write(t, httptest.NewRequest(http.MethodPost, "/", nil), err)
+	assert.Equal(t, "Имя пользователя должно содержать от 3 до 32 символов", p.Errors[0].Message)
+	assert.Equal(t, "auth.invalid_new_password", p.Code, "the use case error gives the more specific code")
+	assert.Len(t, p.Errors, 2)
+}
+
+func TestWrite_LocalizesTitle(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                   "Неверное имя пользователя или пароль",
+		"ru-RU":              "Неверное имя пользователя или пароль",
+		"en-GB,en;q=0.8":     "Invalid username or password",
+		"de":                 "Неверное имя пользователя или пароль",
+		"de, en;q=0.5":       "Invalid username or password",
+		"ru;q=0.4, en;q=0.9": "Invalid username or password",
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("Accept-Language", header)
+		rec, p := write(t, r, usecase.ErrInvalidCredentials)
+		assert.Equal(t, want, p.Title, header)
+		assert.Equal(t, "Accept-Language", rec.Header().Get("Vary"))
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Accept-Language", "ru")
+	rec, p := write(t, r.WithContext(i18n.WithPreference(r.Context(), i18n.EN)), usecase.ErrInvalidCredentials)
+	assert.Equal(t, "Invalid username or password", p.Title, "the stored preference wins over the header")
+	assert.Equal(t, "en", rec.Header().Get("Content-Language"))
+}
+
+func TestRequestID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "edge-7f3a.1")
+	rec, p := write(t, r, ErrForbidden)
+	assert.Equal(t, "edge-7f3a.1", p.RequestID, "a well-formed id from the proxy is kept")
+	assert.Equal(t, "edge-7f3a.1", rec.Header().Get(RequestIDHeader))
+
+	for _, bad := range []string{"id with spaces", "id\r\nX-Injected: 1", strings.Repeat("a", 129)} {
+		r.Header.Set(RequestIDHeader, bad)
+		_, p = write(t, r, ErrForbidden)
+		assert.Len(t, p.RequestID, 32, "an unsafe id is replaced")
+	}
+
+	rec = httptest.NewRecorder()
+	rec.Header().Set(RequestIDHeader, "set-by-middleware")
+	assert.Equal(t, "set-by-middleware", RequestID(rec, httptest.NewRequest(http.MethodGet, "/", nil)))
+	assert.NotEqual(t, NewRequestID(), NewRequestID())
+}
+
+// Перевод на все языки проверяет тест каталога i18n; здесь — что у каждого кода есть заголовок
+func TestKinds_HaveTitles(t *testing.T) {
+	codes := map[string]bool{}
+	for _, k := range append([]kind{internal, validationFailed}, kindsOf()...) {
+		assert.False(t, codes[k.code], "duplicate code %s", k.code)
+		codes[k.code] = true
+		assert.NotEqual(t, "error."+k.code, i18n.T(i18n.Default, "error."+k.code), "%s has no title", k.code)
+	}
+}
+
+func kindsOf() []kind {
+	var result []kind
+	for _, k := range kinds {
+		result = append(result, k.kind)
+	}
+	return result
+}
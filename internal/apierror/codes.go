@@ -0,0 +1,82 @@
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/usecase"
+)
+
+// kind — код и статус ответа; заголовок берется из каталога i18n по ключу "error.<code>"
+type kind struct {
+	code   string
+	status int
+}
+
+// internal отвечает на все ошибки, которых нет в kinds: их текст может раскрыть детали реализации
+var internal = kind{"server.internal", http.StatusInternalServerError}
+
+// validationFailed — ошибка проверки полей без более точного кода
+var validationFailed = kind{"request.validation_failed", http.StatusBadRequest}
+
+// kinds сопоставляет ошибки с кодами; проверяются по порядку через errors.Is. Коды — часть API: не меняйте их
+var kinds = []struct {
+	err error
+	kind
+}{
+	{ErrInvalidRequest, kind{"request.invalid", http.StatusBadRequest}},
+	{ErrNotFound, kind{"request.not_found", http.StatusNotFound}},
+	{ErrMethodNotAllowed, kind{"request.method_not_allowed", http.StatusMethodNotAllowed}},
+	{ErrRequestTooLarge, kind{"request.too_large", http.StatusRequestEntityTooLarge}},
+	{ErrRequestTimeout, kind{"request.timeout", http.StatusServiceUnavailable}},
+	{ErrOriginNotAllowed, kind{"request.origin_not_allowed", http.StatusForbidden}},
+	{ErrRateLimited, kind{"request.rate_limited", http.StatusTooManyRequests}},
+	{ErrMissingToken, kind{"auth.missing_token", http.StatusUnauthorized}},
+	{ErrUnauthorized, kind{"auth.unauthorized", http.StatusUnauthorized}},
+	{ErrForbidden, kind{"auth.forbidden", http.StatusForbidden}},
+
+	{usecase.ErrUserAlreadyExists, kind{"auth.user_exists", http.StatusBadRequest}},
+	{usecase.ErrUsernameReserved, kind{"auth.username_reserved", http.StatusBadRequest}},
+	{usecase.ErrUsernameConfusable, kind{"auth.username_confusable", http.StatusBadRequest}},
+	{usecase.ErrInvalidCredentials, kind{"auth.invalid_credentials", http.StatusUnauthorized}},
+	{usecase.ErrLoginLocked, kind{"auth.login_locked", http.StatusTooManyRequests}},
+	{usecase.ErrInvalidRefreshToken, kind{"auth.invalid_refresh_token", http.StatusUnauthorized}},
+	{usecase.ErrRefreshTokenReused, kind{"auth.token_reused", http.StatusUnauthorized}},
+	{usecase.ErrInvalidAccessToken, kind{"auth.invalid_access_token", http.StatusUnauthorized}},
+	{usecase.ErrInvalidTokenData, kind{"auth.invalid_token_data", http.StatusUnauthorized}},
+	{usecase.ErrTokenRevoked, kind{"auth.token_revoked", http.StatusUnauthorized}},
+	{usecase.ErrInvalidNewPassword, kind{"auth.invalid_new_password", http.StatusBadRequest}},
+	{usecase.ErrInvalidResetToken, kind{"auth.invalid_reset_token", http.StatusBadRequest}},
+	{usecase.ErrInvalidMagicLink, kind{"auth.invalid_magic_link", http.StatusUnauthorized}},
+
+	{usecase.ErrInvalidEmail, kind{"email.invalid", http.StatusBadRequest}},
+	{usecase.ErrEmailTaken, kind{"email.taken", http.StatusConflict}},
+	{usecase.ErrEmailNotVerified, kind{"email.not_verified", http.StatusForbidden}},
+	{usecase.ErrInvalidVerificationToken, kind{"email.invalid_verification_token", http.StatusBadRequest}},
+
+	{usecase.ErrInvalidMFAToken, kind{"mfa.invalid_token", http.StatusUnauthorized}},
+	{usecase.ErrInvalidMFACode, kind{"mfa.invalid_code", http.StatusUnauthorized}},
+	{usecase.ErrTooManyMFAAttempts, kind{"mfa.too_many_attempts", http.StatusTooManyRequests}},
+	{usecase.ErrMFAAlreadyEnabled, kind{"mfa.already_enabled", http.StatusConflict}},
+	{usecase.ErrMFANotEnrolled, kind{"mfa.not_enrolled", http.StatusBadRequest}},
+	{usecase.ErrMFARequired, kind{"mfa.required", http.StatusForbidden}},
+
+	{usecase.ErrInvalidWebAuthnResponse, kind{"webauthn.invalid_response", http.StatusUnauthorized}},
+	{usecase.ErrWebAuthnNotRegistered, kind{"webauthn.not_registered", http.StatusBadRequest}},
+	{usecase.ErrWebAuthnCredentialNotFound, kind{"webauthn.credential_not_found", http.StatusNotFound}},
+}
+
+// lookup находит вид ошибки; ошибка проверки полей без известной причины — request.validation_failed
+func lookup(err error) kind {
+	for _, k := range kinds {
+		if errors.Is(err, k.err) {
+			return k.kind
+		}
+	}
+	var validationErr *model.ValidationError
+	if errors.As(err, &validationErr) {
+		return validationFailed
+	}
+	return internal
+}
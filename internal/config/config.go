@@ -0,0 +1,504 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"sstu-go-forum-auth-service/internal/cors"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/password"
+)
+
+// Config содержит настройки сервиса, читаемые из переменных окружения
+type Config struct {
+	// DatabaseURL определяет хранилище по схеме: postgres://, postgresql://, sqlite://<путь> или memory://
+	DatabaseURL string
+	// AutoMigrate применяет встроенные миграции при старте сервера
+	AutoMigrate bool
+	// JanitorInterval — период очистки устаревших записей, JanitorBatchSize — размер одной пачки удаления
+	JanitorInterval  time.Duration
+	JanitorBatchSize int
+
+	// PasswordHash — алгоритм хеширования новых паролей: argon2id или bcrypt. Хеши другого алгоритма
+	// или с другими параметрами пересчитываются при входе
+	PasswordHash string
+	BcryptCost   int
+	// Argon2Memory — память на одно хеширование в КиБ
+	Argon2Memory      int
+	Argon2Iterations  int
+	Argon2Parallelism int
+	// PasswordPeppers — секреты, которыми пароль подписывается перед хешированием, в виде версия:секрет через запятую.
+	// Они хранятся только в окружении, не в базе. Новые хеши подписываются версией PasswordPepperVersion
+	// (по умолчанию наибольшей); прежние версии нужны, пока ими подписаны хеши пользователей, не входивших после смены
+	PasswordPeppers       []string
+	PasswordPepperVersion int
+
+	// Политика новых паролей: длина в символах, минимум классов символов (строчные, заглавные, цифры, остальные)
+	// и оценки стойкости в битах; ноль отключает правило. PasswordRejectUsername запрещает пароли с именем пользователя.
+	// Значения по умолчанию берутся из password.DefaultPolicy, которой пользуются сценарии без настроенной политики
+	PasswordMinLength      int
+	PasswordMaxLength      int
+	PasswordMinClasses     int
+	PasswordMinEntropy     float64
+	PasswordRejectUsername bool
+	// ReservedUsernames — встроенный список model.ReservedUsernames и имена из RESERVED_USERNAMES,
+	// например названия соседних сервисов
+	ReservedUsernames []string
+	// BreachedPasswordsFile — индекс утекших паролей, собранный командой authctl breached-index; пусто — не проверять
+	BreachedPasswordsFile string
+
+	// Mailer выбирает способ отправки писем: smtp, log или file (письма сохраняются в MailDir)
+	Mailer       string
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+	MailDir      string
+
+	// PasswordResetURL — страница фронтенда, на которую ведет ссылка из письма
+	PasswordResetURL string
+	PasswordResetTTL time.Duration
+	// Не больше PasswordResetAccountLimit писем на аккаунт и PasswordResetIPLimit запросов с IP за PasswordResetWindow
+	PasswordResetAccountLimit int
+	PasswordResetIPLimit      int
+	PasswordResetWindow       time.Duration
+
+	// EmailVerifyURL — страница фронтенда, на которую ведет ссылка подтверждения почты
+	EmailVerifyURL string
+	EmailVerifyTTL time.Duration
+	// Не больше EmailVerifyAccountLimit повторных писем на аккаунт и EmailVerifyIPLimit запросов с IP за EmailVerifyWindow
+	EmailVerifyAccountLimit int
+	EmailVerifyIPLimit      int
+	EmailVerifyWindow       time.Duration
+	// RequireVerifiedEmail: "login" запрещает вход без подтвержденной почты (в том числе пользователям без почты),
+	// "post" пускает в сервис, но gRPC VerifyToken отклоняет их токены, и писать на форуме нельзя; пусто — не требуется
+	RequireVerifiedEmail string
+
+	// MagicLinkURL — страница фронтенда, на которую ведет ссылка для входа без пароля
+	MagicLinkURL string
+	MagicLinkTTL time.Duration
+	// Не больше MagicLinkAccountLimit писем на аккаунт и MagicLinkIPLimit запросов с IP за MagicLinkWindow
+	MagicLinkAccountLimit int
+	MagicLinkIPLimit      int
+	MagicLinkWindow       time.Duration
+
+	// UniformResponseTime — не меньше стольких длятся ответы регистрации и запросов писем по имени пользователя,
+	// чтобы время не выдавало существование аккаунта
+	UniformResponseTime time.Duration
+
+	// MaxBodyBytes — наибольший размер тела HTTP-запроса; RequestTimeout — срок ответа, после которого
+	// клиент получает 503. Сама обработка не прерывается, см. handler.Timeout. Должно быть больше UniformResponseTime
+	MaxBodyBytes   int64
+	RequestTimeout time.Duration
+	// DebugAddr — адрес служебного сервера с /debug/vars (expvar). Он не входит в публичный API и по умолчанию
+	// слушает только localhost; "off" отключает его
+	DebugAddr string
+
+	// CORSAllowedOrigins — сайты, которым браузер разрешит обращаться к API, например https://forum.example
+	// или https://*.forum.example; "*" — любой сайт, несовместимо с CORSAllowCredentials
+	CORSAllowedOrigins   []string
+	CORSAllowedHeaders   []string
+	CORSExposedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+	// CORSRoutes задает отдельным путям свою политику CORS: элементы вида
+	// "/admin/login/unlock;origins=https://admin.forum.example|https://*.ops.forum.example;credentials=false".
+	// Поля origins, methods, headers и exposed — списки через |, credentials — true или false, max_age —
+	// длительность; не указанные поля берутся из политики по умолчанию
+	CORSRoutes []string
+
+	// Первые LoginFreeAttempts ошибок входа по имени проходят без задержки, дальше задержка LoginBackoffBase
+	// удваивается. После LoginLockoutThreshold ошибок по имени или LoginIPLockoutThreshold с IP вход блокируется
+	// на LoginLockout с удвоением до LoginLockoutMax. Счетчик сбрасывается через LoginFailureWindow без ошибок
+	LoginFreeAttempts       int
+	LoginBackoffBase        time.Duration
+	LoginLockoutThreshold   int
+	LoginIPLockoutThreshold int
+	LoginLockout            time.Duration
+	LoginLockoutMax         time.Duration
+	LoginFailureWindow      time.Duration
+
+	// RateLimitBackend: memory — лимиты запросов в памяти процесса, database — в таблице rate_limits хранилища
+	// DATABASE_URL, общие для всех реплик
+	RateLimitBackend string
+	// Не больше RateLimitLoginIP запросов /login с IP (token bucket: всплеск до лимита, дальше равномерно)
+	// и RateLimitLoginUsername на одно имя пользователя за RateLimitLoginWindow
+	RateLimitLoginIP       int
+	RateLimitLoginUsername int
+	RateLimitLoginWindow   time.Duration
+	// Не больше RateLimitRegisterIP регистраций с IP за RateLimitRegisterWindow
+	RateLimitRegisterIP     int
+	RateLimitRegisterWindow time.Duration
+	// Не больше RateLimitRefreshIP запросов /refresh с IP за RateLimitRefreshWindow
+	RateLimitRefreshIP     int
+	RateLimitRefreshWindow time.Duration
+	// Не больше RateLimitGRPC вызовов gRPC от клиента (метаданные x-client-id или адрес) за RateLimitGRPCWindow
+	RateLimitGRPC       int
+	RateLimitGRPCWindow time.Duration
+	// Не больше RateLimitCredentialsIP запросов с IP за RateLimitCredentialsWindow к остальным точкам, которые
+	// проверяют пароль, код или секретную ссылку (см. handler.API.CredentialsIPLimit), и к gRPC ChangePassword
+	RateLimitCredentialsIP     int
+	RateLimitCredentialsWindow time.Duration
+
+	// MFARequiredRoles — роли, которым вход разрешен только со вторым фактором, например ADMIN
+	MFARequiredRoles []string
+	// MFAIssuer — название сервиса в приложении-аутентификаторе
+	MFAIssuer string
+	// Не больше MFAMaxAttempts проверок кода на пользователя за MFAAttemptWindow
+	MFAMaxAttempts   int
+	MFAAttemptWindow time.Duration
+
+	// WebAuthnRPID — домен, к которому привязываются ключи доступа; менять его после регистрации ключей нельзя
+	WebAuthnRPID   string
+	WebAuthnRPName string
+	// WebAuthnOrigins — origin фронтенда, с которого браузер выполняет церемонии, например https://forum.example
+	WebAuthnOrigins    []string
+	WebAuthnSessionTTL time.Duration
+}
+
+const (
+	RequireVerifiedEmailForLogin = "login"
+	RequireVerifiedEmailForPost  = "post"
+)
+
+const (
+	RateLimitBackendMemory   = "memory"
+	RateLimitBackendDatabase = "database"
+)
+
+func Load() Config {
+	return Config{
+		DatabaseURL: os.Getenv("DATABASE_URL"),
+		AutoMigrate: getBool("AUTO_MIGRATE", false),
+
+		JanitorInterval:  getDuration("JANITOR_INTERVAL", 10*time.Minute),
+		JanitorBatchSize: getInt("JANITOR_BATCH_SIZE", 1000),
+
+		PasswordHash:      getString("PASSWORD_HASH", "argon2id"),
+		BcryptCost:        getInt("BCRYPT_COST", 10),
+		Argon2Memory:      getInt("ARGON2_MEMORY", 19*1024),
+		Argon2Iterations:  getInt("ARGON2_ITERATIONS", 2),
+		Argon2Parallelism: getInt("ARGON2_PARALLELISM", 1),
+
+		PasswordPeppers:       getList("PASSWORD_PEPPERS", nil),
+		PasswordPepperVersion: getInt("PASSWORD_PEPPER_VERSION", 0),
+
+		PasswordMinLength:      getInt("PASSWORD_MIN_LENGTH", password.DefaultPolicy.MinLength),
+		PasswordMaxLength:      getInt("PASSWORD_MAX_LENGTH", password.DefaultPolicy.MaxLength),
+		PasswordMinClasses:     getInt("PASSWORD_MIN_CLASSES", password.DefaultPolicy.MinClasses),
+		PasswordMinEntropy:     getFloat("PASSWORD_MIN_ENTROPY", password.DefaultPolicy.MinEntropy),
+		PasswordRejectUsername: getBool("PASSWORD_REJECT_USERNAME", password.DefaultPolicy.RejectUsername),
+		BreachedPasswordsFile:  os.Getenv("BREACHED_PASSWORDS_FILE"),
+		ReservedUsernames:      append(slices.Clone(model.ReservedUsernames), getList("RESERVED_USERNAMES", nil)...),
+
+		Mailer:       getString("MAILER", "log"),
+		SMTPAddr:     os.Getenv("SMTP_ADDR"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		MailFrom:     getString("MAIL_FROM", "no-reply@localhost"),
+		MailDir:      getString("MAIL_DIR", "mail"),
+
+		PasswordResetURL:          getString("PASSWORD_RESET_URL", "http://localhost:3000/password/reset"),
+		PasswordResetTTL:          getDuration("PASSWORD_RESET_TTL", 30*time.Minute),
+		PasswordResetAccountLimit: getInt("PASSWORD_RESET_ACCOUNT_LIMIT", 3),
+		PasswordResetIPLimit:      getInt("PASSWORD_RESET_IP_LIMIT", 20),
+		PasswordResetWindow:       getDuration("PASSWORD_RESET_WINDOW", time.Hour),
+
+		EmailVerifyURL:          getString("EMAIL_VERIFY_URL", "http://localhost:3000/email/verify"),
+		EmailVerifyTTL:          getDuration("EMAIL_VERIFY_TTL", 24*time.Hour),
+		EmailVerifyAccountLimit: getInt("EMAIL_VERIFY_ACCOUNT_LIMIT", 3),
+		EmailVerifyIPLimit:      getInt("EMAIL_VERIFY_IP_LIMIT", 20),
+		EmailVerifyWindow:       getDuration("EMAIL_VERIFY_WINDOW", time.Hour),
+		RequireVerifiedEmail:    os.Getenv("REQUIRE_VERIFIED_EMAIL"),
+
+		MagicLinkURL:          getString("MAGIC_LINK_URL", "http://localhost:3000/login/magic-link"),
+		MagicLinkTTL:          getDuration("MAGIC_LINK_TTL", 15*time.Minute),
+		MagicLinkAccountLimit: getInt("MAGIC_LINK_ACCOUNT_LIMIT", 3),
+		MagicLinkIPLimit:      getInt("MAGIC_LINK_IP_LIMIT", 20),
+		MagicLinkWindow:       getDuration("MAGIC_LINK_WINDOW", time.Hour),
+
+		UniformResponseTime: getDuration("UNIFORM_RESPONSE_TIME", 250*time.Millisecond),
+
+		MaxBodyBytes:   int64(getInt("MAX_BODY_BYTES", 1<<20)),
+		RequestTimeout: getDuration("REQUEST_TIMEOUT", 15*time.Second),
+		DebugAddr:      getString("DEBUG_ADDR", "127.0.0.1:8082"),
+
+		CORSAllowedOrigins: getList("CORS_ALLOWED_ORIGINS", []string{"http://localhost:3000"}),
+		CORSAllowedHeaders: getList("CORS_ALLOWED_HEADERS",
+			[]string{"Content-Type", "Authorization", "Accept-Language", "X-Request-ID"}),
+		CORSExposedHeaders: getList("CORS_EXPOSED_HEADERS",
+			[]string{"Retry-After", "X-Request-ID", "Content-Language", "Deprecation", "Link"}),
+		CORSAllowCredentials: getBool("CORS_ALLOW_CREDENTIALS", false),
+		CORSMaxAge:           getDuration("CORS_MAX_AGE", 10*time.Minute),
+		CORSRoutes:           getList("CORS_ROUTES", nil),
+
+		LoginFreeAttempts:       getInt("LOGIN_FREE_ATTEMPTS", 3),
+		LoginBackoffBase:        getDuration("LOGIN_BACKOFF_BASE", time.Second),
+		LoginLockoutThreshold:   getInt("LOGIN_LOCKOUT_THRESHOLD", 10),
+		LoginIPLockoutThreshold: getInt("LOGIN_IP_LOCKOUT_THRESHOLD", 100),
+		LoginLockout:            getDuration("LOGIN_LOCKOUT", 15*time.Minute),
+		LoginLockoutMax:         getDuration("LOGIN_LOCKOUT_MAX", 24*time.Hour),
+		LoginFailureWindow:      getDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+
+		RateLimitBackend:           getString("RATE_LIMIT_BACKEND", RateLimitBackendMemory),
+		RateLimitLoginIP:           getInt("RATE_LIMIT_LOGIN_IP", 30),
+		RateLimitLoginUsername:     getInt("RATE_LIMIT_LOGIN_USERNAME", 10),
+		RateLimitLoginWindow:       getDuration("RATE_LIMIT_LOGIN_WINDOW", time.Minute),
+		RateLimitRegisterIP:        getInt("RATE_LIMIT_REGISTER_IP", 10),
+		RateLimitRegisterWindow:    getDuration("RATE_LIMIT_REGISTER_WINDOW", time.Hour),
+		RateLimitRefreshIP:         getInt("RATE_LIMIT_REFRESH_IP", 60),
+		RateLimitRefreshWindow:     getDuration("RATE_LIMIT_REFRESH_WINDOW", time.Minute),
+		RateLimitGRPC:              getInt("RATE_LIMIT_GRPC", 1000),
+		RateLimitGRPCWindow:        getDuration("RATE_LIMIT_GRPC_WINDOW", time.Second),
+		RateLimitCredentialsIP:     getInt("RATE_LIMIT_CREDENTIALS_IP", 20),
+		RateLimitCredentialsWindow: getDuration("RATE_LIMIT_CREDENTIALS_WINDOW", time.Minute),
+
+		MFARequiredRoles: getList("MFA_REQUIRED_ROLES", nil),
+		MFAIssuer:        getString("MFA_ISSUER", "SSTU Forum"),
+		MFAMaxAttempts:   getInt("MFA_MAX_ATTEMPTS", 5),
+		MFAAttemptWindow: getDuration("MFA_ATTEMPT_WINDOW", 5*time.Minute),
+
+		WebAuthnRPID:       getString("WEBAUTHN_RP_ID", "localhost"),
+		WebAuthnRPName:     getString("WEBAUTHN_RP_NAME", "SSTU Forum"),
+		WebAuthnOrigins:    getList("WEBAUTHN_ORIGINS", []string{"http://localhost:3000"}),
+		WebAuthnSessionTTL: getDuration("WEBAUTHN_SESSION_TTL", 5*time.Minute),
+	}
+}
+
+// minPepperLength — минимальная длина перца в байтах
+const minPepperLength = 16
+
+// PasswordHasher собирает хешер паролей из PASSWORD_HASH, параметров алгоритмов и перца
+func (c Config) PasswordHasher() (password.PasswordHasher, error) {
+	hasher, err := password.ForAlgorithm(c.PasswordHash,
+		password.Argon2id{
+			Memory:      uint32(c.Argon2Memory),
+			Iterations:  uint32(c.Argon2Iterations),
+			Parallelism: uint8(c.Argon2Parallelism),
+			SaltLength:  password.DefaultArgon2id.SaltLength,
+			KeyLength:   password.DefaultArgon2id.KeyLength,
+		},
+		password.Bcrypt{Cost: c.BcryptCost})
+	if err != nil || len(c.PasswordPeppers) == 0 {
+		return hasher, err
+	}
+
+	peppers := map[int][]byte{}
+	current := c.PasswordPepperVersion
+	for _, entry := range c.PasswordPeppers {
+		versionStr, secret, _ := strings.Cut(entry, ":")
+		version, err := strconv.Atoi(versionStr)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid PASSWORD_PEPPERS entry: version must be a positive number, got %q", versionStr)
+		}
+		if len(secret) < minPepperLength {
+			return nil, fmt.Errorf("pepper version %d is shorter than %d bytes", version, minPepperLength)
+		}
+		if _, ok := peppers[version]; ok {
+			return nil, fmt.Errorf("pepper version %d is configured twice", version)
+		}
+		peppers[version] = []byte(secret)
+		if c.PasswordPepperVersion == 0 {
+			current = max(current, version)
+		}
+	}
+	return password.WithPepper(hasher, peppers, current)
+}
+
+// PasswordPolicy собирает политику паролей; индекс утекших паролей остается открытым до конца работы
+func (c Config) PasswordPolicy() (*password.Policy, error) {
+	policy := &password.Policy{
+		MinLength:      c.PasswordMinLength,
+		MaxLength:      c.PasswordMaxLength,
+		MinClasses:     c.PasswordMinClasses,
+		MinEntropy:     c.PasswordMinEntropy,
+		RejectUsername: c.PasswordRejectUsername,
+	}
+	if c.BreachedPasswordsFile != "" {
+		index, err := password.OpenBreachedIndex(c.BreachedPasswordsFile)
+		if err != nil {
+			return nil, fmt.Errorf("open breached password index: %w", err)
+		}
+		policy.Breached = index
+	}
+	return policy, nil
+}
+
+// ValidateRateLimits проверяет, что у каждого ограничения частоты лимит и окно больше нуля
+// и что на запрос приходится не меньше миллисекунды: отключить ограничение нулем нельзя,
+// лимитеры в БД считают время в миллисекундах, а конструкторы ratelimit на таких значениях паникуют
+func (c Config) ValidateRateLimits() error {
+	limits := []struct {
+		name   string
+		limit  int
+		window time.Duration
+	}{
+		{"PASSWORD_RESET_ACCOUNT_LIMIT", c.PasswordResetAccountLimit, c.PasswordResetWindow},
+		{"PASSWORD_RESET_IP_LIMIT", c.PasswordResetIPLimit, c.PasswordResetWindow},
+		{"EMAIL_VERIFY_ACCOUNT_LIMIT", c.EmailVerifyAccountLimit, c.EmailVerifyWindow},
+		{"EMAIL_VERIFY_IP_LIMIT", c.EmailVerifyIPLimit, c.EmailVerifyWindow},
+		{"MAGIC_LINK_ACCOUNT_LIMIT", c.MagicLinkAccountLimit, c.MagicLinkWindow},
+		{"MAGIC_LINK_IP_LIMIT", c.MagicLinkIPLimit, c.MagicLinkWindow},
+		{"RATE_LIMIT_LOGIN_IP", c.RateLimitLoginIP, c.RateLimitLoginWindow},
+		{"RATE_LIMIT_LOGIN_USERNAME", c.RateLimitLoginUsername, c.RateLimitLoginWindow},
+		{"RATE_LIMIT_REGISTER_IP", c.RateLimitRegisterIP, c.RateLimitRegisterWindow},
+		{"RATE_LIMIT_REFRESH_IP", c.RateLimitRefreshIP, c.RateLimitRefreshWindow},
+		{"RATE_LIMIT_GRPC", c.RateLimitGRPC, c.RateLimitGRPCWindow},
+		{"RATE_LIMIT_CREDENTIALS_IP", c.RateLimitCredentialsIP, c.RateLimitCredentialsWindow},
+		{"MFA_MAX_ATTEMPTS", c.MFAMaxAttempts, c.MFAAttemptWindow},
+	}
+	for _, l := range limits {
+		if l.limit <= 0 || l.window <= 0 {
+			return fmt.Errorf("%s must be positive and have a positive window, got %d per %s", l.name, l.limit, l.window)
+		}
+		if l.window/time.Duration(l.limit) < time.Millisecond {
+			return fmt.Errorf("%s allows at most 1000 requests per second, got %d per %s", l.name, l.limit, l.window)
+		}
+	}
+	return nil
+}
+
+// ValidateRequireVerifiedEmail отклоняет неизвестное значение REQUIRE_VERIFIED_EMAIL: опечатка вроде "ture"
+// иначе молча отключила бы проверку почты
+func (c Config) ValidateRequireVerifiedEmail() error {
+	switch c.RequireVerifiedEmail {
+	case "", RequireVerifiedEmailForLogin, RequireVerifiedEmailForPost:
+		return nil
+	}
+	return fmt.Errorf("REQUIRE_VERIFIED_EMAIL must be empty, %q or %q, got %q",
+		RequireVerifiedEmailForLogin, RequireVerifiedEmailForPost, c.RequireVerifiedEmail)
+}
+
+// CORSPolicies собирает политику CORS по умолчанию и политики путей из CORSRoutes;
+// пути указываются без префикса версии API
+func (c Config) CORSPolicies() (*cors.Policy, map[string]*cors.Policy, error) {
+	def := &cors.Policy{
+		AllowedOrigins:   c.CORSAllowedOrigins,
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders:   c.CORSAllowedHeaders,
+		ExposedHeaders:   c.CORSExposedHeaders,
+		AllowCredentials: c.CORSAllowCredentials,
+		MaxAge:           c.CORSMaxAge,
+	}
+	if err := def.Validate(); err != nil {
+		return nil, nil, err
+	}
+	routes := map[string]*cors.Policy{}
+	for _, entry := range c.CORSRoutes {
+		path, policy, err := routePolicy(*def, entry)
+		if err != nil {
+			return nil, nil, fmt.Errorf("CORS_ROUTES entry %q: %w", entry, err)
+		}
+		if err := policy.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("CORS_ROUTES %s: %w", path, err)
+		}
+		routes[path] = policy
+	}
+	return def, routes, nil
+}
+
+// routePolicy разбирает элемент CORSRoutes, начиная с копии политики по умолчанию
+func routePolicy(policy cors.Policy, entry string) (string, *cors.Policy, error) {
+	fields := strings.Split(entry, ";")
+	path := strings.TrimSpace(fields[0])
+	if !strings.HasPrefix(path, "/") {
+		return "", nil, errors.New("expected /path;field=value;...")
+	}
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return "", nil, fmt.Errorf("expected field=value, got %q", field)
+		}
+		var err error
+		switch key {
+		case "origins":
+			policy.AllowedOrigins = splitValues(value)
+		case "methods":
+			policy.AllowedMethods = splitValues(value)
+		case "headers":
+			policy.AllowedHeaders = splitValues(value)
+		case "exposed":
+			policy.ExposedHeaders = splitValues(value)
+		case "credentials":
+			policy.AllowCredentials, err = strconv.ParseBool(value)
+		case "max_age":
+			policy.MaxAge, err = time.ParseDuration(value)
+		default:
+			return "", nil, fmt.Errorf("unknown field %q", key)
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return path, &policy, nil
+}
+
+// splitValues разбирает список через |; пустая строка — пустой список
+func splitValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, "|") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func getString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// getList разбирает значение через запятую, пропуская пустые элементы
+func getList(key string, def []string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	if list == nil {
+		return def
+	}
+	return list
+}
+
+func getBool(key string, def bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func getInt(key string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func getFloat(key string, def float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func getDuration(key string, def time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/cors"
+	"sstu-go-forum-auth-service/internal/password"
+)
+
+func TestCORSPolicies_RouteOverridesWholePolicy(t *testing.T) {
+	c := Config{
+		CORSAllowedOrigins:   []string{"https://forum.example"},
+		CORSAllowedHeaders:   []string{"Content-Type", "Authorization"},
+		CORSExposedHeaders:   []string{"X-Request-ID"},
+		CORSAllowCredentials: true,
+		CORSMaxAge:           10 * time.Minute,
+		CORSRoutes: []string{
+			"/admin/login/unlock;origins=https://admin.example|https://*.ops.example;methods=POST;credentials=false;max_age=0s;exposed=",
+			"/login;headers=Content-Type",
+		},
+	}
+	def, routes, err := c.CORSPolicies()
+	require.NoError(t, err)
+	assert.Equal(t, &cors.Policy{
+		AllowedOrigins: []string{"https://admin.example", "https://*.ops.example"},
+		AllowedMethods: []string{http.MethodPost},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+	}, routes["/admin/login/unlock"])
+
+	login := *def
+	login.AllowedHeaders = []string{"Content-Type"}
+	assert.Equal(t, &login, routes["/login"], "fields that are not set come from the default policy")
+}
+
+func TestCORSPolicies_InvalidRoutes(t *testing.T) {
+	for _, entry := range []string{
+		"admin;origins=https://admin.example",
+		"/admin;origins",
+		"/admin;origin=https://admin.example",
+		"/admin;credentials=maybe",
+		"/admin;max_age=ten",
+		"/admin;origins=admin.example",
+		"/admin;origins=*;credentials=true",
+	} {
+		_, _, err := Config{CORSAllowedOrigins: []string{"https://forum.example"}, CORSRoutes: []string{entry}}.CORSPolicies()
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestValidateRateLimits(t *testing.T) {
+	c := Load()
+	require.NoError(t, c.ValidateRateLimits())
+
+	c.RateLimitGRPC = 0
+	assert.ErrorContains(t, c.ValidateRateLimits(), "RATE_LIMIT_GRPC")
+	c.RateLimitGRPC, c.RateLimitGRPCWindow = 2000, time.Second
+	assert.ErrorContains(t, c.ValidateRateLimits(), "at most 1000 requests per second")
+}
+
+func TestValidateRequireVerifiedEmail(t *testing.T) {
+	for _, value := range []string{"", RequireVerifiedEmailForLogin, RequireVerifiedEmailForPost} {
+		assert.NoError(t, Config{RequireVerifiedEmail: value}.ValidateRequireVerifiedEmail(), value)
+	}
+	for _, value := range []string{"ture", "true", "Login"} {
+		assert.Error(t, Config{RequireVerifiedEmail: value}.ValidateRequireVerifiedEmail(), value)
+	}
+}
+
+func TestPasswordPolicy_DefaultsMatchDefaultPolicy(t *testing.T) {
+	for _, name := range []string{"PASSWORD_MIN_LENGTH", "PASSWORD_MAX_LENGTH", "PASSWORD_MIN_CLASSES", "PASSWORD_MIN_ENTROPY",
+		"PASSWORD_REJECT_USERNAME", "BREACHED_PASSWORDS_FILE"} {
+		t.Setenv(name, "")
+	}
+	policy, err := Load().PasswordPolicy()
+	require.NoError(t, err)
+	assert.Equal(t, password.DefaultPolicy, policy)
+}
@@ -0,0 +1,180 @@
+// Package cors решает, каким сайтам браузер разрешит обращаться к API, и выставляет заголовки CORS
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy — политика CORS для группы маршрутов
+type Policy struct {
+	// AllowedOrigins — разрешенные origin вида https://forum.example. Шаблон https://*.forum.example разрешает
+	// поддомены любого уровня, но не сам forum.example; порт должен совпадать. "*" разрешает любой origin
+	// и несовместим с AllowCredentials
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders — заголовки ответа, которые браузер покажет скрипту помимо стандартных
+	ExposedHeaders []string
+	// AllowCredentials разрешает браузеру отправлять cookie и отдавать скрипту ответы на такие запросы
+	AllowCredentials bool
+	// MaxAge — сколько браузер может не повторять предварительный запрос; 0 — на усмотрение браузера
+	MaxAge time.Duration
+}
+
+var ErrCredentialsWithAnyOrigin = errors.New("cors: credentials cannot be allowed for any origin")
+
+// Validate проверяет шаблоны origin, чтобы опечатка в настройках не открыла API всем или не закрыла его
+func (p *Policy) Validate() error {
+	for _, pattern := range p.AllowedOrigins {
+		if pattern == "*" {
+			if p.AllowCredentials {
+				return ErrCredentialsWithAnyOrigin
+			}
+			continue
+		}
+		if err := validatePattern(pattern); err != nil {
+			return fmt.Errorf("cors: origin %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func validatePattern(pattern string) error {
+	scheme, host, ok := strings.Cut(pattern, "://")
+	if !ok || scheme != "http" && scheme != "https" {
+		return errors.New("scheme must be http or https")
+	}
+	if strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+		return errors.New("only a leading *. wildcard is supported")
+	}
+	u, err := url.Parse(scheme + "://" + strings.Replace(host, "*.", "wildcard.", 1))
+	if err != nil {
+		return err
+	}
+	if u.Host == "" || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("origin must be scheme://host[:port] without a path")
+	}
+	return nil
+}
+
+// AllowOrigin сообщает, разрешен ли origin из заголовка Origin
+func (p *Policy) AllowOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	origin = strings.ToLower(origin)
+	for _, pattern := range p.AllowedOrigins {
+		if matchOrigin(strings.ToLower(pattern), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" || pattern == origin {
+		return true
+	}
+	scheme, host, ok := strings.Cut(pattern, "://")
+	if !ok || !strings.HasPrefix(host, "*.") {
+		return false
+	}
+	originScheme, originHost, ok := strings.Cut(origin, "://")
+	if !ok || originScheme != scheme {
+		return false
+	}
+	sub, ok := strings.CutSuffix(originHost, host[1:])
+	return ok && validSubdomain(sub)
+}
+
+// validSubdomain не дает подставить в шаблон что-то кроме меток домена, например "evil.com/"
+func validSubdomain(sub string) bool {
+	if sub == "" {
+		return false
+	}
+	for _, label := range strings.Split(sub, ".") {
+		if label == "" || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// IsPreflight сообщает, что r — предварительный запрос браузера, а не обычный OPTIONS
+func IsPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// Actual выставляет заголовки CORS ответа на обычный запрос; с неразрешенного origin ответ остается без них,
+// и браузер не отдаст его скрипту
+func (p *Policy) Actual(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	if !p.AllowOrigin(origin) {
+		return
+	}
+	p.allowOrigin(w, origin)
+	if len(p.ExposedHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+	}
+}
+
+// Preflight выставляет заголовки ответа на предварительный запрос и возвращает true, если политика разрешает
+// origin, метод и заголовки запроса; иначе заголовки CORS не выставляются
+func (p *Policy) Preflight(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Add("Vary", "Origin")
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+	origin := r.Header.Get("Origin")
+	if !p.AllowOrigin(origin) ||
+		!slices.Contains(p.AllowedMethods, r.Header.Get("Access-Control-Request-Method")) ||
+		!p.allowHeaders(r.Header.Get("Access-Control-Request-Headers")) {
+		return false
+	}
+	p.allowOrigin(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(p.AllowedMethods, ", "))
+	if len(p.AllowedHeaders) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(p.AllowedHeaders, ", "))
+	}
+	if p.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(p.MaxAge.Seconds())))
+	}
+	return true
+}
+
+func (p *Policy) allowOrigin(w http.ResponseWriter, origin string) {
+	if slices.Contains(p.AllowedOrigins, "*") && !p.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if p.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (p *Policy) allowHeaders(requested string) bool {
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if !slices.ContainsFunc(p.AllowedHeaders, func(allowed string) bool { return strings.EqualFold(allowed, header) }) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowOrigin(t *testing.T) {
+	p := &Policy{AllowedOrigins: []string{"https://forum.example", "https://*.forum.example", "http://localhost:3000"}}
+	for origin, want := range map[string]bool{
+		"https://forum.example":           true,
+		"HTTPS://Forum.Example":           true,
+		"https://api.forum.example":       true,
+		"https://a.b.forum.example":       true,
+		"http://localhost:3000":           true,
+		"http://forum.example":            false,
+		"https://evilforum.example":       false,
+		"https://forum.example.evil.com":  false,
+		"https://api.forum.example:8443":  false,
+		"https://evil.com/.forum.example": false,
+		"https://-bad.forum.example":      false,
+		"https://a..forum.example":        false,
+		"http://localhost:3001":           false,
+		"null":                            false,
+		"":                                false,
+	} {
+		assert.Equal(t, want, p.AllowOrigin(origin), origin)
+	}
+	assert.True(t, (&Policy{AllowedOrigins: []string{"*"}}).AllowOrigin("https://any.example"))
+}
+
+func TestValidate(t *testing.T) {
+	for _, origins := range [][]string{
+		{"https://forum.example"},
+		{"https://*.forum.example", "http://localhost:3000"},
+		{"*"},
+	} {
+		assert.NoError(t, (&Policy{AllowedOrigins: origins}).Validate(), origins)
+	}
+	for _, origin := range []string{"forum.example", "ftp://forum.example", "https://forum.example/", "https://api.*.forum.example", "https://*"} {
+		assert.Error(t, (&Policy{AllowedOrigins: []string{origin}}).Validate(), origin)
+	}
+	assert.ErrorIs(t, (&Policy{AllowedOrigins: []string{"*"}, AllowCredentials: true}).Validate(), ErrCredentialsWithAnyOrigin)
+}
+
+func TestActual(t *testing.T) {
+	p := &Policy{
+		AllowedOrigins:   []string{"https://forum.example"},
+		ExposedHeaders:   []string{"Retry-After", "X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           time.Minute,
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Origin", "https://forum.example")
+	rec := httptest.NewRecorder()
+	p.Actual(rec, r)
+	assert.Equal(t, "https://forum.example", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Retry-After, X-Request-ID", rec.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", rec.Header().Get("Vary"))
+	assert.Empty(t, rec.Header().Get("Access-Control-Max-Age"), "max-age is only for preflights")
+
+	r.Header.Set("Origin", "https://evil.example")
+	rec = httptest.NewRecorder()
+	p.Actual(rec, r)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Origin", rec.Header().Get("Vary"), "caches must not reuse the response for another origin")
+
+	anyOrigin := &Policy{AllowedOrigins: []string{"*"}}
+	rec = httptest.NewRecorder()
+	anyOrigin.Actual(rec, r)
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+}
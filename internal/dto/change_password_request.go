@@ -0,0 +1,8 @@
+package dto
+
+// ChangePasswordRequest представляет запрос на смену пароля
+// @Description Структура запроса для смены пароля авторизованного пользователя
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"` // Текущий пароль
+	NewPassword     string `json:"new_password"`     // Новый пароль
+}
@@ -0,0 +1,20 @@
+package dto
+
+// VerifyEmailRequest представляет запрос на подтверждение почты токеном из письма
+// @Description Структура запроса для подтверждения адреса электронной почты
+type VerifyEmailRequest struct {
+	Token string `json:"token"` // Токен из письма
+}
+
+// ResendVerificationRequest представляет запрос на повторную отправку письма с подтверждением
+// @Description Структура запроса для повторной отправки письма по имени пользователя
+type ResendVerificationRequest struct {
+	Username string `json:"username"` // Имя пользователя
+}
+
+// ChangeEmailRequest представляет запрос на смену адреса электронной почты
+// @Description Структура запроса для смены почты с подтверждением текущим паролем
+type ChangeEmailRequest struct {
+	CurrentPassword string `json:"current_password"` // Текущий пароль
+	Email           string `json:"email"`            // Новый адрес электронной почты
+}
@@ -0,0 +1,7 @@
+package dto
+
+// ChangeLocaleRequest представляет запрос на смену языка писем и ответов
+// @Description Структура запроса для смены языка; пустой язык возвращает выбор по Accept-Language
+type ChangeLocaleRequest struct {
+	Locale string `json:"locale"` // Язык: ru или en
+}
@@ -5,4 +5,12 @@ package dto
 type LoginRequest struct {
 	Username string `json:"username"` // Имя пользователя
 	Password string `json:"password"` // Пароль пользователя
+	// IP клиента заполняет handler: по нему считаются неудачные попытки
+	IP string `json:"-"`
+}
+
+// UnlockLoginRequest представляет запрос администратора на снятие блокировки входа
+// @Description Структура запроса для снятия блокировки входа по имени пользователя
+type UnlockLoginRequest struct {
+	Username string `json:"username"` // Имя пользователя
 }
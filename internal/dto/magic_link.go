@@ -0,0 +1,21 @@
+package dto
+
+// MagicLinkRequest представляет запрос на отправку ссылки для входа без пароля
+// @Description Структура запроса ссылки для входа по имени пользователя
+type MagicLinkRequest struct {
+	Username string `json:"username"` // Имя пользователя
+}
+
+// MagicLinkResponse возвращается браузеру, запросившему ссылку
+// @Description Ответ с nonce, который нужно сохранить до перехода по ссылке
+type MagicLinkResponse struct {
+	Message string `json:"message"`
+	Nonce   string `json:"nonce"` // Предъявляется вместе с токеном из письма: ссылка сработает только в этом браузере
+}
+
+// MagicLinkLoginRequest представляет вход по ссылке из письма
+// @Description Структура запроса для обмена токена из письма на пару токенов
+type MagicLinkLoginRequest struct {
+	Token string `json:"token"` // Токен из письма
+	Nonce string `json:"nonce"` // Nonce из ответа /login/magic-link
+}
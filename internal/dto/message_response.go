@@ -0,0 +1,7 @@
+package dto
+
+// MessageResponse представляет ответ, содержащий только сообщение
+// @Description Структура ответа с сообщением о результате операции
+type MessageResponse struct {
+	Message string `json:"message"` // Сообщение о результате
+}
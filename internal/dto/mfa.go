@@ -0,0 +1,68 @@
+package dto
+
+import "encoding/json"
+
+// MFAChallengeResponse возвращается вместо токенов, если для входа нужен второй фактор
+// @Description Ответ первого шага входа с токеном для подтверждения кодом TOTP
+type MFAChallengeResponse struct {
+	MFAToken           string `json:"mfa_token"`           // Токен для /login/mfa, действует 5 минут
+	EnrollmentRequired bool   `json:"enrollment_required"` // Роль требует 2FA: сначала привяжите TOTP через /login/mfa/enroll
+}
+
+// MFALoginRequest представляет второй шаг входа
+// @Description Структура запроса для завершения входа кодом TOTP
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token"` // Токен из ответа /login
+	Code     string `json:"code"`      // Код из приложения-аутентификатора
+	// Код восстановления вместо кода TOTP, если доступа к приложению нет
+	RecoveryCode string `json:"recovery_code,omitempty"`
+	// Сессия из /login/mfa/webauthn/begin и ответ ключа доступа вместо кода TOTP
+	WebAuthnSession    string          `json:"webauthn_session,omitempty"`
+	WebAuthnCredential json.RawMessage `json:"webauthn_credential,omitempty" swaggertype:"object"`
+}
+
+// MFAEnrollRequest представляет запрос на привязку TOTP во время входа
+// @Description Структура запроса для привязки TOTP, если роль требует 2FA
+type MFAEnrollRequest struct {
+	MFAToken string `json:"mfa_token"` // Токен из ответа /login
+}
+
+// TOTPEnrollRequest представляет запрос на привязку TOTP
+// @Description Структура запроса для привязки TOTP из профиля
+type TOTPEnrollRequest struct {
+	CurrentPassword string `json:"current_password"` // Текущий пароль
+}
+
+// TOTPEnrollResponse содержит секрет для приложения-аутентификатора
+// @Description Секрет TOTP и otpauth ссылка для QR-кода
+type TOTPEnrollResponse struct {
+	Secret     string `json:"secret"`      // Секрет в base32 для ручного ввода
+	OTPAuthURI string `json:"otpauth_uri"` // Ссылка otpauth:// для QR-кода
+	// Одноразовые коды восстановления; показываются только один раз
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TOTPCodeRequest представляет запрос с кодом TOTP
+// @Description Структура запроса с кодом из приложения-аутентификатора
+type TOTPCodeRequest struct {
+	Code string `json:"code"` // Код из приложения-аутентификатора
+}
+
+// DisableTOTPRequest представляет запрос на отключение TOTP
+// @Description Структура запроса для отключения второго фактора
+type DisableTOTPRequest struct {
+	CurrentPassword string `json:"current_password"` // Текущий пароль
+	Code            string `json:"code"`             // Код из приложения-аутентификатора
+}
+
+// RegenerateRecoveryCodesRequest представляет запрос на новые коды восстановления
+// @Description Структура запроса для замены кодов восстановления
+type RegenerateRecoveryCodesRequest struct {
+	CurrentPassword string `json:"current_password"` // Текущий пароль
+}
+
+// RecoveryCodesResponse содержит новые коды восстановления
+// @Description Новый набор одноразовых кодов восстановления; прежние коды больше не действуют
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // Коды восстановления; показываются только один раз
+}
@@ -0,0 +1,14 @@
+package dto
+
+// ForgotPasswordRequest представляет запрос на отправку ссылки для сброса пароля
+// @Description Структура запроса для восстановления пароля по имени пользователя
+type ForgotPasswordRequest struct {
+	Username string `json:"username"` // Имя пользователя
+}
+
+// ResetPasswordRequest представляет запрос на установку нового пароля по токену из письма
+// @Description Структура запроса для сброса пароля одноразовым токеном
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`        // Токен из письма
+	NewPassword string `json:"new_password"` // Новый пароль
+}
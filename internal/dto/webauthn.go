@@ -0,0 +1,44 @@
+package dto
+
+import "encoding/json"
+
+// WebAuthnBeginResponse содержит параметры церемонии WebAuthn для navigator.credentials
+// @Description Параметры для navigator.credentials.create или navigator.credentials.get и токен сессии церемонии
+type WebAuthnBeginResponse struct {
+	SessionToken string `json:"session_token"`                // Токен для завершения церемонии
+	Options      any    `json:"options" swaggertype:"object"` // PublicKeyCredentialCreationOptions или PublicKeyCredentialRequestOptions
+}
+
+// WebAuthnBeginRegistrationRequest представляет начало регистрации ключа доступа
+// @Description Структура запроса с текущим паролем для начала регистрации ключа доступа
+type WebAuthnBeginRegistrationRequest struct {
+	CurrentPassword string `json:"current_password"` // Текущий пароль
+}
+
+// WebAuthnRegisterRequest представляет завершение регистрации ключа доступа
+// @Description Структура запроса с ответом navigator.credentials.create
+type WebAuthnRegisterRequest struct {
+	SessionToken string          `json:"session_token"`                   // Токен из /webauthn/register/begin
+	Name         string          `json:"name"`                            // Название ключа для списка ключей
+	Credential   json.RawMessage `json:"credential" swaggertype:"object"` // PublicKeyCredential от браузера
+}
+
+// WebAuthnLoginRequest представляет завершение входа по ключу доступа
+// @Description Структура запроса с ответом navigator.credentials.get
+type WebAuthnLoginRequest struct {
+	SessionToken string          `json:"session_token"`                   // Токен из /login/webauthn/begin
+	Credential   json.RawMessage `json:"credential" swaggertype:"object"` // PublicKeyCredential от браузера
+}
+
+// WebAuthnMFABeginRequest представляет запрос на вход ключом доступа вторым фактором
+// @Description Структура запроса с MFA токеном для начала проверки ключом доступа
+type WebAuthnMFABeginRequest struct {
+	MFAToken string `json:"mfa_token"` // Токен из ответа /login
+}
+
+// DeleteWebAuthnCredentialRequest представляет запрос на удаление ключа доступа
+// @Description Структура запроса для удаления ключа доступа
+type DeleteWebAuthnCredentialRequest struct {
+	ID              int    `json:"id"`               // ID ключа из /webauthn/credentials
+	CurrentPassword string `json:"current_password"` // Текущий пароль
+}
@@ -3,8 +3,12 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"net/http"
+	"sstu-go-forum-auth-service/internal/apierror"
 	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/i18n"
+	"strconv"
 
 	"sstu-go-forum-auth-service/internal/model"
 	"sstu-go-forum-auth-service/internal/usecase"
@@ -20,38 +24,33 @@ func NewAuthHandler(uc usecase.AuthUseCase) *AuthHandler { // TODO: приним
 
 // Register обрабатывает запросы на регистрацию нового пользователя
 // @Summary Регистрация нового пользователя
-// @Description Функция для регистрации нового пользователя
+// @Description Функция для регистрации нового пользователя. Если locale не указан, он берется из Accept-Language. Имя — от 3 до 32 букв, цифр и символов _ - . (с буквы или цифры); имена сравниваются без учета регистра и формы записи символов (NFKC), зарезервированные и похожие на занятые имена отклоняются. Поле role игнорируется: регистрация всегда создает USER, администраторов назначает authctl users set-role
 // @Param user body model.User true "Данные пользователя для регистрации"
-// @Success 200 {object} model.RegisterResponse "Ответ с информацией о регистрации"
-// @Failure 400 {string} string "Неверный запрос"
-// @Failure 405 {string} string "Метод не разрешён"
+// @Success 200 {object} dto.RegisterResponse "Ответ с информацией о регистрации"
+// @Failure 400 {object} apierror.Problem "Неверный запрос или поля (список ошибок полей с кодами)"
+// @Failure 405 {object} apierror.Problem "Метод не разрешён"
+// @Failure 429 {object} apierror.Problem "Слишком много регистраций с этого адреса, см. Retry-After"
 // @Router /register [post]
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "use POST", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var u model.User
 	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+		apierror.Write(w, r, decodeError(err))
 		return
 	}
 	defer r.Body.Close()
+	// Без явного выбора письма приходят на языке, на котором пользователь регистрировался
+	if u.Locale == "" {
+		u.Locale = i18n.Match(r.Header.Get("Accept-Language"))
+	}
 
 	createdUser, err := h.UseCase.Register(&u)
 	if err != nil {
-		switch {
-		case errors.Is(err, usecase.ErrUserAlreadyExists):
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		default:
-			http.Error(w, "internal error", http.StatusInternalServerError)
-		}
+		apierror.Write(w, r, err)
 		return
 	}
 
 	resp := dto.RegisterResponse{
-		Message: "Пользователь зарегистрирован",
+		Message: i18n.T(i18n.FromRequest(r), "message.registered"),
 		UserID:  createdUser.ID,
 	}
 	json.NewEncoder(w).Encode(resp)
@@ -59,33 +58,74 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 // Login обрабатывает запросы на авторизацию пользователя
 // @Summary Авторизация пользователя
-// @Description Функция для авторизации пользователя
-// @Param login_request body model.LoginRequest true "Данные для авторизации пользователя"
-// @Success 200 {object} model.AuthResponse "Ответ с токенами"
-// @Failure 400 {string} string "Неверный запрос"
-// @Failure 401 {string} string "Неверный логин или пароль"
+// @Description Функция для авторизации пользователя. Если нужен второй фактор, вместо токенов возвращается MFA токен для /login/mfa
+// @Param login_request body dto.LoginRequest true "Данные для авторизации пользователя"
+// @Success 200 {object} dto.AuthResponse "Ответ с токенами"
+// @Success 202 {object} dto.MFAChallengeResponse "Пароль верный, нужен код TOTP"
+// @Failure 400 {object} apierror.Problem "Неверный запрос"
+// @Failure 401 {object} apierror.Problem "Неверный логин или пароль"
+// @Failure 403 {object} apierror.Problem "Почта не подтверждена"
+// @Failure 429 {object} apierror.Problem "Вход временно заблокирован после неудачных попыток или превышен лимит запросов, см. Retry-After"
 // @Router /login [post]
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+	var req dto.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
 		return
 	}
+	defer r.Body.Close()
 
-	var req dto.LoginRequest
+	req.IP = clientIP(r)
+	result, err := h.UseCase.Login(req)
+	if err != nil {
+		var locked *usecase.LoginLockedError
+		if errors.As(err, &locked) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		}
+		apierror.Write(w, r, err)
+		return
+	}
+
+	writeLoginResult(w, result)
+}
+
+// writeLoginResult отвечает парой токенов или, если нужен второй фактор, MFA токеном со статусом 202
+func writeLoginResult(w http.ResponseWriter, result *usecase.LoginResult) {
+	if result.MFAToken != "" {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(dto.MFAChallengeResponse{
+			MFAToken:           result.MFAToken,
+			EnrollmentRequired: result.MFAEnrollmentRequired,
+		})
+		return
+	}
+	resp := dto.AuthResponse{
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// LoginMFA обрабатывает второй шаг входа
+// @Summary Второй шаг входа
+// @Description Завершает вход кодом из приложения-аутентификатора, одноразовым кодом восстановления или ответом ключа доступа после /login/mfa/webauthn/begin. Если роль требует 2FA и TOTP привязан через /login/mfa/enroll, первый верный код его подключает
+// @Param mfa_login_request body dto.MFALoginRequest true "MFA токен и код, код восстановления или ответ ключа доступа"
+// @Success 200 {object} dto.AuthResponse "Ответ с токенами"
+// @Failure 400 {object} apierror.Problem "Неверный запрос или TOTP не привязан"
+// @Failure 401 {object} apierror.Problem "Неверный MFA токен или код"
+// @Failure 429 {object} apierror.Problem "Слишком много попыток"
+// @Router /login/mfa [post]
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req dto.MFALoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+		apierror.Write(w, r, decodeError(err))
 		return
 	}
 	defer r.Body.Close()
 
-	_, access, refresh, err := h.UseCase.Login(req)
+	_, access, refresh, err := h.UseCase.LoginMFA(req)
 	if err != nil {
-		switch {
-		case errors.Is(err, usecase.ErrInvalidCredentials):
-			http.Error(w, err.Error(), http.StatusUnauthorized)
-		default:
-			http.Error(w, "internal error", http.StatusInternalServerError)
-		}
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -99,33 +139,61 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 // Refresh обрабатывает запросы на обновление токена
 // @Summary Обновление токена авторизации
 // @Description Функция для обновления токенов пользователя
-// @Param refresh_request body model.RefreshRequest true "Данные для обновления токена"
-// @Success 200 {object} model.AuthResponse "Ответ с новым токеном"
-// @Failure 400 {string} string "Неверный запрос"
-// @Failure 401 {string} string "Неверный токен"
+// @Param refresh_request body dto.RefreshRequest true "Данные для обновления токена"
+// @Success 200 {object} dto.AuthResponse "Ответ с новым токеном"
+// @Failure 400 {object} apierror.Problem "Неверный запрос"
+// @Failure 401 {object} apierror.Problem "Неверный токен; auth.token_reused — токен уже обменян, все сессии отозваны"
+// @Failure 403 {object} apierror.Problem "Почта не подтверждена"
+// @Failure 429 {object} apierror.Problem "Слишком много запросов, см. Retry-After"
 // @Router /refresh [post]
 func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+	var req dto.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
 		return
 	}
+	defer r.Body.Close()
 
-	var req dto.RefreshRequest
+	_, access, refresh, err := h.UseCase.RefreshToken(req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	resp := dto.AuthResponse{
+		AccessToken:  access,
+		RefreshToken: refresh,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ChangePassword обрабатывает запросы на смену пароля
+// @Summary Смена пароля
+// @Description Проверяет текущий пароль, устанавливает новый, завершает все остальные сессии и выдает новую пару токенов
+// @Security BearerAuth
+// @Param change_password_request body dto.ChangePasswordRequest true "Текущий и новый пароль"
+// @Success 200 {object} dto.AuthResponse "Ответ с новыми токенами"
+// @Failure 400 {object} apierror.Problem "Неверный запрос или новый пароль не соответствует политике (список ошибок полей с кодами)"
+// @Failure 401 {object} apierror.Problem "Неверный токен или текущий пароль"
+// @Failure 429 {object} apierror.Problem "Слишком много запросов, см. Retry-After"
+// @Router /password/change [post]
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		apierror.Write(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	var req dto.ChangePasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+		apierror.Write(w, r, decodeError(err))
 		return
 	}
 	defer r.Body.Close()
 
-	_, access, refresh, err := h.UseCase.RefreshToken(req)
+	_, access, refresh, err := h.UseCase.ChangePassword(userID, req)
 	if err != nil {
-		switch {
-		case errors.Is(err, usecase.ErrInvalidTokenData),
-			errors.Is(err, usecase.ErrInvalidRefreshToken):
-			http.Error(w, err.Error(), http.StatusUnauthorized)
-		default:
-			http.Error(w, "internal error", http.StatusInternalServerError)
-		}
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -135,3 +203,61 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	}
 	json.NewEncoder(w).Encode(resp)
 }
+
+// UnlockLogin обрабатывает снятие блокировки входа администратором
+// @Summary Снятие блокировки входа
+// @Description Сбрасывает счетчик неудачных входов по имени пользователя. Доступно только роли ADMIN
+// @Security BearerAuth
+// @Param unlock_login_request body dto.UnlockLoginRequest true "Имя пользователя"
+// @Success 200 {object} dto.MessageResponse "Блокировка снята"
+// @Failure 400 {object} apierror.Problem "Неверный запрос"
+// @Failure 401 {object} apierror.Problem "Неверный токен"
+// @Failure 403 {object} apierror.Problem "Недостаточно прав"
+// @Router /admin/login/unlock [post]
+func (h *AuthHandler) UnlockLogin(w http.ResponseWriter, r *http.Request) {
+	var req dto.UnlockLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.UseCase.UnlockLogin(req.Username); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(dto.MessageResponse{Message: i18n.T(i18n.FromRequest(r), "message.login_unlocked")})
+}
+
+// ChangeLocale обрабатывает смену языка пользователя
+// @Summary Смена языка
+// @Description Сохраняет язык писем и ответов (ru или en); он важнее Accept-Language. Пустой язык возвращает выбор по Accept-Language. Выданные access токены содержат прежний язык до обновления
+// @Security BearerAuth
+// @Param change_locale_request body dto.ChangeLocaleRequest true "Новый язык"
+// @Success 200 {object} dto.MessageResponse "Язык изменен"
+// @Failure 400 {object} apierror.Problem "Неверный запрос или неподдерживаемый язык"
+// @Failure 401 {object} apierror.Problem "Неверный токен"
+// @Router /locale/change [post]
+func (h *AuthHandler) ChangeLocale(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		apierror.Write(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	var req dto.ChangeLocaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.UseCase.ChangeLocale(userID, req.Locale); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	// Ответ уже на новом языке
+	locale, _ := i18n.Parse(req.Locale)
+	r = r.WithContext(i18n.WithPreference(r.Context(), locale))
+	json.NewEncoder(w).Encode(dto.MessageResponse{Message: i18n.T(i18n.FromRequest(r), "message.locale_changed")})
+}
@@ -0,0 +1,294 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/apierror"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/password"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	usecaseImpl "sstu-go-forum-auth-service/internal/usecase/impl"
+)
+
+func post(t *testing.T, h http.HandlerFunc, body any) *httptest.ResponseRecorder {
+	return postWithToken(t, h, "", body)
+}
+
+func postWithToken(t *testing.T, h http.HandlerFunc, token string, body any) *httptest.ResponseRecorder {
+	payload, err := json.Marshal(body)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	return rec
+}
+
+func login(t *testing.T, h *AuthHandler, username, password string) dto.AuthResponse {
+	rec := post(t, h.Login, dto.LoginRequest{Username: username, Password: password})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp dto.AuthResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp
+}
+
+// Полный сценарий регистрации, входа и ротации токенов без внешней инфраструктуры
+func TestAuthFlow_InMemory(t *testing.T) {
+	h := NewAuthHandler(usecaseImpl.NewAuthUseCase(memory.NewRepository()))
+	creds := map[string]string{"username": "forum_user", "password": "secret1", "role": "USER"}
+
+	rec := post(t, h.Register, creds)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = post(t, h.Register, creds)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = post(t, h.Login, dto.LoginRequest{Username: "forum_user", Password: "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = post(t, h.Login, dto.LoginRequest{Username: "forum_user", Password: "secret1"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var session dto.AuthResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&session))
+
+	rec = post(t, h.Refresh, dto.RefreshRequest{RefreshToken: session.RefreshToken})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var refreshed dto.AuthResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&refreshed))
+	assert.NotEqual(t, session.RefreshToken, refreshed.RefreshToken)
+
+	rec = post(t, h.Refresh, dto.RefreshRequest{RefreshToken: session.RefreshToken})
+	assert.Equal(t, "auth.token_reused", decodeProblem(t, rec, http.StatusUnauthorized).Code)
+	rec = post(t, h.Refresh, dto.RefreshRequest{RefreshToken: refreshed.RefreshToken})
+	assert.Equal(t, "auth.invalid_refresh_token", decodeProblem(t, rec, http.StatusUnauthorized).Code, "reuse revokes the rotated token too")
+	rec = post(t, h.Refresh, dto.RefreshRequest{RefreshToken: "not a jwt"})
+	assert.Equal(t, "auth.invalid_refresh_token", decodeProblem(t, rec, http.StatusUnauthorized).Code)
+}
+
+func TestChangePassword_RevokesOtherSessions(t *testing.T) {
+	h := NewAuthHandler(usecaseImpl.NewAuthUseCase(memory.NewRepository()))
+	require.Equal(t, http.StatusOK, post(t, h.Register, map[string]string{"username": "forum_user", "password": "secret1", "role": "USER"}).Code)
+	other := login(t, h, "forum_user", "secret1")
+	current := login(t, h, "forum_user", "secret1")
+	changePassword := h.Authenticate(h.ChangePassword)
+
+	rec := postWithToken(t, changePassword, "", dto.ChangePasswordRequest{CurrentPassword: "secret1", NewPassword: "secret2"})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = postWithToken(t, changePassword, current.AccessToken, dto.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "secret2"})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = postWithToken(t, changePassword, current.AccessToken, dto.ChangePasswordRequest{CurrentPassword: "secret1", NewPassword: "123"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = postWithToken(t, changePassword, current.AccessToken, dto.ChangePasswordRequest{CurrentPassword: "secret1", NewPassword: "secret2"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var fresh dto.AuthResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&fresh))
+
+	for _, old := range []dto.AuthResponse{other, current} {
+		rec = postWithToken(t, changePassword, old.AccessToken, dto.ChangePasswordRequest{CurrentPassword: "secret2", NewPassword: "secret3"})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		rec = post(t, h.Refresh, dto.RefreshRequest{RefreshToken: old.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	_, err := h.UseCase.VerifyAccessToken(fresh.AccessToken)
+	assert.NoError(t, err)
+	rec = post(t, h.Refresh, dto.RefreshRequest{RefreshToken: fresh.RefreshToken})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = post(t, h.Login, dto.LoginRequest{Username: "forum_user", Password: "secret1"})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	login(t, h, "forum_user", "secret2")
+}
+
+func TestLogin_LockoutAndAdminUnlock(t *testing.T) {
+	repo := memory.NewRepository()
+	authUC := usecaseImpl.NewAuthUseCase(repo)
+	authUC.Lockout = usecaseImpl.NewLoginLockout(repo, 100, time.Second, 3, 100, time.Minute, time.Hour, time.Hour)
+	h := NewAuthHandler(authUC)
+	for _, u := range []map[string]string{
+		{"username": "forum_user", "password": "secret1", "role": "ADMIN"},
+		{"username": "forum_admin", "password": "secret1", "role": "USER"},
+	} {
+		require.Equal(t, http.StatusOK, post(t, h.Register, u).Code)
+	}
+	promoted, err := repo.GetUserByUsername("forum_admin")
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateRole(promoted.ID, "ADMIN"))
+	admin := login(t, h, "forum_admin", "secret1")
+	user := login(t, h, "forum_user", "secret1")
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusUnauthorized, post(t, h.Login, dto.LoginRequest{Username: "forum_user", Password: "wrong"}).Code)
+	}
+	rec := post(t, h.Login, dto.LoginRequest{Username: "forum_user", Password: "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = post(t, h.Login, dto.LoginRequest{Username: "forum_user", Password: "secret1"})
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	// Секунды отсчитываются от момента блокировки, и к ответу часть минуты уже может пройти
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.True(t, retryAfter >= 1 && retryAfter <= 60, "Retry-After %d is within the lockout", retryAfter)
+
+	unlock := h.Authenticate(RequireRole("ADMIN", h.UnlockLogin))
+	assert.Equal(t, http.StatusForbidden, postWithToken(t, unlock, user.AccessToken, dto.UnlockLoginRequest{Username: "forum_user"}).Code,
+		"role from the registration request is ignored")
+	assert.Equal(t, http.StatusOK, postWithToken(t, unlock, admin.AccessToken, dto.UnlockLoginRequest{Username: "forum_user"}).Code)
+	login(t, h, "forum_user", "secret1")
+}
+
+func TestLogin_ProblemDetails(t *testing.T) {
+	h := NewAuthHandler(usecaseImpl.NewAuthUseCase(memory.NewRepository()))
+	require.Equal(t, http.StatusOK, post(t, h.Register, map[string]string{"username": "forum_user", "password": "secret1", "role": "USER"}).Code)
+
+	payload, err := json.Marshal(dto.LoginRequest{Username: "forum_user", Password: "wrong"})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(payload))
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	req.Header.Set(apierror.RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	h.Login(rec, req)
+
+	resp := decodeProblem(t, rec, http.StatusUnauthorized)
+	assert.Equal(t, apierror.Problem{
+		Type:      "urn:sstu-forum:auth:problem:auth.invalid_credentials",
+		Title:     "Invalid username or password",
+		Status:    http.StatusUnauthorized,
+		Instance:  "/auth/login",
+		Code:      "auth.invalid_credentials",
+		RequestID: "req-42",
+	}, resp)
+	assert.Equal(t, "en", rec.Header().Get("Content-Language"))
+
+	resp = decodeProblem(t, post(t, h.Login, dto.LoginRequest{Username: "forum_user", Password: "wrong"}), http.StatusUnauthorized)
+	assert.Equal(t, "Неверное имя пользователя или пароль", resp.Title, "Russian is the default")
+	assert.NotEmpty(t, resp.RequestID)
+
+	resp = decodeProblem(t, post(t, h.Login, "not an object"), http.StatusBadRequest)
+	assert.Equal(t, "request.invalid", resp.Code)
+}
+
+func TestChangeLocale_PreferenceWinsOverAcceptLanguage(t *testing.T) {
+	h := NewAuthHandler(usecaseImpl.NewAuthUseCase(memory.NewRepository()))
+	send := func(handler http.HandlerFunc, token, acceptLanguage string, body any) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(body)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	message := func(rec *httptest.ResponseRecorder) string {
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp dto.MessageResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		return resp.Message
+	}
+
+	rec := send(h.Register, "", "en-US,en;q=0.9", map[string]string{"username": "forum_user", "password": "secret1", "role": "USER"})
+	var registered dto.RegisterResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&registered))
+	assert.Equal(t, "User registered", registered.Message)
+	user, err := h.UseCase.(*usecaseImpl.AuthUseCaseImpl).Repo.GetUserByID(registered.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, "en", user.Locale, "the registration language is stored")
+
+	changeLocale := h.Authenticate(h.ChangeLocale)
+	session := login(t, h, "forum_user", "secret1")
+	assert.Equal(t, "Language changed", message(send(changeLocale, session.AccessToken, "ru", dto.ChangeLocaleRequest{Locale: "en"})),
+		"the preference in the token wins over the header")
+	assert.Equal(t, "Язык изменен", message(send(changeLocale, session.AccessToken, "en", dto.ChangeLocaleRequest{Locale: "RU"})),
+		"the reply uses the new locale")
+
+	resp := decodeProblem(t, send(changeLocale, session.AccessToken, "en", dto.ChangeLocaleRequest{Locale: "de"}), http.StatusBadRequest)
+	assert.Equal(t, []apierror.FieldViolation{{Field: "locale", Code: model.CodeInvalidValue, Message: "locale must be one of: ru, en"}}, resp.Errors,
+		"the token keeps the locale it was issued with")
+	session = login(t, h, "forum_user", "secret1")
+	resp = decodeProblem(t, send(changeLocale, session.AccessToken, "en", dto.ChangeLocaleRequest{Locale: "de"}), http.StatusBadRequest)
+	assert.Equal(t, "Язык должен быть одним из: ru, en", resp.Errors[0].Message, "a new token carries the stored locale")
+}
+
+func TestLogin_RateLimitedByUsername(t *testing.T) {
+	h := NewAuthHandler(usecaseImpl.NewAuthUseCase(memory.NewRepository()))
+	require.Equal(t, http.StatusOK, post(t, h.Register, map[string]string{"username": "forum_user", "password": "secret1", "role": "USER"}).Code)
+	limited := RateLimit(ratelimit.NewSlidingWindow(2, time.Minute), KeyByUsername, h.Login)
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, post(t, limited, dto.LoginRequest{Username: "forum_user", Password: "secret1"}).Code,
+			"the handler still reads the body")
+	}
+	rec := post(t, limited, dto.LoginRequest{Username: "forum_user", Password: "secret1"})
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusUnauthorized, post(t, limited, dto.LoginRequest{Username: "other", Password: "secret1"}).Code)
+}
+
+type breachedSet map[string]bool
+
+func (b breachedSet) Contains(pw string) (bool, error) { return b[pw], nil }
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder, status int) apierror.Problem {
+	require.Equal(t, status, rec.Code)
+	assert.Equal(t, apierror.ContentType, rec.Header().Get("Content-Type"))
+	var resp apierror.Problem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, status, resp.Status)
+	assert.Equal(t, rec.Header().Get(apierror.RequestIDHeader), resp.RequestID)
+	return resp
+}
+
+func TestRegister_PasswordPolicyViolations(t *testing.T) {
+	authUC := usecaseImpl.NewAuthUseCase(memory.NewRepository())
+	authUC.PasswordPolicy = &password.Policy{MinLength: 8, MinClasses: 2, RejectUsername: true, Breached: breachedSet{"Password123": true}}
+	h := NewAuthHandler(authUC)
+
+	resp := decodeProblem(t, post(t, h.Register, map[string]string{"username": "forum_user", "password": "forum_user", "role": "USER"}), http.StatusBadRequest)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "password", resp.Errors[0].Field)
+	assert.Equal(t, password.RuleContainsUsername, resp.Errors[0].Code)
+
+	resp = decodeProblem(t, post(t, h.Register, map[string]string{"username": "forum_user", "password": "short", "role": "USER"}), http.StatusBadRequest)
+	assert.Equal(t, []apierror.FieldViolation{
+		{Field: "password", Code: password.RuleMinLength, Message: "Пароль должен содержать не менее 8 символов"},
+		{Field: "password", Code: password.RuleCharacterClasses, Message: "Пароль должен содержать символы хотя бы 2 видов из четырех: строчные буквы, заглавные буквы, цифры, другие символы"},
+	}, resp.Errors)
+
+	resp = decodeProblem(t, post(t, h.Register, map[string]string{"username": "forum_user", "password": "Password123", "role": "USER"}), http.StatusBadRequest)
+	assert.Equal(t, password.RuleBreached, resp.Errors[0].Code)
+
+	assert.Equal(t, http.StatusOK, post(t, h.Register, map[string]string{"username": "forum_user", "password": "Correct-Horse1", "role": "USER"}).Code)
+}
+
+func TestRegister_FieldValidationErrors(t *testing.T) {
+	h := NewAuthHandler(usecaseImpl.NewAuthUseCase(memory.NewRepository()))
+
+	resp := decodeProblem(t, post(t, h.Register, map[string]string{"username": "x", "password": "123", "role": "OWNER", "email": "nope"}), http.StatusBadRequest)
+	assert.Equal(t, "request.validation_failed", resp.Code)
+	var fields, codes []string
+	for _, f := range resp.Errors {
+		fields, codes = append(fields, f.Field), append(codes, f.Code)
+	}
+	assert.Equal(t, []string{"username", "email", "password"}, fields, "all problems are reported at once, role is ignored")
+	assert.Equal(t, []string{model.CodeLength, model.CodeInvalidFormat, password.RuleMinLength}, codes)
+
+	require.Equal(t, http.StatusOK, post(t, h.Register, map[string]string{"username": "forum_user", "password": "secret1", "role": "USER"}).Code)
+	for username, code := range map[string]string{"Forum_User": model.CodeTaken, "forum.user": model.CodeConfusable, "Moderator": model.CodeReserved} {
+		resp = decodeProblem(t, post(t, h.Register, map[string]string{"username": username, "password": "secret1", "role": "USER"}), http.StatusBadRequest)
+		assert.Equal(t, []apierror.FieldViolation{{Field: "username", Code: code, Message: resp.Errors[0].Message}}, resp.Errors, username)
+	}
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"sstu-go-forum-auth-service/internal/apierror"
+)
+
+// Middleware оборачивает обработчик общей для всех маршрутов логикой
+type Middleware func(http.Handler) http.Handler
+
+// Chain оборачивает h в middlewares; запрос проходит их в порядке перечисления
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// RequestID выставляет заголовок ответа X-Request-ID, чтобы запрос можно было найти в логах; см. apierror.RequestID
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apierror.RequestID(w, r)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// responseRecorder запоминает статус и размер ответа для журнала запросов
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.size += n
+	return n, err
+}
+
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// AccessLog пишет в журнал каждый запрос; ставится после RequestID, чтобы запись содержала его идентификатор
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		log.Info().
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Int("status", rec.status).
+			Int("size", rec.size).
+			Dur("duration", time.Since(start)).
+			Str("requestID", w.Header().Get(apierror.RequestIDHeader)).
+			Str("ip", clientIP(r)).
+			Msg("Request handled")
+	})
+}
+
+// Recover отвечает 500 вместо разрыва соединения, если обработчик запаниковал
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			log.Error().Str("stack", string(debug.Stack())).Str("path", r.URL.Path).Msg("Handler panicked")
+			apierror.Write(w, r, fmt.Errorf("panic: %v", p))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// BodyLimit ограничивает тело запроса limit байтами: заведомо большие тела отклоняются с 413, а чтение
+// тела без Content-Length обрывается на limit. limit <= 0 снимает ограничение
+func BodyLimit(limit int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				apierror.Write(w, r, apierror.ErrRequestTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// decodeError — ответ на ошибку чтения JSON тела: тело без Content-Length, оборванное BodyLimit, — 413,
+// остальное — 400
+func decodeError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return apierror.ErrRequestTooLarge
+	}
+	return apierror.ErrInvalidRequest
+}
+
+// Timeout отвечает 503, если обработчик не уложился в d, а поздний ответ отбрасывается. В отличие
+// от http.TimeoutHandler ответ на таймаут — problem+json. d <= 0 снимает ограничение.
+// Это только срок ответа клиенту: контекст обработчика отменяется, но сценарии и репозитории его не получают,
+// поэтому начатые запросы к БД и отправка писем доходят до конца в фоне
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			tw := &timeoutWriter{header: w.Header().Clone()}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+			select {
+			case p := <-panicked:
+				// Паника уходит в горутину запроса, где ее перехватит Recover
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				for k, v := range tw.header {
+					w.Header()[k] = v
+				}
+				if tw.status == 0 {
+					tw.status = http.StatusOK
+				}
+				w.WriteHeader(tw.status)
+				w.Write(tw.body.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					apierror.Write(w, r, apierror.ErrRequestTimeout)
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter копит ответ обработчика, пока Timeout не решит, отдавать ли его клиенту
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = status
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.body.Write(b)
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"sstu-go-forum-auth-service/internal/apierror"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	usecaseImpl "sstu-go-forum-auth-service/internal/usecase/impl"
+)
+
+func TestChain_RecoversPanicWithRequestID(t *testing.T) {
+	h := Chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("boom") }),
+		RequestID, AccessLog, Recover)
+	rec := serve(t, h, http.MethodGet, "/", nil)
+	p := decodeProblem(t, rec, http.StatusInternalServerError)
+	assert.Equal(t, "server.internal", p.Code)
+	assert.NotEmpty(t, p.RequestID)
+	assert.NotContains(t, rec.Body.String(), "boom")
+}
+
+func TestBodyLimit(t *testing.T) {
+	var readErr error
+	h := BodyLimit(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	p := decodeProblem(t, serve(t, h, http.MethodPost, "/", strings.Repeat("a", 32)), http.StatusRequestEntityTooLarge)
+	assert.Equal(t, "request.too_large", p.Code)
+
+	// Без Content-Length тело обрывается при чтении
+	req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader(strings.Repeat("a", 32))))
+	req.ContentLength = -1
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	var maxBytes *http.MaxBytesError
+	assert.ErrorAs(t, readErr, &maxBytes)
+
+	serve(t, h, http.MethodPost, "/", "short")
+	assert.NoError(t, readErr)
+
+	// Обработчик с JSON телом отвечает на оборванное тело тем же 413, а не 400
+	login := BodyLimit(16)(http.HandlerFunc(NewAuthHandler(usecaseImpl.NewAuthUseCase(memory.NewRepository())).Login))
+	req = httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader(`{"username": "`+strings.Repeat("a", 32)+`"}`)))
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	login.ServeHTTP(rec, req)
+	assert.Equal(t, "request.too_large", decodeProblem(t, rec, http.StatusRequestEntityTooLarge).Code)
+}
+
+func TestTimeout(t *testing.T) {
+	released := make(chan struct{})
+	slow := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.WriteHeader(http.StatusOK)
+		close(released)
+	}))
+	p := decodeProblem(t, serve(t, Chain(slow, RequestID), http.MethodGet, "/", nil), http.StatusServiceUnavailable)
+	assert.Equal(t, "request.timeout", p.Code)
+	<-released
+
+	fast := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Handler", "fast")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("done"))
+	}))
+	rec := serve(t, Chain(fast, RequestID), http.MethodGet, "/", nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "fast", rec.Header().Get("X-Handler"))
+	assert.NotEmpty(t, rec.Header().Get(apierror.RequestIDHeader))
+	assert.Equal(t, "done", rec.Body.String())
+}
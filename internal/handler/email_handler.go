@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"sstu-go-forum-auth-service/internal/apierror"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/i18n"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/usecase"
+)
+
+type EmailHandler struct {
+	UseCase usecase.EmailUseCase
+	// IPLimiter ограничивает число запросов повторной отправки с одного IP
+	IPLimiter ratelimit.Limiter
+}
+
+func NewEmailHandler(uc usecase.EmailUseCase, ipLimiter ratelimit.Limiter) *EmailHandler {
+	return &EmailHandler{UseCase: uc, IPLimiter: ipLimiter}
+}
+
+// VerifyEmail обрабатывает запросы на подтверждение почты
+// @Summary Подтверждение почты
+// @Description Подтверждает адрес электронной почты одноразовым токеном из письма. Чтобы claim email_verified появился в access токене, клиент обновляет токены через /refresh
+// @Param verify_email_request body dto.VerifyEmailRequest true "Токен из письма"
+// @Success 200 {object} dto.MessageResponse "Почта подтверждена"
+// @Failure 400 {object} apierror.Problem "Неверный запрос или токен"
+// @Failure 429 {object} apierror.Problem "Слишком много запросов, см. Retry-After"
+// @Router /email/verify [post]
+func (h *EmailHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req dto.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.UseCase.VerifyEmail(req); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(dto.MessageResponse{Message: i18n.T(i18n.FromRequest(r), "message.email_verified")})
+}
+
+// ResendVerification обрабатывает запросы на повторную отправку письма с подтверждением
+// @Summary Повторная отправка письма с подтверждением
+// @Description Отправляет новую ссылку для подтверждения почты. Ответ не зависит от того, существует ли аккаунт
+// @Param resend_verification_request body dto.ResendVerificationRequest true "Имя пользователя"
+// @Success 202 {object} dto.MessageResponse "Запрос принят"
+// @Failure 400 {object} apierror.Problem "Неверный запрос"
+// @Failure 429 {object} apierror.Problem "Слишком много запросов"
+// @Router /email/verify/resend [post]
+func (h *EmailHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	if !allowIP(w, r, h.IPLimiter) {
+		return
+	}
+
+	var req dto.ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.UseCase.ResendVerification(req); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(dto.MessageResponse{
+		Message: i18n.T(i18n.FromRequest(r), "message.verification_sent"),
+	})
+}
+
+// ChangeEmail обрабатывает запросы на смену почты
+// @Summary Смена почты
+// @Description Проверяет текущий пароль, меняет адрес почты и отправляет на него письмо для подтверждения. До подтверждения почта считается неподтвержденной
+// @Security BearerAuth
+// @Param change_email_request body dto.ChangeEmailRequest true "Текущий пароль и новый адрес"
+// @Success 202 {object} dto.MessageResponse "Почта изменена, письмо отправлено"
+// @Failure 400 {object} apierror.Problem "Неверный запрос или адрес (список ошибок полей с кодами)"
+// @Failure 401 {object} apierror.Problem "Неверный токен или текущий пароль"
+// @Failure 409 {object} apierror.Problem "Адрес уже используется"
+// @Failure 429 {object} apierror.Problem "Слишком много запросов, см. Retry-After"
+// @Router /email/change [post]
+func (h *EmailHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		apierror.Write(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	var req dto.ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.UseCase.ChangeEmail(userID, req); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(dto.MessageResponse{Message: i18n.T(i18n.FromRequest(r), "message.email_changed")})
+}
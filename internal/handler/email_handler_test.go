@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	usecaseImpl "sstu-go-forum-auth-service/internal/usecase/impl"
+)
+
+var mailTokenPattern = regexp.MustCompile(`\?token=(\S+)`)
+
+func lastMailToken(t *testing.T, mail *inbox) string {
+	require.NotEmpty(t, mail.sent)
+	match := mailTokenPattern.FindStringSubmatch(mail.sent[len(mail.sent)-1].Body)
+	require.NotNil(t, match)
+	return match[1]
+}
+
+// Вход запрещен до подтверждения почты, смена почты требует подтвердить новый адрес
+func TestEmailVerificationFlow_LoginRequiresVerifiedEmail(t *testing.T) {
+	repo := memory.NewRepository()
+	mail := &inbox{}
+	emailUC := usecaseImpl.NewEmailUseCase(repo, mail, ratelimit.NewSlidingWindow(3, time.Hour), "http://localhost/verify", time.Hour)
+	emailUC.Background = runNow
+	authUC := usecaseImpl.NewAuthUseCase(repo)
+	authUC.Verification = emailUC
+	authUC.RequireVerifiedEmail = true
+	auth := NewAuthHandler(authUC)
+	h := NewEmailHandler(emailUC, ratelimit.NewSlidingWindow(100, time.Hour))
+	creds := map[string]string{"username": "forum_user", "password": "secret1", "role": "USER", "email": "User@Example.com"}
+
+	require.Equal(t, http.StatusOK, post(t, auth.Register, creds).Code)
+	creds["username"] = "second_user"
+	creds["email"] = "user@example.COM"
+	assert.Equal(t, http.StatusBadRequest, post(t, auth.Register, creds).Code, "email is unique ignoring case")
+
+	rec := post(t, auth.Login, dto.LoginRequest{Username: "forum_user", Password: "secret1"})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	known := post(t, h.ResendVerification, dto.ResendVerificationRequest{Username: "forum_user"})
+	unknown := post(t, h.ResendVerification, dto.ResendVerificationRequest{Username: "nobody"})
+	assert.Equal(t, http.StatusAccepted, known.Code)
+	assert.Equal(t, known.Body.String(), unknown.Body.String())
+	require.Len(t, mail.sent, 2)
+
+	assert.Equal(t, http.StatusBadRequest, post(t, h.VerifyEmail, dto.VerifyEmailRequest{Token: "bogus"}).Code)
+	require.Equal(t, http.StatusOK, post(t, h.VerifyEmail, dto.VerifyEmailRequest{Token: lastMailToken(t, mail)}).Code)
+	session := login(t, auth, "forum_user", "secret1")
+	claims, err := auth.UseCase.VerifyAccessToken(session.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, true, claims["email_verified"])
+
+	changeEmail := auth.Authenticate(h.ChangeEmail)
+	rec = postWithToken(t, changeEmail, session.AccessToken, dto.ChangeEmailRequest{CurrentPassword: "wrong", Email: "new@example.com"})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = postWithToken(t, changeEmail, session.AccessToken, dto.ChangeEmailRequest{CurrentPassword: "secret1", Email: "new@example.com"})
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "new@example.com", mail.sent[len(mail.sent)-1].To)
+
+	assert.Equal(t, http.StatusForbidden, post(t, auth.Refresh, dto.RefreshRequest{RefreshToken: session.RefreshToken}).Code)
+	require.Equal(t, http.StatusOK, post(t, h.VerifyEmail, dto.VerifyEmailRequest{Token: lastMailToken(t, mail)}).Code)
+	login(t, auth, "forum_user", "secret1")
+}
@@ -2,29 +2,49 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/rs/zerolog/log"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	structpb "google.golang.org/protobuf/types/known/structpb"
-	"sstu-go-forum-auth-service/internal/utils"
+	"sstu-go-forum-auth-service/internal/apierror"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/i18n"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/usecase"
 
 	pb "github.com/snailrake/sstu-auth-proto/proto/auth"
+	accountpb "sstu-go-forum-auth-service/api/proto/account"
 )
 
 type GrpcHandler struct {
 	pb.UnimplementedAuthServiceServer
+	accountpb.UnimplementedAccountServiceServer
+	UseCase usecase.AuthUseCase
+	// RequireVerifiedEmail отклоняет в VerifyToken токены пользователей с неподтвержденной почтой:
+	// так сервис форума не дает им писать, а вход и подтверждение почты остаются доступны
+	RequireVerifiedEmail bool
 }
 
-func NewGrpcHandler() *GrpcHandler {
-	return &GrpcHandler{}
+func NewGrpcHandler(uc usecase.AuthUseCase) *GrpcHandler {
+	return &GrpcHandler{UseCase: uc}
 }
 
 func (h *GrpcHandler) VerifyToken(ctx context.Context, req *pb.VerifyTokenRequest) (*pb.VerifyTokenResponse, error) {
-	log.Debug().Str("token", req.Token).Msg("verifying token")
-	claims, err := utils.VerifyToken(req.Token)
+	log.Debug().Msg("verifying token")
+	claims, err := h.UseCase.VerifyAccessToken(req.Token)
 	if err != nil {
 		log.Error().Err(err).Msg("failed to verify token")
-		return nil, err
+		return nil, grpcError(ctx, err)
+	}
+	if verified, _ := claims["email_verified"].(bool); h.RequireVerifiedEmail && !verified {
+		log.Warn().Msg("token of user with unverified email rejected")
+		return nil, grpcError(ctx, usecase.ErrEmailNotVerified)
 	}
 	structClaims, err := structpb.NewStruct(claims)
 	if err != nil {
@@ -34,3 +54,89 @@ func (h *GrpcHandler) VerifyToken(ctx context.Context, req *pb.VerifyTokenReques
 	log.Info().Msg("token verified successfully")
 	return &pb.VerifyTokenResponse{Claims: structClaims}, nil
 }
+
+func (h *GrpcHandler) ChangePassword(ctx context.Context, req *accountpb.ChangePasswordRequest) (*accountpb.ChangePasswordResponse, error) {
+	userID, claims, err := h.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	locale, _ := claims["locale"].(string)
+	ctx = i18n.WithPreference(ctx, locale)
+	_, access, refresh, err := h.UseCase.ChangePassword(userID, dto.ChangePasswordRequest{
+		CurrentPassword: req.GetCurrentPassword(),
+		NewPassword:     req.GetNewPassword(),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to change password")
+		return nil, grpcError(ctx, err)
+	}
+	return &accountpb.ChangePasswordResponse{AccessToken: access, RefreshToken: refresh}, nil
+}
+
+// authenticate проверяет access токен из метаданных "authorization: Bearer <token>"
+// и возвращает ID пользователя вместе с остальными claims
+func (h *GrpcHandler) authenticate(ctx context.Context) (int, map[string]any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return 0, nil, grpcError(ctx, apierror.ErrMissingToken)
+	}
+	token, ok := bearerToken(values[0])
+	if !ok {
+		return 0, nil, grpcError(ctx, apierror.ErrMissingToken)
+	}
+	claims, err := h.UseCase.VerifyAccessToken(token)
+	if err != nil {
+		return 0, nil, grpcError(ctx, err)
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, nil, grpcError(ctx, usecase.ErrInvalidTokenData)
+	}
+	return int(userID), claims, nil
+}
+
+// grpcError переводит ошибку сценария в статус gRPC с сообщением на языке клиента, как у HTTP API
+func grpcError(ctx context.Context, err error) error {
+	locale := grpcLocale(ctx)
+	var validationErr *model.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return validationStatus(locale, validationErr)
+	case errors.Is(err, apierror.ErrMissingToken),
+		errors.Is(err, usecase.ErrInvalidAccessToken),
+		errors.Is(err, usecase.ErrInvalidTokenData),
+		errors.Is(err, usecase.ErrTokenRevoked),
+		errors.Is(err, usecase.ErrInvalidCredentials):
+		return status.Error(codes.Unauthenticated, apierror.Title(locale, err))
+	case errors.Is(err, usecase.ErrEmailNotVerified):
+		return status.Error(codes.PermissionDenied, apierror.Title(locale, err))
+	default:
+		return status.Error(codes.Internal, apierror.Title(locale, err))
+	}
+}
+
+// grpcLocale — язык сообщений: выбор пользователя, затем метаданные accept-language, как у HTTP
+func grpcLocale(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return i18n.Resolve(i18n.Preference(ctx), strings.Join(md.Get("accept-language"), ","))
+}
+
+// validationStatus — InvalidArgument с подробностями google.rpc.BadRequest: поле, код ошибки в Reason
+// и сообщение на языке locale
+func validationStatus(locale string, err *model.ValidationError) error {
+	badRequest := &errdetails.BadRequest{}
+	for _, f := range err.Fields {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       f.Field,
+			Reason:      f.Code,
+			Description: f.Message(locale),
+		})
+	}
+	message := apierror.Title(locale, err)
+	st, detailsErr := status.New(codes.InvalidArgument, message).WithDetails(badRequest)
+	if detailsErr != nil {
+		return status.Error(codes.InvalidArgument, message)
+	}
+	return st.Err()
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/snailrake/sstu-auth-proto/proto/auth"
+	accountpb "sstu-go-forum-auth-service/api/proto/account"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/password"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	usecaseImpl "sstu-go-forum-auth-service/internal/usecase/impl"
+)
+
+func newGrpcClient(t *testing.T, h *GrpcHandler, opts ...grpc.ServerOption) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(opts...)
+	pb.RegisterAuthServiceServer(s, h)
+	accountpb.RegisterAccountServiceServer(s, h)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGrpcChangePassword(t *testing.T) {
+	uc := usecaseImpl.NewAuthUseCase(memory.NewRepository())
+	_, err := uc.Register(&model.User{Username: "forum_user", Password: "secret1", Role: "USER"})
+	require.NoError(t, err)
+	session, err := uc.Login(dto.LoginRequest{Username: "forum_user", Password: "secret1"})
+	require.NoError(t, err)
+	access := session.AccessToken
+
+	conn := newGrpcClient(t, NewGrpcHandler(uc))
+	authClient := pb.NewAuthServiceClient(conn)
+	accountClient := accountpb.NewAccountServiceClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+access)
+	req := &accountpb.ChangePasswordRequest{CurrentPassword: "secret1", NewPassword: "secret2"}
+
+	_, err = accountClient.ChangePassword(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	resp, err := accountClient.ChangePassword(ctx, req)
+	require.NoError(t, err)
+	fresh := resp.GetAccessToken()
+	assert.NotEmpty(t, fresh)
+	assert.NotEmpty(t, resp.GetRefreshToken())
+
+	_, err = authClient.VerifyToken(context.Background(), &pb.VerifyTokenRequest{Token: access})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	verified, err := authClient.VerifyToken(context.Background(), &pb.VerifyTokenRequest{Token: fresh})
+	require.NoError(t, err)
+	assert.Equal(t, "forum_user", verified.GetClaims().GetFields()["username"].GetStringValue())
+}
+
+func TestGrpcChangePassword_FieldViolations(t *testing.T) {
+	uc := usecaseImpl.NewAuthUseCase(memory.NewRepository())
+	_, err := uc.Register(&model.User{Username: "forum_user", Password: "secret1", Role: "USER"})
+	require.NoError(t, err)
+	session, err := uc.Login(dto.LoginRequest{Username: "forum_user", Password: "secret1"})
+	require.NoError(t, err)
+
+	accountClient := accountpb.NewAccountServiceClient(newGrpcClient(t, NewGrpcHandler(uc)))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+session.AccessToken)
+	req := &accountpb.ChangePasswordRequest{CurrentPassword: "secret1", NewPassword: "123"}
+
+	for locale, description := range map[string]string{
+		"en": "password must be at least 6 characters",
+		"":   "Пароль должен содержать не менее 6 символов",
+	} {
+		_, err = accountClient.ChangePassword(metadata.AppendToOutgoingContext(ctx, "accept-language", locale), req)
+		st := status.Convert(err)
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+		require.Len(t, st.Details(), 1)
+		badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+		require.True(t, ok)
+		require.Len(t, badRequest.GetFieldViolations(), 1)
+		violation := badRequest.GetFieldViolations()[0]
+		assert.Equal(t, "new_password", violation.GetField())
+		assert.Equal(t, password.RuleMinLength, violation.GetReason())
+		assert.Equal(t, description, violation.GetDescription(), locale)
+	}
+}
+
+func TestGrpcVerifyToken_RequiresVerifiedEmail(t *testing.T) {
+	repo := memory.NewRepository()
+	uc := usecaseImpl.NewAuthUseCase(repo)
+	user, err := uc.Register(&model.User{Username: "forum_user", Password: "secret1", Role: "USER", Email: "user@example.com"})
+	require.NoError(t, err)
+	session, err := uc.Login(dto.LoginRequest{Username: "forum_user", Password: "secret1"})
+	require.NoError(t, err)
+	unverified, refresh := session.AccessToken, session.RefreshToken
+
+	h := NewGrpcHandler(uc)
+	h.RequireVerifiedEmail = true
+	authClient := pb.NewAuthServiceClient(newGrpcClient(t, h))
+
+	_, err = authClient.VerifyToken(context.Background(), &pb.VerifyTokenRequest{Token: unverified})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	require.NoError(t, repo.MarkEmailVerified(user.ID, "user@example.com"))
+	_, verified, _, err := uc.RefreshToken(dto.RefreshRequest{RefreshToken: refresh})
+	require.NoError(t, err)
+	resp, err := authClient.VerifyToken(context.Background(), &pb.VerifyTokenRequest{Token: verified})
+	require.NoError(t, err)
+	assert.True(t, resp.GetClaims().GetFields()["email_verified"].GetBoolValue())
+}
+
+func TestGrpcRateLimit_PerClientID(t *testing.T) {
+	uc := usecaseImpl.NewAuthUseCase(memory.NewRepository())
+	interceptor := RateLimitInterceptor(ratelimit.NewTokenBucket(2, time.Minute), GRPCKeyByClientID)
+	authClient := pb.NewAuthServiceClient(newGrpcClient(t, NewGrpcHandler(uc), grpc.UnaryInterceptor(interceptor)))
+	forum := metadata.AppendToOutgoingContext(context.Background(), "x-client-id", "forum")
+
+	for i := 0; i < 2; i++ {
+		_, err := authClient.VerifyToken(forum, &pb.VerifyTokenRequest{Token: "invalid"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+	var header metadata.MD
+	_, err := authClient.VerifyToken(forum, &pb.VerifyTokenRequest{Token: "invalid"}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"30"}, header.Get("retry-after"))
+
+	other := metadata.AppendToOutgoingContext(context.Background(), "x-client-id", "other")
+	_, err = authClient.VerifyToken(other, &pb.VerifyTokenRequest{Token: "invalid"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "clients are limited independently")
+}
+
+func TestGrpcRateLimit_ChangePasswordPerPeer(t *testing.T) {
+	uc := usecaseImpl.NewAuthUseCase(memory.NewRepository())
+	interceptor := MethodRateLimitInterceptor(GRPCCredentialMethods, ratelimit.NewTokenBucket(2, time.Minute), GRPCKeyByPeer)
+	conn := newGrpcClient(t, NewGrpcHandler(uc), grpc.UnaryInterceptor(interceptor))
+	accountClient := accountpb.NewAccountServiceClient(conn)
+	req := &accountpb.ChangePasswordRequest{CurrentPassword: "guess", NewPassword: "secret2"}
+
+	for i := 0; i < 2; i++ {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-client-id", "client-"+strconv.Itoa(i))
+		_, err := accountClient.ChangePassword(ctx, req)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-client-id", "client-2")
+	_, err := accountClient.ChangePassword(ctx, req)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "a new client id does not reset the limit")
+
+	_, err = pb.NewAuthServiceClient(conn).VerifyToken(ctx, &pb.VerifyTokenRequest{Token: "invalid"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "other methods are not limited")
+}
+
+func TestGrpcChangePassword_LocalizedMessages(t *testing.T) {
+	uc := usecaseImpl.NewAuthUseCase(memory.NewRepository())
+	_, err := uc.Register(&model.User{Username: "forum_user", Password: "secret1", Role: "USER", Locale: "en"})
+	require.NoError(t, err)
+	session, err := uc.Login(dto.LoginRequest{Username: "forum_user", Password: "secret1"})
+	require.NoError(t, err)
+	accountClient := accountpb.NewAccountServiceClient(newGrpcClient(t, NewGrpcHandler(uc)))
+	req := &accountpb.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "secret2"}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "accept-language", "ru")
+	_, err = accountClient.ChangePassword(ctx, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, "Нужен access токен в заголовке Authorization", status.Convert(err).Message(), "accept-language without a token")
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+session.AccessToken)
+	_, err = accountClient.ChangePassword(ctx, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, "Invalid username or password", status.Convert(err).Message(), "the saved preference wins")
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	accountpb "sstu-go-forum-auth-service/api/proto/account"
+	"sstu-go-forum-auth-service/internal/apierror"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+)
+
+// RateLimitInterceptor отклоняет вызовы сверх лимита по ключу key(ctx) с кодом ResourceExhausted
+// и заголовком retry-after в секундах
+func RateLimitInterceptor(limiter ratelimit.Limiter, key func(context.Context) string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		k := key(ctx)
+		if ok, retryAfter := limiter.Allow(k); !ok {
+			log.Warn().Str("key", k).Str("method", info.FullMethod).Msg("gRPC call rate limited")
+			seconds := strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+			if err := grpc.SetHeader(ctx, metadata.Pairs("retry-after", seconds)); err != nil {
+				log.Error().Err(err).Msg("failed to set retry-after header")
+			}
+			return nil, status.Error(codes.ResourceExhausted, apierror.Title(grpcLocale(ctx), apierror.ErrRateLimited))
+		}
+		return handler(ctx, req)
+	}
+}
+
+// GRPCCredentialMethods — методы, проверяющие пароль; их стоит ограничивать по адресу отдельно от общего лимита,
+// который клиент может обойти сменой x-client-id
+var GRPCCredentialMethods = []string{accountpb.AccountService_ChangePassword_FullMethodName}
+
+// MethodRateLimitInterceptor — RateLimitInterceptor только для вызовов методов methods, остальные проходят без проверки
+func MethodRateLimitInterceptor(methods []string, limiter ratelimit.Limiter, key func(context.Context) string) grpc.UnaryServerInterceptor {
+	limit := RateLimitInterceptor(limiter, key)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !slices.Contains(methods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		return limit(ctx, req, info, handler)
+	}
+}
+
+// GRPCKeyByPeer считает вызовы по адресу клиента
+func GRPCKeyByPeer(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "ip:"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return "ip:" + p.Addr.String()
+	}
+	return "ip:" + host
+}
+
+// GRPCKeyByClientID считает вызовы по метаданным x-client-id, а без них — по адресу клиента
+func GRPCKeyByClientID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("x-client-id"); len(values) > 0 && values[0] != "" {
+		return "client:" + values[0]
+	}
+	return GRPCKeyByPeer(ctx)
+}
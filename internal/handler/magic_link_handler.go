@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"sstu-go-forum-auth-service/internal/apierror"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/i18n"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/usecase"
+)
+
+type MagicLinkHandler struct {
+	UseCase usecase.MagicLinkUseCase
+	// Auth обменивает токен из письма на пару токенов
+	Auth usecase.AuthUseCase
+	// IPLimiter ограничивает число запросов с одного IP
+	IPLimiter ratelimit.Limiter
+}
+
+func NewMagicLinkHandler(uc usecase.MagicLinkUseCase, auth usecase.AuthUseCase, ipLimiter ratelimit.Limiter) *MagicLinkHandler {
+	return &MagicLinkHandler{UseCase: uc, Auth: auth, IPLimiter: ipLimiter}
+}
+
+// SendLink обрабатывает запросы на ссылку для входа без пароля
+// @Summary Запрос ссылки для входа
+// @Description Отправляет на почту пользователя одноразовую ссылку для входа и возвращает nonce, который браузер предъявляет вместе с токеном из ссылки. Ответ не зависит от того, существует ли аккаунт
+// @Param magic_link_request body dto.MagicLinkRequest true "Имя пользователя"
+// @Success 202 {object} dto.MagicLinkResponse "Запрос принят"
+// @Failure 400 {object} apierror.Problem "Неверный запрос"
+// @Failure 429 {object} apierror.Problem "Слишком много запросов"
+// @Router /login/magic-link [post]
+func (h *MagicLinkHandler) SendLink(w http.ResponseWriter, r *http.Request) {
+	if !allowIP(w, r, h.IPLimiter) {
+		return
+	}
+
+	var req dto.MagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	nonce, err := h.UseCase.SendLink(req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(dto.MagicLinkResponse{
+		Message: i18n.T(i18n.FromRequest(r), "message.magic_link_sent"),
+		Nonce:   nonce,
+	})
+}
+
+// Login обрабатывает вход по ссылке из письма
+// @Summary Вход по ссылке из письма
+// @Description Обменивает одноразовый токен из письма и nonce браузера на пару токенов. Если подключен второй фактор, возвращается MFA токен для /login/mfa
+// @Param magic_link_login_request body dto.MagicLinkLoginRequest true "Токен из письма и nonce"
+// @Success 200 {object} dto.AuthResponse "Ответ с токенами"
+// @Success 202 {object} dto.MFAChallengeResponse "Ссылка верна, нужен второй фактор"
+// @Failure 400 {object} apierror.Problem "Неверный запрос"
+// @Failure 401 {object} apierror.Problem "Неверная, истекшая или уже использованная ссылка"
+// @Failure 429 {object} apierror.Problem "Слишком много запросов"
+// @Router /login/magic-link/finish [post]
+func (h *MagicLinkHandler) Login(w http.ResponseWriter, r *http.Request) {
+	if !allowIP(w, r, h.IPLimiter) {
+		return
+	}
+
+	var req dto.MagicLinkLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	result, err := h.Auth.LoginMagicLink(req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	writeLoginResult(w, result)
+}
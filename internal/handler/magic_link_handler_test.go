@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	usecaseImpl "sstu-go-forum-auth-service/internal/usecase/impl"
+)
+
+func TestMagicLinkFlow_InMemory(t *testing.T) {
+	repo := memory.NewRepository()
+	mail := &inbox{}
+	magicLinkUC := usecaseImpl.NewMagicLinkUseCase(repo, mail, ratelimit.NewSlidingWindow(3, time.Hour), "http://localhost/login/magic-link", 15*time.Minute)
+	authUC := usecaseImpl.NewAuthUseCase(repo)
+	authUC.MagicLink = magicLinkUC
+	auth := NewAuthHandler(authUC)
+	h := NewMagicLinkHandler(magicLinkUC, authUC, ratelimit.NewSlidingWindow(4, time.Hour))
+	require.Equal(t, http.StatusOK, post(t, auth.Register, map[string]string{
+		"username": "forum_user", "password": "secret1", "role": "USER", "email": "user@example.com",
+	}).Code)
+
+	rec := post(t, h.SendLink, dto.MagicLinkRequest{Username: "forum_user"})
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var sent dto.MagicLinkResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&sent))
+	require.Len(t, mail.sent, 1)
+	token := lastMailToken(t, mail)
+	assert.Equal(t, http.StatusAccepted, post(t, h.SendLink, dto.MagicLinkRequest{Username: "nobody"}).Code)
+
+	rec = post(t, h.Login, dto.MagicLinkLoginRequest{Token: token, Nonce: sent.Nonce})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var session dto.AuthResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&session))
+	claims, err := auth.UseCase.VerifyAccessToken(session.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, true, claims["email_verified"])
+
+	rec = post(t, h.Login, dto.MagicLinkLoginRequest{Token: token, Nonce: sent.Nonce})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "replayed link")
+
+	rec = post(t, h.Login, dto.MagicLinkLoginRequest{Token: token, Nonce: sent.Nonce})
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+}
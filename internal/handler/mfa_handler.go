@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"sstu-go-forum-auth-service/internal/apierror"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/i18n"
+	"sstu-go-forum-auth-service/internal/usecase"
+)
+
+type MFAHandler struct {
+	UseCase usecase.MFAUseCase
+	// Auth проверяет MFA токены при привязке TOTP во время входа
+	Auth usecase.AuthUseCase
+}
+
+func NewMFAHandler(uc usecase.MFAUseCase, auth usecase.AuthUseCase) *MFAHandler {
+	return &MFAHandler{UseCase: uc, Auth: auth}
+}
+
+// EnrollDuringLogin обрабатывает привязку TOTP для ролей с обязательной 2FA, у которых ее еще нет
+// @Summary Привязка TOTP во время входа
+// @Description Создает секрет TOTP по MFA токену из /login. Вход завершается через /login/mfa первым кодом
+// @Param mfa_enroll_request body dto.MFAEnrollRequest true "MFA токен"
+// @Success 200 {object} dto.TOTPEnrollResponse "Секрет, otpauth ссылка и коды восстановления"
+// @Failure 400 {object} apierror.Problem "Неверный запрос"
+// @Failure 401 {object} apierror.Problem "Неверный MFA токен"
+// @Failure 409 {object} apierror.Problem "TOTP уже подключен"
+// @Failure 429 {object} apierror.Problem "Слишком много запросов, см. Retry-After"
+// @Router /login/mfa/enroll [post]
+func (h *MFAHandler) EnrollDuringLogin(w http.ResponseWriter, r *http.Request) {
+	var req dto.MFAEnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	userID, err := h.Auth.VerifyMFAToken(req.MFAToken)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	resp, err := h.UseCase.EnrollTOTPDuringLogin(userID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Enroll обрабатывает запросы на привязку TOTP
+// @Summary Привязка TOTP
+// @Description Создает новый секрет TOTP после проверки текущего пароля. Второй фактор включается после подтверждения кодом через /mfa/totp/confirm
+// @Security BearerAuth
+// @Param totp_enroll_request body dto.TOTPEnrollRequest true "Текущий пароль"
+// @Success 200 {object} dto.TOTPEnrollResponse "Секрет, otpauth ссылка и коды восстановления"
+// @Failure 400 {object} apierror.Problem "Неверный запрос"
+// @Failure 401 {object} apierror.Problem "Неверный токен или пароль"
+// @Failure 409 {object} apierror.Problem "TOTP уже подключен"
+// @Failure 429 {object} apierror.Problem "Слишком много попыток"
+// @Router /mfa/totp/enroll [post]
+func (h *MFAHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		apierror.Write(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	var req dto.TOTPEnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	resp, err := h.UseCase.EnrollTOTP(userID, req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Confirm обрабатывает подтверждение привязки TOTP
+// @Summary Подтверждение TOTP
+// @Description Включает второй фактор после проверки первого кода
+// @Security BearerAuth
+// @Param totp_code_request body dto.TOTPCodeRequest true "Код из приложения-аутентификатора"
+// @Success 200 {object} dto.MessageResponse "Второй фактор включен"
+// @Failure 400 {object} apierror.Problem "Неверный запрос или TOTP не привязан"
+// @Failure 401 {object} apierror.Problem "Неверный токен или код"
+// @Failure 409 {object} apierror.Problem "TOTP уже подключен"
+// @Failure 429 {object} apierror.Problem "Слишком много попыток"
+// @Router /mfa/totp/confirm [post]
+func (h *MFAHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		apierror.Write(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	var req dto.TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.UseCase.ConfirmTOTP(userID, req); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(dto.MessageResponse{Message: i18n.T(i18n.FromRequest(r), "message.mfa_enabled")})
+}
+
+// Disable обрабатывает отключение TOTP
+// @Summary Отключение TOTP
+// @Description Отключает второй фактор после проверки пароля и кода. Для ролей с обязательной 2FA недоступно
+// @Security BearerAuth
+// @Param disable_totp_request body dto.DisableTOTPRequest true "Текущий пароль и код"
+// @Success 200 {object} dto.MessageResponse "Второй фактор отключен"
+// @Failure 400 {object} apierror.Problem "Неверный запрос или TOTP не привязан"
+// @Failure 401 {object} apierror.Problem "Неверный токен, пароль или код"
+// @Failure 403 {object} apierror.Problem "Роль требует 2FA"
+// @Failure 429 {object} apierror.Problem "Слишком много попыток"
+// @Router /mfa/totp/disable [post]
+func (h *MFAHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		apierror.Write(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	var req dto.DisableTOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.UseCase.DisableTOTP(userID, req); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(dto.MessageResponse{Message: i18n.T(i18n.FromRequest(r), "message.mfa_disabled")})
+}
+
+// RegenerateRecoveryCodes обрабатывает запросы на новые коды восстановления
+// @Summary Новые коды восстановления
+// @Description Проверяет текущий пароль и выдает новый набор одноразовых кодов восстановления. Прежние коды перестают действовать
+// @Security BearerAuth
+// @Param regenerate_recovery_codes_request body dto.RegenerateRecoveryCodesRequest true "Текущий пароль"
+// @Success 200 {object} dto.RecoveryCodesResponse "Новые коды восстановления"
+// @Failure 400 {object} apierror.Problem "Неверный запрос или TOTP не подключен"
+// @Failure 401 {object} apierror.Problem "Неверный токен или пароль"
+// @Failure 429 {object} apierror.Problem "Слишком много запросов, см. Retry-After"
+// @Router /mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		apierror.Write(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	var req dto.RegenerateRecoveryCodesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	codes, err := h.UseCase.RegenerateRecoveryCodes(userID, req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
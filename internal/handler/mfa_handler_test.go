@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	"sstu-go-forum-auth-service/internal/totp"
+	usecaseImpl "sstu-go-forum-auth-service/internal/usecase/impl"
+)
+
+func totpCode(t *testing.T, secret string, offset int64) string {
+	code, err := totp.Code(secret, totp.Step(time.Now())+offset)
+	require.NoError(t, err)
+	return code
+}
+
+func newMFAHandlers(requiredRoles ...string) (*AuthHandler, *MFAHandler, *memory.AuthRepository) {
+	repo := memory.NewRepository()
+	mfaUC := usecaseImpl.NewMFAUseCase(repo, "SSTU Forum", requiredRoles, ratelimit.NewSlidingWindow(5, time.Minute))
+	authUC := usecaseImpl.NewAuthUseCase(repo)
+	authUC.MFA = mfaUC
+	return NewAuthHandler(authUC), NewMFAHandler(mfaUC, authUC), repo
+}
+
+func mfaChallenge(t *testing.T, h *AuthHandler, username, password string) dto.MFAChallengeResponse {
+	rec := post(t, h.Login, dto.LoginRequest{Username: username, Password: password})
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var resp dto.MFAChallengeResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp
+}
+
+func TestTOTPFlow_EnrollAndLogin(t *testing.T) {
+	auth, h, _ := newMFAHandlers()
+	require.Equal(t, http.StatusOK, post(t, auth.Register, map[string]string{"username": "forum_user", "password": "secret1", "role": "USER"}).Code)
+	session := login(t, auth, "forum_user", "secret1")
+
+	rec := postWithToken(t, auth.Authenticate(h.Enroll), session.AccessToken, dto.TOTPEnrollRequest{CurrentPassword: "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "a stolen access token alone cannot enroll a second factor")
+	rec = postWithToken(t, auth.Authenticate(h.Enroll), session.AccessToken, dto.TOTPEnrollRequest{CurrentPassword: "secret1"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var enrollment dto.TOTPEnrollResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&enrollment))
+	rec = postWithToken(t, auth.Authenticate(h.Confirm), session.AccessToken, dto.TOTPCodeRequest{Code: totpCode(t, enrollment.Secret, -1)})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	challenge := mfaChallenge(t, auth, "forum_user", "secret1")
+	assert.False(t, challenge.EnrollmentRequired)
+	rec = post(t, auth.LoginMFA, dto.MFALoginRequest{MFAToken: challenge.MFAToken, Code: "000000"})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = post(t, auth.LoginMFA, dto.MFALoginRequest{MFAToken: challenge.MFAToken, Code: totpCode(t, enrollment.Secret, 0)})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp dto.AuthResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.NotEmpty(t, resp.AccessToken)
+
+	rec = postWithToken(t, auth.Authenticate(h.Enroll), challenge.MFAToken, dto.TOTPEnrollRequest{CurrentPassword: "secret1"})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "mfa token is not an access token")
+}
+
+func TestTOTPFlow_RequiredRoleEnrollsDuringLogin(t *testing.T) {
+	auth, h, repo := newMFAHandlers("ADMIN")
+	require.Equal(t, http.StatusOK, post(t, auth.Register, map[string]string{"username": "forum_admin", "password": "secret1"}).Code)
+	admin, err := repo.GetUserByUsername("forum_admin")
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateRole(admin.ID, "ADMIN"))
+
+	challenge := mfaChallenge(t, auth, "forum_admin", "secret1")
+	require.True(t, challenge.EnrollmentRequired)
+	rec := post(t, h.EnrollDuringLogin, dto.MFAEnrollRequest{MFAToken: challenge.MFAToken})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var enrollment dto.TOTPEnrollResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&enrollment))
+
+	rec = post(t, auth.LoginMFA, dto.MFALoginRequest{MFAToken: challenge.MFAToken, Code: totpCode(t, enrollment.Secret, 0)})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var session dto.AuthResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&session))
+
+	assert.Equal(t, http.StatusConflict, post(t, h.EnrollDuringLogin, dto.MFAEnrollRequest{MFAToken: challenge.MFAToken}).Code)
+	rec = postWithToken(t, auth.Authenticate(h.Disable), session.AccessToken,
+		dto.DisableTOTPRequest{CurrentPassword: "secret1", Code: totpCode(t, enrollment.Secret, 1)})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/rs/zerolog/log"
+	"sstu-go-forum-auth-service/internal/apierror"
+	"sstu-go-forum-auth-service/internal/i18n"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+)
+
+type claimsKey struct{}
+
+// Authenticate пропускает запрос дальше только с действующим access токеном в заголовке Authorization
+func (h *AuthHandler) Authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r.Header.Get("Authorization"))
+		if !ok {
+			apierror.Write(w, r, apierror.ErrMissingToken)
+			return
+		}
+		claims, err := h.UseCase.VerifyAccessToken(token)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), claimsKey{}, claims)
+		// Язык, выбранный пользователем, важнее Accept-Language; в токене он может отставать до его обновления
+		locale, _ := claims["locale"].(string)
+		next(w, r.WithContext(i18n.WithPreference(ctx, locale)))
+	}
+}
+
+// RequireRole пропускает запрос только с ролью role; ставится внутрь Authenticate
+func RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value(claimsKey{}).(jwt.MapClaims)
+		if !ok {
+			apierror.Write(w, r, apierror.ErrUnauthorized)
+			return
+		}
+		if got, _ := claims["role"].(string); got != role {
+			apierror.Write(w, r, apierror.ErrForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// UniformLatency задерживает ответ до minDuration с начала запроса. Так ответы для существующих
+// и несуществующих аккаунтов занимают одинаковое время, если работа укладывается в minDuration
+func UniformLatency(minDuration time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deadline := time.Now().Add(minDuration)
+		defer func() { time.Sleep(time.Until(deadline)) }()
+		next(w, r)
+	}
+}
+
+// RateLimit пропускает запрос, только если limiter разрешает его по ключу key(r); иначе отвечает 429 с Retry-After
+func RateLimit(limiter ratelimit.Limiter, key func(*http.Request) string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		k := key(r)
+		if !allow(w, r, limiter, k) {
+			log.Warn().Str("key", k).Str("path", r.URL.Path).Msg("Request rate limited")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// KeyByIP считает запросы по адресу клиента
+func KeyByIP(r *http.Request) string {
+	return "ip:" + clientIP(r)
+}
+
+// KeyByUsername считает запросы по полю username JSON-тела; тело остается доступным обработчику.
+// Запросы без имени считаются по адресу клиента
+func KeyByUsername(r *http.Request) string {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+	var req struct {
+		Username string `json:"username"`
+	}
+	if err != nil || json.Unmarshal(body, &req) != nil || req.Username == "" {
+		return KeyByIP(r)
+	}
+	return "username:" + model.UsernameKey(req.Username)
+}
+
+// KeyByClientID считает запросы по заголовку X-Client-ID, а без него — по адресу клиента.
+// Заголовок задает сам клиент, поэтому ключ подходит для доверенных сервисов, а не для защиты от перебора
+func KeyByClientID(r *http.Request) string {
+	if id := r.Header.Get("X-Client-ID"); id != "" {
+		return "client:" + id
+	}
+	return KeyByIP(r)
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// userIDFromContext возвращает ID пользователя из claims, сохраненных Authenticate
+func userIDFromContext(ctx context.Context) (int, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(jwt.MapClaims)
+	if !ok {
+		return 0, false
+	}
+	uid, ok := claims["user_id"].(float64)
+	return int(uid), ok
+}
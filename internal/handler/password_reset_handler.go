@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+
+	"sstu-go-forum-auth-service/internal/apierror"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/i18n"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/usecase"
+)
+
+type PasswordResetHandler struct {
+	UseCase usecase.PasswordResetUseCase
+	// IPLimiter ограничивает число запросов с одного IP
+	IPLimiter ratelimit.Limiter
+}
+
+func NewPasswordResetHandler(uc usecase.PasswordResetUseCase, ipLimiter ratelimit.Limiter) *PasswordResetHandler {
+	return &PasswordResetHandler{UseCase: uc, IPLimiter: ipLimiter}
+}
+
+// ForgotPassword обрабатывает запросы на отправку ссылки для сброса пароля
+// @Summary Запрос сброса пароля
+// @Description Отправляет на почту пользователя одноразовую ссылку для сброса пароля. Ответ не зависит от того, существует ли аккаунт
+// @Param forgot_password_request body dto.ForgotPasswordRequest true "Имя пользователя"
+// @Success 202 {object} dto.MessageResponse "Запрос принят"
+// @Failure 400 {object} apierror.Problem "Неверный запрос"
+// @Failure 429 {object} apierror.Problem "Слишком много запросов"
+// @Router /password/forgot [post]
+func (h *PasswordResetHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if !allowIP(w, r, h.IPLimiter) {
+		return
+	}
+
+	var req dto.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.UseCase.ForgotPassword(req); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(dto.MessageResponse{
+		Message: i18n.T(i18n.FromRequest(r), "message.password_reset_sent"),
+	})
+}
+
+// ResetPassword обрабатывает запросы на установку нового пароля по токену из письма
+// @Summary Сброс пароля
+// @Description Устанавливает новый пароль по одноразовому токену и завершает все сессии пользователя
+// @Param reset_password_request body dto.ResetPasswordRequest true "Токен из письма и новый пароль"
+// @Success 200 {object} dto.MessageResponse "Пароль изменен"
+// @Failure 400 {object} apierror.Problem "Неверный запрос, токен или новый пароль (список ошибок полей с кодами)"
+// @Failure 429 {object} apierror.Problem "Слишком много запросов"
+// @Router /password/reset [post]
+func (h *PasswordResetHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if !allowIP(w, r, h.IPLimiter) {
+		return
+	}
+
+	var req dto.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.UseCase.ResetPassword(req); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(dto.MessageResponse{Message: i18n.T(i18n.FromRequest(r), "message.password_reset")})
+}
+
+// allowIP применяет ограничение по IP и при превышении отвечает 429 с Retry-After
+func allowIP(w http.ResponseWriter, r *http.Request, limiter ratelimit.Limiter) bool {
+	return allow(w, r, limiter, clientIP(r))
+}
+
+func allow(w http.ResponseWriter, r *http.Request, limiter ratelimit.Limiter, key string) bool {
+	ok, retryAfter := limiter.Allow(key)
+	if !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		apierror.Write(w, r, apierror.ErrRateLimited)
+	}
+	return ok
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
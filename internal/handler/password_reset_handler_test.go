@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/mailer"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	"sstu-go-forum-auth-service/internal/timingtest"
+	usecaseImpl "sstu-go-forum-auth-service/internal/usecase/impl"
+)
+
+type inbox struct {
+	sent []mailer.Message
+}
+
+func (m *inbox) Send(msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// runNow выполняет фоновую работу usecase сразу, чтобы письмо было в inbox к концу запроса
+func runNow(task func()) { task() }
+
+func TestPasswordResetFlow_InMemory(t *testing.T) {
+	repo := memory.NewRepository()
+	auth := NewAuthHandler(usecaseImpl.NewAuthUseCase(repo))
+	mail := &inbox{}
+	resetUC := usecaseImpl.NewPasswordResetUseCase(repo, mail, ratelimit.NewSlidingWindow(3, time.Hour), "http://localhost/reset", 30*time.Minute)
+	resetUC.Background = runNow
+	h := NewPasswordResetHandler(resetUC, ratelimit.NewSlidingWindow(100, time.Hour))
+	require.Equal(t, http.StatusOK, post(t, auth.Register, map[string]string{
+		"username": "forum_user", "password": "secret1", "role": "USER", "email": "user@example.com",
+	}).Code)
+	session := login(t, auth, "forum_user", "secret1")
+
+	known := post(t, h.ForgotPassword, dto.ForgotPasswordRequest{Username: "forum_user"})
+	unknown := post(t, h.ForgotPassword, dto.ForgotPasswordRequest{Username: "nobody"})
+	assert.Equal(t, http.StatusAccepted, known.Code)
+	assert.Equal(t, known.Code, unknown.Code)
+	assert.Equal(t, known.Body.String(), unknown.Body.String())
+	require.Len(t, mail.sent, 1)
+	token := lastMailToken(t, mail)
+
+	rec := post(t, h.ResetPassword, dto.ResetPasswordRequest{Token: "bogus", NewPassword: "secret2"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = post(t, h.ResetPassword, dto.ResetPasswordRequest{Token: token, NewPassword: "secret2"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = post(t, h.ResetPassword, dto.ResetPasswordRequest{Token: token, NewPassword: "secret3"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	_, err := auth.UseCase.VerifyAccessToken(session.AccessToken)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, post(t, auth.Refresh, dto.RefreshRequest{RefreshToken: session.RefreshToken}).Code)
+	assert.Equal(t, http.StatusUnauthorized, post(t, auth.Login, dto.LoginRequest{Username: "forum_user", Password: "secret1"}).Code)
+	login(t, auth, "forum_user", "secret2")
+}
+
+func TestPasswordReset_ThrottledPerIP(t *testing.T) {
+	mail := &inbox{}
+	h := NewPasswordResetHandler(
+		usecaseImpl.NewPasswordResetUseCase(memory.NewRepository(), mail, ratelimit.NewSlidingWindow(3, time.Hour), "http://localhost/reset", 30*time.Minute),
+		ratelimit.NewSlidingWindow(2, time.Hour),
+	)
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusAccepted, post(t, h.ForgotPassword, dto.ForgotPasswordRequest{Username: "nobody"}).Code)
+	}
+	rec := post(t, h.ForgotPassword, dto.ForgotPasswordRequest{Username: "nobody"})
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+}
+
+func TestForgotPassword_UniformLatency(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing test")
+	}
+	repo := memory.NewRepository()
+	require.NoError(t, repo.CreateUser(&model.User{Username: "forum_user", Password: "hash", Role: "USER", Email: "user@example.com"}))
+	h := NewPasswordResetHandler(
+		usecaseImpl.NewPasswordResetUseCase(repo, &inbox{}, ratelimit.NewSlidingWindow(1000, time.Hour), "http://localhost/reset", 30*time.Minute),
+		ratelimit.NewSlidingWindow(1000, time.Hour),
+	)
+	forgot := UniformLatency(20*time.Millisecond, h.ForgotPassword)
+
+	known, unknown := timingtest.Sample(30,
+		func() { post(t, forgot, dto.ForgotPasswordRequest{Username: "forum_user"}) },
+		func() { post(t, forgot, dto.ForgotPasswordRequest{Username: "nobody"}) },
+	)
+	timingtest.AssertIndistinguishable(t, known, unknown)
+	assert.GreaterOrEqual(t, slices.Min(unknown), 20*time.Millisecond)
+}
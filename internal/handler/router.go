@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"sstu-go-forum-auth-service/internal/apierror"
+	"sstu-go-forum-auth-service/internal/cors"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+)
+
+// APIPrefix — префикс текущей версии API. Те же маршруты без префикса оставлены для старых клиентов
+// и помечены устаревшими заголовками Deprecation и Link
+const APIPrefix = "/api/v1"
+
+// Router сопоставляет запросы с маршрутами по методу и пути. На известный путь с другим методом
+// отвечает 405 с заголовком Allow, на неизвестный — 404, оба в формате problem+json
+type Router struct {
+	mux *http.ServeMux
+	// methods — разрешенные методы каждого пути без префикса
+	methods map[string][]string
+	// cors — политики CORS путей без префикса, заменяющие политику по умолчанию
+	cors map[string]*cors.Policy
+}
+
+func NewRouter() *Router {
+	rt := &Router{mux: http.NewServeMux(), methods: map[string][]string{}, cors: map[string]*cors.Policy{}}
+	rt.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		apierror.Write(w, r, apierror.ErrNotFound)
+	})
+	return rt
+}
+
+// Handle регистрирует h на method и path под APIPrefix, а также на устаревшем пути без префикса
+func (rt *Router) Handle(method, path string, h http.HandlerFunc) {
+	if _, ok := rt.methods[path]; !ok {
+		allowed := rt.allow(path)
+		rt.mux.HandleFunc(APIPrefix+path, allowed)
+		rt.mux.HandleFunc(path, allowed)
+	}
+	rt.methods[path] = append(rt.methods[path], method)
+	rt.mux.HandleFunc(method+" "+APIPrefix+path, h)
+	rt.mux.HandleFunc(method+" "+path, deprecated(APIPrefix+path, h))
+}
+
+// Mount регистрирует h на шаблон ServeMux как есть, без версии; для служебных страниц вроде документации
+func (rt *Router) Mount(pattern string, h http.Handler) {
+	rt.mux.Handle(pattern, h)
+}
+
+// SetCORS задает пути path (без префикса) свою политику CORS вместо политики по умолчанию
+func (rt *Router) SetCORS(path string, policy *cors.Policy) {
+	rt.cors[path] = policy
+}
+
+// CORS применяет к запросу политику его маршрута, см. SetCORS, или def. Предварительный запрос к методу,
+// которого у маршрута нет, или с неразрешенными origin, методом или заголовками получает 403.
+// Ставится перед BodyLimit и Timeout, чтобы их ответы тоже были доступны фронтенду
+func (rt *Router) CORS(def *cors.Policy) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path, methods := rt.route(r)
+			policy, ok := rt.cors[path]
+			if !ok {
+				policy = def
+			}
+			if !cors.IsPreflight(r) {
+				policy.Actual(w, r)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !slices.Contains(methods, r.Header.Get("Access-Control-Request-Method")) || !policy.Preflight(w, r) {
+				log.Debug().Str("origin", r.Header.Get("Origin")).Str("path", r.URL.Path).Msg("CORS preflight rejected")
+				apierror.Write(w, r, apierror.ErrOriginNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// route находит путь маршрута запроса без префикса и его методы
+func (rt *Router) route(r *http.Request) (string, []string) {
+	_, pattern := rt.mux.Handler(r)
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method, path = "", pattern
+	}
+	path = strings.TrimPrefix(path, APIPrefix)
+	if methods, ok := rt.methods[path]; ok {
+		return path, methods
+	}
+	if method != "" {
+		return path, []string{method}
+	}
+	return path, nil
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.mux.ServeHTTP(w, r)
+}
+
+// allow отвечает на запрос к path методом, для которого нет маршрута
+func (rt *Router) allow(path string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		methods := slices.Clone(rt.methods[path])
+		if slices.Contains(methods, http.MethodGet) {
+			methods = append(methods, http.MethodHead)
+		}
+		slices.Sort(methods)
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		apierror.Write(w, r, apierror.ErrMethodNotAllowed)
+	}
+}
+
+// deprecated помечает ответ устаревшего пути ссылкой на путь-преемник (RFC 9745, RFC 8288)
+func deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		next(w, r)
+	}
+}
+
+// API — обработчики и ограничения, из которых собираются маршруты HTTP API
+type API struct {
+	Auth      *AuthHandler
+	Email     *EmailHandler
+	MFA       *MFAHandler
+	WebAuthn  *WebAuthnHandler
+	MagicLink *MagicLinkHandler
+	Reset     *PasswordResetHandler
+
+	LoginIPLimit       ratelimit.Limiter
+	LoginUsernameLimit ratelimit.Limiter
+	RegisterIPLimit    ratelimit.Limiter
+	RefreshIPLimit     ratelimit.Limiter
+	// CredentialsIPLimit — общий лимит с IP для остальных маршрутов, проверяющих пароль, код второго фактора,
+	// ключ доступа или токен из письма. Ссылка входа и сброс пароля ограничены по IP в своих обработчиках
+	CredentialsIPLimit ratelimit.Limiter
+	// UniformResponseTime — см. UniformLatency
+	UniformResponseTime time.Duration
+}
+
+// Routes регистрирует все маршруты API
+func (a *API) Routes() *Router {
+	auth := a.Auth.Authenticate
+	uniform := func(next http.HandlerFunc) http.HandlerFunc { return UniformLatency(a.UniformResponseTime, next) }
+	credentials := func(next http.HandlerFunc) http.HandlerFunc { return RateLimit(a.CredentialsIPLimit, KeyByIP, next) }
+
+	rt := NewRouter()
+	rt.Handle(http.MethodPost, "/register", RateLimit(a.RegisterIPLimit, KeyByIP, uniform(a.Auth.Register)))
+	rt.Handle(http.MethodPost, "/login", RateLimit(a.LoginIPLimit, KeyByIP,
+		RateLimit(a.LoginUsernameLimit, KeyByUsername, a.Auth.Login)))
+	rt.Handle(http.MethodPost, "/login/mfa", credentials(a.Auth.LoginMFA))
+	rt.Handle(http.MethodPost, "/login/mfa/enroll", credentials(a.MFA.EnrollDuringLogin))
+	rt.Handle(http.MethodPost, "/login/mfa/webauthn/begin", a.WebAuthn.BeginMFA)
+	rt.Handle(http.MethodPost, "/login/webauthn/begin", a.WebAuthn.BeginLogin)
+	rt.Handle(http.MethodPost, "/login/webauthn/finish", credentials(a.WebAuthn.FinishLogin))
+	rt.Handle(http.MethodPost, "/login/magic-link", uniform(a.MagicLink.SendLink))
+	rt.Handle(http.MethodPost, "/login/magic-link/finish", a.MagicLink.Login)
+	rt.Handle(http.MethodPost, "/refresh", RateLimit(a.RefreshIPLimit, KeyByIP, a.Auth.Refresh))
+	rt.Handle(http.MethodPost, "/password/change", credentials(auth(a.Auth.ChangePassword)))
+	rt.Handle(http.MethodPost, "/password/forgot", uniform(a.Reset.ForgotPassword))
+	rt.Handle(http.MethodPost, "/password/reset", a.Reset.ResetPassword)
+	rt.Handle(http.MethodPost, "/email/verify", credentials(a.Email.VerifyEmail))
+	rt.Handle(http.MethodPost, "/email/verify/resend", uniform(a.Email.ResendVerification))
+	rt.Handle(http.MethodPost, "/email/change", credentials(auth(a.Email.ChangeEmail)))
+	rt.Handle(http.MethodPost, "/locale/change", auth(a.Auth.ChangeLocale))
+	rt.Handle(http.MethodPost, "/mfa/totp/enroll", credentials(auth(a.MFA.Enroll)))
+	rt.Handle(http.MethodPost, "/mfa/totp/confirm", credentials(auth(a.MFA.Confirm)))
+	rt.Handle(http.MethodPost, "/mfa/totp/disable", credentials(auth(a.MFA.Disable)))
+	rt.Handle(http.MethodPost, "/mfa/recovery-codes", credentials(auth(a.MFA.RegenerateRecoveryCodes)))
+	rt.Handle(http.MethodPost, "/webauthn/register/begin", credentials(auth(a.WebAuthn.BeginRegistration)))
+	rt.Handle(http.MethodPost, "/webauthn/register/finish", auth(a.WebAuthn.FinishRegistration))
+	rt.Handle(http.MethodGet, "/webauthn/credentials", auth(a.WebAuthn.Credentials))
+	rt.Handle(http.MethodPost, "/webauthn/credentials/delete", credentials(auth(a.WebAuthn.DeleteCredential)))
+	rt.Handle(http.MethodPost, "/admin/login/unlock", auth(RequireRole("ADMIN", a.Auth.UnlockLogin)))
+	return rt
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/cors"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	usecaseImpl "sstu-go-forum-auth-service/internal/usecase/impl"
+)
+
+func newTestRoutes() *Router {
+	limit := func() ratelimit.Limiter { return ratelimit.NewSlidingWindow(100, time.Minute) }
+	api := &API{
+		Auth:               NewAuthHandler(usecaseImpl.NewAuthUseCase(memory.NewRepository())),
+		LoginIPLimit:       limit(),
+		LoginUsernameLimit: limit(),
+		RegisterIPLimit:    limit(),
+		RefreshIPLimit:     limit(),
+		CredentialsIPLimit: limit(),
+	}
+	return api.Routes()
+}
+
+func serve(t *testing.T, h http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(payload)))
+	return rec
+}
+
+func TestRouter_VersionedAndDeprecatedPaths(t *testing.T) {
+	routes := newTestRoutes()
+	creds := map[string]string{"username": "forum_user", "password": "secret1", "role": "USER"}
+	require.Equal(t, http.StatusOK, serve(t, routes, http.MethodPost, "/api/v1/register", creds).Code)
+
+	rec := serve(t, routes, http.MethodPost, "/api/v1/login", dto.LoginRequest{Username: "forum_user", Password: "secret1"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+
+	rec = serve(t, routes, http.MethodPost, "/login", dto.LoginRequest{Username: "forum_user", Password: "secret1"})
+	assert.Equal(t, http.StatusOK, rec.Code, "the old path still works")
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Equal(t, `</api/v1/login>; rel="successor-version"`, rec.Header().Get("Link"))
+}
+
+func TestRouter_UnknownMethodAndPath(t *testing.T) {
+	routes := newTestRoutes()
+	for _, c := range []struct{ method, path, allow string }{
+		{http.MethodGet, "/api/v1/login", "POST"},
+		{http.MethodGet, "/login", "POST"},
+		{http.MethodDelete, "/api/v1/webauthn/credentials", "GET, HEAD"},
+	} {
+		rec := serve(t, routes, c.method, c.path, nil)
+		p := decodeProblem(t, rec, http.StatusMethodNotAllowed)
+		assert.Equal(t, "request.method_not_allowed", p.Code, c.path)
+		assert.Equal(t, c.allow, rec.Header().Get("Allow"), c.path)
+	}
+
+	p := decodeProblem(t, serve(t, routes, http.MethodPost, "/api/v2/login", nil), http.StatusNotFound)
+	assert.Equal(t, "request.not_found", p.Code)
+}
+
+func preflight(routes *Router, def *cors.Policy, path, origin, method, headers string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, path, nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		req.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	rec := httptest.NewRecorder()
+	Chain(routes, routes.CORS(def)).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRouter_CORSPreflight(t *testing.T) {
+	routes := newTestRoutes()
+	def := &cors.Policy{
+		AllowedOrigins:   []string{"https://forum.example", "https://*.forum.example"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	routes.SetCORS("/admin/login/unlock", &cors.Policy{
+		AllowedOrigins: []string{"https://admin.example"},
+		AllowedMethods: []string{http.MethodPost},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+	})
+
+	for _, c := range []struct {
+		name, path, origin, method, headers string
+		allowed                             bool
+	}{
+		{"exact origin", "/api/v1/login", "https://forum.example", "POST", "content-type", true},
+		{"subdomain", "/api/v1/login", "https://app.forum.example", "POST", "Content-Type, Authorization", true},
+		{"deprecated path", "/login", "https://forum.example", "POST", "", true},
+		{"GET route", "/api/v1/webauthn/credentials", "https://forum.example", "GET", "authorization", true},
+		{"unknown origin", "/api/v1/login", "https://evil.example", "POST", "", false},
+		{"lookalike origin", "/api/v1/login", "https://forum.example.evil.example", "POST", "", false},
+		{"method the route lacks", "/api/v1/login", "https://forum.example", "GET", "", false},
+		{"method the policy lacks", "/api/v1/login", "https://forum.example", "DELETE", "", false},
+		{"header not allowed", "/api/v1/login", "https://forum.example", "POST", "X-Custom", false},
+		{"unknown path", "/api/v1/nope", "https://forum.example", "POST", "", false},
+		{"route override allows", "/api/v1/admin/login/unlock", "https://admin.example", "POST", "authorization", true},
+		{"route override replaces default", "/api/v1/admin/login/unlock", "https://forum.example", "POST", "", false},
+	} {
+		rec := preflight(routes, def, c.path, c.origin, c.method, c.headers)
+		if !c.allowed {
+			p := decodeProblem(t, rec, http.StatusForbidden)
+			assert.Equal(t, "request.origin_not_allowed", p.Code, c.name)
+			assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"), c.name)
+			continue
+		}
+		assert.Equal(t, http.StatusNoContent, rec.Code, c.name)
+		assert.Equal(t, c.origin, rec.Header().Get("Access-Control-Allow-Origin"), c.name)
+		assert.Contains(t, rec.Header().Values("Vary"), "Origin", c.name)
+	}
+
+	rec := preflight(routes, def, "/api/v1/login", "https://forum.example", "POST", "")
+	assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "GET, POST", rec.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, Authorization", rec.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+
+	rec = preflight(routes, def, "/api/v1/admin/login/unlock", "https://admin.example", "POST", "")
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"), "the override has its own credentials setting")
+	assert.Empty(t, rec.Header().Get("Access-Control-Max-Age"))
+}
+
+func TestRouter_CORSActualRequest(t *testing.T) {
+	routes := newTestRoutes()
+	def := &cors.Policy{AllowedOrigins: []string{"https://forum.example"}, ExposedHeaders: []string{"X-Request-ID"}}
+	h := Chain(routes, routes.CORS(def))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader("{"))
+	req.Header.Set("Origin", "https://forum.example")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	decodeProblem(t, rec, http.StatusBadRequest)
+	assert.Equal(t, "https://forum.example", rec.Header().Get("Access-Control-Allow-Origin"), "errors are readable by the frontend")
+	assert.Equal(t, "X-Request-ID", rec.Header().Get("Access-Control-Expose-Headers"))
+
+	req.Header.Set("Origin", "https://evil.example")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "the request itself is served, the browser hides the response")
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+	req = httptest.NewRequest(http.MethodOptions, "/api/v1/login", nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	decodeProblem(t, rec, http.StatusMethodNotAllowed)
+	assert.Equal(t, "POST", rec.Header().Get("Allow"), "OPTIONS without CORS headers is not a preflight")
+}
+
+func TestRouter_CredentialRoutesShareIPLimit(t *testing.T) {
+	api := &API{
+		Auth:               NewAuthHandler(usecaseImpl.NewAuthUseCase(memory.NewRepository())),
+		CredentialsIPLimit: ratelimit.NewSlidingWindow(1, time.Minute),
+	}
+	routes := api.Routes()
+	decodeProblem(t, serve(t, routes, http.MethodPost, "/api/v1/password/change", nil), http.StatusUnauthorized)
+	rec := serve(t, routes, http.MethodPost, "/api/v1/mfa/totp/disable", nil)
+	p := decodeProblem(t, rec, http.StatusTooManyRequests)
+	assert.Equal(t, "request.rate_limited", p.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"sstu-go-forum-auth-service/internal/apierror"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/i18n"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/usecase"
+)
+
+type WebAuthnHandler struct {
+	UseCase usecase.WebAuthnUseCase
+	// Auth выдает токены после входа по ключу и проверяет MFA токены
+	Auth usecase.AuthUseCase
+}
+
+func NewWebAuthnHandler(uc usecase.WebAuthnUseCase, auth usecase.AuthUseCase) *WebAuthnHandler {
+	return &WebAuthnHandler{UseCase: uc, Auth: auth}
+}
+
+// BeginRegistration обрабатывает начало регистрации ключа доступа
+// @Summary Начало регистрации ключа доступа
+// @Description Требует текущий пароль. Возвращает параметры для navigator.credentials.create. Ответ браузера отправляется в /webauthn/register/finish
+// @Security BearerAuth
+// @Param webauthn_begin_registration_request body dto.WebAuthnBeginRegistrationRequest true "Текущий пароль"
+// @Success 200 {object} dto.WebAuthnBeginResponse "Параметры регистрации"
+// @Failure 400 {object} apierror.Problem "Неверный запрос"
+// @Failure 401 {object} apierror.Problem "Неверный токен или пароль"
+// @Failure 429 {object} apierror.Problem "Слишком много запросов, см. Retry-After"
+// @Router /webauthn/register/begin [post]
+func (h *WebAuthnHandler) BeginRegistration(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		apierror.Write(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	var req dto.WebAuthnBeginRegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	resp, err := h.UseCase.BeginRegistration(userID, req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// FinishRegistration обрабатывает завершение регистрации ключа доступа
+// @Summary Завершение регистрации ключа доступа
+// @Description Проверяет ответ navigator.credentials.create и сохраняет ключ. После этого ключ служит для входа без пароля и вторым фактором
+// @Security BearerAuth
+// @Param webauthn_register_request body dto.WebAuthnRegisterRequest true "Ответ браузера"
+// @Success 200 {object} model.WebAuthnCredential "Сохраненный ключ"
+// @Failure 400 {object} apierror.Problem "Неверный запрос или ответ ключа"
+// @Failure 401 {object} apierror.Problem "Неверный токен"
+// @Router /webauthn/register/finish [post]
+func (h *WebAuthnHandler) FinishRegistration(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		apierror.Write(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	var req dto.WebAuthnRegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	credential, err := h.UseCase.FinishRegistration(userID, req)
+	if err != nil {
+		// Пользователь уже вошел, поэтому неверная аттестация ключа — ошибка запроса, а не входа
+		apierror.Write(w, r, apierror.WithStatus(err, http.StatusBadRequest))
+		return
+	}
+	json.NewEncoder(w).Encode(credential)
+}
+
+// Credentials обрабатывает запросы на список ключей доступа
+// @Summary Список ключей доступа
+// @Security BearerAuth
+// @Success 200 {array} model.WebAuthnCredential "Ключи пользователя"
+// @Failure 401 {object} apierror.Problem "Неверный токен"
+// @Router /webauthn/credentials [get]
+func (h *WebAuthnHandler) Credentials(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		apierror.Write(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	credentials, err := h.UseCase.ListCredentials(userID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if credentials == nil {
+		credentials = []model.WebAuthnCredential{}
+	}
+	json.NewEncoder(w).Encode(credentials)
+}
+
+// DeleteCredential обрабатывает удаление ключа доступа
+// @Summary Удаление ключа доступа
+// @Security BearerAuth
+// @Description Требует текущий пароль. Последний второй фактор роли, которой он обязателен, удалить нельзя
+// @Param delete_webauthn_credential_request body dto.DeleteWebAuthnCredentialRequest true "ID ключа и текущий пароль"
+// @Success 200 {object} dto.MessageResponse "Ключ удален"
+// @Failure 400 {object} apierror.Problem "Неверный запрос"
+// @Failure 401 {object} apierror.Problem "Неверный токен или пароль"
+// @Failure 403 {object} apierror.Problem "Второй фактор обязателен для роли"
+// @Failure 404 {object} apierror.Problem "Ключ не найден"
+// @Failure 429 {object} apierror.Problem "Слишком много запросов, см. Retry-After"
+// @Router /webauthn/credentials/delete [post]
+func (h *WebAuthnHandler) DeleteCredential(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		apierror.Write(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	var req dto.DeleteWebAuthnCredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.UseCase.DeleteCredential(userID, req); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(dto.MessageResponse{Message: i18n.T(i18n.FromRequest(r), "message.webauthn_credential_deleted")})
+}
+
+// BeginLogin обрабатывает начало входа по passkey
+// @Summary Начало входа по passkey
+// @Description Возвращает параметры для navigator.credentials.get без имени пользователя: браузер предложит сохраненные passkey
+// @Success 200 {object} dto.WebAuthnBeginResponse "Параметры входа"
+// @Router /login/webauthn/begin [post]
+func (h *WebAuthnHandler) BeginLogin(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.UseCase.BeginLogin(0)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// FinishLogin обрабатывает завершение входа по passkey
+// @Summary Вход по passkey
+// @Description Проверяет ответ navigator.credentials.get и выдает токены. Ключ проверяет пользователя сам, поэтому второй фактор не запрашивается
+// @Param webauthn_login_request body dto.WebAuthnLoginRequest true "Ответ браузера"
+// @Success 200 {object} dto.AuthResponse "Ответ с токенами"
+// @Failure 400 {object} apierror.Problem "Неверный запрос"
+// @Failure 401 {object} apierror.Problem "Неверный ответ ключа"
+// @Failure 403 {object} apierror.Problem "Почта не подтверждена"
+// @Failure 429 {object} apierror.Problem "Слишком много запросов, см. Retry-After"
+// @Router /login/webauthn/finish [post]
+func (h *WebAuthnHandler) FinishLogin(w http.ResponseWriter, r *http.Request) {
+	var req dto.WebAuthnLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	_, access, refresh, err := h.Auth.LoginWebAuthn(req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(dto.AuthResponse{AccessToken: access, RefreshToken: refresh})
+}
+
+// BeginMFA обрабатывает начало проверки ключом доступа на втором шаге входа
+// @Summary Ключ доступа как второй фактор
+// @Description Возвращает параметры для navigator.credentials.get по ключам пользователя из MFA токена. Ответ браузера отправляется в /login/mfa
+// @Param webauthn_mfa_begin_request body dto.WebAuthnMFABeginRequest true "MFA токен"
+// @Success 200 {object} dto.WebAuthnBeginResponse "Параметры входа"
+// @Failure 400 {object} apierror.Problem "Неверный запрос или нет ключей"
+// @Failure 401 {object} apierror.Problem "Неверный MFA токен"
+// @Router /login/mfa/webauthn/begin [post]
+func (h *WebAuthnHandler) BeginMFA(w http.ResponseWriter, r *http.Request) {
+	var req dto.WebAuthnMFABeginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return
+	}
+	defer r.Body.Close()
+
+	userID, err := h.Auth.VerifyMFAToken(req.MFAToken)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	resp, err := h.UseCase.BeginLogin(userID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	usecaseImpl "sstu-go-forum-auth-service/internal/usecase/impl"
+	"sstu-go-forum-auth-service/internal/webauthntest"
+)
+
+func newWebAuthnHandlers(t *testing.T) (*AuthHandler, *WebAuthnHandler) {
+	repo := memory.NewRepository()
+	wa, err := webauthn.New(&webauthn.Config{RPID: "forum.example", RPDisplayName: "SSTU Forum", RPOrigins: []string{"https://forum.example"}})
+	require.NoError(t, err)
+	webAuthnUC := usecaseImpl.NewWebAuthnUseCase(repo, wa, time.Minute)
+	authUC := usecaseImpl.NewAuthUseCase(repo)
+	authUC.MFA = usecaseImpl.NewMFAUseCase(repo, "SSTU Forum", nil, ratelimit.NewSlidingWindow(5, time.Minute))
+	authUC.WebAuthn = webAuthnUC
+	return NewAuthHandler(authUC), NewWebAuthnHandler(webAuthnUC, authUC)
+}
+
+func decodeBegin(t *testing.T, rec *httptest.ResponseRecorder) dto.WebAuthnBeginResponse {
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp dto.WebAuthnBeginResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp
+}
+
+func TestWebAuthnFlow_PasskeyAndSecondFactor(t *testing.T) {
+	auth, h := newWebAuthnHandlers(t)
+	require.Equal(t, http.StatusOK, post(t, auth.Register, map[string]string{"username": "forum_user", "password": "secret1", "role": "USER"}).Code)
+	session := login(t, auth, "forum_user", "secret1")
+	key := webauthntest.New("https://forum.example")
+
+	rec := postWithToken(t, auth.Authenticate(h.BeginRegistration), session.AccessToken, dto.WebAuthnBeginRegistrationRequest{CurrentPassword: "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "a stolen access token alone cannot add a passkey")
+	begin := decodeBegin(t, postWithToken(t, auth.Authenticate(h.BeginRegistration), session.AccessToken,
+		dto.WebAuthnBeginRegistrationRequest{CurrentPassword: "secret1"}))
+	response, err := key.Create(begin.Options)
+	require.NoError(t, err)
+	rec = post(t, h.FinishRegistration, dto.WebAuthnRegisterRequest{SessionToken: begin.SessionToken, Credential: response})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "registration requires an access token")
+	rec = postWithToken(t, auth.Authenticate(h.FinishRegistration), session.AccessToken, dto.WebAuthnRegisterRequest{SessionToken: begin.SessionToken, Name: "Телефон", Credential: response})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+session.AccessToken)
+	rec = httptest.NewRecorder()
+	auth.Authenticate(h.Credentials)(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var credentials []model.WebAuthnCredential
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&credentials))
+	require.Len(t, credentials, 1)
+	assert.Equal(t, "Телефон", credentials[0].Name)
+
+	// Вход без пароля
+	begin = decodeBegin(t, post(t, h.BeginLogin, nil))
+	response, err = key.Get(begin.Options)
+	require.NoError(t, err)
+	rec = post(t, h.FinishLogin, dto.WebAuthnLoginRequest{SessionToken: begin.SessionToken, Credential: response})
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = post(t, h.FinishLogin, dto.WebAuthnLoginRequest{SessionToken: begin.SessionToken, Credential: response})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "replayed response")
+
+	// Ключ как второй фактор после пароля
+	challenge := mfaChallenge(t, auth, "forum_user", "secret1")
+	begin = decodeBegin(t, post(t, h.BeginMFA, dto.WebAuthnMFABeginRequest{MFAToken: challenge.MFAToken}))
+	response, err = key.Get(begin.Options)
+	require.NoError(t, err)
+	rec = post(t, auth.LoginMFA, dto.MFALoginRequest{MFAToken: challenge.MFAToken, WebAuthnSession: begin.SessionToken, WebAuthnCredential: response})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp dto.AuthResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.NotEmpty(t, resp.AccessToken)
+
+	rec = postWithToken(t, auth.Authenticate(h.DeleteCredential), session.AccessToken, dto.DeleteWebAuthnCredentialRequest{ID: credentials[0].ID + 1, CurrentPassword: "secret1"})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = postWithToken(t, auth.Authenticate(h.DeleteCredential), session.AccessToken, dto.DeleteWebAuthnCredentialRequest{ID: credentials[0].ID})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "deleting a passkey requires the current password")
+	rec = postWithToken(t, auth.Authenticate(h.DeleteCredential), session.AccessToken, dto.DeleteWebAuthnCredentialRequest{ID: credentials[0].ID, CurrentPassword: "secret1"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	login(t, auth, "forum_user", "secret1")
+}
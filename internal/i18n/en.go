@@ -0,0 +1,90 @@
+package i18n
+
+var en = map[string]string{
+	"message.registered":                  "User registered",
+	"message.login_unlocked":              "Login lock removed",
+	"message.locale_changed":              "Language changed",
+	"message.email_verified":              "Email address verified",
+	"message.verification_sent":           "If the account exists and its email is not verified, a message has been sent to it",
+	"message.email_changed":               "Email address changed, confirm the new address using the link in the message",
+	"message.password_reset_sent":         "If the account exists, a password reset link has been sent to its email",
+	"message.password_reset":              "Password changed",
+	"message.magic_link_sent":             "If the account exists, a login link has been sent to its email",
+	"message.mfa_enabled":                 "Two-factor authentication enabled",
+	"message.mfa_disabled":                "Two-factor authentication disabled",
+	"message.webauthn_credential_deleted": "Passkey deleted",
+
+	"error.server.internal":                  "Internal server error",
+	"error.request.invalid":                  "Malformed request",
+	"error.request.validation_failed":        "Some fields are invalid",
+	"error.request.not_found":                "Resource not found",
+	"error.request.method_not_allowed":       "Method not allowed",
+	"error.request.too_large":                "Request body is too large",
+	"error.request.timeout":                  "The server did not finish the request in time, try again later",
+	"error.request.origin_not_allowed":       "Requests from this origin are not allowed",
+	"error.request.rate_limited":             "Too many requests, try again later",
+	"error.auth.missing_token":               "Bearer token is required",
+	"error.auth.unauthorized":                "Authentication required",
+	"error.auth.forbidden":                   "Forbidden",
+	"error.auth.user_exists":                 "Username is already taken",
+	"error.auth.username_reserved":           "Username is reserved",
+	"error.auth.username_confusable":         "Username is too similar to an existing one",
+	"error.auth.invalid_credentials":         "Invalid username or password",
+	"error.auth.login_locked":                "Login is temporarily locked after failed attempts",
+	"error.auth.invalid_refresh_token":       "Refresh token is invalid, expired or already used",
+	"error.auth.token_reused":                "Refresh token was already used; all sessions have been signed out, please sign in again",
+	"error.auth.invalid_access_token":        "Access token is invalid or expired",
+	"error.auth.invalid_token_data":          "Token contains invalid data",
+	"error.auth.token_revoked":               "Token has been revoked, log in again",
+	"error.auth.invalid_new_password":        "New password does not meet the requirements",
+	"error.auth.invalid_reset_token":         "Password reset link is invalid or expired",
+	"error.auth.invalid_magic_link":          "Login link is invalid or expired",
+	"error.email.invalid":                    "Invalid email address",
+	"error.email.taken":                      "Email address is already in use",
+	"error.email.not_verified":               "Email address is not verified",
+	"error.email.invalid_verification_token": "Verification link is invalid or expired",
+	"error.mfa.invalid_token":                "MFA token is invalid or expired",
+	"error.mfa.invalid_code":                 "Invalid verification code",
+	"error.mfa.too_many_attempts":            "Too many code attempts",
+	"error.mfa.already_enabled":              "Two-factor authentication is already enabled",
+	"error.mfa.not_enrolled":                 "Two-factor authentication is not set up",
+	"error.mfa.required":                     "Two-factor authentication is required for this role",
+	"error.webauthn.invalid_response":        "Passkey response is invalid or expired",
+	"error.webauthn.not_registered":          "No passkeys are registered",
+	"error.webauthn.credential_not_found":    "Passkey not found",
+
+	"field.username.required":           "username is required",
+	"field.username.length":             "username must be %d to %d characters",
+	"field.username.invalid_characters": "username may contain only letters, digits, '_', '-' and '.', and must start and end with a letter or digit",
+	"field.username.reserved":           "username is reserved",
+	"field.username.confusable":         "username is too similar to an existing one",
+	"field.username.taken":              "username is already taken",
+	"field.role.invalid_value":          "role must be USER or ADMIN",
+	"field.email.invalid_format":        "email is invalid",
+	"field.locale.invalid_value":        "locale must be one of: %s",
+
+	"password.min_length":        "password must be at least %d characters",
+	"password.max_length":        "password must be at most %d characters",
+	"password.character_classes": "password must contain at least %d of: lowercase letters, uppercase letters, digits, other characters",
+	"password.entropy":           "password is too easy to guess",
+	"password.contains_username": "password must not contain the username",
+	"password.breached":          "password has appeared in a data breach, choose another one",
+
+	"mail.verify_email.subject": "Confirm your email address",
+	"mail.verify_email.body": "Hello, %s!\n\nTo confirm your email address, follow this link:\n%s\n\n" +
+		"The link is valid for %d h. If you did not sign up for the forum, just ignore this message.\n",
+	"mail.email_changed.subject": "Email address changed",
+	"mail.email_changed.body": "Hello, %s!\n\nThe email address of your account has been changed to %s. " +
+		"If it was not you, recover access to your account and change your password.\n",
+	"mail.password_reset.subject": "Password reset",
+	"mail.password_reset.body": "Hello, %s!\n\nTo set a new password, follow this link:\n%s\n\n" +
+		"The link is valid for %d minutes and can be used once. " +
+		"If you did not request a password reset, just ignore this message.\n",
+	"mail.magic_link.subject": "Log in to the forum",
+	"mail.magic_link.body": "Hello, %s!\n\nTo log in to the forum without a password, follow this link:\n%s\n\n" +
+		"The link is valid for %d minutes, can be used once and only in the browser where you requested it. " +
+		"If you did not request to log in, just ignore this message.\n",
+	"mail.recovery_code_used.subject": "Recovery code used",
+	"mail.recovery_code_used.body": "Hello, %s!\n\nA recovery code was used to log in to your account. Codes left: %d.\n" +
+		"If it was not you, change your password and generate new recovery codes.\n",
+}
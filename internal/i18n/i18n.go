@@ -0,0 +1,106 @@
+// Package i18n хранит тексты, которые видит пользователь: сообщения ответов, ошибки, ошибки полей и письма.
+// Каждый ключ должен быть переведен на все языки из Locales, это проверяет тест каталога
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/text/language"
+)
+
+// Поддерживаемые языки; Default используется, когда язык не выбран или не поддерживается
+const (
+	RU      = "ru"
+	EN      = "en"
+	Default = RU
+)
+
+var Locales = []string{RU, EN}
+
+var catalogs = map[string]map[string]string{RU: ru, EN: en}
+
+// matcher сопоставляет Accept-Language с Locales; порядок тегов совпадает с Locales
+var matcher = language.NewMatcher([]language.Tag{language.Russian, language.English})
+
+// T возвращает текст ключа key на языке locale, подставляя args как в fmt.Sprintf. Неподдерживаемый язык
+// заменяется на Default, а ключ без перевода — текстом на Default или самим ключом
+func T(locale, key string, args ...any) string {
+	text, ok := catalogs[Supported(locale)][key]
+	if !ok {
+		text, ok = catalogs[Default][key]
+	}
+	if !ok {
+		log.Warn().Str("key", key).Str("locale", locale).Msg("Message key is missing from the catalog")
+		return key
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// Supported возвращает locale, если язык поддерживается, иначе Default
+func Supported(locale string) string {
+	if _, ok := catalogs[locale]; ok {
+		return locale
+	}
+	return Default
+}
+
+// Parse приводит выбор пользователя вроде "en-US" или "RU" к поддерживаемому языку; ok = false, если такого нет
+func Parse(locale string) (string, bool) {
+	tag, err := language.Parse(strings.TrimSpace(locale))
+	if err != nil {
+		return "", false
+	}
+	base, _ := tag.Base()
+	if _, ok := catalogs[base.String()]; !ok {
+		return "", false
+	}
+	return base.String(), true
+}
+
+// Match выбирает язык по заголовку Accept-Language с учетом q-весов; "", если ни один язык не подошел
+func Match(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return ""
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return ""
+	}
+	return Locales[index]
+}
+
+// Resolve выбирает язык ответа: сохраненный выбор пользователя, затем Accept-Language, иначе Default
+func Resolve(preference, acceptLanguage string) string {
+	if locale, ok := Parse(preference); ok {
+		return locale
+	}
+	if locale := Match(acceptLanguage); locale != "" {
+		return locale
+	}
+	return Default
+}
+
+type preferenceKey struct{}
+
+// WithPreference запоминает в контексте язык, выбранный вошедшим пользователем
+func WithPreference(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, preferenceKey{}, locale)
+}
+
+func Preference(ctx context.Context) string {
+	locale, _ := ctx.Value(preferenceKey{}).(string)
+	return locale
+}
+
+// FromRequest — язык ответа на HTTP-запрос r, см. Resolve
+func FromRequest(r *http.Request) string {
+	return Resolve(Preference(r.Context()), r.Header.Get("Accept-Language"))
+}
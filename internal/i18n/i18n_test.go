@@ -0,0 +1,97 @@
+package i18n
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var verb = regexp.MustCompile(`%(\[\d+\])?[-+# 0]*\d*(\.\d+)?[a-zA-Z%]`)
+
+func verbs(text string) []string {
+	found := verb.FindAllString(text, -1)
+	sort.Strings(found)
+	return found
+}
+
+// Ключ без перевода на один из языков или с другим набором подстановок ломает тексты для части пользователей
+func TestCatalogs_TranslateEveryKey(t *testing.T) {
+	keys := map[string]bool{}
+	for _, locale := range Locales {
+		require.Contains(t, catalogs, locale)
+		for key := range catalogs[locale] {
+			keys[key] = true
+		}
+	}
+	for key := range keys {
+		for _, locale := range Locales {
+			text, ok := catalogs[locale][key]
+			if !assert.True(t, ok, "key %q has no %s translation", key, locale) {
+				continue
+			}
+			assert.NotEmpty(t, strings.TrimSpace(text), "key %q is empty in %s", key, locale)
+			assert.Equal(t, verbs(catalogs[Default][key]), verbs(text), "key %q has different placeholders in %s", key, locale)
+		}
+	}
+}
+
+var keyLiteral = regexp.MustCompile(`"((?:message|error|field|password|mail)\.[a-z_]+(?:\.[a-z_]+)*)"`)
+
+// Ключи, записанные в коде строковыми литералами, должны быть в каталоге; составные ключи вроде "error."+code
+// проверяют тесты пакетов, которые их собирают
+func TestCatalogs_ContainKeysUsedInCode(t *testing.T) {
+	root := filepath.Join("..", "..")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (d.Name() == "docs" || d.Name() == "i18n" || strings.HasPrefix(d.Name(), ".")) {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, m := range keyLiteral.FindAllStringSubmatch(string(src), -1) {
+			assert.Contains(t, catalogs[Default], m[1], "%s uses a key missing from the catalog", path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "username must be 3 to 32 characters", T(EN, "field.username.length", 3, 32))
+	assert.Equal(t, "Пользователь зарегистрирован", T("", "message.registered"), "no locale means the default one")
+	assert.Equal(t, "Пользователь зарегистрирован", T("de", "message.registered"))
+	assert.Equal(t, "no.such.key", T(EN, "no.such.key"))
+}
+
+func TestResolve(t *testing.T) {
+	for _, c := range []struct{ preference, header, want string }{
+		{"", "", RU},
+		{"", "en-US,en;q=0.9", EN},
+		{"", "de, en;q=0.5", EN},
+		{"", "de", RU},
+		{"", "ru;q=0.4, en;q=0.9", EN},
+		{"en", "ru-RU", EN},
+		{"ru", "en", RU},
+		{"EN-gb", "", EN},
+		{"de", "en", EN},
+	} {
+		assert.Equal(t, c.want, Resolve(c.preference, c.header), "%+v", c)
+	}
+	assert.Equal(t, EN, Preference(WithPreference(context.Background(), EN)))
+	assert.Empty(t, Preference(context.Background()))
+}
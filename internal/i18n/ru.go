@@ -0,0 +1,90 @@
+package i18n
+
+var ru = map[string]string{
+	"message.registered":                  "Пользователь зарегистрирован",
+	"message.login_unlocked":              "Блокировка входа снята",
+	"message.locale_changed":              "Язык изменен",
+	"message.email_verified":              "Почта подтверждена",
+	"message.verification_sent":           "Если аккаунт существует и почта не подтверждена, на нее отправлено письмо",
+	"message.email_changed":               "Почта изменена, подтвердите новый адрес по ссылке из письма",
+	"message.password_reset_sent":         "Если аккаунт существует, на его почту отправлена ссылка для сброса пароля",
+	"message.password_reset":              "Пароль изменен",
+	"message.magic_link_sent":             "Если аккаунт существует, на его почту отправлена ссылка для входа",
+	"message.mfa_enabled":                 "Двухфакторная аутентификация включена",
+	"message.mfa_disabled":                "Двухфакторная аутентификация отключена",
+	"message.webauthn_credential_deleted": "Ключ доступа удален",
+
+	"error.server.internal":                  "Внутренняя ошибка сервера",
+	"error.request.invalid":                  "Неверный запрос",
+	"error.request.validation_failed":        "Некоторые поля заполнены неверно",
+	"error.request.not_found":                "Ресурс не найден",
+	"error.request.method_not_allowed":       "Метод не разрешён",
+	"error.request.too_large":                "Тело запроса слишком большое",
+	"error.request.timeout":                  "Сервер не успел обработать запрос, повторите позже",
+	"error.request.origin_not_allowed":       "Запросы с этого сайта не разрешены",
+	"error.request.rate_limited":             "Слишком много запросов, повторите позже",
+	"error.auth.missing_token":               "Нужен access токен в заголовке Authorization",
+	"error.auth.unauthorized":                "Требуется авторизация",
+	"error.auth.forbidden":                   "Недостаточно прав",
+	"error.auth.user_exists":                 "Имя пользователя уже занято",
+	"error.auth.username_reserved":           "Это имя зарезервировано",
+	"error.auth.username_confusable":         "Имя слишком похоже на уже занятое",
+	"error.auth.invalid_credentials":         "Неверное имя пользователя или пароль",
+	"error.auth.login_locked":                "Вход временно заблокирован после неудачных попыток",
+	"error.auth.invalid_refresh_token":       "Refresh токен недействителен, истек или уже использован",
+	"error.auth.token_reused":                "Refresh токен уже использовался; все сессии завершены, войдите заново",
+	"error.auth.invalid_access_token":        "Access токен недействителен или истек",
+	"error.auth.invalid_token_data":          "Токен содержит неверные данные",
+	"error.auth.token_revoked":               "Токен отозван, войдите заново",
+	"error.auth.invalid_new_password":        "Новый пароль не соответствует требованиям",
+	"error.auth.invalid_reset_token":         "Ссылка для сброса пароля недействительна или истекла",
+	"error.auth.invalid_magic_link":          "Ссылка для входа недействительна или истекла",
+	"error.email.invalid":                    "Неверный адрес электронной почты",
+	"error.email.taken":                      "Адрес электронной почты уже используется",
+	"error.email.not_verified":               "Почта не подтверждена",
+	"error.email.invalid_verification_token": "Ссылка подтверждения недействительна или истекла",
+	"error.mfa.invalid_token":                "MFA токен недействителен или истек",
+	"error.mfa.invalid_code":                 "Неверный код подтверждения",
+	"error.mfa.too_many_attempts":            "Слишком много попыток ввода кода",
+	"error.mfa.already_enabled":              "Двухфакторная аутентификация уже включена",
+	"error.mfa.not_enrolled":                 "Двухфакторная аутентификация не настроена",
+	"error.mfa.required":                     "Для этой роли нужна двухфакторная аутентификация",
+	"error.webauthn.invalid_response":        "Ответ ключа доступа недействителен или истек",
+	"error.webauthn.not_registered":          "Ключи доступа не зарегистрированы",
+	"error.webauthn.credential_not_found":    "Ключ доступа не найден",
+
+	"field.username.required":           "Укажите имя пользователя",
+	"field.username.length":             "Имя пользователя должно содержать от %d до %d символов",
+	"field.username.invalid_characters": "Имя может содержать только буквы, цифры, «_», «-» и «.» и должно начинаться и заканчиваться буквой или цифрой",
+	"field.username.reserved":           "Это имя зарезервировано",
+	"field.username.confusable":         "Имя слишком похоже на уже занятое",
+	"field.username.taken":              "Имя пользователя уже занято",
+	"field.role.invalid_value":          "Роль должна быть USER или ADMIN",
+	"field.email.invalid_format":        "Неверный адрес электронной почты",
+	"field.locale.invalid_value":        "Язык должен быть одним из: %s",
+
+	"password.min_length":        "Пароль должен содержать не менее %d символов",
+	"password.max_length":        "Пароль должен содержать не более %d символов",
+	"password.character_classes": "Пароль должен содержать символы хотя бы %d видов из четырех: строчные буквы, заглавные буквы, цифры, другие символы",
+	"password.entropy":           "Пароль слишком легко подобрать",
+	"password.contains_username": "Пароль не должен содержать имя пользователя",
+	"password.breached":          "Этот пароль встречался в утечках данных, выберите другой",
+
+	"mail.verify_email.subject": "Подтверждение почты",
+	"mail.verify_email.body": "Здравствуйте, %s!\n\nЧтобы подтвердить адрес почты, перейдите по ссылке:\n%s\n\n" +
+		"Ссылка действует %d ч. Если вы не регистрировались на форуме, просто проигнорируйте это письмо.\n",
+	"mail.email_changed.subject": "Адрес почты изменен",
+	"mail.email_changed.body": "Здравствуйте, %s!\n\nАдрес почты вашего аккаунта изменен на %s. " +
+		"Если это сделали не вы, восстановите доступ к аккаунту и смените пароль.\n",
+	"mail.password_reset.subject": "Сброс пароля",
+	"mail.password_reset.body": "Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s\n\n" +
+		"Ссылка действует %d минут и может быть использована один раз. " +
+		"Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.\n",
+	"mail.magic_link.subject": "Вход на форум",
+	"mail.magic_link.body": "Здравствуйте, %s!\n\nЧтобы войти на форум без пароля, перейдите по ссылке:\n%s\n\n" +
+		"Ссылка действует %d минут, может быть использована один раз и только в браузере, в котором вы ее запросили. " +
+		"Если вы не запрашивали вход, просто проигнорируйте это письмо.\n",
+	"mail.recovery_code_used.subject": "Использован код восстановления",
+	"mail.recovery_code_used.body": "Здравствуйте, %s!\n\nДля входа в ваш аккаунт использован код восстановления. Осталось кодов: %d.\n" +
+		"Если это были не вы, смените пароль и создайте новые коды восстановления.\n",
+}
@@ -0,0 +1,142 @@
+// Package janitor периодически удаляет устаревшие записи: истекшие refresh токены и другие данные с истекшим сроком
+package janitor
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository"
+)
+
+// Метрики публикуются через expvar и доступны на /debug/vars служебного сервера, см. config.Config.DebugAddr
+var (
+	removedTotal = expvar.NewMap("janitor_removed_total")
+	errorsTotal  = expvar.NewMap("janitor_errors_total")
+	runsTotal    = expvar.NewInt("janitor_runs_total")
+	skippedTotal = expvar.NewInt("janitor_runs_skipped_total")
+)
+
+// Task удаляет не более limit записей, устаревших к моменту before, и возвращает их количество
+type Task struct {
+	Name  string
+	Purge func(before time.Time, limit int) (int, error)
+}
+
+func RefreshTokensTask(repo repository.AuthRepository) Task {
+	return Task{Name: "refresh_tokens", Purge: repo.DeleteExpiredRefreshTokens}
+}
+
+func DenylistTask(repo repository.AuthRepository) Task {
+	return Task{Name: "access_token_denylist", Purge: repo.DeleteExpiredDenylistEntries}
+}
+
+func PasswordResetTokensTask(repo repository.AuthRepository) Task {
+	return Task{Name: "password_reset_tokens", Purge: repo.DeleteExpiredPasswordResetTokens}
+}
+
+func EmailVerificationTokensTask(repo repository.AuthRepository) Task {
+	return Task{Name: "email_verification_tokens", Purge: repo.DeleteExpiredEmailVerificationTokens}
+}
+
+func MagicLinkTokensTask(repo repository.AuthRepository) Task {
+	return Task{Name: "magic_link_tokens", Purge: repo.DeleteExpiredMagicLinkTokens}
+}
+
+func LoginFailuresTask(repo repository.AuthRepository) Task {
+	return Task{Name: "login_failures", Purge: repo.DeleteExpiredLoginFailures}
+}
+
+func WebAuthnSessionsTask(repo repository.AuthRepository) Task {
+	return Task{Name: "webauthn_sessions", Purge: repo.DeleteExpiredWebAuthnSessions}
+}
+
+// RateLimitsTask очищает таблицу rate_limits; нужна, только если лимиты хранятся в базе
+func RateLimitsTask(db *sql.DB) Task {
+	return Task{Name: "rate_limits", Purge: func(before time.Time, limit int) (int, error) {
+		return ratelimit.DeleteExpired(db, before, limit)
+	}}
+}
+
+type Janitor struct {
+	locker    Locker
+	tasks     []Task
+	interval  time.Duration
+	batchSize int
+	// maxBatches ограничивает работу одного прохода, остаток удаляется на следующем
+	maxBatches int
+}
+
+func New(locker Locker, interval time.Duration, batchSize int, tasks ...Task) *Janitor {
+	return &Janitor{
+		locker:     locker,
+		tasks:      tasks,
+		interval:   interval,
+		batchSize:  batchSize,
+		maxBatches: 100,
+	}
+}
+
+// Run выполняет очистку каждые interval до отмены ctx
+func (j *Janitor) Run(ctx context.Context) {
+	log.Info().Dur("interval", j.interval).Int("batchSize", j.batchSize).Msg("Janitor started")
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		j.RunOnce()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce выполняет один проход, если этот экземпляр стал лидером, и возвращает число удаленных записей по задачам
+func (j *Janitor) RunOnce() map[string]int {
+	release, ok, err := j.locker.TryLock()
+	if err != nil {
+		log.Error().Err(err).Msg("Janitor failed to acquire leader lock")
+		errorsTotal.Add("lock", 1)
+		return nil
+	}
+	if !ok {
+		log.Debug().Msg("Janitor skipped: another instance is the leader")
+		skippedTotal.Add(1)
+		return nil
+	}
+	defer release()
+
+	runsTotal.Add(1)
+	now := time.Now()
+	removed := make(map[string]int, len(j.tasks))
+	for _, task := range j.tasks {
+		n, err := j.purge(task, now)
+		removed[task.Name] = n
+		removedTotal.Add(task.Name, int64(n))
+		if err != nil {
+			log.Error().Err(err).Str("task", task.Name).Int("removed", n).Msg("Janitor task failed")
+			errorsTotal.Add(task.Name, 1)
+			continue
+		}
+		if n > 0 {
+			log.Info().Str("task", task.Name).Int("removed", n).Msg("Janitor removed expired records")
+		}
+	}
+	return removed
+}
+
+func (j *Janitor) purge(task Task, before time.Time) (int, error) {
+	total := 0
+	for i := 0; i < j.maxBatches; i++ {
+		n, err := task.Purge(before, j.batchSize)
+		total += n
+		if err != nil || n < j.batchSize {
+			return total, err
+		}
+	}
+	return total, nil
+}
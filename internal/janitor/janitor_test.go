@@ -0,0 +1,85 @@
+package janitor
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/repository"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+)
+
+type deniedLocker struct{}
+
+func (deniedLocker) TryLock() (func(), bool, error) {
+	return nil, false, nil
+}
+
+func seedTokens(t *testing.T, repo repository.AuthRepository, expired, valid int) {
+	user := &model.User{Username: "user", Password: "hash", Role: "USER"}
+	require.NoError(t, repo.CreateUser(user))
+	for i := 0; i < expired; i++ {
+		require.NoError(t, repo.SaveRefreshToken(&model.RefreshToken{UserID: user.ID, Token: fmt.Sprintf("expired-%d", i), ExpiresAt: time.Now().Add(-time.Hour)}))
+	}
+	for i := 0; i < valid; i++ {
+		require.NoError(t, repo.SaveRefreshToken(&model.RefreshToken{UserID: user.ID, Token: fmt.Sprintf("valid-%d", i), ExpiresAt: time.Now().Add(time.Hour)}))
+	}
+}
+
+func TestRunOnce_PurgesInBatches(t *testing.T) {
+	repo := memory.NewRepository()
+	seedTokens(t, repo, 7, 2)
+	batches := 0
+	task := RefreshTokensTask(repo)
+	purge := task.Purge
+	task.Purge = func(before time.Time, limit int) (int, error) {
+		batches++
+		return purge(before, limit)
+	}
+
+	removed := New(LocalLocker{}, time.Minute, 3, task).RunOnce()
+
+	assert.Equal(t, map[string]int{"refresh_tokens": 7}, removed)
+	assert.Equal(t, 3, batches)
+	_, err := repo.ConsumeRefreshToken("valid-0")
+	assert.NoError(t, err)
+}
+
+func TestRunOnce_BoundedByMaxBatches(t *testing.T) {
+	repo := memory.NewRepository()
+	seedTokens(t, repo, 10, 0)
+	j := New(LocalLocker{}, time.Minute, 2, RefreshTokensTask(repo))
+	j.maxBatches = 2
+
+	assert.Equal(t, 4, j.RunOnce()["refresh_tokens"])
+	assert.Equal(t, 4, j.RunOnce()["refresh_tokens"])
+	assert.Equal(t, 2, j.RunOnce()["refresh_tokens"])
+}
+
+func TestRunOnce_SkipsWhenNotLeader(t *testing.T) {
+	repo := memory.NewRepository()
+	seedTokens(t, repo, 1, 0)
+
+	removed := New(deniedLocker{}, time.Minute, 10, RefreshTokensTask(repo)).RunOnce()
+
+	assert.Nil(t, removed)
+	_, err := repo.ConsumeRefreshToken("expired-0")
+	assert.NoError(t, err)
+}
+
+func TestRunOnce_FailingTaskDoesNotStopOthers(t *testing.T) {
+	repo := memory.NewRepository()
+	seedTokens(t, repo, 2, 0)
+	failing := Task{Name: "broken", Purge: func(time.Time, int) (int, error) {
+		return 0, errors.New("boom")
+	}}
+
+	removed := New(LocalLocker{}, time.Minute, 10, failing, RefreshTokensTask(repo)).RunOnce()
+
+	assert.Equal(t, 0, removed["broken"])
+	assert.Equal(t, 2, removed["refresh_tokens"])
+}
@@ -0,0 +1,48 @@
+package janitor
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Locker выбирает единственный экземпляр сервиса, который выполняет очистку
+type Locker interface {
+	// TryLock не блокируется: ok=false означает, что лидер уже есть
+	TryLock() (release func(), ok bool, err error)
+}
+
+// LocalLocker подходит для хранилищ, с которыми работает один процесс (memory, SQLite)
+type LocalLocker struct{}
+
+func (LocalLocker) TryLock() (func(), bool, error) {
+	return func() {}, true, nil
+}
+
+// janitorLockID — ключ advisory lock лидера очистки
+const janitorLockID = 7305150002
+
+// PostgresLocker выбирает лидера через pg_try_advisory_lock на выделенном соединении
+type PostgresLocker struct {
+	DB *sql.DB
+}
+
+func (l PostgresLocker) TryLock() (func(), bool, error) {
+	ctx := context.Background()
+	conn, err := l.DB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	var ok bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", janitorLockID).Scan(&ok); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if !ok {
+		conn.Close()
+		return nil, false, nil
+	}
+	return func() {
+		conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", janitorLockID)
+		conn.Close()
+	}, true, nil
+}
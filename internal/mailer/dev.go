@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// LogMailer пишет письма в лог вместо отправки — для локальной разработки
+type LogMailer struct{}
+
+func (LogMailer) Send(msg Message) error {
+	log.Info().Str("to", msg.To).Str("subject", msg.Subject).Str("body", msg.Body).Msg("Mail (not sent)")
+	return nil
+}
+
+// FileMailer сохраняет каждое письмо в отдельный .eml файл каталога Dir — для разработки и тестов
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	return os.WriteFile(filepath.Join(m.Dir, name), render(m.From, msg), 0o600)
+}
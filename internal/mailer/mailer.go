@@ -0,0 +1,44 @@
+// Package mailer отправляет письма пользователям; реализация выбирается конфигурацией
+package mailer
+
+import "github.com/rs/zerolog/log"
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Async отправляет письма в фоне, чтобы время ответа API не зависело от почтового сервера
+// и не выдавало, было ли письмо отправлено
+type Async struct {
+	next  Mailer
+	queue chan Message
+}
+
+func NewAsync(next Mailer, queueSize int) *Async {
+	a := &Async{next: next, queue: make(chan Message, queueSize)}
+	go a.loop()
+	return a
+}
+
+func (a *Async) Send(msg Message) error {
+	select {
+	case a.queue <- msg:
+	default:
+		log.Error().Str("subject", msg.Subject).Msg("Mail queue is full, message dropped")
+	}
+	return nil
+}
+
+func (a *Async) loop() {
+	for msg := range a.queue {
+		if err := a.next.Send(msg); err != nil {
+			log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to send mail")
+		}
+	}
+}
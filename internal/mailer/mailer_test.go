@@ -0,0 +1,49 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer_WritesMessage(t *testing.T) {
+	dir := t.TempDir()
+	m := &FileMailer{Dir: dir, From: "no-reply@example.com"}
+
+	require.NoError(t, m.Send(Message{To: "user@example.com", Subject: "Сброс пароля", Body: "текст"}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	raw, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), "To: user@example.com")
+	assert.Contains(t, string(raw), "From: no-reply@example.com")
+}
+
+type recordingMailer struct {
+	sent chan Message
+}
+
+func (m recordingMailer) Send(msg Message) error {
+	m.sent <- msg
+	return nil
+}
+
+func TestAsync_DeliversInBackground(t *testing.T) {
+	next := recordingMailer{sent: make(chan Message, 1)}
+	a := NewAsync(next, 1)
+
+	require.NoError(t, a.Send(Message{To: "user@example.com", Subject: "s"}))
+
+	select {
+	case msg := <-next.sent:
+		assert.Equal(t, "user@example.com", msg.To)
+	case <-time.After(time.Second):
+		t.Fatal("message was not delivered")
+	}
+}
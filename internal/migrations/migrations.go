@@ -0,0 +1,233 @@
+// Package migrations содержит встроенные в бинарник миграции схемы и применяет их.
+// Версия хранится в таблице schema_migrations в формате golang-migrate,
+// поэтому базы, которые раньше мигрировались через CLI migrate, подхватываются без изменений
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// lockID — ключ advisory lock, под которым мигрирует только один экземпляр сервиса
+const lockID = 7305150001
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+var ErrDirty = errors.New("database is in dirty state, fix the schema manually and reset schema_migrations.dirty")
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	Applied bool
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+func New(db *sql.DB, dialect Dialect) (*Migrator, error) {
+	migrations, err := load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+func load(dialect Dialect) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, string(dialect))
+	if err != nil {
+		return nil, fmt.Errorf("unknown migrations dialect %q: %w", dialect, err)
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := files.ReadFile(string(dialect) + "/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up применяет все непримененные миграции и возвращает их
+func (m *Migrator) Up() ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(func(conn *sql.Conn) error {
+		current, err := m.version(conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if mig.Version <= current {
+				continue
+			}
+			if err := m.apply(conn, mig.Up, mig.Version, upSteps[mig.Version]); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down откатывает steps последних примененных миграций и возвращает их
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(func(conn *sql.Conn) error {
+		current, err := m.version(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mig := m.migrations[i]
+			if mig.Version > current {
+				continue
+			}
+			previous := 0
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			if err := m.apply(conn, mig.Down, previous, step{}); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", mig.Version, mig.Name, err)
+			}
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+func (m *Migrator) Status() ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(func(conn *sql.Conn) error {
+		current, err := m.version(conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			statuses = append(statuses, Status{Migration: mig, Applied: mig.Version <= current})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// withLock выполняет fn на выделенном соединении; в Postgres оно удерживает advisory lock,
+// чтобы несколько реплик, стартующих одновременно, не применяли миграции параллельно
+func (m *Migrator) withLock(fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialect == Postgres {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockID)
+	}
+	if _, err := conn.ExecContext(ctx,
+		"CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)",
+	); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) version(conn *sql.Conn) (int, error) {
+	var (
+		version int
+		dirty   bool
+	)
+	err := conn.QueryRowContext(context.Background(), "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	if dirty {
+		return 0, fmt.Errorf("version %d: %w", version, ErrDirty)
+	}
+	return version, nil
+}
+
+// apply выполняет скрипт вместе с шагами на Go и записывает новую версию в одной транзакции
+func (m *Migrator) apply(conn *sql.Conn, script string, version int, s step) error {
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if s.before != nil {
+		if err := s.before(ctx, tx); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if s.after != nil {
+		if err := s.after(ctx, tx); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+	if version > 0 {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)", version, false); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,138 @@
+package migrations
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLiteMigrator(t *testing.T) (*Migrator, *sql.DB) {
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "auth.db")+"?_foreign_keys=on")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	m, err := New(db, SQLite)
+	require.NoError(t, err)
+	return m, db
+}
+
+func TestLoad_DialectsHaveSameVersions(t *testing.T) {
+	pg, err := load(Postgres)
+	require.NoError(t, err)
+	lite, err := load(SQLite)
+	require.NoError(t, err)
+
+	require.Equal(t, len(pg), len(lite))
+	for i := range pg {
+		assert.Equal(t, pg[i].Version, lite[i].Version)
+		assert.Equal(t, pg[i].Name, lite[i].Name)
+		assert.NotEmpty(t, pg[i].Up)
+		assert.NotEmpty(t, pg[i].Down)
+		assert.NotEmpty(t, lite[i].Up)
+		assert.NotEmpty(t, lite[i].Down)
+	}
+}
+
+func TestUpDownStatus(t *testing.T) {
+	m, _ := newSQLiteMigrator(t)
+
+	applied, err := m.Up()
+	require.NoError(t, err)
+	assert.Len(t, applied, len(m.migrations))
+
+	applied, err = m.Up()
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := m.Down(1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, m.migrations[len(m.migrations)-1].Version, reverted[0].Version)
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	for i, s := range statuses {
+		assert.Equal(t, i < len(statuses)-1, s.Applied, "version %d", s.Version)
+	}
+
+	reverted, err = m.Down(len(m.migrations))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(m.migrations)-1)
+}
+
+func TestRoleDefaultAndCheck(t *testing.T) {
+	m, db := newSQLiteMigrator(t)
+	_, err := m.Up()
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO users (username, password) VALUES ('default', 'hash')")
+	require.NoError(t, err)
+	var role string
+	require.NoError(t, db.QueryRow("SELECT role FROM users WHERE username = 'default'").Scan(&role))
+	assert.Equal(t, "USER", role)
+
+	_, err = db.Exec("INSERT INTO users (username, password, role) VALUES ('lower', 'hash', 'user')")
+	assert.Error(t, err)
+}
+
+func TestUp_RefusesDirtyDatabase(t *testing.T) {
+	m, db := newSQLiteMigrator(t)
+	_, err := db.Exec("CREATE TABLE schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO schema_migrations (version, dirty) VALUES (1, true)")
+	require.NoError(t, err)
+
+	_, err = m.Up()
+
+	assert.ErrorIs(t, err, ErrDirty)
+}
+
+// migrateTo применяет миграции до версии version включительно
+func migrateTo(t *testing.T, m *Migrator, version int) {
+	all := m.migrations
+	defer func() { m.migrations = all }()
+	for i, mig := range all {
+		if mig.Version == version {
+			m.migrations = all[:i+1]
+		}
+	}
+	_, err := m.Up()
+	require.NoError(t, err)
+}
+
+func TestUsernameNormalization_FillsKeysAndRefusesCollisions(t *testing.T) {
+	m, db := newSQLiteMigrator(t)
+	migrateTo(t, m, 13)
+	for _, username := range []string{"Forum.User", "ＰＥＴＲ", "Иван"} {
+		_, err := db.Exec("INSERT INTO users (username, password) VALUES ($1, 'hash')", username)
+		require.NoError(t, err)
+	}
+	_, err := m.Up()
+	require.NoError(t, err)
+
+	var normalized, skeleton string
+	for username, want := range map[string][2]string{
+		"Forum.User": {"forum.user", "forum_user"},
+		"ＰＥＴＲ":       {"petr", "petr"},
+		"Иван":       {"иван", "иbah"},
+	} {
+		require.NoError(t, db.QueryRow("SELECT username_normalized, username_skeleton FROM users WHERE username = $1", username).Scan(&normalized, &skeleton))
+		assert.Equal(t, want, [2]string{normalized, skeleton}, username)
+	}
+
+	m, db = newSQLiteMigrator(t)
+	migrateTo(t, m, 13)
+	for _, username := range []string{"Admin", "admin", "Иван", "иван", "user"} {
+		_, err := db.Exec("INSERT INTO users (username, password) VALUES ($1, 'hash')", username)
+		require.NoError(t, err)
+	}
+	_, err = m.Up()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Admin, admin; Иван, иван")
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	assert.False(t, statuses[13].Applied, "the failed migration is rolled back")
+}
@@ -0,0 +1,5 @@
+UPDATE users SET role = UPPER(role) WHERE role <> UPPER(role);
+
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'USER';
+
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('USER', 'ADMIN'));
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS access_token_denylist (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    revoked_before TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS access_token_denylist_expires_at_idx ON access_token_denylist (expires_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS email_verification_tokens;
+DROP INDEX IF EXISTS users_email_lower_idx;
+ALTER TABLE users DROP COLUMN email_verified;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS login_failures (
+    subject VARCHAR(320) PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    locked_until TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS login_failures_expires_at_idx ON login_failures (expires_at);
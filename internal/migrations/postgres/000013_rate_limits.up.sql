@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS rate_limits (
+    limiter_key VARCHAR(320) PRIMARY KEY,
+    tat_ms BIGINT NOT NULL DEFAULT 0,
+    window_start_ms BIGINT NOT NULL DEFAULT 0,
+    current_count BIGINT NOT NULL DEFAULT 0,
+    previous_count BIGINT NOT NULL DEFAULT 0,
+    allowed BOOLEAN NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_expires_at_idx ON rate_limits (expires_at);
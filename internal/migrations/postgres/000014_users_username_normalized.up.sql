@@ -0,0 +1,7 @@
+ALTER TABLE users ADD COLUMN username_normalized TEXT;
+ALTER TABLE users ADD COLUMN username_skeleton TEXT;
+UPDATE users SET username_normalized = LOWER(username), username_skeleton = LOWER(username);
+ALTER TABLE users ALTER COLUMN username_normalized SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_normalized_idx ON users (username_normalized);
+CREATE INDEX IF NOT EXISTS users_username_skeleton_idx ON users (username_skeleton);
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS users_role_check_update;
+DROP TRIGGER IF EXISTS users_role_check_insert;
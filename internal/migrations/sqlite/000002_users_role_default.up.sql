@@ -0,0 +1,16 @@
+-- SQLite не умеет добавлять CHECK через ALTER TABLE, поэтому ограничение реализовано триггерами
+UPDATE users SET role = UPPER(role) WHERE role <> UPPER(role);
+
+CREATE TRIGGER IF NOT EXISTS users_role_check_insert
+BEFORE INSERT ON users
+WHEN NEW.role NOT IN ('USER', 'ADMIN')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: users_role_check');
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_role_check_update
+BEFORE UPDATE OF role ON users
+WHEN NEW.role NOT IN ('USER', 'ADMIN')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: users_role_check');
+END;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_username_skeleton_idx;
+DROP INDEX IF EXISTS users_username_normalized_idx;
+ALTER TABLE users DROP COLUMN username_skeleton;
+ALTER TABLE users DROP COLUMN username_normalized;
@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN username_normalized TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN username_skeleton TEXT;
+UPDATE users SET username_normalized = LOWER(username), username_skeleton = LOWER(username);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_normalized_idx ON users (username_normalized);
+CREATE INDEX IF NOT EXISTS users_username_skeleton_idx ON users (username_skeleton);
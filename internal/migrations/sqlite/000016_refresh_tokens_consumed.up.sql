@@ -0,0 +1 @@
+ALTER TABLE refresh_tokens ADD COLUMN consumed BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"sstu-go-forum-auth-service/internal/model"
+)
+
+// step — часть миграции, которую нельзя выразить в SQL: before выполняется до скрипта, after — после него,
+// в той же транзакции
+type step struct {
+	before, after func(ctx context.Context, tx *sql.Tx) error
+}
+
+// upSteps — шаги на Go по версиям миграций
+var upSteps = map[int]step{
+	14: {before: checkUsernameCollisions, after: fillUsernameKeys},
+}
+
+// checkUsernameCollisions отказывает в миграции, если имена совпадают после нормализации: уникальный индекс
+// на username_normalized все равно не создастся, а так видно, какие имена нужно развести вручную
+func checkUsernameCollisions(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, "SELECT username FROM users ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byKey := map[string][]string{}
+	var keys []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return err
+		}
+		key := model.UsernameKey(username)
+		if len(byKey[key]) == 1 {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], username)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	groups := make([]string, len(keys))
+	for i, key := range keys {
+		groups[i] = strings.Join(byKey[key], ", ")
+	}
+	return fmt.Errorf("usernames that differ only by case or character form must be renamed before this migration: %s",
+		strings.Join(groups, "; "))
+}
+
+// fillUsernameKeys заменяет ключи, заполненные скриптом через LOWER, на model.UsernameKey и model.UsernameSkeleton:
+// LOWER не знает NFKC и похожих символов, а в SQLite — и регистра не-ASCII букв
+func fillUsernameKeys(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id, username FROM users")
+	if err != nil {
+		return err
+	}
+	usernames := map[int]string{}
+	for rows.Next() {
+		var (
+			id       int
+			username string
+		)
+		if err := rows.Scan(&id, &username); err != nil {
+			rows.Close()
+			return err
+		}
+		usernames[id] = username
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, username := range usernames {
+		_, err := tx.ExecContext(ctx, "UPDATE users SET username_normalized = $1, username_skeleton = $2 WHERE id = $3",
+			model.UsernameKey(username), model.UsernameSkeleton(username), id)
+		if err != nil {
+			return fmt.Errorf("normalize username %q: %w", username, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// DenylistEntry отзывает все access токены пользователя, выпущенные раньше RevokedBefore.
+// После ExpiresAt такие токены истекли бы сами, и запись можно удалить
+type DenylistEntry struct {
+	UserID        int       `json:"user_id"`
+	RevokedBefore time.Time `json:"revoked_before"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
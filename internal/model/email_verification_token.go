@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// EmailVerificationToken подтверждает конкретный адрес: после смены почты старые токены недействительны
+type EmailVerificationToken struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	TokenHash string    `json:"-"`
+	Email     string    `json:"email"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// LoginFailure — счетчик неудачных входов по субъекту: имени пользователя или IP.
+// Вход разрешен, когда LockedUntil в прошлом; после ExpiresAt без новых ошибок счетчик сбрасывается
+type LoginFailure struct {
+	Subject     string    `json:"subject"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// MagicLinkToken — одноразовый токен входа по ссылке из письма. NonceHash привязывает его к браузеру,
+// запросившему ссылку, а Email — к адресу, на который она отправлена
+type MagicLinkToken struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	TokenHash string    `json:"-"`
+	NonceHash string    `json:"-"`
+	Email     string    `json:"email"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// PasswordResetToken хранит только SHA-256 хеш одноразового токена сброса пароля
+type PasswordResetToken struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	TokenHash string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
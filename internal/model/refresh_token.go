@@ -7,4 +7,7 @@ type RefreshToken struct {
 	UserID    int       `json:"user_id"`
 	Token     string    `json:"token"`
 	ExpiresAt time.Time `json:"expires_at"`
+	// Consumed — токен уже обменян на новый. Такие токены хранятся до истечения, чтобы повторное
+	// предъявление отличалось от неизвестного токена
+	Consumed bool `json:"consumed"`
 }
@@ -0,0 +1,10 @@
+package model
+
+// TOTP — второй фактор пользователя. До подтверждения первым кодом не действует при входе
+type TOTP struct {
+	UserID    int    `json:"user_id"`
+	Secret    string `json:"-"`
+	Confirmed bool   `json:"confirmed"`
+	// LastUsedStep — последний принятый шаг RFC 6238: один код нельзя использовать дважды
+	LastUsedStep int64 `json:"-"`
+}
@@ -1,28 +1,66 @@
 package model
 
 import (
-	"errors"
+	"net/mail"
 	"strings"
+
+	"sstu-go-forum-auth-service/internal/i18n"
 )
 
 // User представляет собой пользователя системы
 // @Description Структура пользователя с полями для хранения информации о пользователе
 type User struct {
-	ID       int    `json:"id"`       // ID пользователя
-	Username string `json:"username"` // Имя пользователя
-	Password string `json:"password"` // Пароль пользователя
-	Role     string `json:"role"`     // Роль пользователя (USER или ADMIN)
+	ID            int    `json:"id"`             // ID пользователя
+	Username      string `json:"username"`       // Имя пользователя
+	Password      string `json:"password"`       // Пароль пользователя
+	Role          string `json:"role"`           // Роль пользователя (USER или ADMIN); при регистрации всегда USER
+	Email         string `json:"email"`          // Адрес электронной почты для восстановления доступа
+	EmailVerified bool   `json:"email_verified"` // Подтвержден ли адрес; сбрасывается при смене почты
+	Locale        string `json:"locale"`         // Язык писем и ответов (ru или en); пустой — по Accept-Language
 }
 
+// Validate проверяет поля пользователя, кроме пароля: его проверяет политика паролей.
+// Имя должно быть уже приведено NormalizeUsername. Все ошибки возвращаются сразу как *ValidationError
 func (u *User) Validate() error {
-	if len(strings.TrimSpace(u.Username)) < 3 {
-		return errors.New("username must be at least 3 characters")
-	}
-	if len(u.Password) < 6 {
-		return errors.New("password must be at least 6 characters")
+	var fields []FieldError
+	if err := ValidateUsername(u.Username); err != nil {
+		fields = append(fields, *err)
 	}
 	if u.Role != "USER" && u.Role != "ADMIN" {
-		return errors.New("role must be USER or ADMIN")
+		fields = append(fields, *NewFieldError("role", CodeInvalidValue))
+	}
+	if u.Email != "" {
+		if err := ValidateEmail(u.Email); err != nil {
+			fields = append(fields, *err)
+		}
+	}
+	if u.Locale != "" {
+		if err := ValidateLocale(u.Locale); err != nil {
+			fields = append(fields, *err)
+		}
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
 	}
 	return nil
 }
+
+func ValidateEmail(email string) *FieldError {
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return NewFieldError("email", CodeInvalidFormat)
+	}
+	return nil
+}
+
+// ValidateLocale проверяет, что язык приведен i18n.Parse к одному из i18n.Locales
+func ValidateLocale(locale string) *FieldError {
+	if i18n.Supported(locale) != locale {
+		return NewFieldError("locale", CodeInvalidValue, strings.Join(i18n.Locales, ", "))
+	}
+	return nil
+}
+
+// NormalizeEmail приводит адрес к виду, в котором он хранится: уникальность почты не зависит от регистра
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	UsernameMinLength = 3
+	UsernameMaxLength = 32
+)
+
+// ReservedUsernames — имена ролей, служебных учетных записей и самого сервиса; сравниваются по UsernameSkeleton
+var ReservedUsernames = []string{
+	"admin", "administrator", "moderator", "mod", "root", "system", "support", "security", "staff", "official",
+	"auth", "forum", "sstu", "api", "service", "noreply", "no-reply", "postmaster", "anonymous", "guest", "null",
+}
+
+var folder = cases.Fold()
+
+// NormalizeUsername приводит имя к виду, в котором оно хранится и показывается: NFKC без пробелов по краям,
+// регистр сохраняется
+func NormalizeUsername(username string) string {
+	return norm.NFKC.String(strings.TrimSpace(username))
+}
+
+// UsernameKey — ключ уникальности и поиска имени: NFKC с приведением регистра (NFKC_Casefold),
+// поэтому "Admin", "ADMIN" и "ａｄｍｉｎ" — одно и то же имя
+func UsernameKey(username string) string {
+	return norm.NFKC.String(folder.String(NormalizeUsername(username)))
+}
+
+// confusables — подмножество таблицы похожих символов Unicode (UTS #39) для латиницы, кириллицы, греческого и цифр:
+// символ заменяется латинской буквой, на которую он похож. После приведения регистра заглавная I неотличима от i,
+// поэтому i, l, 1 и | считаются одним символом
+var confusables = map[rune]rune{
+	'а': 'a', 'в': 'b', 'г': 'r', 'е': 'e', 'ё': 'e', 'з': '3', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'п': 'n', 'р': 'p', 'с': 'c',
+	'т': 't', 'у': 'y', 'х': 'x', 'ь': 'b', 'і': 'l', 'ї': 'l', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'һ': 'h', 'ӏ': 'l',
+	'ԛ': 'q', 'ԝ': 'w', 'ү': 'y',
+	'α': 'a', 'β': 'b', 'γ': 'y', 'ε': 'e', 'η': 'n', 'ι': 'l', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't',
+	'υ': 'u', 'χ': 'x', 'ω': 'w',
+	'0': 'o', '1': 'l', '|': 'l', 'i': 'l', 'ı': 'l',
+	'-': '_', '.': '_',
+}
+
+// UsernameSkeleton возвращает «скелет» имени: два имени с одинаковым скелетом выглядят одинаково, как "admin"
+// и "аdmin" с кириллической "а" или "forum_user" и "forum.user"
+func UsernameSkeleton(username string) string {
+	key := UsernameKey(username)
+	var b strings.Builder
+	for _, r := range key {
+		if c, ok := confusables[r]; ok {
+			r = c
+		}
+		b.WriteRune(r)
+	}
+	return strings.NewReplacer("rn", "m", "vv", "w", "cl", "d").Replace(b.String())
+}
+
+// ValidateUsername проверяет нормализованное имя: длину в символах и допустимые символы
+func ValidateUsername(username string) *FieldError {
+	n := utf8.RuneCountInString(username)
+	switch {
+	case n == 0:
+		return NewFieldError("username", CodeRequired)
+	case n < UsernameMinLength || n > UsernameMaxLength:
+		return NewFieldError("username", CodeLength, UsernameMinLength, UsernameMaxLength)
+	}
+	runes := []rune(username)
+	for i, r := range runes {
+		alnum := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if !alnum && (i == 0 || i == len(runes)-1 || !strings.ContainsRune("_-.", r)) {
+			return NewFieldError("username", CodeInvalidCharacters)
+		}
+	}
+	return nil
+}
+
+// IsReservedUsername сообщает, совпадает ли имя с зарезервированным или похоже на него
+func IsReservedUsername(username string, reserved []string) bool {
+	skeleton := UsernameSkeleton(username)
+	for _, name := range reserved {
+		if UsernameSkeleton(name) == skeleton {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sstu-go-forum-auth-service/internal/i18n"
+)
+
+func TestUsernameKey_NFKCAndCaseFolding(t *testing.T) {
+	assert.Equal(t, "forum_user", UsernameKey("  Forum_USER "))
+	assert.Equal(t, "admin", UsernameKey("ａｄｍｉｎ"), "fullwidth letters")
+	assert.Equal(t, "strasse", UsernameKey("STRAßE"))
+	assert.Equal(t, "иван", UsernameKey("ИВАН"))
+	assert.Equal(t, "Forum_User", NormalizeUsername(" Forum_User "), "the display form keeps the case")
+}
+
+func TestUsernameSkeleton_Confusables(t *testing.T) {
+	for _, name := range []string{"аdmin", "АDМIN", "adm1n", "admln", "adrnin"} {
+		assert.Equal(t, UsernameSkeleton("admin"), UsernameSkeleton(name), name)
+	}
+	assert.Equal(t, UsernameSkeleton("forum_user"), UsernameSkeleton("forum-user"))
+	assert.NotEqual(t, UsernameSkeleton("forum_user"), UsernameSkeleton("forum_users"))
+
+	assert.True(t, IsReservedUsername("Moderator", ReservedUsernames))
+	assert.True(t, IsReservedUsername("ѕуѕtеm", ReservedUsernames))
+	assert.False(t, IsReservedUsername("admin_fan", ReservedUsernames))
+}
+
+func TestValidateUsername(t *testing.T) {
+	for _, name := range []string{"bob", "forum_user", "john.doe-2", "Иван_Петров", "用户名"} {
+		assert.Nil(t, ValidateUsername(name), name)
+	}
+	for name, code := range map[string]string{
+		"":                                   CodeRequired,
+		"ab":                                 CodeLength,
+		"a234567890123456789012345678901234": CodeLength,
+		"_bob":                               CodeInvalidCharacters,
+		"bob.":                               CodeInvalidCharacters,
+		"bob smith":                          CodeInvalidCharacters,
+		"bob@mail":                           CodeInvalidCharacters,
+		"bob\u200b":                          CodeInvalidCharacters,
+	} {
+		if err := ValidateUsername(name); assert.NotNil(t, err, name) {
+			assert.Equal(t, code, err.Code, name)
+			assert.Equal(t, "username", err.Field)
+		}
+	}
+}
+
+func TestUserValidate_ReportsEveryField(t *testing.T) {
+	err := (&User{Username: "a", Role: "OWNER", Email: "not an email", Locale: "de"}).Validate()
+	var verr *ValidationError
+	if assert.ErrorAs(t, err, &verr) {
+		var codes, messages []string
+		for _, f := range verr.Fields {
+			codes = append(codes, f.Field+":"+f.Code)
+			messages = append(messages, f.Message(i18n.EN))
+		}
+		assert.Equal(t, []string{"username:length", "role:invalid_value", "email:invalid_format", "locale:invalid_value"}, codes)
+		assert.Equal(t, []string{
+			"username must be 3 to 32 characters",
+			"role must be USER or ADMIN",
+			"email is invalid",
+			"locale must be one of: ru, en",
+		}, messages)
+		assert.Equal(t, "Имя пользователя должно содержать от 3 до 32 символов", verr.Fields[0].Message(i18n.RU))
+	}
+	assert.NoError(t, (&User{Username: "forum_user", Role: "USER", Locale: "en"}).Validate())
+}
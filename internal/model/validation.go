@@ -0,0 +1,62 @@
+package model
+
+import (
+	"strings"
+
+	"sstu-go-forum-auth-service/internal/i18n"
+)
+
+// Коды ошибок полей; они стабильны, и по ним клиент выбирает, что показать. Нарушения политики паролей
+// передаются с кодами правил password.Rule*
+const (
+	CodeRequired          = "required"
+	CodeLength            = "length"
+	CodeInvalidCharacters = "invalid_characters"
+	CodeInvalidFormat     = "invalid_format"
+	CodeInvalidValue      = "invalid_value"
+	CodeReserved          = "reserved"
+	CodeConfusable        = "confusable"
+	CodeTaken             = "taken"
+)
+
+// FieldError — ошибка одного поля; Field — имя поля в JSON запроса, Key и Args — текст ошибки в каталоге i18n
+type FieldError struct {
+	Field string
+	Code  string
+	Key   string
+	Args  []any
+}
+
+// NewFieldError возвращает ошибку с текстом "field.<field>.<code>" из каталога
+func NewFieldError(field, code string, args ...any) *FieldError {
+	return &FieldError{Field: field, Code: code, Key: "field." + field + "." + code, Args: args}
+}
+
+func (f FieldError) Message(locale string) string {
+	return i18n.T(locale, f.Key, f.Args...)
+}
+
+// ValidationError перечисляет все неверные поля запроса, чтобы клиент показал их сразу.
+// Err, если задан, — ошибка сценария вроде usecase.ErrInvalidNewPassword, доступная через errors.Is
+type ValidationError struct {
+	Err    error
+	Fields []FieldError
+}
+
+// NewValidationError возвращает ошибку одного поля, см. NewFieldError
+func NewValidationError(err error, field, code string, args ...any) *ValidationError {
+	return &ValidationError{Err: err, Fields: []FieldError{*NewFieldError(field, code, args...)}}
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Field + ": " + f.Message(i18n.EN)
+	}
+	if e.Err != nil {
+		return e.Err.Error() + ": " + strings.Join(messages, "; ")
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error { return e.Err }
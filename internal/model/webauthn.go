@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// WebAuthnCredential — ключ доступа пользователя: passkey или аппаратный ключ
+type WebAuthnCredential struct {
+	ID              int      `json:"id"`
+	UserID          int      `json:"user_id"`
+	Name            string   `json:"name"`
+	CredentialID    []byte   `json:"-"`
+	PublicKey       []byte   `json:"-"`
+	AttestationType string   `json:"-"`
+	Transports      []string `json:"transports"`
+	AAGUID          []byte   `json:"-"`
+	// SignCount — последний принятый счетчик подписей: если он не растет, ключ мог быть скопирован
+	SignCount      uint32    `json:"-"`
+	BackupEligible bool      `json:"-"`
+	BackupState    bool      `json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// WebAuthnSession — незавершенная церемония WebAuthn. Вызов хранится на сервере и принимается один раз
+type WebAuthnSession struct {
+	ID        int    `json:"id"`
+	TokenHash string `json:"-"`
+	// Data — сериализованный webauthn.SessionData
+	Data      []byte    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
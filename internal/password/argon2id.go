@@ -0,0 +1,80 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// DefaultArgon2id — минимальные параметры, рекомендованные OWASP: 19 МиБ памяти, 2 прохода, 1 поток
+var DefaultArgon2id = Argon2id{Memory: 19 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+// Argon2id хранит хеши в формате $argon2id$v=19$m=<КиБ>,t=<проходы>,p=<потоки>$<соль>$<хеш> (base64 без padding)
+type Argon2id struct {
+	// Memory — объем памяти в КиБ
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+const argon2idPrefix = "$argon2id$"
+
+var b64 = base64.RawStdEncoding
+
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		a.Memory, a.Iterations, a.Parallelism, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+func (a Argon2id) Verify(hash, password string) (bool, bool, error) {
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false, false, err
+	}
+	got := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(got, key) != 1 {
+		return false, false, nil
+	}
+	return true, params != a, nil
+}
+
+func (a Argon2id) Owns(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+// parseArgon2id разбирает хеш; в возвращенных параметрах длины соли и ключа взяты из самого хеша
+func parseArgon2id(hash string) (Argon2id, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2id{}, nil, nil, ErrUnsupportedHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2id{}, nil, nil, fmt.Errorf("%w: argon2 version %q", ErrUnsupportedHash, parts[2])
+	}
+	var p Argon2id
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil || p.Iterations == 0 || p.Parallelism == 0 {
+		return Argon2id{}, nil, nil, fmt.Errorf("%w: argon2 params %q", ErrUnsupportedHash, parts[3])
+	}
+	salt, err := b64.DecodeString(parts[4])
+	if err != nil {
+		return Argon2id{}, nil, nil, fmt.Errorf("%w: argon2 salt: %v", ErrUnsupportedHash, err)
+	}
+	key, err := b64.DecodeString(parts[5])
+	if err != nil {
+		return Argon2id{}, nil, nil, fmt.Errorf("%w: argon2 key: %v", ErrUnsupportedHash, err)
+	}
+	p.SaltLength, p.KeyLength = uint32(len(salt)), uint32(len(key))
+	return p, salt, key, nil
+}
@@ -0,0 +1,49 @@
+package password
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultBcrypt — параметры, с которыми сервис хешировал пароли до перехода на argon2id
+var DefaultBcrypt = Bcrypt{Cost: bcrypt.DefaultCost}
+
+// Bcrypt хранит хеши в модульном формате crypt ($2a$<cost>$...), из которого вырос PHC.
+// bcrypt учитывает только первые 72 байта пароля, поэтому более длинные пароли он не хеширует
+type Bcrypt struct {
+	Cost int
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (b Bcrypt) Verify(hash, password string) (bool, bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false, false, err
+	}
+	return true, cost != b.Cost, nil
+}
+
+func (b Bcrypt) Owns(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Индекс утекших паролей — файл с усеченными SHA-1 хешами, разложенными по корзинам первых двух байт:
+//
+//	magic "BRCHIDX1" | длина суффикса L (1 байт) | 65537 смещений uint32 BE | записи по L байт
+//
+// Записи корзины b — с номера offsets[b] по offsets[b+1], отсортированы. Хранятся байты хеша со 2 по 2+L,
+// при L=6 ложное совпадение для списка из миллиарда паролей случается с вероятностью около 1e-10.
+// Таблица смещений читается в память (256 КиБ), записи — с диска, поэтому индекс работает без сети и
+// почти без памяти даже для полного списка Have I Been Pwned
+
+const (
+	breachedMagic   = "BRCHIDX1"
+	breachedBuckets = 1 << 16
+	// BreachedSuffixLength — сколько байт хеша после номера корзины хранит WriteBreachedIndex
+	BreachedSuffixLength = 6
+)
+
+var ErrInvalidBreachedIndex = errors.New("invalid breached password index")
+
+// BreachedIndex проверяет пароль по индексу утекших паролей
+type BreachedIndex struct {
+	r       io.ReaderAt
+	closer  io.Closer
+	suffix  int
+	offsets []uint32
+	entries int64
+}
+
+// OpenBreachedIndex открывает файл индекса, созданный WriteBreachedIndex
+func OpenBreachedIndex(path string) (*BreachedIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := NewBreachedIndex(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	idx.closer = f
+	return idx, nil
+}
+
+func NewBreachedIndex(r io.ReaderAt) (*BreachedIndex, error) {
+	header := make([]byte, len(breachedMagic)+1+4*(breachedBuckets+1))
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBreachedIndex, err)
+	}
+	if string(header[:len(breachedMagic)]) != breachedMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrInvalidBreachedIndex)
+	}
+	suffix := int(header[len(breachedMagic)])
+	if suffix < 1 || suffix > sha1.Size-2 {
+		return nil, fmt.Errorf("%w: suffix length %d", ErrInvalidBreachedIndex, suffix)
+	}
+	offsets := make([]uint32, breachedBuckets+1)
+	for i := range offsets {
+		offsets[i] = binary.BigEndian.Uint32(header[len(breachedMagic)+1+4*i:])
+		if i > 0 && offsets[i] < offsets[i-1] {
+			return nil, fmt.Errorf("%w: offsets are not sorted", ErrInvalidBreachedIndex)
+		}
+	}
+	return &BreachedIndex{r: r, suffix: suffix, offsets: offsets, entries: int64(len(header))}, nil
+}
+
+// Contains сообщает, есть ли пароль в индексе
+func (idx *BreachedIndex) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	bucket := binary.BigEndian.Uint16(sum[:2])
+	want := sum[2 : 2+idx.suffix]
+	lo, hi := int64(idx.offsets[bucket]), int64(idx.offsets[bucket+1])
+	entry := make([]byte, idx.suffix)
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if _, err := idx.r.ReadAt(entry, idx.entries+mid*int64(idx.suffix)); err != nil {
+			return false, fmt.Errorf("read breached index: %w", err)
+		}
+		switch c := bytes.Compare(entry, want); {
+		case c == 0:
+			return true, nil
+		case c < 0:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return false, nil
+}
+
+func (idx *BreachedIndex) Close() error {
+	if idx.closer == nil {
+		return nil
+	}
+	return idx.closer.Close()
+}
+
+// WriteBreachedIndex записывает индекс по SHA-1 хешам паролей; повторы хранятся один раз
+func WriteBreachedIndex(w io.Writer, hashes [][sha1.Size]byte) error {
+	entries := make([][]byte, 0, len(hashes))
+	for _, h := range hashes {
+		entries = append(entries, h[:2+BreachedSuffixLength])
+	}
+	slices.SortFunc(entries, bytes.Compare)
+	entries = slices.CompactFunc(entries, bytes.Equal)
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(breachedMagic)
+	bw.WriteByte(BreachedSuffixLength)
+	var offset uint32
+	next := 0
+	for bucket := 0; bucket <= breachedBuckets; bucket++ {
+		for next < len(entries) && int(binary.BigEndian.Uint16(entries[next][:2])) < bucket {
+			next++
+			offset++
+		}
+		binary.Write(bw, binary.BigEndian, offset)
+	}
+	for _, e := range entries {
+		bw.Write(e[2:])
+	}
+	return bw.Flush()
+}
+
+// ParseBreachedLine читает строку списка утекших паролей: SHA-1 в hex (формат Have I Been Pwned,
+// допускается суффикс :<число утечек>) или сам пароль
+func ParseBreachedLine(line string) ([sha1.Size]byte, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return [sha1.Size]byte{}, false
+	}
+	if hexHash, _, _ := strings.Cut(line, ":"); len(hexHash) == 2*sha1.Size {
+		var h [sha1.Size]byte
+		if _, err := hex.Decode(h[:], []byte(hexHash)); err == nil {
+			return h, true
+		}
+	}
+	return sha1.Sum([]byte(line)), true
+}
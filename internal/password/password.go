@@ -0,0 +1,115 @@
+// Package password хеширует пароли пользователей. Хеши хранятся в формате PHC ($<алгоритм>$<параметры>$...),
+// поэтому по хешу видно, каким алгоритмом и с какими параметрами он получен, и его можно пересчитать при входе
+package password
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrUnsupportedHash = errors.New("unsupported password hash format")
+
+type PasswordHasher interface {
+	// Hash возвращает хеш пароля в формате PHC
+	Hash(password string) (string, error)
+	// Verify сравнивает пароль с хешем; needsRehash сообщает, что хеш получен устаревшим алгоритмом или параметрами
+	// и его стоит пересчитать, пока известен пароль
+	Verify(hash, password string) (ok, needsRehash bool, err error)
+}
+
+// Algorithm — алгоритм, распознающий свои хеши
+type Algorithm interface {
+	PasswordHasher
+	Owns(hash string) bool
+}
+
+// hasher хеширует алгоритмом current и проверяет хеши любого из известных алгоритмов
+type hasher struct {
+	current Algorithm
+	all     []Algorithm
+
+	decoysOnce sync.Once
+	decoys     []string
+}
+
+// New возвращает PasswordHasher, который хеширует алгоритмом current, а хеши алгоритмов legacy проверяет
+// и помечает для пересчета
+func New(current Algorithm, legacy ...Algorithm) PasswordHasher {
+	return &hasher{current: current, all: append([]Algorithm{current}, legacy...)}
+}
+
+// Default — argon2id с параметрами по умолчанию; bcrypt-хеши пересчитываются
+func Default() PasswordHasher {
+	return New(DefaultArgon2id, DefaultBcrypt)
+}
+
+// ForAlgorithm выбирает текущий алгоритм по имени: argon2id или bcrypt; второй остается для проверки старых хешей
+func ForAlgorithm(name string, argon Argon2id, bcrypt Bcrypt) (PasswordHasher, error) {
+	switch name {
+	case "argon2id":
+		return New(argon, bcrypt), nil
+	case "bcrypt":
+		return New(bcrypt, argon), nil
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q, use argon2id or bcrypt", name)
+	}
+}
+
+func (h *hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+func (h *hasher) Verify(hash, password string) (bool, bool, error) {
+	for i, alg := range h.all {
+		if !alg.Owns(hash) {
+			continue
+		}
+		ok, needsRehash, err := alg.Verify(hash, password)
+		return ok, needsRehash || i > 0, err
+	}
+	return false, false, ErrUnsupportedHash
+}
+
+// decoyPassword — пароль фиктивных хешей; совпадение с ним ни на что не влияет
+const decoyPassword = "decoy password"
+
+// uniformVerifier проверяет пароль по фиктивным хешам алгоритмов, которыми не получен hash
+type uniformVerifier interface {
+	verifyDecoys(hash, password string)
+}
+
+// VerifyUniform — Verify, время которого не зависит ни от алгоритма и параметров хеша, ни от того, есть ли хеш:
+// кроме hash пароль проверяется по фиктивным хешам всех остальных алгоритмов, которые принимает h. Пустой hash —
+// аккаунта нет: проверяются только фиктивные хеши, ok = false. Фиктивные хеши получены с настроенными параметрами,
+// поэтому хеши в базе со старыми параметрами выравниваются лишь приблизительно и пересчитываются при входе
+func VerifyUniform(h PasswordHasher, hash, password string) (ok, needsRehash bool, err error) {
+	if hash != "" {
+		ok, needsRehash, err = h.Verify(hash, password)
+	}
+	if u, isUniform := h.(uniformVerifier); isUniform {
+		u.verifyDecoys(hash, password)
+	} else if hash == "" {
+		// Хеширование стоит столько же, сколько проверка
+		h.Hash(password)
+	}
+	return ok, needsRehash, err
+}
+
+func (h *hasher) verifyDecoys(hash, password string) {
+	h.decoysOnce.Do(func() {
+		for _, alg := range h.all {
+			decoy, err := alg.Hash(decoyPassword)
+			if err != nil {
+				panic(fmt.Sprintf("password: decoy hash: %v", err))
+			}
+			h.decoys = append(h.decoys, decoy)
+		}
+	})
+	for i, alg := range h.all {
+		if hash != "" && alg.Owns(hash) {
+			continue
+		}
+		alg.Verify(h.decoys[i], password)
+	}
+}
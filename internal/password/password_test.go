@@ -0,0 +1,238 @@
+package password
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var fastArgon2id = Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2id_PHCFormat(t *testing.T) {
+	hash, err := fastArgon2id.Hash("secret1")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), hash)
+	other, err := fastArgon2id.Hash("secret1")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "salted")
+
+	ok, needsRehash, err := fastArgon2id.Verify(hash, "secret1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+	ok, _, err = fastArgon2id.Verify(hash, "secret2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	stronger := fastArgon2id
+	stronger.Iterations = 2
+	ok, needsRehash, err = stronger.Verify(hash, "secret1")
+	require.NoError(t, err)
+	assert.True(t, ok, "parameters are read from the hash")
+	assert.True(t, needsRehash)
+
+	_, _, err = fastArgon2id.Verify("$argon2id$v=19$m=1024,t=1,p=0$c2FsdA$a2V5", "secret1")
+	assert.ErrorIs(t, err, ErrUnsupportedHash)
+}
+
+func TestArgon2id_LongPasswordsAreNotTruncated(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	hash, err := fastArgon2id.Hash(long)
+	require.NoError(t, err)
+	ok, _, err := fastArgon2id.Verify(hash, strings.Repeat("a", 72)+"b")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = Bcrypt{Cost: bcrypt.MinCost}.Hash(long)
+	assert.Error(t, err, "bcrypt refuses instead of truncating")
+}
+
+func TestHasher_UpgradesLegacyAndOutdatedHashes(t *testing.T) {
+	h := New(fastArgon2id, Bcrypt{Cost: bcrypt.MinCost + 1})
+
+	current, err := h.Hash("secret1")
+	require.NoError(t, err)
+	ok, needsRehash, err := h.Verify(current, "secret1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+
+	legacy, err := Bcrypt{Cost: bcrypt.MinCost + 1}.Hash("secret1")
+	require.NoError(t, err)
+	ok, needsRehash, err = h.Verify(legacy, "secret1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, needsRehash, "another algorithm")
+
+	h, err = ForAlgorithm("bcrypt", fastArgon2id, Bcrypt{Cost: bcrypt.MinCost})
+	require.NoError(t, err)
+	ok, needsRehash, err = h.Verify(legacy, "secret1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, needsRehash, "another cost")
+	ok, needsRehash, err = h.Verify(legacy, "wrong")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, needsRehash)
+
+	_, _, err = h.Verify("plaintext", "plaintext")
+	assert.ErrorIs(t, err, ErrUnsupportedHash)
+	_, err = ForAlgorithm("md5", fastArgon2id, Bcrypt{})
+	assert.Error(t, err)
+}
+
+func TestVerifyUniform(t *testing.T) {
+	h := New(Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}, Bcrypt{Cost: 4})
+	hash, err := h.Hash("secret1")
+	require.NoError(t, err)
+
+	ok, needsRehash, err := VerifyUniform(h, hash, "secret1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+
+	ok, _, err = VerifyUniform(h, "", decoyPassword)
+	require.NoError(t, err)
+	assert.False(t, ok, "a missing account never matches, even the decoy password")
+
+	_, _, err = VerifyUniform(h, "plain-text", "secret1")
+	assert.ErrorIs(t, err, ErrUnsupportedHash)
+
+	peppered, err := WithPepper(h, map[int][]byte{1: []byte("first-pepper-secret")}, 1)
+	require.NoError(t, err)
+	ok, needsRehash, err = VerifyUniform(peppered, hash, "secret1")
+	require.NoError(t, err)
+	assert.True(t, ok, "a hash without pepper still verifies")
+	assert.True(t, needsRehash)
+}
+
+func TestPepper_RotationAndLegacyHashes(t *testing.T) {
+	v1 := []byte("first-pepper-secret")
+	h, err := WithPepper(fastArgon2id, map[int][]byte{1: v1}, 1)
+	require.NoError(t, err)
+	hash, err := h.Hash("secret1")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$pepper$v=1$argon2id$"), hash)
+
+	ok, needsRehash, err := h.Verify(hash, "secret1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+	ok, _, err = fastArgon2id.Verify(strings.TrimPrefix(hash, "$pepper$v=1"), "secret1")
+	require.NoError(t, err)
+	assert.False(t, ok, "the stored hash is useless without the pepper")
+
+	other, err := WithPepper(fastArgon2id, map[int][]byte{1: []byte("another-pepper-secret")}, 1)
+	require.NoError(t, err)
+	ok, _, err = other.Verify(hash, "secret1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	rotated, err := WithPepper(fastArgon2id, map[int][]byte{1: v1, 2: []byte("second-pepper-secret")}, 2)
+	require.NoError(t, err)
+	ok, needsRehash, err = rotated.Verify(hash, "secret1")
+	require.NoError(t, err)
+	assert.True(t, ok, "previous versions still verify")
+	assert.True(t, needsRehash)
+
+	legacy, err := fastArgon2id.Hash("secret1")
+	require.NoError(t, err)
+	ok, needsRehash, err = rotated.Verify(legacy, "secret1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, needsRehash, "hashes without pepper are migrated")
+
+	dropped, err := WithPepper(fastArgon2id, map[int][]byte{2: []byte("second-pepper-secret")}, 2)
+	require.NoError(t, err)
+	_, _, err = dropped.Verify(hash, "secret1")
+	assert.Error(t, err)
+	_, err = WithPepper(fastArgon2id, map[int][]byte{1: v1}, 2)
+	assert.Error(t, err)
+}
+
+func TestPepper_LongPasswordsWithBcrypt(t *testing.T) {
+	h, err := WithPepper(Bcrypt{Cost: bcrypt.MinCost}, map[int][]byte{1: []byte("first-pepper-secret")}, 1)
+	require.NoError(t, err)
+	hash, err := h.Hash(strings.Repeat("a", 100))
+	require.NoError(t, err)
+	ok, _, err := h.Verify(hash, strings.Repeat("a", 72)+"b")
+	require.NoError(t, err)
+	assert.False(t, ok, "the pepper digest covers the whole password")
+}
+
+func TestPolicy_ReportsEveryViolatedRule(t *testing.T) {
+	policy := &Policy{MinLength: 8, MaxLength: 16, MinClasses: 3, RejectUsername: true}
+
+	err := policy.Check("alice1", "Alice")
+	var policyErr *PolicyError
+	require.ErrorAs(t, err, &policyErr)
+	var rules []string
+	for _, v := range policyErr.Violations {
+		rules = append(rules, v.Rule)
+	}
+	assert.Equal(t, []string{RuleMinLength, RuleCharacterClasses, RuleContainsUsername}, rules)
+
+	err = policy.Check(strings.Repeat("Ab1", 6), "alice")
+	require.ErrorAs(t, err, &policyErr)
+	assert.Equal(t, RuleMaxLength, policyErr.Violations[0].Rule)
+
+	assert.NoError(t, policy.Check("Correct-Horse1", "alice"))
+	assert.NoError(t, policy.Check("пароль-Пароль", "alice"), "length is counted in characters")
+	assert.NoError(t, policy.Check("xx-Al-Horse1", "Al"), "short usernames are not checked")
+}
+
+func TestPolicy_EntropyPenalizesSequencesAndRepeats(t *testing.T) {
+	assert.Less(t, EstimateEntropy("abcdefgh"), EstimateEntropy("hqzmcwra"))
+	assert.Less(t, EstimateEntropy("aaaaaaaa"), EstimateEntropy("hqzmcwra"))
+	assert.Less(t, EstimateEntropy("87654321"), EstimateEntropy("83719462"))
+	assert.Zero(t, EstimateEntropy(""))
+
+	policy := &Policy{MinEntropy: 40}
+	var policyErr *PolicyError
+	require.ErrorAs(t, policy.Check("abcdefghijkl", ""), &policyErr)
+	assert.Equal(t, RuleEntropy, policyErr.Violations[0].Rule)
+	assert.NoError(t, policy.Check("tX9!qL2#vR", ""))
+}
+
+func TestBreachedIndex_RoundTrip(t *testing.T) {
+	var hashes [][sha1.Size]byte
+	for _, line := range []string{
+		"password",
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493", // SHA-1 от "password"
+		"b1b3773a05c0ed0176787a4f1574ff0075f7521e",         // SHA-1 от "qwerty"
+		"123456\r\n",
+		"",
+	} {
+		if h, ok := ParseBreachedLine(line); ok {
+			hashes = append(hashes, h)
+		}
+	}
+	require.Len(t, hashes, 4)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteBreachedIndex(&buf, hashes))
+	idx, err := NewBreachedIndex(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), idx.entries+3*BreachedSuffixLength, "duplicates are stored once")
+
+	for _, pw := range []string{"password", "qwerty", "123456"} {
+		ok, err := idx.Contains(pw)
+		require.NoError(t, err)
+		assert.True(t, ok, pw)
+	}
+	ok, err := idx.Contains("Correct-Horse1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	var policyErr *PolicyError
+	require.ErrorAs(t, (&Policy{Breached: idx}).Check("qwerty", ""), &policyErr)
+	assert.Equal(t, RuleBreached, policyErr.Violations[0].Rule)
+
+	_, err = NewBreachedIndex(bytes.NewReader([]byte("not an index")))
+	assert.ErrorIs(t, err, ErrInvalidBreachedIndex)
+}
@@ -0,0 +1,76 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const pepperPrefix = "$pepper$v="
+
+// peppered перед хешированием подписывает пароль HMAC-SHA256 с секретом, который хранится вне базы:
+// без него утекшие хеши нельзя перебирать офлайн. Хеш получает префикс $pepper$v=<версия>,
+// поэтому секрет можно сменить: старые версии остаются для проверки, а хеши пересчитываются при входе
+type peppered struct {
+	next    PasswordHasher
+	peppers map[int][]byte
+	current int
+}
+
+// WithPepper добавляет к next перец версии current; peppers должен содержать все версии, которыми
+// еще подписаны хеши в базе. Хеши без перца и с другими версиями помечаются для пересчета
+func WithPepper(next PasswordHasher, peppers map[int][]byte, current int) (PasswordHasher, error) {
+	if _, ok := peppers[current]; !ok {
+		return nil, fmt.Errorf("pepper version %d is not configured", current)
+	}
+	return &peppered{next: next, peppers: peppers, current: current}, nil
+}
+
+func (p *peppered) Hash(password string) (string, error) {
+	hash, err := p.next.Hash(pepper(p.peppers[p.current], password))
+	if err != nil {
+		return "", err
+	}
+	return pepperPrefix + strconv.Itoa(p.current) + hash, nil
+}
+
+func (p *peppered) Verify(hash, password string) (bool, bool, error) {
+	if !strings.HasPrefix(hash, pepperPrefix) {
+		ok, _, err := p.next.Verify(hash, password)
+		return ok, true, err
+	}
+	versionStr, inner, found := strings.Cut(strings.TrimPrefix(hash, pepperPrefix), "$")
+	version, err := strconv.Atoi(versionStr)
+	if !found || err != nil {
+		return false, false, fmt.Errorf("%w: pepper version %q", ErrUnsupportedHash, versionStr)
+	}
+	secret, ok := p.peppers[version]
+	if !ok {
+		return false, false, fmt.Errorf("pepper version %d is not configured", version)
+	}
+	ok, needsRehash, err := p.next.Verify("$"+inner, pepper(secret, password))
+	return ok, needsRehash || version != p.current, err
+}
+
+func (p *peppered) verifyDecoys(hash, password string) {
+	u, ok := p.next.(uniformVerifier)
+	if !ok {
+		return
+	}
+	if strings.HasPrefix(hash, pepperPrefix) {
+		if _, inner, found := strings.Cut(strings.TrimPrefix(hash, pepperPrefix), "$"); found {
+			hash = "$" + inner
+		}
+	}
+	u.verifyDecoys(hash, pepper(p.peppers[p.current], password))
+}
+
+// pepper возвращает HMAC в base64: 44 символа без нулевых байт укладываются в 72 байта bcrypt
+func pepper(secret []byte, password string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,161 @@
+package password
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"sstu-go-forum-auth-service/internal/i18n"
+)
+
+// Правила политики; по ним клиент узнает, что исправить
+const (
+	RuleMinLength        = "min_length"
+	RuleMaxLength        = "max_length"
+	RuleCharacterClasses = "character_classes"
+	RuleEntropy          = "entropy"
+	RuleContainsUsername = "contains_username"
+	RuleBreached         = "breached"
+)
+
+// BreachedList — список утекших паролей
+type BreachedList interface {
+	Contains(password string) (bool, error)
+}
+
+// Policy — требования к новому паролю. Нулевые ограничения не проверяются
+type Policy struct {
+	// MinLength и MaxLength считаются в символах, а не в байтах
+	MinLength int
+	MaxLength int
+	// MinClasses — сколько классов символов нужно из четырех: строчные, заглавные, цифры, остальные
+	MinClasses int
+	// MinEntropy — минимальная оценка стойкости в битах, см. EstimateEntropy
+	MinEntropy float64
+	// RejectUsername запрещает пароли, содержащие имя пользователя
+	RejectUsername bool
+	Breached       BreachedList
+}
+
+// DefaultPolicy повторяет прежнее правило (не короче 6 символов) и ограничивает длину.
+// Из нее же берутся значения по умолчанию переменных PASSWORD_* в config
+var DefaultPolicy = &Policy{MinLength: 6, MaxLength: 128, RejectUsername: true}
+
+// Violation — нарушенное правило; текст берется из каталога i18n по ключу "password.<rule>" с подстановкой Args
+type Violation struct {
+	Rule string
+	Args []any
+}
+
+func (v Violation) Message(locale string) string {
+	return i18n.T(locale, "password."+v.Rule, v.Args...)
+}
+
+// PolicyError перечисляет все нарушенные правила сразу, чтобы пользователь исправил пароль за одну попытку
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message(i18n.EN)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Check проверяет пароль пользователя username; при нарушениях возвращает *PolicyError.
+// Другая ошибка означает, что список утекших паролей недоступен
+func (p *Policy) Check(password, username string) error {
+	var violations []Violation
+	add := func(rule string, args ...any) {
+		violations = append(violations, Violation{Rule: rule, Args: args})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		add(RuleMinLength, p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		add(RuleMaxLength, p.MaxLength)
+	}
+	if len(classSizes(password)) < p.MinClasses {
+		add(RuleCharacterClasses, p.MinClasses)
+	}
+	if p.MinEntropy > 0 && EstimateEntropy(password) < p.MinEntropy {
+		add(RuleEntropy)
+	}
+	if p.RejectUsername && utf8.RuneCountInString(username) >= 3 &&
+		strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		add(RuleContainsUsername)
+	}
+	if p.Breached != nil && password != "" {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			add(RuleBreached)
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// classSizes возвращает размеры встреченных в пароле классов символов: строчные, заглавные, цифры, остальные
+func classSizes(password string) []int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	var sizes []int
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {other, 33}} {
+		if class.present {
+			sizes = append(sizes, class.size)
+		}
+	}
+	return sizes
+}
+
+// EstimateEntropy грубо оценивает стойкость пароля в битах в духе zxcvbn: каждый символ дает log2 размера
+// алфавита из встреченных классов, а повтор предыдущего символа или шаг последовательности (abc, 321) — один бит
+func EstimateEntropy(password string) float64 {
+	pool := 0
+	for _, size := range classSizes(password) {
+		pool += size
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	perChar := math.Log2(float64(pool))
+	var bits float64
+	prev, step := rune(-1), rune(0)
+	for i, r := range []rune(password) {
+		d := r - prev
+		switch {
+		case i > 0 && (d == 0 || (d == 1 || d == -1) && (i == 1 || d == step)):
+			bits++
+		default:
+			bits += perChar
+		}
+		prev, step = r, d
+	}
+	return bits
+}
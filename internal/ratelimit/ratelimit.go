@@ -0,0 +1,86 @@
+// Package ratelimit ограничивает частоту действий по произвольному ключу (IP, аккаунт)
+package ratelimit
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+type Limiter interface {
+	// Allow учитывает попытку и сообщает, разрешена ли она; при отказе возвращает, через сколько повторить
+	Allow(key string) (ok bool, retryAfter time.Duration)
+}
+
+// Backend создает лимитеры в памяти процесса, а если задана DB — в таблице rate_limits, общей для всех реплик.
+// name различает лимитеры в общей таблице
+type Backend struct {
+	DB *sql.DB
+}
+
+func (b Backend) TokenBucket(name string, limit int, per time.Duration) Limiter {
+	if b.DB == nil {
+		return NewTokenBucket(limit, per)
+	}
+	return NewSQLTokenBucket(b.DB, name, limit, per)
+}
+
+func (b Backend) SlidingWindow(name string, limit int, window time.Duration) Limiter {
+	if b.DB == nil {
+		return NewSlidingWindow(limit, window)
+	}
+	return NewSQLSlidingWindow(b.DB, name, limit, window)
+}
+
+// SlidingWindow — приближенное скользящее окно на двух соседних фиксированных окнах.
+// Память ограничена ключами, активными за последние два окна
+type SlidingWindow struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu       sync.Mutex
+	start    time.Time
+	current  map[string]int
+	previous map[string]int
+}
+
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	mustBePositive(limit, window)
+	return &SlidingWindow{
+		limit:    limit,
+		window:   window,
+		now:      time.Now,
+		current:  map[string]int{},
+		previous: map[string]int{},
+	}
+}
+
+func (l *SlidingWindow) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.advance(now)
+	elapsed := now.Sub(l.start)
+	weight := 1 - float64(elapsed)/float64(l.window)
+	if float64(l.previous[key])*weight+float64(l.current[key]) >= float64(l.limit) {
+		return false, l.window - elapsed
+	}
+	l.current[key]++
+	return true, 0
+}
+
+func (l *SlidingWindow) advance(now time.Time) {
+	start := now.Truncate(l.window)
+	if !start.After(l.start) {
+		return
+	}
+	if start.Sub(l.start) == l.window {
+		l.previous = l.current
+	} else {
+		l.previous = map[string]int{}
+	}
+	l.current = map[string]int{}
+	l.start = start
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlidingWindow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewSlidingWindow(2, time.Minute)
+	l.now = func() time.Time { return now }
+	testSlidingWindow(t, l, &now)
+}
+
+// testSlidingWindow проверяет лимит 2 за скользящую минуту
+func testSlidingWindow(t *testing.T, l Limiter, now *time.Time) {
+	ok, _ := l.Allow("a")
+	assert.True(t, ok)
+	ok, _ = l.Allow("a")
+	assert.True(t, ok)
+	ok, retryAfter := l.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, time.Minute, retryAfter)
+	ok, _ = l.Allow("b")
+	assert.True(t, ok, "keys are limited independently")
+
+	// В середине следующего окна предыдущее учитывается с весом 1/2
+	*now = now.Add(90 * time.Second)
+	ok, _ = l.Allow("a")
+	assert.True(t, ok)
+	ok, _ = l.Allow("a")
+	assert.False(t, ok)
+
+	*now = now.Add(2 * time.Minute)
+	ok, _ = l.Allow("a")
+	assert.True(t, ok)
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewTokenBucket(2, time.Minute)
+	l.now = func() time.Time { return now }
+	testTokenBucket(t, l, &now)
+}
+
+// testTokenBucket проверяет лимит 2 в минуту: всплеск из двух запросов, затем один раз в 30 секунд
+func testTokenBucket(t *testing.T, l Limiter, now *time.Time) {
+	ok, _ := l.Allow("a")
+	assert.True(t, ok)
+	ok, _ = l.Allow("a")
+	assert.True(t, ok, "burst")
+	ok, retryAfter := l.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, 30*time.Second, retryAfter)
+	ok, _ = l.Allow("b")
+	assert.True(t, ok, "keys are limited independently")
+
+	*now = now.Add(30 * time.Second)
+	ok, _ = l.Allow("a")
+	assert.True(t, ok, "one token refilled")
+	ok, retryAfter = l.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	*now = now.Add(10 * time.Minute)
+	for i := 0; i < 2; i++ {
+		ok, _ = l.Allow("a")
+		assert.True(t, ok, "refill is capped at the burst")
+	}
+	ok, _ = l.Allow("a")
+	assert.False(t, ok)
+}
+
+func TestConstructors_RejectNonPositiveLimits(t *testing.T) {
+	assert.Panics(t, func() { NewTokenBucket(0, time.Minute) })
+	assert.Panics(t, func() { NewTokenBucket(1, 0) })
+	assert.Panics(t, func() { NewSlidingWindow(0, time.Minute) })
+	assert.Panics(t, func() { NewSQLTokenBucket(nil, "login_ip", 0, time.Minute) })
+	assert.Panics(t, func() { NewSQLSlidingWindow(nil, "login_ip", -1, time.Minute) })
+	assert.Panics(t, func() { NewSQLTokenBucket(nil, "login_ip", 2000, time.Second) }, "the interval would round down to 0ms")
+	assert.NotPanics(t, func() { NewSQLTokenBucket(nil, "login_ip", 1000, time.Second) })
+}
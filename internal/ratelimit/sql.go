@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Лимитеры ниже хранят состояние в таблице rate_limits, поэтому лимит общий для всех реплик сервиса.
+// Каждая попытка — один атомарный upsert; запросы работают и в Postgres, и в SQLite.
+// Время хранится в миллисекундах, чтобы вся арифметика оставалась целочисленной
+
+// SQLTokenBucket — TokenBucket в базе
+type SQLTokenBucket struct {
+	db       *sql.DB
+	name     string
+	interval time.Duration
+	burst    time.Duration
+	now      func() time.Time
+}
+
+func NewSQLTokenBucket(db *sql.DB, name string, limit int, per time.Duration) *SQLTokenBucket {
+	mustFitMilliseconds(limit, per)
+	interval := per / time.Duration(limit)
+	return &SQLTokenBucket{
+		db:       db,
+		name:     name,
+		interval: interval,
+		burst:    interval * time.Duration(limit),
+		now:      time.Now,
+	}
+}
+
+func (l *SQLTokenBucket) Allow(key string) (bool, time.Duration) {
+	now := l.now()
+	nowMs, intervalMs, burstMs := now.UnixMilli(), l.interval.Milliseconds(), l.burst.Milliseconds()
+	var (
+		tat     int64
+		allowed bool
+	)
+	err := l.db.QueryRow(
+		`INSERT INTO rate_limits (limiter_key, tat_ms, allowed, expires_at) VALUES ($1, $2, TRUE, $3)
+		ON CONFLICT (limiter_key) DO UPDATE SET
+			tat_ms = CASE
+				WHEN rate_limits.tat_ms <= $4 THEN $2
+				WHEN rate_limits.tat_ms + $5 - $4 <= $6 THEN rate_limits.tat_ms + $5
+				ELSE rate_limits.tat_ms END,
+			allowed = CASE WHEN rate_limits.tat_ms <= $4 THEN TRUE ELSE rate_limits.tat_ms + $5 - $4 <= $6 END,
+			expires_at = $3
+		RETURNING tat_ms, allowed`,
+		l.name+":"+key, nowMs+intervalMs, now.Add(l.burst).UTC(), nowMs, intervalMs, burstMs,
+	).Scan(&tat, &allowed)
+	if err != nil {
+		return failOpen(err, l.name, key)
+	}
+	if !allowed {
+		return false, time.Duration(tat+intervalMs-nowMs-burstMs) * time.Millisecond
+	}
+	return true, 0
+}
+
+// SQLSlidingWindow — SlidingWindow в базе
+type SQLSlidingWindow struct {
+	db     *sql.DB
+	name   string
+	limit  int
+	window time.Duration
+	now    func() time.Time
+}
+
+func NewSQLSlidingWindow(db *sql.DB, name string, limit int, window time.Duration) *SQLSlidingWindow {
+	mustFitMilliseconds(limit, window)
+	return &SQLSlidingWindow{db: db, name: name, limit: limit, window: window, now: time.Now}
+}
+
+func (l *SQLSlidingWindow) Allow(key string) (bool, time.Duration) {
+	now := l.now()
+	start := now.Truncate(l.window)
+	elapsed := now.Sub(start)
+	windowMs := l.window.Milliseconds()
+	var allowed bool
+	// Условие previous*(window-elapsed)/window + current < limit умножено на window
+	err := l.db.QueryRow(
+		`INSERT INTO rate_limits (limiter_key, window_start_ms, current_count, previous_count, allowed, expires_at)
+		VALUES ($1, $2, 1, 0, TRUE, $3)
+		ON CONFLICT (limiter_key) DO UPDATE SET
+			window_start_ms = $2,
+			previous_count = CASE
+				WHEN rate_limits.window_start_ms = $2 THEN rate_limits.previous_count
+				WHEN rate_limits.window_start_ms = $4 THEN rate_limits.current_count
+				ELSE 0 END,
+			current_count = CASE WHEN rate_limits.window_start_ms = $2 THEN rate_limits.current_count ELSE 0 END + CASE
+				WHEN (CASE
+					WHEN rate_limits.window_start_ms = $2 THEN rate_limits.previous_count
+					WHEN rate_limits.window_start_ms = $4 THEN rate_limits.current_count
+					ELSE 0 END) * $5
+					+ (CASE WHEN rate_limits.window_start_ms = $2 THEN rate_limits.current_count ELSE 0 END) * $6 < $7
+				THEN 1 ELSE 0 END,
+			allowed = (CASE
+				WHEN rate_limits.window_start_ms = $2 THEN rate_limits.previous_count
+				WHEN rate_limits.window_start_ms = $4 THEN rate_limits.current_count
+				ELSE 0 END) * $5
+				+ (CASE WHEN rate_limits.window_start_ms = $2 THEN rate_limits.current_count ELSE 0 END) * $6 < $7,
+			expires_at = $3
+		RETURNING allowed`,
+		l.name+":"+key, start.UnixMilli(), start.Add(2*l.window).UTC(), start.Add(-l.window).UnixMilli(),
+		(l.window - elapsed).Milliseconds(), windowMs, int64(l.limit)*windowMs,
+	).Scan(&allowed)
+	if err != nil {
+		return failOpen(err, l.name, key)
+	}
+	if !allowed {
+		return false, l.window - elapsed
+	}
+	return true, 0
+}
+
+// failOpen пропускает запрос без ограничения, если база недоступна: она не должна останавливать вход.
+// Каждый такой пропуск попадает в лог, чтобы отключившийся лимит был заметен
+func failOpen(err error, name, key string) (bool, time.Duration) {
+	log.Error().Err(err).Str("limiter", name).Str("key", key).
+		Msg("Rate limit database unavailable, request allowed WITHOUT rate limiting")
+	return true, 0
+}
+
+// DeleteExpired удаляет не более limit записей rate_limits, устаревших к моменту before
+func DeleteExpired(db *sql.DB, before time.Time, limit int) (int, error) {
+	res, err := db.Exec(
+		"DELETE FROM rate_limits WHERE limiter_key IN (SELECT limiter_key FROM rate_limits WHERE expires_at < $1 ORDER BY limiter_key LIMIT $2)",
+		before.UTC(), limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
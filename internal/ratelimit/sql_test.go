@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/migrations"
+	"sstu-go-forum-auth-service/internal/repository/sqlite"
+)
+
+// newTestDB открывает SQLite в памяти — локальную замену Postgres для общих лимитов
+func newTestDB(t *testing.T) *sql.DB {
+	db, err := sqlite.Open(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	m, err := migrations.New(db, migrations.SQLite)
+	require.NoError(t, err)
+	_, err = m.Up()
+	require.NoError(t, err)
+	return db
+}
+
+func TestSQLTokenBucket(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewSQLTokenBucket(newTestDB(t), "test", 2, time.Minute)
+	l.now = func() time.Time { return now }
+	testTokenBucket(t, l, &now)
+}
+
+func TestSQLSlidingWindow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewSQLSlidingWindow(newTestDB(t), "test", 2, time.Minute)
+	l.now = func() time.Time { return now }
+	testSlidingWindow(t, l, &now)
+}
+
+func TestSQL_ReplicasShareLimitsAndNamesAreSeparate(t *testing.T) {
+	db := newTestDB(t)
+	first, second := Backend{DB: db}.SlidingWindow("login", 2, time.Minute), Backend{DB: db}.SlidingWindow("login", 2, time.Minute)
+	other := Backend{DB: db}.SlidingWindow("register", 2, time.Minute)
+
+	ok, _ := first.Allow("a")
+	assert.True(t, ok)
+	ok, _ = second.Allow("a")
+	assert.True(t, ok)
+	ok, _ = first.Allow("a")
+	assert.False(t, ok, "the limit is shared between replicas")
+	ok, _ = other.Allow("a")
+	assert.True(t, ok)
+
+	n, err := DeleteExpired(db, time.Now().Add(time.Hour), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	ok, _ = first.Allow("a")
+	assert.True(t, ok)
+}
+
+func TestSQL_FailsOpen(t *testing.T) {
+	db := newTestDB(t)
+	l := NewSQLTokenBucket(db, "test", 1, time.Minute)
+	require.NoError(t, db.Close())
+	for i := 0; i < 3; i++ {
+		ok, _ := l.Allow("a")
+		assert.True(t, ok)
+	}
+}
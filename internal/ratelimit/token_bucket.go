@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket пропускает всплеск до limit запросов и затем по одному раз в per/limit (алгоритм GCRA).
+// Для ключа хранится только момент, когда корзина снова станет полной; полные корзины удаляются
+type TokenBucket struct {
+	interval time.Duration
+	burst    time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	tat       map[string]time.Time
+	nextSweep time.Time
+}
+
+func NewTokenBucket(limit int, per time.Duration) *TokenBucket {
+	mustBePositive(limit, per)
+	interval := per / time.Duration(limit)
+	return &TokenBucket{
+		interval: interval,
+		burst:    interval * time.Duration(limit),
+		now:      time.Now,
+		tat:      map[string]time.Time{},
+	}
+}
+
+func (l *TokenBucket) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	tat := l.tat[key]
+	if tat.Before(now) {
+		tat = now
+	}
+	tat = tat.Add(l.interval)
+	if wait := tat.Sub(now) - l.burst; wait > 0 {
+		return false, wait
+	}
+	l.tat[key] = tat
+	return true, 0
+}
+
+// sweep раз в burst удаляет ключи, корзины которых уже полны
+func (l *TokenBucket) sweep(now time.Time) {
+	if now.Before(l.nextSweep) {
+		return
+	}
+	for key, tat := range l.tat {
+		if !tat.After(now) {
+			delete(l.tat, key)
+		}
+	}
+	l.nextSweep = now.Add(l.burst)
+}
+
+// mustBePositive паникует, как time.NewTicker, на лимите или периоде <= 0: иначе TokenBucket делит на ноль,
+// а SlidingWindow молча блокирует все запросы. Такие настройки отсекает config.Config.ValidateRateLimits
+func mustBePositive(limit int, per time.Duration) {
+	if limit <= 0 || per <= 0 {
+		panic("ratelimit: non-positive limit or period")
+	}
+}
+
+// mustFitMilliseconds — лимитеры в БД хранят время в миллисекундах, и более частый интервал округлился бы до нуля
+func mustFitMilliseconds(limit int, per time.Duration) {
+	mustBePositive(limit, per)
+	if per/time.Duration(limit) < time.Millisecond {
+		panic("ratelimit: more than one request per millisecond")
+	}
+}
@@ -1,12 +1,91 @@
 package repository
 
-import "sstu-go-forum-auth-service/internal/model"
+import (
+	"time"
+
+	"sstu-go-forum-auth-service/internal/model"
+)
 
 type AuthRepository interface {
+	// WithTx выполняет fn в одной транзакции: при ошибке все изменения откатываются
+	WithTx(fn func(repo AuthRepository) error) error
 	CreateUser(user *model.User) error
+	GetUserByID(id int) (*model.User, error)
+	// GetUserByUsername ищет пользователя по model.UsernameKey, то есть без учета регистра и формы записи символов
 	GetUserByUsername(username string) (*model.User, error)
-	DeleteRefreshTokensByUserID(userID int) error
+	// GetLookalikeUser возвращает пользователя, имя которого выглядит как username (тот же model.UsernameSkeleton);
+	// ErrNotFound, если такого нет
+	GetLookalikeUser(username string) (*model.User, error)
+	UpdatePassword(userID int, passwordHash string) error
+	// RehashPassword заменяет хеш пароля, только если он все еще равен oldHash, иначе ErrNotFound
+	RehashPassword(userID int, oldHash, newHash string) error
+	// UpdateEmail меняет почту и снимает отметку о ее подтверждении
+	UpdateEmail(userID int, email string) error
+	// UpdateRole меняет роль пользователя; роль не выбирается при регистрации и назначается только через authctl
+	UpdateRole(userID int, role string) error
+	// UpdateLocale меняет язык писем и ответов пользователя; пустой язык означает выбор по Accept-Language
+	UpdateLocale(userID int, locale string) error
+	// MarkEmailVerified подтверждает почту, только если у пользователя все еще адрес email, иначе ErrNotFound
+	MarkEmailVerified(userID int, email string) error
+	// DeleteActiveRefreshTokensByUserID отзывает сессии пользователя: удаляет его неиспользованные refresh токены.
+	// Использованные остаются до истечения, иначе их повторное предъявление не распознать
+	DeleteActiveRefreshTokensByUserID(userID int) error
 	SaveRefreshToken(token *model.RefreshToken) error
-	GetRefreshToken(tokenString string) (*model.RefreshToken, error)
-	DeleteRefreshToken(tokenString string) error
+	// ConsumeRefreshToken атомарно помечает токен использованным и возвращает его: из конкурирующих вызовов
+	// успешен только один. Уже использованный токен возвращается вместе с ErrConflict, неизвестный — ErrNotFound
+	ConsumeRefreshToken(tokenString string) (*model.RefreshToken, error)
+	// DeleteExpiredRefreshTokens удаляет не более limit токенов, истекших до before, и возвращает их количество
+	DeleteExpiredRefreshTokens(before time.Time, limit int) (int, error)
+	SavePasswordResetToken(token *model.PasswordResetToken) error
+	// ConsumePasswordResetToken атомарно удаляет токен сброса по хешу и возвращает его
+	ConsumePasswordResetToken(tokenHash string) (*model.PasswordResetToken, error)
+	DeletePasswordResetTokensByUserID(userID int) error
+	DeleteExpiredPasswordResetTokens(before time.Time, limit int) (int, error)
+	SaveEmailVerificationToken(token *model.EmailVerificationToken) error
+	// ConsumeEmailVerificationToken атомарно удаляет токен подтверждения почты по хешу и возвращает его
+	ConsumeEmailVerificationToken(tokenHash string) (*model.EmailVerificationToken, error)
+	DeleteEmailVerificationTokensByUserID(userID int) error
+	DeleteExpiredEmailVerificationTokens(before time.Time, limit int) (int, error)
+	SaveMagicLinkToken(token *model.MagicLinkToken) error
+	// ConsumeMagicLinkToken атомарно удаляет токен входа по ссылке и возвращает его
+	ConsumeMagicLinkToken(tokenHash string) (*model.MagicLinkToken, error)
+	DeleteMagicLinkTokensByUserID(userID int) error
+	DeleteExpiredMagicLinkTokens(before time.Time, limit int) (int, error)
+	// SaveTOTP создает или заменяет секрет пользователя; новая запись не подтверждена
+	SaveTOTP(totp *model.TOTP) error
+	GetTOTP(userID int) (*model.TOTP, error)
+	ConfirmTOTP(userID int) error
+	// UseTOTPStep атомарно запоминает принятый шаг; ErrConflict, если этот или более поздний шаг уже использован
+	UseTOTPStep(userID int, step int64) error
+	DeleteTOTP(userID int) error
+	SaveRecoveryCodes(userID int, codeHashes []string) error
+	// UseRecoveryCode удаляет код пользователя; ErrNotFound, если такого неиспользованного кода нет
+	UseRecoveryCode(userID int, codeHash string) error
+	CountRecoveryCodes(userID int) (int, error)
+	DeleteRecoveryCodesByUserID(userID int) error
+	// SaveWebAuthnCredential сохраняет ключ доступа; ErrConflict, если ключ с таким ID уже зарегистрирован
+	SaveWebAuthnCredential(credential *model.WebAuthnCredential) error
+	ListWebAuthnCredentials(userID int) ([]model.WebAuthnCredential, error)
+	// UseWebAuthnCredential атомарно запоминает счетчик подписей; ErrConflict, если он не вырос
+	// (счетчик 0 означает, что ключ его не ведет)
+	UseWebAuthnCredential(credentialID []byte, signCount uint32, backupState bool) error
+	DeleteWebAuthnCredential(userID, id int) error
+	SaveWebAuthnSession(session *model.WebAuthnSession) error
+	// ConsumeWebAuthnSession атомарно удаляет и возвращает сессию церемонии
+	ConsumeWebAuthnSession(tokenHash string) (*model.WebAuthnSession, error)
+	DeleteExpiredWebAuthnSessions(before time.Time, limit int) (int, error)
+	GetLoginFailure(subject string) (*model.LoginFailure, error)
+	// RecordLoginAttempt атомарно учитывает попытку входа и возвращает счетчик: увеличивает его и продлевает
+	// до expiresAt, а истекший к now начинает заново. Пока вход заблокирован, счетчик не меняется
+	RecordLoginAttempt(subject string, now, expiresAt time.Time) (*model.LoginFailure, error)
+	// ReleaseLoginAttempt отменяет учет одной попытки, если счетчик есть
+	ReleaseLoginAttempt(subject string) error
+	// LockLogin запрещает вход до until и хранит счетчик до expiresAt; ErrNotFound, если счетчика нет
+	LockLogin(subject string, until, expiresAt time.Time) error
+	DeleteLoginFailure(subject string) error
+	DeleteExpiredLoginFailures(before time.Time, limit int) (int, error)
+	// SaveDenylistEntry создает или заменяет запись об отзыве access токенов пользователя
+	SaveDenylistEntry(entry *model.DenylistEntry) error
+	GetDenylistEntry(userID int) (*model.DenylistEntry, error)
+	DeleteExpiredDenylistEntries(before time.Time, limit int) (int, error)
 }
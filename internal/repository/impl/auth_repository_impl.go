@@ -2,62 +2,567 @@ package impl
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/repository"
 )
 
+// querier — общее подмножество *sql.DB и *sql.Tx
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
 type AuthRepositoryImpl struct {
-	DB *sql.DB
+	DB      *sql.DB
+	q       querier
+	dialect Dialect
 }
 
 func NewRepository(db *sql.DB) *AuthRepositoryImpl {
-	return &AuthRepositoryImpl{DB: db}
+	return NewRepositoryWithDialect(db, PostgresDialect{})
+}
+
+func NewRepositoryWithDialect(db *sql.DB, dialect Dialect) *AuthRepositoryImpl {
+	return &AuthRepositoryImpl{DB: db, q: db, dialect: dialect}
+}
+
+func (r *AuthRepositoryImpl) WithTx(fn func(repo repository.AuthRepository) error) error {
+	if _, ok := r.q.(*sql.Tx); ok {
+		return fn(r)
+	}
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&AuthRepositoryImpl{DB: r.DB, q: tx, dialect: r.dialect}); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (r *AuthRepositoryImpl) CreateUser(user *model.User) error {
-	return r.DB.QueryRow(
-		"INSERT INTO users (username, password, role) VALUES ($1, $2, $3) RETURNING id",
-		user.Username, user.Password, user.Role,
-	).Scan(&user.ID)
+	return r.mapError(r.q.QueryRow(
+		`INSERT INTO users (username, username_normalized, username_skeleton, password, role, email, email_verified, locale)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8) RETURNING id`,
+		user.Username, model.UsernameKey(user.Username), model.UsernameSkeleton(user.Username),
+		user.Password, user.Role, user.Email, user.EmailVerified, user.Locale,
+	).Scan(&user.ID))
+}
+
+func (r *AuthRepositoryImpl) GetUserByID(id int) (*model.User, error) {
+	user := &model.User{}
+	err := r.q.QueryRow(
+		"SELECT id, username, password, role, COALESCE(email, ''), email_verified, locale FROM users WHERE id = $1",
+		id,
+	).Scan(&user.ID, &user.Username, &user.Password, &user.Role, &user.Email, &user.EmailVerified, &user.Locale)
+	if err != nil {
+		return nil, r.mapError(err)
+	}
+	return user, nil
 }
 
 func (r *AuthRepositoryImpl) GetUserByUsername(username string) (*model.User, error) {
 	user := &model.User{}
-	err := r.DB.QueryRow(
-		"SELECT id, username, password, role FROM users WHERE username = $1",
-		username,
-	).Scan(&user.ID, &user.Username, &user.Password, &user.Role)
+	err := r.q.QueryRow(
+		"SELECT id, username, password, role, COALESCE(email, ''), email_verified, locale FROM users WHERE username_normalized = $1",
+		model.UsernameKey(username),
+	).Scan(&user.ID, &user.Username, &user.Password, &user.Role, &user.Email, &user.EmailVerified, &user.Locale)
 	if err != nil {
-		return nil, err
+		return nil, r.mapError(err)
 	}
 	return user, nil
 }
 
-func (r *AuthRepositoryImpl) DeleteRefreshTokensByUserID(userID int) error {
-	_, err := r.DB.Exec("DELETE FROM refresh_tokens WHERE user_id = $1", userID)
+func (r *AuthRepositoryImpl) GetLookalikeUser(username string) (*model.User, error) {
+	user := &model.User{}
+	err := r.q.QueryRow(
+		"SELECT id, username, password, role, COALESCE(email, ''), email_verified, locale FROM users WHERE username_skeleton = $1 ORDER BY id LIMIT 1",
+		model.UsernameSkeleton(username),
+	).Scan(&user.ID, &user.Username, &user.Password, &user.Role, &user.Email, &user.EmailVerified, &user.Locale)
+	if err != nil {
+		return nil, r.mapError(err)
+	}
+	return user, nil
+}
+
+func (r *AuthRepositoryImpl) UpdatePassword(userID int, passwordHash string) error {
+	res, err := r.q.Exec("UPDATE users SET password = $1 WHERE id = $2", passwordHash, userID)
+	if err != nil {
+		return r.mapError(err)
+	}
+	return requireAffected(res)
+}
+
+func (r *AuthRepositoryImpl) RehashPassword(userID int, oldHash, newHash string) error {
+	res, err := r.q.Exec("UPDATE users SET password = $1 WHERE id = $2 AND password = $3", newHash, userID, oldHash)
+	if err != nil {
+		return r.mapError(err)
+	}
+	return requireAffected(res)
+}
+
+func (r *AuthRepositoryImpl) UpdateEmail(userID int, email string) error {
+	res, err := r.q.Exec("UPDATE users SET email = NULLIF($1, ''), email_verified = FALSE WHERE id = $2", email, userID)
+	if err != nil {
+		return r.mapError(err)
+	}
+	return requireAffected(res)
+}
+
+func (r *AuthRepositoryImpl) UpdateRole(userID int, role string) error {
+	res, err := r.q.Exec("UPDATE users SET role = $1 WHERE id = $2", role, userID)
+	if err != nil {
+		return r.mapError(err)
+	}
+	return requireAffected(res)
+}
+
+func (r *AuthRepositoryImpl) UpdateLocale(userID int, locale string) error {
+	res, err := r.q.Exec("UPDATE users SET locale = $1 WHERE id = $2", locale, userID)
+	if err != nil {
+		return r.mapError(err)
+	}
+	return requireAffected(res)
+}
+
+func (r *AuthRepositoryImpl) MarkEmailVerified(userID int, email string) error {
+	res, err := r.q.Exec("UPDATE users SET email_verified = TRUE WHERE id = $1 AND LOWER(email) = LOWER($2)", userID, email)
+	if err != nil {
+		return r.mapError(err)
+	}
+	return requireAffected(res)
+}
+
+func (r *AuthRepositoryImpl) DeleteActiveRefreshTokensByUserID(userID int) error {
+	_, err := r.q.Exec("DELETE FROM refresh_tokens WHERE user_id = $1 AND NOT consumed", userID)
 	return err
 }
 
 func (r *AuthRepositoryImpl) SaveRefreshToken(token *model.RefreshToken) error {
-	return r.DB.QueryRow(
+	return r.mapError(r.q.QueryRow(
 		"INSERT INTO refresh_tokens (user_id, token, expires_at) VALUES ($1, $2, $3) RETURNING id",
-		token.UserID, token.Token, token.ExpiresAt,
-	).Scan(&token.ID)
+		token.UserID, token.Token, token.ExpiresAt.UTC(),
+	).Scan(&token.ID))
 }
 
-func (r *AuthRepositoryImpl) GetRefreshToken(tokenString string) (*model.RefreshToken, error) {
+func (r *AuthRepositoryImpl) ConsumeRefreshToken(tokenString string) (*model.RefreshToken, error) {
 	rt := &model.RefreshToken{}
-	err := r.DB.QueryRow(
-		"SELECT id, user_id, token, expires_at FROM refresh_tokens WHERE token = $1",
+	err := r.q.QueryRow(
+		"UPDATE refresh_tokens SET consumed = TRUE WHERE token = $1 AND NOT consumed RETURNING id, user_id, token, expires_at",
 		tokenString,
 	).Scan(&rt.ID, &rt.UserID, &rt.Token, &rt.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = r.q.QueryRow(
+			"SELECT id, user_id, token, expires_at FROM refresh_tokens WHERE token = $1",
+			tokenString,
+		).Scan(&rt.ID, &rt.UserID, &rt.Token, &rt.ExpiresAt)
+		if err == nil {
+			rt.Consumed = true
+			return rt, fmt.Errorf("%w: refresh token already used", repository.ErrConflict)
+		}
+	}
 	if err != nil {
-		return nil, err
+		return nil, r.mapError(err)
 	}
+	rt.Consumed = true
 	return rt, nil
 }
 
-func (r *AuthRepositoryImpl) DeleteRefreshToken(tokenString string) error {
-	_, err := r.DB.Exec("DELETE FROM refresh_tokens WHERE token = $1", tokenString)
-	return err
+func (r *AuthRepositoryImpl) DeleteExpiredRefreshTokens(before time.Time, limit int) (int, error) {
+	return r.deleteBatch(
+		"DELETE FROM refresh_tokens WHERE id IN (SELECT id FROM refresh_tokens WHERE expires_at < $1 ORDER BY id LIMIT $2)",
+		before, limit,
+	)
+}
+
+func (r *AuthRepositoryImpl) SavePasswordResetToken(token *model.PasswordResetToken) error {
+	return r.mapError(r.q.QueryRow(
+		"INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3) RETURNING id",
+		token.UserID, token.TokenHash, token.ExpiresAt.UTC(),
+	).Scan(&token.ID))
+}
+
+func (r *AuthRepositoryImpl) ConsumePasswordResetToken(tokenHash string) (*model.PasswordResetToken, error) {
+	t := &model.PasswordResetToken{}
+	err := r.q.QueryRow(
+		"DELETE FROM password_reset_tokens WHERE token_hash = $1 RETURNING id, user_id, token_hash, expires_at",
+		tokenHash,
+	).Scan(&t.ID, &t.UserID, &t.TokenHash, &t.ExpiresAt)
+	if err != nil {
+		return nil, r.mapError(err)
+	}
+	return t, nil
+}
+
+func (r *AuthRepositoryImpl) DeletePasswordResetTokensByUserID(userID int) error {
+	_, err := r.q.Exec("DELETE FROM password_reset_tokens WHERE user_id = $1", userID)
+	return r.mapError(err)
+}
+
+func (r *AuthRepositoryImpl) DeleteExpiredPasswordResetTokens(before time.Time, limit int) (int, error) {
+	return r.deleteBatch(
+		"DELETE FROM password_reset_tokens WHERE id IN (SELECT id FROM password_reset_tokens WHERE expires_at < $1 ORDER BY id LIMIT $2)",
+		before, limit,
+	)
+}
+
+func (r *AuthRepositoryImpl) SaveEmailVerificationToken(token *model.EmailVerificationToken) error {
+	return r.mapError(r.q.QueryRow(
+		"INSERT INTO email_verification_tokens (user_id, token_hash, email, expires_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		token.UserID, token.TokenHash, token.Email, token.ExpiresAt.UTC(),
+	).Scan(&token.ID))
+}
+
+func (r *AuthRepositoryImpl) ConsumeEmailVerificationToken(tokenHash string) (*model.EmailVerificationToken, error) {
+	t := &model.EmailVerificationToken{}
+	err := r.q.QueryRow(
+		"DELETE FROM email_verification_tokens WHERE token_hash = $1 RETURNING id, user_id, token_hash, email, expires_at",
+		tokenHash,
+	).Scan(&t.ID, &t.UserID, &t.TokenHash, &t.Email, &t.ExpiresAt)
+	if err != nil {
+		return nil, r.mapError(err)
+	}
+	return t, nil
+}
+
+func (r *AuthRepositoryImpl) DeleteEmailVerificationTokensByUserID(userID int) error {
+	_, err := r.q.Exec("DELETE FROM email_verification_tokens WHERE user_id = $1", userID)
+	return r.mapError(err)
+}
+
+func (r *AuthRepositoryImpl) DeleteExpiredEmailVerificationTokens(before time.Time, limit int) (int, error) {
+	return r.deleteBatch(
+		"DELETE FROM email_verification_tokens WHERE id IN (SELECT id FROM email_verification_tokens WHERE expires_at < $1 ORDER BY id LIMIT $2)",
+		before, limit,
+	)
+}
+
+func (r *AuthRepositoryImpl) SaveMagicLinkToken(token *model.MagicLinkToken) error {
+	return r.mapError(r.q.QueryRow(
+		"INSERT INTO magic_link_tokens (user_id, token_hash, nonce_hash, email, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		token.UserID, token.TokenHash, token.NonceHash, token.Email, token.ExpiresAt.UTC(),
+	).Scan(&token.ID))
+}
+
+func (r *AuthRepositoryImpl) ConsumeMagicLinkToken(tokenHash string) (*model.MagicLinkToken, error) {
+	t := &model.MagicLinkToken{}
+	err := r.q.QueryRow(
+		"DELETE FROM magic_link_tokens WHERE token_hash = $1 RETURNING id, user_id, token_hash, nonce_hash, email, expires_at",
+		tokenHash,
+	).Scan(&t.ID, &t.UserID, &t.TokenHash, &t.NonceHash, &t.Email, &t.ExpiresAt)
+	if err != nil {
+		return nil, r.mapError(err)
+	}
+	return t, nil
+}
+
+func (r *AuthRepositoryImpl) DeleteMagicLinkTokensByUserID(userID int) error {
+	_, err := r.q.Exec("DELETE FROM magic_link_tokens WHERE user_id = $1", userID)
+	return r.mapError(err)
+}
+
+func (r *AuthRepositoryImpl) DeleteExpiredMagicLinkTokens(before time.Time, limit int) (int, error) {
+	return r.deleteBatch(
+		"DELETE FROM magic_link_tokens WHERE id IN (SELECT id FROM magic_link_tokens WHERE expires_at < $1 ORDER BY id LIMIT $2)",
+		before, limit,
+	)
+}
+
+func (r *AuthRepositoryImpl) SaveTOTP(totp *model.TOTP) error {
+	_, err := r.q.Exec(
+		`INSERT INTO user_totp (user_id, secret, confirmed, last_used_step) VALUES ($1, $2, FALSE, 0)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, confirmed = FALSE, last_used_step = 0`,
+		totp.UserID, totp.Secret,
+	)
+	return r.mapError(err)
+}
+
+func (r *AuthRepositoryImpl) GetTOTP(userID int) (*model.TOTP, error) {
+	totp := &model.TOTP{}
+	err := r.q.QueryRow(
+		"SELECT user_id, secret, confirmed, last_used_step FROM user_totp WHERE user_id = $1",
+		userID,
+	).Scan(&totp.UserID, &totp.Secret, &totp.Confirmed, &totp.LastUsedStep)
+	if err != nil {
+		return nil, r.mapError(err)
+	}
+	return totp, nil
+}
+
+func (r *AuthRepositoryImpl) ConfirmTOTP(userID int) error {
+	res, err := r.q.Exec("UPDATE user_totp SET confirmed = TRUE WHERE user_id = $1", userID)
+	if err != nil {
+		return r.mapError(err)
+	}
+	return requireAffected(res)
+}
+
+func (r *AuthRepositoryImpl) UseTOTPStep(userID int, step int64) error {
+	res, err := r.q.Exec("UPDATE user_totp SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1", step, userID)
+	if err != nil {
+		return r.mapError(err)
+	}
+	if err := requireAffected(res); err != nil {
+		if _, err := r.GetTOTP(userID); err != nil {
+			return err
+		}
+		return repository.ErrConflict
+	}
+	return nil
+}
+
+func (r *AuthRepositoryImpl) DeleteTOTP(userID int) error {
+	_, err := r.q.Exec("DELETE FROM user_totp WHERE user_id = $1", userID)
+	return r.mapError(err)
+}
+
+func (r *AuthRepositoryImpl) SaveRecoveryCodes(userID int, codeHashes []string) error {
+	for _, hash := range codeHashes {
+		if _, err := r.q.Exec("INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, hash); err != nil {
+			return r.mapError(err)
+		}
+	}
+	return nil
+}
+
+func (r *AuthRepositoryImpl) UseRecoveryCode(userID int, codeHash string) error {
+	res, err := r.q.Exec("DELETE FROM recovery_codes WHERE user_id = $1 AND code_hash = $2", userID, codeHash)
+	if err != nil {
+		return r.mapError(err)
+	}
+	return requireAffected(res)
+}
+
+func (r *AuthRepositoryImpl) CountRecoveryCodes(userID int) (int, error) {
+	var n int
+	if err := r.q.QueryRow("SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1", userID).Scan(&n); err != nil {
+		return 0, r.mapError(err)
+	}
+	return n, nil
+}
+
+func (r *AuthRepositoryImpl) DeleteRecoveryCodesByUserID(userID int) error {
+	_, err := r.q.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID)
+	return r.mapError(err)
+}
+
+func (r *AuthRepositoryImpl) SaveWebAuthnCredential(c *model.WebAuthnCredential) error {
+	return r.mapError(r.q.QueryRow(
+		`INSERT INTO webauthn_credentials (user_id, name, credential_id, public_key, attestation_type, transports, aaguid,
+		sign_count, backup_eligible, backup_state, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`,
+		c.UserID, c.Name, c.CredentialID, c.PublicKey, c.AttestationType, strings.Join(c.Transports, ","), c.AAGUID,
+		c.SignCount, c.BackupEligible, c.BackupState, c.CreatedAt.UTC(),
+	).Scan(&c.ID))
+}
+
+func (r *AuthRepositoryImpl) ListWebAuthnCredentials(userID int) ([]model.WebAuthnCredential, error) {
+	rows, err := r.q.Query(
+		`SELECT id, user_id, name, credential_id, public_key, attestation_type, transports, aaguid,
+		sign_count, backup_eligible, backup_state, created_at FROM webauthn_credentials WHERE user_id = $1 ORDER BY id`,
+		userID,
+	)
+	if err != nil {
+		return nil, r.mapError(err)
+	}
+	defer rows.Close()
+
+	var credentials []model.WebAuthnCredential
+	for rows.Next() {
+		var c model.WebAuthnCredential
+		var transports string
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.CredentialID, &c.PublicKey, &c.AttestationType, &transports, &c.AAGUID,
+			&c.SignCount, &c.BackupEligible, &c.BackupState, &c.CreatedAt); err != nil {
+			return nil, r.mapError(err)
+		}
+		if transports != "" {
+			c.Transports = strings.Split(transports, ",")
+		}
+		credentials = append(credentials, c)
+	}
+	return credentials, r.mapError(rows.Err())
+}
+
+func (r *AuthRepositoryImpl) UseWebAuthnCredential(credentialID []byte, signCount uint32, backupState bool) error {
+	res, err := r.q.Exec(
+		`UPDATE webauthn_credentials SET sign_count = $1, backup_state = $2
+		WHERE credential_id = $3 AND (sign_count < $1 OR (sign_count = 0 AND $1 = 0))`,
+		signCount, backupState, credentialID,
+	)
+	if err != nil {
+		return r.mapError(err)
+	}
+	if err := requireAffected(res); err != nil {
+		var exists bool
+		if err := r.q.QueryRow("SELECT TRUE FROM webauthn_credentials WHERE credential_id = $1", credentialID).Scan(&exists); err != nil {
+			return r.mapError(err)
+		}
+		return repository.ErrConflict
+	}
+	return nil
+}
+
+func (r *AuthRepositoryImpl) DeleteWebAuthnCredential(userID, id int) error {
+	res, err := r.q.Exec("DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return r.mapError(err)
+	}
+	return requireAffected(res)
+}
+
+func (r *AuthRepositoryImpl) SaveWebAuthnSession(session *model.WebAuthnSession) error {
+	return r.mapError(r.q.QueryRow(
+		"INSERT INTO webauthn_sessions (token_hash, data, expires_at) VALUES ($1, $2, $3) RETURNING id",
+		session.TokenHash, string(session.Data), session.ExpiresAt.UTC(),
+	).Scan(&session.ID))
+}
+
+func (r *AuthRepositoryImpl) ConsumeWebAuthnSession(tokenHash string) (*model.WebAuthnSession, error) {
+	s := &model.WebAuthnSession{}
+	var data string
+	err := r.q.QueryRow(
+		"DELETE FROM webauthn_sessions WHERE token_hash = $1 RETURNING id, token_hash, data, expires_at",
+		tokenHash,
+	).Scan(&s.ID, &s.TokenHash, &data, &s.ExpiresAt)
+	if err != nil {
+		return nil, r.mapError(err)
+	}
+	s.Data = []byte(data)
+	return s, nil
+}
+
+func (r *AuthRepositoryImpl) DeleteExpiredWebAuthnSessions(before time.Time, limit int) (int, error) {
+	return r.deleteBatch(
+		"DELETE FROM webauthn_sessions WHERE id IN (SELECT id FROM webauthn_sessions WHERE expires_at < $1 ORDER BY id LIMIT $2)",
+		before, limit,
+	)
+}
+
+func (r *AuthRepositoryImpl) GetLoginFailure(subject string) (*model.LoginFailure, error) {
+	f := &model.LoginFailure{}
+	err := r.q.QueryRow(
+		"SELECT subject, failures, locked_until, expires_at FROM login_failures WHERE subject = $1",
+		subject,
+	).Scan(&f.Subject, &f.Failures, &f.LockedUntil, &f.ExpiresAt)
+	if err != nil {
+		return nil, r.mapError(err)
+	}
+	return f, nil
+}
+
+func (r *AuthRepositoryImpl) RecordLoginAttempt(subject string, now, expiresAt time.Time) (*model.LoginFailure, error) {
+	f := &model.LoginFailure{}
+	err := r.q.QueryRow(
+		`INSERT INTO login_failures (subject, failures, locked_until, expires_at) VALUES ($1, 1, $2, $3)
+		ON CONFLICT (subject) DO UPDATE SET
+			failures = CASE
+				WHEN login_failures.locked_until > $2 AND login_failures.expires_at >= $2 THEN login_failures.failures
+				WHEN login_failures.expires_at < $2 THEN 1
+				ELSE login_failures.failures + 1 END,
+			expires_at = CASE
+				WHEN login_failures.locked_until > $2 AND login_failures.expires_at >= $2 THEN login_failures.expires_at
+				WHEN login_failures.locked_until > $3 THEN login_failures.locked_until
+				ELSE $3 END
+		RETURNING subject, failures, locked_until, expires_at`,
+		subject, now.UTC(), expiresAt.UTC(),
+	).Scan(&f.Subject, &f.Failures, &f.LockedUntil, &f.ExpiresAt)
+	if err != nil {
+		return nil, r.mapError(err)
+	}
+	return f, nil
+}
+
+func (r *AuthRepositoryImpl) ReleaseLoginAttempt(subject string) error {
+	_, err := r.q.Exec("UPDATE login_failures SET failures = failures - 1 WHERE subject = $1 AND failures > 0", subject)
+	return r.mapError(err)
+}
+
+func (r *AuthRepositoryImpl) LockLogin(subject string, until, expiresAt time.Time) error {
+	res, err := r.q.Exec(
+		"UPDATE login_failures SET locked_until = $1, expires_at = $2 WHERE subject = $3",
+		until.UTC(), expiresAt.UTC(), subject,
+	)
+	if err != nil {
+		return r.mapError(err)
+	}
+	return requireAffected(res)
+}
+
+func (r *AuthRepositoryImpl) DeleteLoginFailure(subject string) error {
+	_, err := r.q.Exec("DELETE FROM login_failures WHERE subject = $1", subject)
+	return r.mapError(err)
+}
+
+func (r *AuthRepositoryImpl) DeleteExpiredLoginFailures(before time.Time, limit int) (int, error) {
+	return r.deleteBatch(
+		"DELETE FROM login_failures WHERE subject IN (SELECT subject FROM login_failures WHERE expires_at < $1 ORDER BY subject LIMIT $2)",
+		before, limit,
+	)
+}
+
+func (r *AuthRepositoryImpl) SaveDenylistEntry(entry *model.DenylistEntry) error {
+	_, err := r.q.Exec(
+		`INSERT INTO access_token_denylist (user_id, revoked_before, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET revoked_before = EXCLUDED.revoked_before, expires_at = EXCLUDED.expires_at`,
+		entry.UserID, entry.RevokedBefore.UTC(), entry.ExpiresAt.UTC(),
+	)
+	return r.mapError(err)
+}
+
+func (r *AuthRepositoryImpl) GetDenylistEntry(userID int) (*model.DenylistEntry, error) {
+	entry := &model.DenylistEntry{}
+	err := r.q.QueryRow(
+		"SELECT user_id, revoked_before, expires_at FROM access_token_denylist WHERE user_id = $1",
+		userID,
+	).Scan(&entry.UserID, &entry.RevokedBefore, &entry.ExpiresAt)
+	if err != nil {
+		return nil, r.mapError(err)
+	}
+	return entry, nil
+}
+
+func (r *AuthRepositoryImpl) DeleteExpiredDenylistEntries(before time.Time, limit int) (int, error) {
+	return r.deleteBatch(
+		"DELETE FROM access_token_denylist WHERE user_id IN (SELECT user_id FROM access_token_denylist WHERE expires_at < $1 ORDER BY user_id LIMIT $2)",
+		before, limit,
+	)
+}
+
+func (r *AuthRepositoryImpl) deleteBatch(query string, before time.Time, limit int) (int, error) {
+	res, err := r.q.Exec(query, before.UTC(), limit)
+	if err != nil {
+		return 0, r.mapError(err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// requireAffected возвращает repository.ErrNotFound, если запрос не изменил ни одной строки
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// mapError переводит ошибки драйвера в ошибки пакета repository
+func (r *AuthRepositoryImpl) mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+	return r.dialect.MapError(err)
 }
@@ -0,0 +1,56 @@
+package impl
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/migrations"
+	"sstu-go-forum-auth-service/internal/repository"
+	"sstu-go-forum-auth-service/internal/repository/repotest"
+)
+
+// Тесты запускаются против настоящего Postgres, только если задан TEST_DATABASE_URL
+func TestAuthRepositoryConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.New(db, migrations.Postgres)
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+
+	tables := migratedTables(t, db)
+	repotest.Run(t, func(t *testing.T) repository.AuthRepository {
+		_, err := db.Exec("TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE")
+		require.NoError(t, err)
+		return NewRepository(db)
+	})
+}
+
+// migratedTables — все таблицы схемы, кроме версии миграций: тесты не должны зависеть от состояния,
+// оставленного предыдущими, в том числе в таблицах, добавленных новыми миграциями
+func migratedTables(t *testing.T, db *sql.DB) []string {
+	rows, err := db.Query(
+		"SELECT quote_ident(tablename) FROM pg_tables WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'",
+	)
+	require.NoError(t, err)
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var table string
+		require.NoError(t, rows.Scan(&table))
+		tables = append(tables, table)
+	}
+	require.NoError(t, rows.Err())
+	require.NotEmpty(t, tables)
+	return tables
+}
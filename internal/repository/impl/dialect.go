@@ -0,0 +1,37 @@
+package impl
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"sstu-go-forum-auth-service/internal/repository"
+)
+
+// Dialect описывает особенности SQL-хранилища, на котором работает AuthRepositoryImpl.
+// Запросы пишутся в подмножестве SQL, общем для Postgres и SQLite
+type Dialect interface {
+	// MapError переводит ошибки драйвера в repository.ErrConflict и repository.ErrNotFound
+	MapError(err error) error
+}
+
+// Коды ошибок Postgres, которые переводятся в ошибки пакета repository
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+type PostgresDialect struct{}
+
+func (PostgresDialect) MapError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case uniqueViolation:
+			return fmt.Errorf("%w: %s", repository.ErrConflict, pqErr.Constraint)
+		case foreignKeyViolation:
+			return fmt.Errorf("%w: %s", repository.ErrNotFound, pqErr.Constraint)
+		}
+	}
+	return err
+}
@@ -0,0 +1,770 @@
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/repository"
+)
+
+// AuthRepository — потокобезопасная реализация repository.AuthRepository в памяти процесса
+// с той же семантикой, что и SQL-реализация: уникальность, ErrNotFound, ErrConflict, откат транзакций
+type AuthRepository struct {
+	mu   *sync.Mutex
+	st   *state
+	inTx bool
+}
+
+type state struct {
+	users       map[int]model.User
+	userIDs     map[string]int
+	emails      map[string]int
+	tokens      map[string]model.RefreshToken
+	denylist    map[int]model.DenylistEntry
+	resets      map[string]model.PasswordResetToken
+	verifies    map[string]model.EmailVerificationToken
+	magicLinks  map[string]model.MagicLinkToken
+	failures    map[string]model.LoginFailure
+	totp        map[int]model.TOTP
+	recovery    map[int][]string
+	credentials map[int]model.WebAuthnCredential
+	ceremonies  map[string]model.WebAuthnSession
+	lastUserID  int
+	lastTokenID int
+	lastResetID int
+	lastVerifID int
+	lastMagicID int
+	lastCredID  int
+	lastCeremID int
+}
+
+func NewRepository() *AuthRepository {
+	return &AuthRepository{
+		mu: &sync.Mutex{},
+		st: &state{
+			users:       map[int]model.User{},
+			userIDs:     map[string]int{},
+			emails:      map[string]int{},
+			tokens:      map[string]model.RefreshToken{},
+			denylist:    map[int]model.DenylistEntry{},
+			resets:      map[string]model.PasswordResetToken{},
+			verifies:    map[string]model.EmailVerificationToken{},
+			magicLinks:  map[string]model.MagicLinkToken{},
+			failures:    map[string]model.LoginFailure{},
+			totp:        map[int]model.TOTP{},
+			recovery:    map[int][]string{},
+			credentials: map[int]model.WebAuthnCredential{},
+			ceremonies:  map[string]model.WebAuthnSession{},
+		},
+	}
+}
+
+func (s *state) clone() state {
+	c := *s
+	c.users = make(map[int]model.User, len(s.users))
+	for k, v := range s.users {
+		c.users[k] = v
+	}
+	c.userIDs = make(map[string]int, len(s.userIDs))
+	for k, v := range s.userIDs {
+		c.userIDs[k] = v
+	}
+	c.emails = make(map[string]int, len(s.emails))
+	for k, v := range s.emails {
+		c.emails[k] = v
+	}
+	c.tokens = make(map[string]model.RefreshToken, len(s.tokens))
+	for k, v := range s.tokens {
+		c.tokens[k] = v
+	}
+	c.denylist = make(map[int]model.DenylistEntry, len(s.denylist))
+	for k, v := range s.denylist {
+		c.denylist[k] = v
+	}
+	c.resets = make(map[string]model.PasswordResetToken, len(s.resets))
+	for k, v := range s.resets {
+		c.resets[k] = v
+	}
+	c.verifies = make(map[string]model.EmailVerificationToken, len(s.verifies))
+	for k, v := range s.verifies {
+		c.verifies[k] = v
+	}
+	c.magicLinks = make(map[string]model.MagicLinkToken, len(s.magicLinks))
+	for k, v := range s.magicLinks {
+		c.magicLinks[k] = v
+	}
+	c.failures = make(map[string]model.LoginFailure, len(s.failures))
+	for k, v := range s.failures {
+		c.failures[k] = v
+	}
+	c.totp = make(map[int]model.TOTP, len(s.totp))
+	for k, v := range s.totp {
+		c.totp[k] = v
+	}
+	c.recovery = make(map[int][]string, len(s.recovery))
+	for k, v := range s.recovery {
+		c.recovery[k] = append([]string(nil), v...)
+	}
+	c.credentials = make(map[int]model.WebAuthnCredential, len(s.credentials))
+	for k, v := range s.credentials {
+		c.credentials[k] = v
+	}
+	c.ceremonies = make(map[string]model.WebAuthnSession, len(s.ceremonies))
+	for k, v := range s.ceremonies {
+		c.ceremonies[k] = v
+	}
+	return c
+}
+
+// lock захватывает мьютекс, если вызов не находится внутри WithTx, который уже его держит
+func (r *AuthRepository) lock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.mu.Lock()
+	return r.mu.Unlock
+}
+
+func (r *AuthRepository) WithTx(fn func(repo repository.AuthRepository) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := r.st.clone()
+	committed := false
+	defer func() {
+		if !committed {
+			*r.st = snapshot
+		}
+	}()
+
+	if err := fn(&AuthRepository{mu: r.mu, st: r.st, inTx: true}); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+func (r *AuthRepository) CreateUser(user *model.User) error {
+	defer r.lock()()
+	if _, ok := r.st.userIDs[model.UsernameKey(user.Username)]; ok {
+		return fmt.Errorf("%w: username %q", repository.ErrConflict, user.Username)
+	}
+	if _, ok := r.st.emails[emailKey(user.Email)]; ok && user.Email != "" {
+		return fmt.Errorf("%w: email", repository.ErrConflict)
+	}
+	r.st.lastUserID++
+	user.ID = r.st.lastUserID
+	r.st.users[user.ID] = *user
+	r.st.userIDs[model.UsernameKey(user.Username)] = user.ID
+	if user.Email != "" {
+		r.st.emails[emailKey(user.Email)] = user.ID
+	}
+	return nil
+}
+
+// emailKey повторяет уникальный индекс по LOWER(email) в SQL-хранилищах
+func emailKey(email string) string {
+	return strings.ToLower(email)
+}
+
+func (r *AuthRepository) GetUserByID(id int) (*model.User, error) {
+	defer r.lock()()
+	user, ok := r.st.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *AuthRepository) GetUserByUsername(username string) (*model.User, error) {
+	defer r.lock()()
+	id, ok := r.st.userIDs[model.UsernameKey(username)]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	user := r.st.users[id]
+	return &user, nil
+}
+
+func (r *AuthRepository) GetLookalikeUser(username string) (*model.User, error) {
+	defer r.lock()()
+	skeleton := model.UsernameSkeleton(username)
+	var found *model.User
+	for _, user := range r.st.users {
+		if model.UsernameSkeleton(user.Username) == skeleton && (found == nil || user.ID < found.ID) {
+			found = &user
+		}
+	}
+	if found == nil {
+		return nil, repository.ErrNotFound
+	}
+	return found, nil
+}
+
+func (r *AuthRepository) UpdatePassword(userID int, passwordHash string) error {
+	defer r.lock()()
+	user, ok := r.st.users[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	user.Password = passwordHash
+	r.st.users[userID] = user
+	return nil
+}
+
+func (r *AuthRepository) RehashPassword(userID int, oldHash, newHash string) error {
+	defer r.lock()()
+	user, ok := r.st.users[userID]
+	if !ok || user.Password != oldHash {
+		return repository.ErrNotFound
+	}
+	user.Password = newHash
+	r.st.users[userID] = user
+	return nil
+}
+
+func (r *AuthRepository) UpdateEmail(userID int, email string) error {
+	defer r.lock()()
+	user, ok := r.st.users[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if id, ok := r.st.emails[emailKey(email)]; ok && email != "" && id != userID {
+		return fmt.Errorf("%w: email", repository.ErrConflict)
+	}
+	delete(r.st.emails, emailKey(user.Email))
+	if email != "" {
+		r.st.emails[emailKey(email)] = userID
+	}
+	user.Email = email
+	user.EmailVerified = false
+	r.st.users[userID] = user
+	return nil
+}
+
+func (r *AuthRepository) UpdateRole(userID int, role string) error {
+	defer r.lock()()
+	user, ok := r.st.users[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	user.Role = role
+	r.st.users[userID] = user
+	return nil
+}
+
+func (r *AuthRepository) UpdateLocale(userID int, locale string) error {
+	defer r.lock()()
+	user, ok := r.st.users[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	user.Locale = locale
+	r.st.users[userID] = user
+	return nil
+}
+
+func (r *AuthRepository) MarkEmailVerified(userID int, email string) error {
+	defer r.lock()()
+	user, ok := r.st.users[userID]
+	if !ok || user.Email == "" || emailKey(user.Email) != emailKey(email) {
+		return repository.ErrNotFound
+	}
+	user.EmailVerified = true
+	r.st.users[userID] = user
+	return nil
+}
+
+func (r *AuthRepository) DeleteActiveRefreshTokensByUserID(userID int) error {
+	defer r.lock()()
+	for k, rt := range r.st.tokens {
+		if rt.UserID == userID && !rt.Consumed {
+			delete(r.st.tokens, k)
+		}
+	}
+	return nil
+}
+
+func (r *AuthRepository) SaveRefreshToken(token *model.RefreshToken) error {
+	defer r.lock()()
+	if _, ok := r.st.users[token.UserID]; !ok {
+		return fmt.Errorf("%w: user %d", repository.ErrNotFound, token.UserID)
+	}
+	if _, ok := r.st.tokens[token.Token]; ok {
+		return fmt.Errorf("%w: refresh token", repository.ErrConflict)
+	}
+	r.st.lastTokenID++
+	token.ID = r.st.lastTokenID
+	r.st.tokens[token.Token] = *token
+	return nil
+}
+
+func (r *AuthRepository) ConsumeRefreshToken(tokenString string) (*model.RefreshToken, error) {
+	defer r.lock()()
+	rt, ok := r.st.tokens[tokenString]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	if rt.Consumed {
+		return &rt, fmt.Errorf("%w: refresh token already used", repository.ErrConflict)
+	}
+	rt.Consumed = true
+	r.st.tokens[tokenString] = rt
+	return &rt, nil
+}
+
+func (r *AuthRepository) DeleteExpiredRefreshTokens(before time.Time, limit int) (int, error) {
+	defer r.lock()()
+	deleted := 0
+	for k, rt := range r.st.tokens {
+		if deleted >= limit {
+			break
+		}
+		if rt.ExpiresAt.Before(before) {
+			delete(r.st.tokens, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *AuthRepository) SavePasswordResetToken(token *model.PasswordResetToken) error {
+	defer r.lock()()
+	if _, ok := r.st.users[token.UserID]; !ok {
+		return fmt.Errorf("%w: user %d", repository.ErrNotFound, token.UserID)
+	}
+	if _, ok := r.st.resets[token.TokenHash]; ok {
+		return fmt.Errorf("%w: password reset token", repository.ErrConflict)
+	}
+	r.st.lastResetID++
+	token.ID = r.st.lastResetID
+	r.st.resets[token.TokenHash] = *token
+	return nil
+}
+
+func (r *AuthRepository) ConsumePasswordResetToken(tokenHash string) (*model.PasswordResetToken, error) {
+	defer r.lock()()
+	t, ok := r.st.resets[tokenHash]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	delete(r.st.resets, tokenHash)
+	return &t, nil
+}
+
+func (r *AuthRepository) DeletePasswordResetTokensByUserID(userID int) error {
+	defer r.lock()()
+	for k, t := range r.st.resets {
+		if t.UserID == userID {
+			delete(r.st.resets, k)
+		}
+	}
+	return nil
+}
+
+func (r *AuthRepository) DeleteExpiredPasswordResetTokens(before time.Time, limit int) (int, error) {
+	defer r.lock()()
+	deleted := 0
+	for k, t := range r.st.resets {
+		if deleted >= limit {
+			break
+		}
+		if t.ExpiresAt.Before(before) {
+			delete(r.st.resets, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *AuthRepository) SaveEmailVerificationToken(token *model.EmailVerificationToken) error {
+	defer r.lock()()
+	if _, ok := r.st.users[token.UserID]; !ok {
+		return fmt.Errorf("%w: user %d", repository.ErrNotFound, token.UserID)
+	}
+	if _, ok := r.st.verifies[token.TokenHash]; ok {
+		return fmt.Errorf("%w: email verification token", repository.ErrConflict)
+	}
+	r.st.lastVerifID++
+	token.ID = r.st.lastVerifID
+	r.st.verifies[token.TokenHash] = *token
+	return nil
+}
+
+func (r *AuthRepository) ConsumeEmailVerificationToken(tokenHash string) (*model.EmailVerificationToken, error) {
+	defer r.lock()()
+	t, ok := r.st.verifies[tokenHash]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	delete(r.st.verifies, tokenHash)
+	return &t, nil
+}
+
+func (r *AuthRepository) DeleteEmailVerificationTokensByUserID(userID int) error {
+	defer r.lock()()
+	for k, t := range r.st.verifies {
+		if t.UserID == userID {
+			delete(r.st.verifies, k)
+		}
+	}
+	return nil
+}
+
+func (r *AuthRepository) DeleteExpiredEmailVerificationTokens(before time.Time, limit int) (int, error) {
+	defer r.lock()()
+	deleted := 0
+	for k, t := range r.st.verifies {
+		if deleted >= limit {
+			break
+		}
+		if t.ExpiresAt.Before(before) {
+			delete(r.st.verifies, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *AuthRepository) SaveMagicLinkToken(token *model.MagicLinkToken) error {
+	defer r.lock()()
+	if _, ok := r.st.users[token.UserID]; !ok {
+		return fmt.Errorf("%w: user %d", repository.ErrNotFound, token.UserID)
+	}
+	if _, ok := r.st.magicLinks[token.TokenHash]; ok {
+		return fmt.Errorf("%w: magic link token", repository.ErrConflict)
+	}
+	r.st.lastMagicID++
+	token.ID = r.st.lastMagicID
+	r.st.magicLinks[token.TokenHash] = *token
+	return nil
+}
+
+func (r *AuthRepository) ConsumeMagicLinkToken(tokenHash string) (*model.MagicLinkToken, error) {
+	defer r.lock()()
+	t, ok := r.st.magicLinks[tokenHash]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	delete(r.st.magicLinks, tokenHash)
+	return &t, nil
+}
+
+func (r *AuthRepository) DeleteMagicLinkTokensByUserID(userID int) error {
+	defer r.lock()()
+	for k, t := range r.st.magicLinks {
+		if t.UserID == userID {
+			delete(r.st.magicLinks, k)
+		}
+	}
+	return nil
+}
+
+func (r *AuthRepository) DeleteExpiredMagicLinkTokens(before time.Time, limit int) (int, error) {
+	defer r.lock()()
+	deleted := 0
+	for k, t := range r.st.magicLinks {
+		if deleted >= limit {
+			break
+		}
+		if t.ExpiresAt.Before(before) {
+			delete(r.st.magicLinks, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *AuthRepository) SaveTOTP(totp *model.TOTP) error {
+	defer r.lock()()
+	if _, ok := r.st.users[totp.UserID]; !ok {
+		return fmt.Errorf("%w: user %d", repository.ErrNotFound, totp.UserID)
+	}
+	r.st.totp[totp.UserID] = model.TOTP{UserID: totp.UserID, Secret: totp.Secret}
+	return nil
+}
+
+func (r *AuthRepository) GetTOTP(userID int) (*model.TOTP, error) {
+	defer r.lock()()
+	totp, ok := r.st.totp[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &totp, nil
+}
+
+func (r *AuthRepository) ConfirmTOTP(userID int) error {
+	defer r.lock()()
+	totp, ok := r.st.totp[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	totp.Confirmed = true
+	r.st.totp[userID] = totp
+	return nil
+}
+
+func (r *AuthRepository) UseTOTPStep(userID int, step int64) error {
+	defer r.lock()()
+	totp, ok := r.st.totp[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if totp.LastUsedStep >= step {
+		return repository.ErrConflict
+	}
+	totp.LastUsedStep = step
+	r.st.totp[userID] = totp
+	return nil
+}
+
+func (r *AuthRepository) DeleteTOTP(userID int) error {
+	defer r.lock()()
+	delete(r.st.totp, userID)
+	return nil
+}
+
+func (r *AuthRepository) SaveRecoveryCodes(userID int, codeHashes []string) error {
+	defer r.lock()()
+	if _, ok := r.st.users[userID]; !ok {
+		return fmt.Errorf("%w: user %d", repository.ErrNotFound, userID)
+	}
+	for _, hash := range codeHashes {
+		if slices.Contains(r.st.recovery[userID], hash) {
+			return repository.ErrConflict
+		}
+		r.st.recovery[userID] = append(r.st.recovery[userID], hash)
+	}
+	return nil
+}
+
+func (r *AuthRepository) UseRecoveryCode(userID int, codeHash string) error {
+	defer r.lock()()
+	codes := r.st.recovery[userID]
+	i := slices.Index(codes, codeHash)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	r.st.recovery[userID] = slices.Delete(codes, i, i+1)
+	return nil
+}
+
+func (r *AuthRepository) CountRecoveryCodes(userID int) (int, error) {
+	defer r.lock()()
+	return len(r.st.recovery[userID]), nil
+}
+
+func (r *AuthRepository) DeleteRecoveryCodesByUserID(userID int) error {
+	defer r.lock()()
+	delete(r.st.recovery, userID)
+	return nil
+}
+
+func (r *AuthRepository) SaveWebAuthnCredential(c *model.WebAuthnCredential) error {
+	defer r.lock()()
+	if _, ok := r.st.users[c.UserID]; !ok {
+		return fmt.Errorf("%w: user %d", repository.ErrNotFound, c.UserID)
+	}
+	if _, ok := r.findCredential(c.CredentialID); ok {
+		return fmt.Errorf("%w: webauthn credential", repository.ErrConflict)
+	}
+	r.st.lastCredID++
+	c.ID = r.st.lastCredID
+	r.st.credentials[c.ID] = *c
+	return nil
+}
+
+func (r *AuthRepository) ListWebAuthnCredentials(userID int) ([]model.WebAuthnCredential, error) {
+	defer r.lock()()
+	var credentials []model.WebAuthnCredential
+	for _, c := range r.st.credentials {
+		if c.UserID == userID {
+			credentials = append(credentials, c)
+		}
+	}
+	slices.SortFunc(credentials, func(a, b model.WebAuthnCredential) int { return a.ID - b.ID })
+	return credentials, nil
+}
+
+func (r *AuthRepository) UseWebAuthnCredential(credentialID []byte, signCount uint32, backupState bool) error {
+	defer r.lock()()
+	c, ok := r.findCredential(credentialID)
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if c.SignCount >= signCount && (c.SignCount != 0 || signCount != 0) {
+		return repository.ErrConflict
+	}
+	c.SignCount = signCount
+	c.BackupState = backupState
+	r.st.credentials[c.ID] = c
+	return nil
+}
+
+func (r *AuthRepository) DeleteWebAuthnCredential(userID, id int) error {
+	defer r.lock()()
+	c, ok := r.st.credentials[id]
+	if !ok || c.UserID != userID {
+		return repository.ErrNotFound
+	}
+	delete(r.st.credentials, id)
+	return nil
+}
+
+func (r *AuthRepository) findCredential(credentialID []byte) (model.WebAuthnCredential, bool) {
+	for _, c := range r.st.credentials {
+		if bytes.Equal(c.CredentialID, credentialID) {
+			return c, true
+		}
+	}
+	return model.WebAuthnCredential{}, false
+}
+
+func (r *AuthRepository) SaveWebAuthnSession(session *model.WebAuthnSession) error {
+	defer r.lock()()
+	if _, ok := r.st.ceremonies[session.TokenHash]; ok {
+		return fmt.Errorf("%w: webauthn session", repository.ErrConflict)
+	}
+	r.st.lastCeremID++
+	session.ID = r.st.lastCeremID
+	r.st.ceremonies[session.TokenHash] = *session
+	return nil
+}
+
+func (r *AuthRepository) ConsumeWebAuthnSession(tokenHash string) (*model.WebAuthnSession, error) {
+	defer r.lock()()
+	s, ok := r.st.ceremonies[tokenHash]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	delete(r.st.ceremonies, tokenHash)
+	return &s, nil
+}
+
+func (r *AuthRepository) DeleteExpiredWebAuthnSessions(before time.Time, limit int) (int, error) {
+	defer r.lock()()
+	deleted := 0
+	for k, s := range r.st.ceremonies {
+		if deleted >= limit {
+			break
+		}
+		if s.ExpiresAt.Before(before) {
+			delete(r.st.ceremonies, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *AuthRepository) GetLoginFailure(subject string) (*model.LoginFailure, error) {
+	defer r.lock()()
+	f, ok := r.st.failures[subject]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &f, nil
+}
+
+func (r *AuthRepository) RecordLoginAttempt(subject string, now, expiresAt time.Time) (*model.LoginFailure, error) {
+	defer r.lock()()
+	f, ok := r.st.failures[subject]
+	switch {
+	case !ok:
+		f = model.LoginFailure{Subject: subject, Failures: 1, LockedUntil: now}
+	case f.LockedUntil.After(now) && !f.ExpiresAt.Before(now):
+		return &f, nil
+	case f.ExpiresAt.Before(now):
+		f.Failures = 1
+	default:
+		f.Failures++
+	}
+	f.ExpiresAt = expiresAt
+	if f.LockedUntil.After(expiresAt) {
+		f.ExpiresAt = f.LockedUntil
+	}
+	r.st.failures[subject] = f
+	return &f, nil
+}
+
+func (r *AuthRepository) ReleaseLoginAttempt(subject string) error {
+	defer r.lock()()
+	if f, ok := r.st.failures[subject]; ok && f.Failures > 0 {
+		f.Failures--
+		r.st.failures[subject] = f
+	}
+	return nil
+}
+
+func (r *AuthRepository) LockLogin(subject string, until, expiresAt time.Time) error {
+	defer r.lock()()
+	f, ok := r.st.failures[subject]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	f.LockedUntil = until
+	f.ExpiresAt = expiresAt
+	r.st.failures[subject] = f
+	return nil
+}
+
+func (r *AuthRepository) DeleteLoginFailure(subject string) error {
+	defer r.lock()()
+	delete(r.st.failures, subject)
+	return nil
+}
+
+func (r *AuthRepository) DeleteExpiredLoginFailures(before time.Time, limit int) (int, error) {
+	defer r.lock()()
+	deleted := 0
+	for k, f := range r.st.failures {
+		if deleted >= limit {
+			break
+		}
+		if f.ExpiresAt.Before(before) {
+			delete(r.st.failures, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *AuthRepository) SaveDenylistEntry(entry *model.DenylistEntry) error {
+	defer r.lock()()
+	if _, ok := r.st.users[entry.UserID]; !ok {
+		return fmt.Errorf("%w: user %d", repository.ErrNotFound, entry.UserID)
+	}
+	r.st.denylist[entry.UserID] = *entry
+	return nil
+}
+
+func (r *AuthRepository) GetDenylistEntry(userID int) (*model.DenylistEntry, error) {
+	defer r.lock()()
+	entry, ok := r.st.denylist[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &entry, nil
+}
+
+func (r *AuthRepository) DeleteExpiredDenylistEntries(before time.Time, limit int) (int, error) {
+	defer r.lock()()
+	deleted := 0
+	for k, entry := range r.st.denylist {
+		if deleted >= limit {
+			break
+		}
+		if entry.ExpiresAt.Before(before) {
+			delete(r.st.denylist, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
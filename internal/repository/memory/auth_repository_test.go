@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"sstu-go-forum-auth-service/internal/repository"
+	"sstu-go-forum-auth-service/internal/repository/repotest"
+)
+
+func TestAuthRepositoryConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.AuthRepository {
+		return NewRepository()
+	})
+}
@@ -12,6 +12,8 @@ package mocks
 import (
 	reflect "reflect"
 	model "sstu-go-forum-auth-service/internal/model"
+	repository "sstu-go-forum-auth-service/internal/repository"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -40,6 +42,110 @@ func (m *MockAuthRepository) EXPECT() *MockAuthRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ConfirmTOTP mocks base method.
+func (m *MockAuthRepository) ConfirmTOTP(userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTOTP", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConfirmTOTP indicates an expected call of ConfirmTOTP.
+func (mr *MockAuthRepositoryMockRecorder) ConfirmTOTP(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTP", reflect.TypeOf((*MockAuthRepository)(nil).ConfirmTOTP), userID)
+}
+
+// ConsumeEmailVerificationToken mocks base method.
+func (m *MockAuthRepository) ConsumeEmailVerificationToken(tokenHash string) (*model.EmailVerificationToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeEmailVerificationToken", tokenHash)
+	ret0, _ := ret[0].(*model.EmailVerificationToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeEmailVerificationToken indicates an expected call of ConsumeEmailVerificationToken.
+func (mr *MockAuthRepositoryMockRecorder) ConsumeEmailVerificationToken(tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeEmailVerificationToken", reflect.TypeOf((*MockAuthRepository)(nil).ConsumeEmailVerificationToken), tokenHash)
+}
+
+// ConsumeMagicLinkToken mocks base method.
+func (m *MockAuthRepository) ConsumeMagicLinkToken(tokenHash string) (*model.MagicLinkToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeMagicLinkToken", tokenHash)
+	ret0, _ := ret[0].(*model.MagicLinkToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeMagicLinkToken indicates an expected call of ConsumeMagicLinkToken.
+func (mr *MockAuthRepositoryMockRecorder) ConsumeMagicLinkToken(tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeMagicLinkToken", reflect.TypeOf((*MockAuthRepository)(nil).ConsumeMagicLinkToken), tokenHash)
+}
+
+// ConsumePasswordResetToken mocks base method.
+func (m *MockAuthRepository) ConsumePasswordResetToken(tokenHash string) (*model.PasswordResetToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumePasswordResetToken", tokenHash)
+	ret0, _ := ret[0].(*model.PasswordResetToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumePasswordResetToken indicates an expected call of ConsumePasswordResetToken.
+func (mr *MockAuthRepositoryMockRecorder) ConsumePasswordResetToken(tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumePasswordResetToken", reflect.TypeOf((*MockAuthRepository)(nil).ConsumePasswordResetToken), tokenHash)
+}
+
+// ConsumeRefreshToken mocks base method.
+func (m *MockAuthRepository) ConsumeRefreshToken(tokenString string) (*model.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeRefreshToken", tokenString)
+	ret0, _ := ret[0].(*model.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeRefreshToken indicates an expected call of ConsumeRefreshToken.
+func (mr *MockAuthRepositoryMockRecorder) ConsumeRefreshToken(tokenString any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeRefreshToken", reflect.TypeOf((*MockAuthRepository)(nil).ConsumeRefreshToken), tokenString)
+}
+
+// ConsumeWebAuthnSession mocks base method.
+func (m *MockAuthRepository) ConsumeWebAuthnSession(tokenHash string) (*model.WebAuthnSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeWebAuthnSession", tokenHash)
+	ret0, _ := ret[0].(*model.WebAuthnSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeWebAuthnSession indicates an expected call of ConsumeWebAuthnSession.
+func (mr *MockAuthRepositoryMockRecorder) ConsumeWebAuthnSession(tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeWebAuthnSession", reflect.TypeOf((*MockAuthRepository)(nil).ConsumeWebAuthnSession), tokenHash)
+}
+
+// CountRecoveryCodes mocks base method.
+func (m *MockAuthRepository) CountRecoveryCodes(userID int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountRecoveryCodes", userID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountRecoveryCodes indicates an expected call of CountRecoveryCodes.
+func (mr *MockAuthRepositoryMockRecorder) CountRecoveryCodes(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountRecoveryCodes", reflect.TypeOf((*MockAuthRepository)(nil).CountRecoveryCodes), userID)
+}
+
 // CreateUser mocks base method.
 func (m *MockAuthRepository) CreateUser(user *model.User) error {
 	m.ctrl.T.Helper()
@@ -54,47 +160,296 @@ func (mr *MockAuthRepositoryMockRecorder) CreateUser(user any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockAuthRepository)(nil).CreateUser), user)
 }
 
-// DeleteRefreshToken mocks base method.
-func (m *MockAuthRepository) DeleteRefreshToken(tokenString string) error {
+// DeleteActiveRefreshTokensByUserID mocks base method.
+func (m *MockAuthRepository) DeleteActiveRefreshTokensByUserID(userID int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteRefreshToken", tokenString)
+	ret := m.ctrl.Call(m, "DeleteActiveRefreshTokensByUserID", userID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteRefreshToken indicates an expected call of DeleteRefreshToken.
-func (mr *MockAuthRepositoryMockRecorder) DeleteRefreshToken(tokenString any) *gomock.Call {
+// DeleteActiveRefreshTokensByUserID indicates an expected call of DeleteActiveRefreshTokensByUserID.
+func (mr *MockAuthRepositoryMockRecorder) DeleteActiveRefreshTokensByUserID(userID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRefreshToken", reflect.TypeOf((*MockAuthRepository)(nil).DeleteRefreshToken), tokenString)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteActiveRefreshTokensByUserID", reflect.TypeOf((*MockAuthRepository)(nil).DeleteActiveRefreshTokensByUserID), userID)
 }
 
-// DeleteRefreshTokensByUserID mocks base method.
-func (m *MockAuthRepository) DeleteRefreshTokensByUserID(userID int) error {
+// DeleteEmailVerificationTokensByUserID mocks base method.
+func (m *MockAuthRepository) DeleteEmailVerificationTokensByUserID(userID int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteRefreshTokensByUserID", userID)
+	ret := m.ctrl.Call(m, "DeleteEmailVerificationTokensByUserID", userID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteRefreshTokensByUserID indicates an expected call of DeleteRefreshTokensByUserID.
-func (mr *MockAuthRepositoryMockRecorder) DeleteRefreshTokensByUserID(userID any) *gomock.Call {
+// DeleteEmailVerificationTokensByUserID indicates an expected call of DeleteEmailVerificationTokensByUserID.
+func (mr *MockAuthRepositoryMockRecorder) DeleteEmailVerificationTokensByUserID(userID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRefreshTokensByUserID", reflect.TypeOf((*MockAuthRepository)(nil).DeleteRefreshTokensByUserID), userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteEmailVerificationTokensByUserID", reflect.TypeOf((*MockAuthRepository)(nil).DeleteEmailVerificationTokensByUserID), userID)
 }
 
-// GetRefreshToken mocks base method.
-func (m *MockAuthRepository) GetRefreshToken(tokenString string) (*model.RefreshToken, error) {
+// DeleteExpiredDenylistEntries mocks base method.
+func (m *MockAuthRepository) DeleteExpiredDenylistEntries(before time.Time, limit int) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetRefreshToken", tokenString)
-	ret0, _ := ret[0].(*model.RefreshToken)
+	ret := m.ctrl.Call(m, "DeleteExpiredDenylistEntries", before, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredDenylistEntries indicates an expected call of DeleteExpiredDenylistEntries.
+func (mr *MockAuthRepositoryMockRecorder) DeleteExpiredDenylistEntries(before, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredDenylistEntries", reflect.TypeOf((*MockAuthRepository)(nil).DeleteExpiredDenylistEntries), before, limit)
+}
+
+// DeleteExpiredEmailVerificationTokens mocks base method.
+func (m *MockAuthRepository) DeleteExpiredEmailVerificationTokens(before time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredEmailVerificationTokens", before, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredEmailVerificationTokens indicates an expected call of DeleteExpiredEmailVerificationTokens.
+func (mr *MockAuthRepositoryMockRecorder) DeleteExpiredEmailVerificationTokens(before, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredEmailVerificationTokens", reflect.TypeOf((*MockAuthRepository)(nil).DeleteExpiredEmailVerificationTokens), before, limit)
+}
+
+// DeleteExpiredLoginFailures mocks base method.
+func (m *MockAuthRepository) DeleteExpiredLoginFailures(before time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredLoginFailures", before, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredLoginFailures indicates an expected call of DeleteExpiredLoginFailures.
+func (mr *MockAuthRepositoryMockRecorder) DeleteExpiredLoginFailures(before, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredLoginFailures", reflect.TypeOf((*MockAuthRepository)(nil).DeleteExpiredLoginFailures), before, limit)
+}
+
+// DeleteExpiredMagicLinkTokens mocks base method.
+func (m *MockAuthRepository) DeleteExpiredMagicLinkTokens(before time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredMagicLinkTokens", before, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredMagicLinkTokens indicates an expected call of DeleteExpiredMagicLinkTokens.
+func (mr *MockAuthRepositoryMockRecorder) DeleteExpiredMagicLinkTokens(before, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredMagicLinkTokens", reflect.TypeOf((*MockAuthRepository)(nil).DeleteExpiredMagicLinkTokens), before, limit)
+}
+
+// DeleteExpiredPasswordResetTokens mocks base method.
+func (m *MockAuthRepository) DeleteExpiredPasswordResetTokens(before time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredPasswordResetTokens", before, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredPasswordResetTokens indicates an expected call of DeleteExpiredPasswordResetTokens.
+func (mr *MockAuthRepositoryMockRecorder) DeleteExpiredPasswordResetTokens(before, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredPasswordResetTokens", reflect.TypeOf((*MockAuthRepository)(nil).DeleteExpiredPasswordResetTokens), before, limit)
+}
+
+// DeleteExpiredRefreshTokens mocks base method.
+func (m *MockAuthRepository) DeleteExpiredRefreshTokens(before time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredRefreshTokens", before, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredRefreshTokens indicates an expected call of DeleteExpiredRefreshTokens.
+func (mr *MockAuthRepositoryMockRecorder) DeleteExpiredRefreshTokens(before, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredRefreshTokens", reflect.TypeOf((*MockAuthRepository)(nil).DeleteExpiredRefreshTokens), before, limit)
+}
+
+// DeleteExpiredWebAuthnSessions mocks base method.
+func (m *MockAuthRepository) DeleteExpiredWebAuthnSessions(before time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredWebAuthnSessions", before, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredWebAuthnSessions indicates an expected call of DeleteExpiredWebAuthnSessions.
+func (mr *MockAuthRepositoryMockRecorder) DeleteExpiredWebAuthnSessions(before, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredWebAuthnSessions", reflect.TypeOf((*MockAuthRepository)(nil).DeleteExpiredWebAuthnSessions), before, limit)
+}
+
+// DeleteLoginFailure mocks base method.
+func (m *MockAuthRepository) DeleteLoginFailure(subject string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLoginFailure", subject)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteLoginFailure indicates an expected call of DeleteLoginFailure.
+func (mr *MockAuthRepositoryMockRecorder) DeleteLoginFailure(subject any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLoginFailure", reflect.TypeOf((*MockAuthRepository)(nil).DeleteLoginFailure), subject)
+}
+
+// DeleteMagicLinkTokensByUserID mocks base method.
+func (m *MockAuthRepository) DeleteMagicLinkTokensByUserID(userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMagicLinkTokensByUserID", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteMagicLinkTokensByUserID indicates an expected call of DeleteMagicLinkTokensByUserID.
+func (mr *MockAuthRepositoryMockRecorder) DeleteMagicLinkTokensByUserID(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMagicLinkTokensByUserID", reflect.TypeOf((*MockAuthRepository)(nil).DeleteMagicLinkTokensByUserID), userID)
+}
+
+// DeletePasswordResetTokensByUserID mocks base method.
+func (m *MockAuthRepository) DeletePasswordResetTokensByUserID(userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePasswordResetTokensByUserID", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePasswordResetTokensByUserID indicates an expected call of DeletePasswordResetTokensByUserID.
+func (mr *MockAuthRepositoryMockRecorder) DeletePasswordResetTokensByUserID(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePasswordResetTokensByUserID", reflect.TypeOf((*MockAuthRepository)(nil).DeletePasswordResetTokensByUserID), userID)
+}
+
+// DeleteRecoveryCodesByUserID mocks base method.
+func (m *MockAuthRepository) DeleteRecoveryCodesByUserID(userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRecoveryCodesByUserID", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRecoveryCodesByUserID indicates an expected call of DeleteRecoveryCodesByUserID.
+func (mr *MockAuthRepositoryMockRecorder) DeleteRecoveryCodesByUserID(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRecoveryCodesByUserID", reflect.TypeOf((*MockAuthRepository)(nil).DeleteRecoveryCodesByUserID), userID)
+}
+
+// DeleteTOTP mocks base method.
+func (m *MockAuthRepository) DeleteTOTP(userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTOTP", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTOTP indicates an expected call of DeleteTOTP.
+func (mr *MockAuthRepositoryMockRecorder) DeleteTOTP(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTOTP", reflect.TypeOf((*MockAuthRepository)(nil).DeleteTOTP), userID)
+}
+
+// DeleteWebAuthnCredential mocks base method.
+func (m *MockAuthRepository) DeleteWebAuthnCredential(userID, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebAuthnCredential", userID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebAuthnCredential indicates an expected call of DeleteWebAuthnCredential.
+func (mr *MockAuthRepositoryMockRecorder) DeleteWebAuthnCredential(userID, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebAuthnCredential", reflect.TypeOf((*MockAuthRepository)(nil).DeleteWebAuthnCredential), userID, id)
+}
+
+// GetDenylistEntry mocks base method.
+func (m *MockAuthRepository) GetDenylistEntry(userID int) (*model.DenylistEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDenylistEntry", userID)
+	ret0, _ := ret[0].(*model.DenylistEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDenylistEntry indicates an expected call of GetDenylistEntry.
+func (mr *MockAuthRepositoryMockRecorder) GetDenylistEntry(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDenylistEntry", reflect.TypeOf((*MockAuthRepository)(nil).GetDenylistEntry), userID)
+}
+
+// GetLoginFailure mocks base method.
+func (m *MockAuthRepository) GetLoginFailure(subject string) (*model.LoginFailure, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoginFailure", subject)
+	ret0, _ := ret[0].(*model.LoginFailure)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoginFailure indicates an expected call of GetLoginFailure.
+func (mr *MockAuthRepositoryMockRecorder) GetLoginFailure(subject any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoginFailure", reflect.TypeOf((*MockAuthRepository)(nil).GetLoginFailure), subject)
+}
+
+// GetLookalikeUser mocks base method.
+func (m *MockAuthRepository) GetLookalikeUser(username string) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLookalikeUser", username)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLookalikeUser indicates an expected call of GetLookalikeUser.
+func (mr *MockAuthRepositoryMockRecorder) GetLookalikeUser(username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLookalikeUser", reflect.TypeOf((*MockAuthRepository)(nil).GetLookalikeUser), username)
+}
+
+// GetTOTP mocks base method.
+func (m *MockAuthRepository) GetTOTP(userID int) (*model.TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTOTP", userID)
+	ret0, _ := ret[0].(*model.TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTOTP indicates an expected call of GetTOTP.
+func (mr *MockAuthRepositoryMockRecorder) GetTOTP(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTOTP", reflect.TypeOf((*MockAuthRepository)(nil).GetTOTP), userID)
+}
+
+// GetUserByID mocks base method.
+func (m *MockAuthRepository) GetUserByID(id int) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", id)
+	ret0, _ := ret[0].(*model.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetRefreshToken indicates an expected call of GetRefreshToken.
-func (mr *MockAuthRepositoryMockRecorder) GetRefreshToken(tokenString any) *gomock.Call {
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockAuthRepositoryMockRecorder) GetUserByID(id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshToken", reflect.TypeOf((*MockAuthRepository)(nil).GetRefreshToken), tokenString)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockAuthRepository)(nil).GetUserByID), id)
 }
 
 // GetUserByUsername mocks base method.
@@ -112,6 +467,162 @@ func (mr *MockAuthRepositoryMockRecorder) GetUserByUsername(username any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUsername", reflect.TypeOf((*MockAuthRepository)(nil).GetUserByUsername), username)
 }
 
+// ListWebAuthnCredentials mocks base method.
+func (m *MockAuthRepository) ListWebAuthnCredentials(userID int) ([]model.WebAuthnCredential, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebAuthnCredentials", userID)
+	ret0, _ := ret[0].([]model.WebAuthnCredential)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebAuthnCredentials indicates an expected call of ListWebAuthnCredentials.
+func (mr *MockAuthRepositoryMockRecorder) ListWebAuthnCredentials(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebAuthnCredentials", reflect.TypeOf((*MockAuthRepository)(nil).ListWebAuthnCredentials), userID)
+}
+
+// LockLogin mocks base method.
+func (m *MockAuthRepository) LockLogin(subject string, until, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockLogin", subject, until, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockLogin indicates an expected call of LockLogin.
+func (mr *MockAuthRepositoryMockRecorder) LockLogin(subject, until, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockLogin", reflect.TypeOf((*MockAuthRepository)(nil).LockLogin), subject, until, expiresAt)
+}
+
+// MarkEmailVerified mocks base method.
+func (m *MockAuthRepository) MarkEmailVerified(userID int, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEmailVerified", userID, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEmailVerified indicates an expected call of MarkEmailVerified.
+func (mr *MockAuthRepositoryMockRecorder) MarkEmailVerified(userID, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEmailVerified", reflect.TypeOf((*MockAuthRepository)(nil).MarkEmailVerified), userID, email)
+}
+
+// RecordLoginAttempt mocks base method.
+func (m *MockAuthRepository) RecordLoginAttempt(subject string, now, expiresAt time.Time) (*model.LoginFailure, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordLoginAttempt", subject, now, expiresAt)
+	ret0, _ := ret[0].(*model.LoginFailure)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordLoginAttempt indicates an expected call of RecordLoginAttempt.
+func (mr *MockAuthRepositoryMockRecorder) RecordLoginAttempt(subject, now, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordLoginAttempt", reflect.TypeOf((*MockAuthRepository)(nil).RecordLoginAttempt), subject, now, expiresAt)
+}
+
+// RehashPassword mocks base method.
+func (m *MockAuthRepository) RehashPassword(userID int, oldHash, newHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RehashPassword", userID, oldHash, newHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RehashPassword indicates an expected call of RehashPassword.
+func (mr *MockAuthRepositoryMockRecorder) RehashPassword(userID, oldHash, newHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RehashPassword", reflect.TypeOf((*MockAuthRepository)(nil).RehashPassword), userID, oldHash, newHash)
+}
+
+// ReleaseLoginAttempt mocks base method.
+func (m *MockAuthRepository) ReleaseLoginAttempt(subject string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseLoginAttempt", subject)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseLoginAttempt indicates an expected call of ReleaseLoginAttempt.
+func (mr *MockAuthRepositoryMockRecorder) ReleaseLoginAttempt(subject any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseLoginAttempt", reflect.TypeOf((*MockAuthRepository)(nil).ReleaseLoginAttempt), subject)
+}
+
+// SaveDenylistEntry mocks base method.
+func (m *MockAuthRepository) SaveDenylistEntry(entry *model.DenylistEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDenylistEntry", entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDenylistEntry indicates an expected call of SaveDenylistEntry.
+func (mr *MockAuthRepositoryMockRecorder) SaveDenylistEntry(entry any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDenylistEntry", reflect.TypeOf((*MockAuthRepository)(nil).SaveDenylistEntry), entry)
+}
+
+// SaveEmailVerificationToken mocks base method.
+func (m *MockAuthRepository) SaveEmailVerificationToken(token *model.EmailVerificationToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveEmailVerificationToken", token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveEmailVerificationToken indicates an expected call of SaveEmailVerificationToken.
+func (mr *MockAuthRepositoryMockRecorder) SaveEmailVerificationToken(token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveEmailVerificationToken", reflect.TypeOf((*MockAuthRepository)(nil).SaveEmailVerificationToken), token)
+}
+
+// SaveMagicLinkToken mocks base method.
+func (m *MockAuthRepository) SaveMagicLinkToken(token *model.MagicLinkToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveMagicLinkToken", token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveMagicLinkToken indicates an expected call of SaveMagicLinkToken.
+func (mr *MockAuthRepositoryMockRecorder) SaveMagicLinkToken(token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveMagicLinkToken", reflect.TypeOf((*MockAuthRepository)(nil).SaveMagicLinkToken), token)
+}
+
+// SavePasswordResetToken mocks base method.
+func (m *MockAuthRepository) SavePasswordResetToken(token *model.PasswordResetToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavePasswordResetToken", token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavePasswordResetToken indicates an expected call of SavePasswordResetToken.
+func (mr *MockAuthRepositoryMockRecorder) SavePasswordResetToken(token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePasswordResetToken", reflect.TypeOf((*MockAuthRepository)(nil).SavePasswordResetToken), token)
+}
+
+// SaveRecoveryCodes mocks base method.
+func (m *MockAuthRepository) SaveRecoveryCodes(userID int, codeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRecoveryCodes", userID, codeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveRecoveryCodes indicates an expected call of SaveRecoveryCodes.
+func (mr *MockAuthRepositoryMockRecorder) SaveRecoveryCodes(userID, codeHashes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRecoveryCodes", reflect.TypeOf((*MockAuthRepository)(nil).SaveRecoveryCodes), userID, codeHashes)
+}
+
 // SaveRefreshToken mocks base method.
 func (m *MockAuthRepository) SaveRefreshToken(token *model.RefreshToken) error {
 	m.ctrl.T.Helper()
@@ -125,3 +636,157 @@ func (mr *MockAuthRepositoryMockRecorder) SaveRefreshToken(token any) *gomock.Ca
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRefreshToken", reflect.TypeOf((*MockAuthRepository)(nil).SaveRefreshToken), token)
 }
+
+// SaveTOTP mocks base method.
+func (m *MockAuthRepository) SaveTOTP(totp *model.TOTP) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTOTP", totp)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTOTP indicates an expected call of SaveTOTP.
+func (mr *MockAuthRepositoryMockRecorder) SaveTOTP(totp any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTP", reflect.TypeOf((*MockAuthRepository)(nil).SaveTOTP), totp)
+}
+
+// SaveWebAuthnCredential mocks base method.
+func (m *MockAuthRepository) SaveWebAuthnCredential(credential *model.WebAuthnCredential) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveWebAuthnCredential", credential)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveWebAuthnCredential indicates an expected call of SaveWebAuthnCredential.
+func (mr *MockAuthRepositoryMockRecorder) SaveWebAuthnCredential(credential any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveWebAuthnCredential", reflect.TypeOf((*MockAuthRepository)(nil).SaveWebAuthnCredential), credential)
+}
+
+// SaveWebAuthnSession mocks base method.
+func (m *MockAuthRepository) SaveWebAuthnSession(session *model.WebAuthnSession) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveWebAuthnSession", session)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveWebAuthnSession indicates an expected call of SaveWebAuthnSession.
+func (mr *MockAuthRepositoryMockRecorder) SaveWebAuthnSession(session any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveWebAuthnSession", reflect.TypeOf((*MockAuthRepository)(nil).SaveWebAuthnSession), session)
+}
+
+// UpdateEmail mocks base method.
+func (m *MockAuthRepository) UpdateEmail(userID int, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEmail", userID, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEmail indicates an expected call of UpdateEmail.
+func (mr *MockAuthRepositoryMockRecorder) UpdateEmail(userID, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEmail", reflect.TypeOf((*MockAuthRepository)(nil).UpdateEmail), userID, email)
+}
+
+// UpdateLocale mocks base method.
+func (m *MockAuthRepository) UpdateLocale(userID int, locale string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLocale", userID, locale)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLocale indicates an expected call of UpdateLocale.
+func (mr *MockAuthRepositoryMockRecorder) UpdateLocale(userID, locale any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLocale", reflect.TypeOf((*MockAuthRepository)(nil).UpdateLocale), userID, locale)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockAuthRepository) UpdatePassword(userID int, passwordHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", userID, passwordHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockAuthRepositoryMockRecorder) UpdatePassword(userID, passwordHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockAuthRepository)(nil).UpdatePassword), userID, passwordHash)
+}
+
+// UpdateRole mocks base method.
+func (m *MockAuthRepository) UpdateRole(userID int, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRole", userID, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRole indicates an expected call of UpdateRole.
+func (mr *MockAuthRepositoryMockRecorder) UpdateRole(userID, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRole", reflect.TypeOf((*MockAuthRepository)(nil).UpdateRole), userID, role)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockAuthRepository) UseRecoveryCode(userID int, codeHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", userID, codeHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockAuthRepositoryMockRecorder) UseRecoveryCode(userID, codeHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockAuthRepository)(nil).UseRecoveryCode), userID, codeHash)
+}
+
+// UseTOTPStep mocks base method.
+func (m *MockAuthRepository) UseTOTPStep(userID int, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", userID, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseTOTPStep indicates an expected call of UseTOTPStep.
+func (mr *MockAuthRepositoryMockRecorder) UseTOTPStep(userID, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockAuthRepository)(nil).UseTOTPStep), userID, step)
+}
+
+// UseWebAuthnCredential mocks base method.
+func (m *MockAuthRepository) UseWebAuthnCredential(credentialID []byte, signCount uint32, backupState bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseWebAuthnCredential", credentialID, signCount, backupState)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseWebAuthnCredential indicates an expected call of UseWebAuthnCredential.
+func (mr *MockAuthRepositoryMockRecorder) UseWebAuthnCredential(credentialID, signCount, backupState any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseWebAuthnCredential", reflect.TypeOf((*MockAuthRepository)(nil).UseWebAuthnCredential), credentialID, signCount, backupState)
+}
+
+// WithTx mocks base method.
+func (m *MockAuthRepository) WithTx(fn func(repository.AuthRepository) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockAuthRepositoryMockRecorder) WithTx(fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockAuthRepository)(nil).WithTx), fn)
+}
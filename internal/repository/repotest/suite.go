@@ -0,0 +1,733 @@
+// Package repotest содержит общий набор тестов, которому должна соответствовать каждая реализация репозиториев
+package repotest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/repository"
+)
+
+// Factory возвращает пустой репозиторий для одного теста
+type Factory func(t *testing.T) repository.AuthRepository
+
+func Run(t *testing.T, newRepo Factory) {
+	tests := map[string]func(t *testing.T, repo repository.AuthRepository){
+		"CreateUserAssignsID":                testCreateUserAssignsID,
+		"DuplicateUsernameIsConflict":        testDuplicateUsernameIsConflict,
+		"GetUserByUsernameNotFound":          testGetUserByUsernameNotFound,
+		"GetUserByID":                        testGetUserByID,
+		"UpdatePassword":                     testUpdatePassword,
+		"RehashPasswordIfUnchanged":          testRehashPasswordIfUnchanged,
+		"DenylistEntryUpsert":                testDenylistEntryUpsert,
+		"UserEmailRoundTrip":                 testUserEmailRoundTrip,
+		"PasswordResetTokens":                testPasswordResetTokens,
+		"EmailIsUniqueIgnoringCase":          testEmailIsUniqueIgnoringCase,
+		"UsernameIsUniqueNormalized":         testUsernameIsUniqueNormalized,
+		"GetLookalikeUser":                   testGetLookalikeUser,
+		"UpdateEmailResetsVerification":      testUpdateEmailResetsVerification,
+		"UserLocale":                         testUserLocale,
+		"UserRole":                           testUserRole,
+		"EmailVerificationTokens":            testEmailVerificationTokens,
+		"MagicLinkTokens":                    testMagicLinkTokens,
+		"TOTPLifecycle":                      testTOTPLifecycle,
+		"UseTOTPStepExactlyOnce":             testUseTOTPStepExactlyOnce,
+		"RecoveryCodes":                      testRecoveryCodes,
+		"WebAuthnCredentials":                testWebAuthnCredentials,
+		"UseWebAuthnCredentialSignCount":     testUseWebAuthnCredentialSignCount,
+		"WebAuthnSessions":                   testWebAuthnSessions,
+		"LoginAttempts":                      testLoginAttempts,
+		"DeleteExpiredDenylistEntries":       testDeleteExpiredDenylistEntries,
+		"SaveRefreshTokenUnknownUser":        testSaveRefreshTokenUnknownUser,
+		"DuplicateRefreshTokenIsConflict":    testDuplicateRefreshTokenIsConflict,
+		"ConsumeRefreshTokenKeepsExpiry":     testConsumeRefreshTokenKeepsExpiry,
+		"ConsumeRefreshTokenNotFound":        testConsumeRefreshTokenNotFound,
+		"ConsumeRefreshTokenTwice":           testConsumeRefreshTokenTwice,
+		"DeleteActiveRefreshTokensByUserID":        testDeleteActiveRefreshTokensByUserID,
+		"DeleteExpiredRefreshTokens":         testDeleteExpiredRefreshTokens,
+		"WithTxCommits":                      testWithTxCommits,
+		"WithTxRollsBackOnError":             testWithTxRollsBackOnError,
+		"ConsumeRefreshTokenExactlyOnce":     testConsumeRefreshTokenExactlyOnce,
+		"ConcurrentRegistrationSingleWinner": testConcurrentRegistrationSingleWinner,
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newRepo(t))
+		})
+	}
+}
+
+func createUser(t *testing.T, repo repository.AuthRepository, username string) *model.User {
+	user := &model.User{Username: username, Password: "hash", Role: "USER"}
+	require.NoError(t, repo.CreateUser(user))
+	return user
+}
+
+func saveToken(t *testing.T, repo repository.AuthRepository, userID int, token string, expiresAt time.Time) {
+	require.NoError(t, repo.SaveRefreshToken(&model.RefreshToken{UserID: userID, Token: token, ExpiresAt: expiresAt}))
+}
+
+func testCreateUserAssignsID(t *testing.T, repo repository.AuthRepository) {
+	first := createUser(t, repo, "first")
+	second := createUser(t, repo, "second")
+
+	assert.NotZero(t, first.ID)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	got, err := repo.GetUserByUsername("second")
+	require.NoError(t, err)
+	assert.Equal(t, *second, *got)
+}
+
+func testDuplicateUsernameIsConflict(t *testing.T, repo repository.AuthRepository) {
+	createUser(t, repo, "user")
+
+	err := repo.CreateUser(&model.User{Username: "user", Password: "other", Role: "USER"})
+
+	assert.ErrorIs(t, err, repository.ErrConflict)
+}
+
+func testGetUserByUsernameNotFound(t *testing.T, repo repository.AuthRepository) {
+	_, err := repo.GetUserByUsername("missing")
+
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testGetUserByID(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+
+	got, err := repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, *user, *got)
+
+	_, err = repo.GetUserByID(user.ID + 100)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testUpdatePassword(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+
+	require.NoError(t, repo.UpdatePassword(user.ID, "new-hash"))
+	got, err := repo.GetUserByUsername("user")
+	require.NoError(t, err)
+	assert.Equal(t, "new-hash", got.Password)
+
+	assert.ErrorIs(t, repo.UpdatePassword(user.ID+100, "hash"), repository.ErrNotFound)
+}
+
+func testRehashPasswordIfUnchanged(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+
+	require.NoError(t, repo.RehashPassword(user.ID, user.Password, "rehashed"))
+	assert.ErrorIs(t, repo.RehashPassword(user.ID, user.Password, "stale"), repository.ErrNotFound,
+		"password changed since it was read")
+	got, err := repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "rehashed", got.Password)
+}
+
+func testUserEmailRoundTrip(t *testing.T, repo repository.AuthRepository) {
+	user := &model.User{Username: "mailer", Password: "hash", Role: "USER", Email: "mailer@example.com"}
+	require.NoError(t, repo.CreateUser(user))
+	createUser(t, repo, "no-email")
+
+	got, err := repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "mailer@example.com", got.Email)
+	got, err = repo.GetUserByUsername("no-email")
+	require.NoError(t, err)
+	assert.Empty(t, got.Email)
+}
+
+func testPasswordResetTokens(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	now := time.Now()
+	for _, hash := range []string{"a", "b"} {
+		require.NoError(t, repo.SavePasswordResetToken(&model.PasswordResetToken{UserID: user.ID, TokenHash: hash, ExpiresAt: now.Add(time.Hour)}))
+	}
+	require.NoError(t, repo.SavePasswordResetToken(&model.PasswordResetToken{UserID: user.ID, TokenHash: "expired", ExpiresAt: now.Add(-time.Minute)}))
+	assert.ErrorIs(t, repo.SavePasswordResetToken(&model.PasswordResetToken{UserID: user.ID, TokenHash: "a", ExpiresAt: now}), repository.ErrConflict)
+	assert.ErrorIs(t, repo.SavePasswordResetToken(&model.PasswordResetToken{UserID: user.ID + 100, TokenHash: "c", ExpiresAt: now}), repository.ErrNotFound)
+
+	got, err := repo.ConsumePasswordResetToken("a")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, got.UserID)
+	_, err = repo.ConsumePasswordResetToken("a")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	n, err := repo.DeleteExpiredPasswordResetTokens(now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.NoError(t, repo.DeletePasswordResetTokensByUserID(user.ID))
+	_, err = repo.ConsumePasswordResetToken("b")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testEmailIsUniqueIgnoringCase(t *testing.T, repo repository.AuthRepository) {
+	require.NoError(t, repo.CreateUser(&model.User{Username: "first", Password: "hash", Role: "USER", Email: "user@example.com"}))
+	createUser(t, repo, "no-email-1")
+	createUser(t, repo, "no-email-2")
+
+	err := repo.CreateUser(&model.User{Username: "second", Password: "hash", Role: "USER", Email: "User@Example.com"})
+	assert.ErrorIs(t, err, repository.ErrConflict)
+
+	other := createUser(t, repo, "third")
+	assert.ErrorIs(t, repo.UpdateEmail(other.ID, "USER@example.com"), repository.ErrConflict)
+}
+
+func testUsernameIsUniqueNormalized(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "Forum_User")
+
+	for _, name := range []string{"forum_user", "FORUM_USER", "Ｆｏｒｕｍ_Ｕｓｅｒ"} {
+		got, err := repo.GetUserByUsername(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, user.ID, got.ID)
+		assert.Equal(t, "Forum_User", got.Username, "the display form is kept")
+		assert.ErrorIs(t, repo.CreateUser(&model.User{Username: name, Password: "hash", Role: "USER"}), repository.ErrConflict, name)
+	}
+}
+
+func testGetLookalikeUser(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "forum_user")
+	createUser(t, repo, "other")
+
+	for _, name := range []string{"Forum_User", "forum.user", "f\u043erum_user", "forum_usег"} {
+		got, err := repo.GetLookalikeUser(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, user.ID, got.ID, name)
+	}
+	_, err := repo.GetLookalikeUser("forum_users")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testUpdateEmailResetsVerification(t *testing.T, repo repository.AuthRepository) {
+	user := &model.User{Username: "user", Password: "hash", Role: "USER", Email: "old@example.com", EmailVerified: true}
+	require.NoError(t, repo.CreateUser(user))
+	got, err := repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.True(t, got.EmailVerified)
+
+	require.NoError(t, repo.UpdateEmail(user.ID, "new@example.com"))
+	got, err = repo.GetUserByUsername("user")
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", got.Email)
+	assert.False(t, got.EmailVerified)
+
+	assert.ErrorIs(t, repo.MarkEmailVerified(user.ID, "old@example.com"), repository.ErrNotFound)
+	require.NoError(t, repo.MarkEmailVerified(user.ID, "new@example.com"))
+	got, err = repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.True(t, got.EmailVerified)
+
+	// Старый адрес освобождается для других пользователей
+	require.NoError(t, repo.CreateUser(&model.User{Username: "other", Password: "hash", Role: "USER", Email: "old@example.com"}))
+	assert.ErrorIs(t, repo.UpdateEmail(user.ID+100, "x@example.com"), repository.ErrNotFound)
+}
+
+func testUserLocale(t *testing.T, repo repository.AuthRepository) {
+	user := &model.User{Username: "user", Password: "hash", Role: "USER", Locale: "en"}
+	require.NoError(t, repo.CreateUser(user))
+	got, err := repo.GetUserByUsername("user")
+	require.NoError(t, err)
+	assert.Equal(t, "en", got.Locale)
+
+	require.NoError(t, repo.UpdateLocale(user.ID, "ru"))
+	got, err = repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "ru", got.Locale)
+	assert.Empty(t, createUser(t, repo, "other").Locale)
+	assert.ErrorIs(t, repo.UpdateLocale(user.ID+100, "en"), repository.ErrNotFound)
+}
+
+func testUserRole(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	require.NoError(t, repo.UpdateRole(user.ID, "ADMIN"))
+	got, err := repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "ADMIN", got.Role)
+	assert.ErrorIs(t, repo.UpdateRole(user.ID+100, "ADMIN"), repository.ErrNotFound)
+}
+
+func testEmailVerificationTokens(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	now := time.Now()
+	for _, hash := range []string{"a", "b"} {
+		require.NoError(t, repo.SaveEmailVerificationToken(&model.EmailVerificationToken{UserID: user.ID, TokenHash: hash, Email: "user@example.com", ExpiresAt: now.Add(time.Hour)}))
+	}
+	require.NoError(t, repo.SaveEmailVerificationToken(&model.EmailVerificationToken{UserID: user.ID, TokenHash: "expired", Email: "user@example.com", ExpiresAt: now.Add(-time.Minute)}))
+	assert.ErrorIs(t, repo.SaveEmailVerificationToken(&model.EmailVerificationToken{UserID: user.ID, TokenHash: "a", Email: "user@example.com", ExpiresAt: now}), repository.ErrConflict)
+	assert.ErrorIs(t, repo.SaveEmailVerificationToken(&model.EmailVerificationToken{UserID: user.ID + 100, TokenHash: "c", Email: "user@example.com", ExpiresAt: now}), repository.ErrNotFound)
+
+	got, err := repo.ConsumeEmailVerificationToken("a")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, got.UserID)
+	assert.Equal(t, "user@example.com", got.Email)
+	_, err = repo.ConsumeEmailVerificationToken("a")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	n, err := repo.DeleteExpiredEmailVerificationTokens(now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.NoError(t, repo.DeleteEmailVerificationTokensByUserID(user.ID))
+	_, err = repo.ConsumeEmailVerificationToken("b")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testMagicLinkTokens(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	now := time.Now()
+	for _, hash := range []string{"a", "b"} {
+		require.NoError(t, repo.SaveMagicLinkToken(&model.MagicLinkToken{UserID: user.ID, TokenHash: hash, NonceHash: "n" + hash, Email: "user@example.com", ExpiresAt: now.Add(time.Hour)}))
+	}
+	require.NoError(t, repo.SaveMagicLinkToken(&model.MagicLinkToken{UserID: user.ID, TokenHash: "expired", NonceHash: "n", Email: "user@example.com", ExpiresAt: now.Add(-time.Minute)}))
+	assert.ErrorIs(t, repo.SaveMagicLinkToken(&model.MagicLinkToken{UserID: user.ID, TokenHash: "a", NonceHash: "n", Email: "user@example.com", ExpiresAt: now}), repository.ErrConflict)
+	assert.ErrorIs(t, repo.SaveMagicLinkToken(&model.MagicLinkToken{UserID: user.ID + 100, TokenHash: "c", NonceHash: "n", Email: "user@example.com", ExpiresAt: now}), repository.ErrNotFound)
+
+	got, err := repo.ConsumeMagicLinkToken("a")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, got.UserID)
+	assert.Equal(t, "na", got.NonceHash)
+	assert.Equal(t, "user@example.com", got.Email)
+	_, err = repo.ConsumeMagicLinkToken("a")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	n, err := repo.DeleteExpiredMagicLinkTokens(now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.NoError(t, repo.DeleteMagicLinkTokensByUserID(user.ID))
+	_, err = repo.ConsumeMagicLinkToken("b")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testTOTPLifecycle(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	_, err := repo.GetTOTP(user.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.ErrorIs(t, repo.ConfirmTOTP(user.ID), repository.ErrNotFound)
+	assert.ErrorIs(t, repo.UseTOTPStep(user.ID, 1), repository.ErrNotFound)
+
+	require.NoError(t, repo.SaveTOTP(&model.TOTP{UserID: user.ID, Secret: "FIRST"}))
+	require.NoError(t, repo.ConfirmTOTP(user.ID))
+	require.NoError(t, repo.UseTOTPStep(user.ID, 100))
+	got, err := repo.GetTOTP(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.TOTP{UserID: user.ID, Secret: "FIRST", Confirmed: true, LastUsedStep: 100}, *got)
+
+	// Повторная привязка сбрасывает подтверждение и использованные шаги
+	require.NoError(t, repo.SaveTOTP(&model.TOTP{UserID: user.ID, Secret: "SECOND"}))
+	got, err = repo.GetTOTP(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.TOTP{UserID: user.ID, Secret: "SECOND"}, *got)
+
+	require.NoError(t, repo.DeleteTOTP(user.ID))
+	_, err = repo.GetTOTP(user.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.ErrorIs(t, repo.SaveTOTP(&model.TOTP{UserID: user.ID + 100, Secret: "X"}), repository.ErrNotFound)
+}
+
+func testUseTOTPStepExactlyOnce(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	require.NoError(t, repo.SaveTOTP(&model.TOTP{UserID: user.ID, Secret: "SECRET"}))
+
+	const attempts = 10
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.UseTOTPStep(user.ID, 42)
+			if err == nil {
+				succeeded.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, repository.ErrConflict)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), succeeded.Load())
+	assert.ErrorIs(t, repo.UseTOTPStep(user.ID, 41), repository.ErrConflict)
+	assert.NoError(t, repo.UseTOTPStep(user.ID, 43))
+}
+
+func testRecoveryCodes(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	other := createUser(t, repo, "other")
+	require.NoError(t, repo.SaveRecoveryCodes(user.ID, []string{"a", "b"}))
+	require.NoError(t, repo.SaveRecoveryCodes(other.ID, []string{"a"}))
+	assert.ErrorIs(t, repo.SaveRecoveryCodes(user.ID+100, []string{"c"}), repository.ErrNotFound)
+
+	require.NoError(t, repo.UseRecoveryCode(user.ID, "a"))
+	assert.ErrorIs(t, repo.UseRecoveryCode(user.ID, "a"), repository.ErrNotFound)
+	assert.ErrorIs(t, repo.UseRecoveryCode(user.ID, "c"), repository.ErrNotFound)
+	n, err := repo.CountRecoveryCodes(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.NoError(t, repo.DeleteRecoveryCodesByUserID(user.ID))
+	assert.ErrorIs(t, repo.UseRecoveryCode(user.ID, "b"), repository.ErrNotFound)
+	assert.NoError(t, repo.UseRecoveryCode(other.ID, "a"))
+}
+
+func newCredential(userID int, id string) *model.WebAuthnCredential {
+	return &model.WebAuthnCredential{
+		UserID:          userID,
+		Name:            "key " + id,
+		CredentialID:    []byte(id),
+		PublicKey:       []byte("public key"),
+		AttestationType: "none",
+		Transports:      []string{"internal", "hybrid"},
+		AAGUID:          make([]byte, 16),
+		CreatedAt:       time.Now().Truncate(time.Second),
+	}
+}
+
+func testWebAuthnCredentials(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	other := createUser(t, repo, "other")
+	first := newCredential(user.ID, "first")
+	require.NoError(t, repo.SaveWebAuthnCredential(first))
+	assert.NotZero(t, first.ID)
+	require.NoError(t, repo.SaveWebAuthnCredential(newCredential(user.ID, "second")))
+	assert.ErrorIs(t, repo.SaveWebAuthnCredential(newCredential(other.ID, "first")), repository.ErrConflict)
+	assert.ErrorIs(t, repo.SaveWebAuthnCredential(newCredential(user.ID+100, "third")), repository.ErrNotFound)
+
+	got, err := repo.ListWebAuthnCredentials(user.ID)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	got[0].CreatedAt = got[0].CreatedAt.Local()
+	assert.Equal(t, *first, got[0])
+	assert.Equal(t, []byte("second"), got[1].CredentialID)
+
+	assert.ErrorIs(t, repo.DeleteWebAuthnCredential(other.ID, first.ID), repository.ErrNotFound)
+	require.NoError(t, repo.DeleteWebAuthnCredential(user.ID, first.ID))
+	got, err = repo.ListWebAuthnCredentials(user.ID)
+	require.NoError(t, err)
+	assert.Len(t, got, 1)
+	got, err = repo.ListWebAuthnCredentials(other.ID)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func testUseWebAuthnCredentialSignCount(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	require.NoError(t, repo.SaveWebAuthnCredential(newCredential(user.ID, "counter")))
+	require.NoError(t, repo.SaveWebAuthnCredential(newCredential(user.ID, "no-counter")))
+
+	require.NoError(t, repo.UseWebAuthnCredential([]byte("counter"), 5, false))
+	assert.ErrorIs(t, repo.UseWebAuthnCredential([]byte("counter"), 5, false), repository.ErrConflict)
+	assert.ErrorIs(t, repo.UseWebAuthnCredential([]byte("counter"), 0, false), repository.ErrConflict)
+	require.NoError(t, repo.UseWebAuthnCredential([]byte("counter"), 6, true))
+
+	// Ключи без счетчика всегда присылают 0
+	require.NoError(t, repo.UseWebAuthnCredential([]byte("no-counter"), 0, false))
+	require.NoError(t, repo.UseWebAuthnCredential([]byte("no-counter"), 0, false))
+	assert.ErrorIs(t, repo.UseWebAuthnCredential([]byte("missing"), 1, false), repository.ErrNotFound)
+
+	got, err := repo.ListWebAuthnCredentials(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(6), got[0].SignCount)
+	assert.True(t, got[0].BackupState)
+}
+
+func testWebAuthnSessions(t *testing.T, repo repository.AuthRepository) {
+	now := time.Now()
+	require.NoError(t, repo.SaveWebAuthnSession(&model.WebAuthnSession{TokenHash: "a", Data: []byte(`{"challenge":"x"}`), ExpiresAt: now.Add(time.Minute)}))
+	require.NoError(t, repo.SaveWebAuthnSession(&model.WebAuthnSession{TokenHash: "expired", Data: []byte("{}"), ExpiresAt: now.Add(-time.Minute)}))
+	assert.ErrorIs(t, repo.SaveWebAuthnSession(&model.WebAuthnSession{TokenHash: "a", Data: []byte("{}"), ExpiresAt: now}), repository.ErrConflict)
+
+	got, err := repo.ConsumeWebAuthnSession("a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte(`{"challenge":"x"}`), got.Data)
+	_, err = repo.ConsumeWebAuthnSession("a")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	n, err := repo.DeleteExpiredWebAuthnSessions(now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func testDenylistEntryUpsert(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	_, err := repo.GetDenylistEntry(user.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	first := time.Now().Add(-time.Minute).Truncate(time.Second)
+	require.NoError(t, repo.SaveDenylistEntry(&model.DenylistEntry{UserID: user.ID, RevokedBefore: first, ExpiresAt: first.Add(time.Hour)}))
+	second := time.Now().Truncate(time.Second)
+	require.NoError(t, repo.SaveDenylistEntry(&model.DenylistEntry{UserID: user.ID, RevokedBefore: second, ExpiresAt: second.Add(time.Hour)}))
+
+	entry, err := repo.GetDenylistEntry(user.ID)
+	require.NoError(t, err)
+	assert.True(t, second.Equal(entry.RevokedBefore), "revoked_before %v != %v", entry.RevokedBefore, second)
+	assert.True(t, second.Add(time.Hour).Equal(entry.ExpiresAt))
+
+	err = repo.SaveDenylistEntry(&model.DenylistEntry{UserID: user.ID + 100, RevokedBefore: second, ExpiresAt: second})
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testLoginAttempts(t *testing.T, repo repository.AuthRepository) {
+	now := time.Now().Truncate(time.Second)
+	_, err := repo.GetLoginFailure("account:user")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.ErrorIs(t, repo.LockLogin("account:user", now, now), repository.ErrNotFound)
+
+	for i := 1; i <= 3; i++ {
+		f, err := repo.RecordLoginAttempt("account:user", now, now.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, i, f.Failures)
+	}
+	require.NoError(t, repo.LockLogin("account:user", now.Add(2*time.Hour), now.Add(3*time.Hour)))
+	got, err := repo.GetLoginFailure("account:user")
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.Failures)
+	assert.True(t, got.LockedUntil.Equal(now.Add(2*time.Hour)))
+	assert.True(t, got.ExpiresAt.Equal(now.Add(3*time.Hour)))
+
+	// Попытка во время блокировки не учитывается, но возвращает блокировку
+	got, err = repo.RecordLoginAttempt("account:user", now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.Failures)
+	assert.True(t, got.LockedUntil.Equal(now.Add(2*time.Hour)))
+	assert.True(t, got.ExpiresAt.Equal(now.Add(3*time.Hour)))
+
+	// После блокировки счетчик растет дальше, а истекший начинается заново
+	got, err = repo.RecordLoginAttempt("account:user", now.Add(2*time.Hour), now.Add(2*time.Hour+time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 4, got.Failures)
+	require.NoError(t, repo.ReleaseLoginAttempt("account:user"))
+	got, err = repo.GetLoginFailure("account:user")
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.Failures)
+	got, err = repo.RecordLoginAttempt("account:user", now.Add(3*time.Hour), now.Add(4*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Failures)
+	require.NoError(t, repo.ReleaseLoginAttempt("ip:unknown"))
+
+	_, err = repo.RecordLoginAttempt("ip:10.0.0.1", now.Add(-2*time.Hour), now.Add(-time.Hour))
+	require.NoError(t, err)
+	deleted, err := repo.DeleteExpiredLoginFailures(now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	require.NoError(t, repo.DeleteLoginFailure("account:user"))
+	_, err = repo.GetLoginFailure("account:user")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testDeleteExpiredDenylistEntries(t *testing.T, repo repository.AuthRepository) {
+	now := time.Now()
+	expired := createUser(t, repo, "expired")
+	valid := createUser(t, repo, "valid")
+	require.NoError(t, repo.SaveDenylistEntry(&model.DenylistEntry{UserID: expired.ID, RevokedBefore: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)}))
+	require.NoError(t, repo.SaveDenylistEntry(&model.DenylistEntry{UserID: valid.ID, RevokedBefore: now, ExpiresAt: now.Add(time.Hour)}))
+
+	n, err := repo.DeleteExpiredDenylistEntries(now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = repo.GetDenylistEntry(expired.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.GetDenylistEntry(valid.ID)
+	assert.NoError(t, err)
+}
+
+func testSaveRefreshTokenUnknownUser(t *testing.T, repo repository.AuthRepository) {
+	err := repo.SaveRefreshToken(&model.RefreshToken{UserID: 4242, Token: "t", ExpiresAt: time.Now().Add(time.Hour)})
+
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testDuplicateRefreshTokenIsConflict(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	saveToken(t, repo, user.ID, "t", time.Now().Add(time.Hour))
+
+	err := repo.SaveRefreshToken(&model.RefreshToken{UserID: user.ID, Token: "t", ExpiresAt: time.Now().Add(time.Hour)})
+
+	assert.ErrorIs(t, err, repository.ErrConflict)
+}
+
+// Просроченные токены возвращаются как есть: проверка срока — задача usecase
+func testConsumeRefreshTokenKeepsExpiry(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	expiresAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	saveToken(t, repo, user.ID, "expired", expiresAt)
+
+	rt, err := repo.ConsumeRefreshToken("expired")
+
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, rt.UserID)
+	assert.True(t, expiresAt.Equal(rt.ExpiresAt), "expires_at %v != %v", rt.ExpiresAt, expiresAt)
+}
+
+func testConsumeRefreshTokenNotFound(t *testing.T, repo repository.AuthRepository) {
+	_, err := repo.ConsumeRefreshToken("missing")
+
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+// Повторно предъявленный токен возвращается с ErrConflict, чтобы usecase отличил его от неизвестного
+func testConsumeRefreshTokenTwice(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	saveToken(t, repo, user.ID, "t", time.Now().Add(time.Hour))
+
+	_, err := repo.ConsumeRefreshToken("t")
+	require.NoError(t, err)
+	rt, err := repo.ConsumeRefreshToken("t")
+
+	assert.ErrorIs(t, err, repository.ErrConflict)
+	require.NotNil(t, rt)
+	assert.Equal(t, user.ID, rt.UserID)
+	assert.True(t, rt.Consumed)
+}
+
+func testDeleteActiveRefreshTokensByUserID(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	other := createUser(t, repo, "other")
+	saveToken(t, repo, user.ID, "a", time.Now().Add(time.Hour))
+	saveToken(t, repo, user.ID, "b", time.Now().Add(time.Hour))
+	saveToken(t, repo, user.ID, "used", time.Now().Add(time.Hour))
+	saveToken(t, repo, other.ID, "c", time.Now().Add(time.Hour))
+	_, err := repo.ConsumeRefreshToken("used")
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteActiveRefreshTokensByUserID(user.ID))
+
+	_, err = repo.ConsumeRefreshToken("a")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.ConsumeRefreshToken("b")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.ConsumeRefreshToken("used")
+	assert.ErrorIs(t, err, repository.ErrConflict, "consumed tokens are kept to detect reuse")
+	_, err = repo.ConsumeRefreshToken("c")
+	assert.NoError(t, err)
+}
+
+func testDeleteExpiredRefreshTokens(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		saveToken(t, repo, user.ID, fmt.Sprintf("expired-%d", i), now.Add(-time.Duration(i+1)*time.Minute))
+	}
+	saveToken(t, repo, user.ID, "valid", now.Add(time.Hour))
+
+	n, err := repo.DeleteExpiredRefreshTokens(now, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = repo.DeleteExpiredRefreshTokens(now, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	n, err = repo.DeleteExpiredRefreshTokens(now, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	_, err = repo.ConsumeRefreshToken("valid")
+	assert.NoError(t, err)
+}
+
+func testWithTxCommits(t *testing.T, repo repository.AuthRepository) {
+	err := repo.WithTx(func(tx repository.AuthRepository) error {
+		user := createUser(t, tx, "user")
+		saveToken(t, tx, user.ID, "t", time.Now().Add(time.Hour))
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = repo.ConsumeRefreshToken("t")
+	assert.NoError(t, err)
+}
+
+func testWithTxRollsBackOnError(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	saveToken(t, repo, user.ID, "old", time.Now().Add(time.Hour))
+
+	failure := errors.New("crash between statements")
+	err := repo.WithTx(func(tx repository.AuthRepository) error {
+		if err := tx.DeleteActiveRefreshTokensByUserID(user.ID); err != nil {
+			return err
+		}
+		createUser(t, tx, "created-in-tx")
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	rt, err := repo.ConsumeRefreshToken("old")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, rt.UserID)
+	_, err = repo.GetUserByUsername("created-in-tx")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testConsumeRefreshTokenExactlyOnce(t *testing.T, repo repository.AuthRepository) {
+	user := createUser(t, repo, "user")
+	saveToken(t, repo, user.ID, "shared", time.Now().Add(time.Hour))
+
+	const attempts = 10
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.WithTx(func(tx repository.AuthRepository) error {
+				if _, err := tx.ConsumeRefreshToken("shared"); err != nil {
+					return err
+				}
+				time.Sleep(5 * time.Millisecond)
+				return tx.SaveRefreshToken(&model.RefreshToken{UserID: user.ID, Token: fmt.Sprintf("rotated-%d", i), ExpiresAt: time.Now().Add(time.Hour)})
+			})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+				return
+			}
+			assert.ErrorIs(t, err, repository.ErrConflict)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, succeeded)
+}
+
+func testConcurrentRegistrationSingleWinner(t *testing.T, repo repository.AuthRepository) {
+	const attempts = 10
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.CreateUser(&model.User{Username: "racer", Password: "hash", Role: "USER"})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+				return
+			}
+			assert.ErrorIs(t, err, repository.ErrConflict)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, succeeded)
+}
@@ -0,0 +1,41 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+	"sstu-go-forum-auth-service/internal/repository"
+	"sstu-go-forum-auth-service/internal/repository/impl"
+)
+
+// Open открывает базу SQLite по пути к файлу (или ":memory:"); схему создает пакет migrations
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite допускает одного писателя, а база ":memory:" существует только в рамках соединения
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+func NewRepository(db *sql.DB) *impl.AuthRepositoryImpl {
+	return impl.NewRepositoryWithDialect(db, Dialect{})
+}
+
+type Dialect struct{}
+
+func (Dialect) MapError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			return fmt.Errorf("%w: %s", repository.ErrConflict, sqliteErr.Error())
+		case sqlite3.ErrConstraintForeignKey:
+			return fmt.Errorf("%w: %s", repository.ErrNotFound, sqliteErr.Error())
+		}
+	}
+	return err
+}
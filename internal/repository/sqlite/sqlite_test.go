@@ -0,0 +1,25 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/migrations"
+	"sstu-go-forum-auth-service/internal/repository"
+	"sstu-go-forum-auth-service/internal/repository/repotest"
+)
+
+func TestAuthRepositoryConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.AuthRepository {
+		db, err := Open(filepath.Join(t.TempDir(), "auth.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		migrator, err := migrations.New(db, migrations.SQLite)
+		require.NoError(t, err)
+		_, err = migrator.Up()
+		require.NoError(t, err)
+		return NewRepository(db)
+	})
+}
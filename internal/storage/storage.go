@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	"sstu-go-forum-auth-service/internal/migrations"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository"
+	"sstu-go-forum-auth-service/internal/repository/impl"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	"sstu-go-forum-auth-service/internal/repository/sqlite"
+)
+
+// Storage объединяет репозитории одного хранилища
+type Storage struct {
+	Auth repository.AuthRepository
+	// DB и Dialect заданы только для SQL-хранилищ
+	DB      *sql.DB
+	Dialect migrations.Dialect
+}
+
+// Open выбирает реализацию хранилища по схеме DSN; при autoMigrate применяет встроенные миграции
+func Open(dsn string, autoMigrate bool) (*Storage, error) {
+	scheme, rest, _ := strings.Cut(dsn, "://")
+	var s *Storage
+	switch scheme {
+	case "memory":
+		return &Storage{Auth: memory.NewRepository()}, nil
+	case "postgres", "postgresql":
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			return nil, fmt.Errorf("open postgres: %w", err)
+		}
+		if err := db.Ping(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("ping postgres: %w", err)
+		}
+		s = &Storage{Auth: impl.NewRepository(db), DB: db, Dialect: migrations.Postgres}
+	case "sqlite":
+		db, err := sqlite.Open(rest)
+		if err != nil {
+			return nil, fmt.Errorf("open sqlite: %w", err)
+		}
+		s = &Storage{Auth: sqlite.NewRepository(db), DB: db, Dialect: migrations.SQLite}
+	default:
+		return nil, fmt.Errorf("unsupported storage scheme %q", scheme)
+	}
+
+	if autoMigrate {
+		if err := s.Migrate(); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Migrate применяет непримененные миграции SQL-хранилища
+func (s *Storage) Migrate() error {
+	migrator, err := migrations.New(s.DB, s.Dialect)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up()
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	for _, m := range applied {
+		log.Info().Int("version", m.Version).Str("name", m.Name).Msg("Migration applied")
+	}
+	return nil
+}
+
+// RateLimits возвращает лимитеры в памяти для backend "memory" или в базе хранилища для "database"
+func (s *Storage) RateLimits(backend string) (ratelimit.Backend, error) {
+	switch backend {
+	case "memory":
+		return ratelimit.Backend{}, nil
+	case "database":
+		if s.DB == nil {
+			return ratelimit.Backend{}, fmt.Errorf("rate limit backend %q requires an SQL storage", backend)
+		}
+		return ratelimit.Backend{DB: s.DB}, nil
+	default:
+		return ratelimit.Backend{}, fmt.Errorf("unknown rate limit backend %q, use memory or database", backend)
+	}
+}
+
+func (s *Storage) Close() error {
+	if s.DB == nil {
+		return nil
+	}
+	return s.DB.Close()
+}
@@ -0,0 +1,66 @@
+// Package timingtest проверяет, что время ответа не различает два случая, например существующий и несуществующий аккаунт
+package timingtest
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Critical001 — коэффициент c(α) критерия Колмогорова-Смирнова для уровня значимости 0.001
+const Critical001 = 1.95
+
+// Sample измеряет a и b по n раз, чередуя вызовы, чтобы фоновая нагрузка влияла на обе выборки одинаково
+func Sample(n int, a, b func()) (timesA, timesB []time.Duration) {
+	for i := 0; i < n; i++ {
+		timesA = append(timesA, measure(a))
+		timesB = append(timesB, measure(b))
+	}
+	return timesA, timesB
+}
+
+func measure(fn func()) time.Duration {
+	start := time.Now()
+	fn()
+	return time.Since(start)
+}
+
+// KolmogorovSmirnov возвращает статистику D двухвыборочного критерия: наибольшее расстояние
+// между эмпирическими функциями распределения
+func KolmogorovSmirnov(a, b []time.Duration) float64 {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	var d float64
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		x := min(a[i], b[j])
+		for i < len(a) && a[i] == x {
+			i++
+		}
+		for j < len(b) && b[j] == x {
+			j++
+		}
+		d = math.Max(d, math.Abs(float64(i)/float64(len(a))-float64(j)/float64(len(b))))
+	}
+	return d
+}
+
+// AssertIndistinguishable проваливает тест, если критерий Колмогорова-Смирнова отвергает
+// одинаковость распределений на уровне 0.001
+func AssertIndistinguishable(t *testing.T, a, b []time.Duration) {
+	t.Helper()
+	n, m := float64(len(a)), float64(len(b))
+	d := KolmogorovSmirnov(a, b)
+	critical := Critical001 * math.Sqrt((n+m)/(n*m))
+	if d > critical {
+		t.Errorf("timing distributions differ: D=%.3f > %.3f (median %v vs %v)", d, critical, median(a), median(b))
+	}
+}
+
+func median(samples []time.Duration) time.Duration {
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	return sorted[len(sorted)/2]
+}
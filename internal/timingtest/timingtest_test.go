@@ -0,0 +1,18 @@
+package timingtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKolmogorovSmirnov(t *testing.T) {
+	same := []time.Duration{1, 2, 3, 4, 5}
+	assert.Zero(t, KolmogorovSmirnov(same, same))
+
+	fast := []time.Duration{1, 2, 3, 4}
+	slow := []time.Duration{10, 20, 30, 40}
+	assert.Equal(t, 1.0, KolmogorovSmirnov(fast, slow), "separated samples")
+	assert.Equal(t, 0.5, KolmogorovSmirnov([]time.Duration{1, 2, 3, 4}, []time.Duration{3, 4, 5, 6}))
+}
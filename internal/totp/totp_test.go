@@ -0,0 +1,60 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тестовые векторы RFC 6238, приложение B (SHA1)
+func TestCode_RFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for unix, want := range vectors {
+		assert.Equal(t, want, code(key, Step(time.Unix(unix, 0)), 8), "t=%d", unix)
+	}
+
+	secret := encoding.EncodeToString(key)
+	got, err := Code(secret, Step(time.Unix(59, 0)))
+	require.NoError(t, err)
+	assert.Equal(t, "287082", got)
+}
+
+func TestValidate_AcceptsAdjacentSteps(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Unix(1_700_000_000, 0)
+
+	for _, offset := range []time.Duration{-Period, 0, Period} {
+		c, err := Code(secret, Step(now.Add(offset)))
+		require.NoError(t, err)
+		step, ok := Validate(secret, c, now)
+		assert.True(t, ok)
+		assert.Equal(t, Step(now.Add(offset)), step)
+	}
+
+	c, err := Code(secret, Step(now.Add(2*Period)))
+	require.NoError(t, err)
+	_, ok := Validate(secret, c, now)
+	assert.False(t, ok)
+	_, ok = Validate(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("SSTU Forum", "admin", "JBSWY3DPEHPK3PXP")
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/SSTU%20Forum:admin?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=SSTU+Forum")
+}
@@ -1,12 +1,45 @@
 package usecase
 
 import (
+	"github.com/golang-jwt/jwt/v4"
 	"sstu-go-forum-auth-service/internal/dto"
 	"sstu-go-forum-auth-service/internal/model"
 )
 
 type AuthUseCase interface {
 	Register(u *model.User) (*model.User, error) // TODO: вынести формирование ответа клиенту в handler
-	Login(req dto.LoginRequest) (*model.User, string, string, error)
+	// Login проверяет пароль. Если у пользователя подключен второй фактор или роль его требует,
+	// вместо пары токенов возвращается MFA токен для LoginMFA. После неудачных попыток
+	// возвращается LoginLockedError
+	Login(req dto.LoginRequest) (*LoginResult, error)
+	// UnlockLogin снимает блокировку входа по имени пользователя
+	UnlockLogin(username string) error
+	// LoginMFA завершает вход кодом TOTP, кодом восстановления или ключом доступа;
+	// при обязательной, но еще не подключенной 2FA код TOTP подтверждает привязку
+	LoginMFA(req dto.MFALoginRequest) (*model.User, string, string, error)
+	// LoginWebAuthn выполняет вход без пароля по passkey; проверка пользователя ключом заменяет второй фактор
+	LoginWebAuthn(req dto.WebAuthnLoginRequest) (*model.User, string, string, error)
+	// LoginMagicLink обменивает токен из письма на пару токенов; ссылка заменяет только пароль,
+	// поэтому при подключенном втором факторе возвращается MFA токен, как в Login
+	LoginMagicLink(req dto.MagicLinkLoginRequest) (*LoginResult, error)
+	// VerifyMFAToken проверяет MFA токен первого шага входа и возвращает ID пользователя
+	VerifyMFAToken(token string) (int, error)
 	RefreshToken(req dto.RefreshRequest) (*model.User, string, string, error)
+	// ChangePassword меняет пароль, отзывает все сессии пользователя и выдает новую пару токенов текущему устройству
+	ChangePassword(userID int, req dto.ChangePasswordRequest) (*model.User, string, string, error)
+	// ChangeLocale сохраняет язык писем и ответов пользователя; пустой язык возвращает выбор по Accept-Language.
+	// В access токенах язык обновится при их следующей выдаче
+	ChangeLocale(userID int, locale string) error
+	// VerifyAccessToken проверяет подпись, тип и отзыв access токена
+	VerifyAccessToken(token string) (jwt.MapClaims, error)
+}
+
+// LoginResult — итог первого шага входа: пара токенов или MFAToken, если нужен второй фактор
+type LoginResult struct {
+	User         *model.User
+	AccessToken  string
+	RefreshToken string
+	MFAToken     string
+	// MFAEnrollmentRequired означает, что роль требует 2FA, а TOTP еще не подключен
+	MFAEnrollmentRequired bool
 }
@@ -0,0 +1,13 @@
+package usecase
+
+import "sstu-go-forum-auth-service/internal/dto"
+
+type EmailUseCase interface {
+	// SendVerification отправляет ссылку для подтверждения текущей почты пользователя, если она еще не подтверждена
+	SendVerification(userID int) error
+	// ResendVerification повторяет письмо по имени пользователя. Результат одинаков для существующих и несуществующих аккаунтов
+	ResendVerification(req dto.ResendVerificationRequest) error
+	VerifyEmail(req dto.VerifyEmailRequest) error
+	// ChangeEmail меняет почту после проверки пароля; новый адрес требует повторного подтверждения
+	ChangeEmail(userID int, req dto.ChangeEmailRequest) error
+}
@@ -1,10 +1,50 @@
 package usecase
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
-	ErrUserAlreadyExists   = errors.New("user already exists")
+	ErrUserAlreadyExists = errors.New("user already exists")
+	ErrUsernameReserved  = errors.New("username is reserved")
+	// ErrUsernameConfusable — имя выглядит как уже занятое, например отличается только кириллической буквой
+	ErrUsernameConfusable  = errors.New("username is too similar to an existing one")
 	ErrInvalidCredentials  = errors.New("invalid credentials")
 	ErrInvalidRefreshToken = errors.New("invalid refresh token")
-	ErrInvalidTokenData    = errors.New("invalid token data")
+	// ErrRefreshTokenReused — предъявлен уже обмененный refresh токен; все refresh токены пользователя отозваны
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+	ErrInvalidTokenData   = errors.New("invalid token data")
+	ErrInvalidAccessToken = errors.New("invalid access token")
+	ErrTokenRevoked       = errors.New("token revoked")
+	ErrInvalidNewPassword = errors.New("invalid new password")
+	ErrInvalidResetToken  = errors.New("invalid or expired reset token")
+	ErrInvalidEmail       = errors.New("invalid email")
+	ErrEmailTaken         = errors.New("email already in use")
+	ErrEmailNotVerified   = errors.New("email not verified")
+	// ErrInvalidVerificationToken возвращается и для токена, выданного на адрес, который уже сменили
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	// ErrInvalidMagicLink объединяет истекший, использованный и открытый в другом браузере токен
+	ErrInvalidMagicLink   = errors.New("invalid or expired magic link")
+	ErrInvalidMFAToken    = errors.New("invalid or expired mfa token")
+	ErrInvalidMFACode     = errors.New("invalid mfa code")
+	ErrTooManyMFAAttempts = errors.New("too many mfa attempts")
+	ErrMFAAlreadyEnabled  = errors.New("two-factor authentication already enabled")
+	ErrMFANotEnrolled     = errors.New("two-factor authentication not enrolled")
+	ErrMFARequired        = errors.New("two-factor authentication is required for this role")
+	// ErrInvalidWebAuthnResponse объединяет истекшую церемонию, неверную подпись и чужой или скопированный ключ
+	ErrInvalidWebAuthnResponse    = errors.New("invalid or expired webauthn response")
+	ErrWebAuthnNotRegistered      = errors.New("no webauthn credentials registered")
+	ErrWebAuthnCredentialNotFound = errors.New("webauthn credential not found")
+	// ErrLoginLocked — вход временно запрещен после неудачных попыток; подробности в LoginLockedError
+	ErrLoginLocked = errors.New("too many failed login attempts")
 )
+
+// LoginLockedError сообщает, через сколько можно повторить вход; errors.Is(err, ErrLoginLocked) для нее верно
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string { return ErrLoginLocked.Error() }
+
+func (e *LoginLockedError) Unwrap() error { return ErrLoginLocked }
@@ -1,138 +1,595 @@
 package usecase
 
 import (
+	"errors"
 	"sstu-go-forum-auth-service/internal/dto"
 	"sstu-go-forum-auth-service/internal/repository"
 	"time"
 
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/rs/zerolog/log"
-	"golang.org/x/crypto/bcrypt"
+	"sstu-go-forum-auth-service/internal/i18n"
 	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/password"
+	"sstu-go-forum-auth-service/internal/ratelimit"
 	"sstu-go-forum-auth-service/internal/usecase"
 	"sstu-go-forum-auth-service/internal/utils"
 )
 
 type AuthUseCaseImpl struct {
 	Repo repository.AuthRepository
+	// Verification, если задан, отправляет письмо с подтверждением почты после регистрации
+	Verification usecase.EmailUseCase
+	// RequireVerifiedEmail запрещает вход и обновление токенов, пока почта не подтверждена
+	RequireVerifiedEmail bool
+	// MFA решает, нужен ли при входе второй фактор, и проверяет его коды
+	MFA usecase.MFAUseCase
+	// WebAuthn, если задан, разрешает вход по ключам доступа
+	WebAuthn usecase.WebAuthnUseCase
+	// MagicLink, если задан, разрешает вход по ссылке из письма
+	MagicLink usecase.MagicLinkUseCase
+	// Lockout, если задан, задерживает и блокирует вход по паролю после неудачных попыток
+	Lockout *LoginLockout
+	// Hasher хеширует новые пароли; устаревшие хеши пересчитываются при успешном входе
+	Hasher password.PasswordHasher
+	// PasswordPolicy проверяет пароли при регистрации и смене
+	PasswordPolicy *password.Policy
+	// ReservedUsernames нельзя занять при регистрации, как и похожие на них имена
+	ReservedUsernames []string
 }
 
 func NewAuthUseCase(repo repository.AuthRepository) *AuthUseCaseImpl {
 	log.Info().Msg("AuthUseCaseImpl initialized")
-	return &AuthUseCaseImpl{Repo: repo}
+	return &AuthUseCaseImpl{
+		Repo:              repo,
+		MFA:               NewMFAUseCase(repo, DefaultMFAIssuer, nil, ratelimit.NewSlidingWindow(DefaultMFAAttempts, utils.MFATokenTTL)),
+		Hasher:            password.Default(),
+		PasswordPolicy:    password.DefaultPolicy,
+		ReservedUsernames: model.ReservedUsernames,
+	}
 }
 
 func (uc *AuthUseCaseImpl) Register(u *model.User) (*model.User, error) {
 	log.Debug().Str("username", u.Username).Msg("Registering user")
 
+	u.Username = model.NormalizeUsername(u.Username)
+	u.Email = model.NormalizeEmail(u.Email)
+	u.EmailVerified = false
+	// Роль из запроса не принимается: иначе любой мог бы зарегистрироваться администратором.
+	// Администраторов назначает authctl users set-role
+	u.Role = "USER"
+	if locale, ok := i18n.Parse(u.Locale); ok {
+		u.Locale = locale
+	}
 	if err := u.Validate(); err != nil {
+		// Нарушения политики паролей добавляются к ошибкам остальных полей, чтобы клиент показал все сразу
+		var validationErr, policyErr *model.ValidationError
+		if errors.As(err, &validationErr) &&
+			errors.As(checkPasswordPolicy(uc.PasswordPolicy, u.Password, u.Username, "password"), &policyErr) {
+			validationErr.Fields = append(validationErr.Fields, policyErr.Fields...)
+		}
 		log.Warn().Err(err).Msg("User validation failed")
 		return nil, err
 	}
-	existing, err := uc.Repo.GetUserByUsername(u.Username) // TODO: обработать ошибку
-	if err != nil {
-		log.Error().Err(err).Str("username", u.Username).Msg("Failed to check if user exists")
-		return nil, err
+	if model.IsReservedUsername(u.Username, uc.ReservedUsernames) {
+		log.Warn().Str("username", u.Username).Msg("Reserved username refused")
+		return nil, model.NewValidationError(usecase.ErrUsernameReserved, "username", model.CodeReserved)
 	}
-	if existing != nil {
+	// Проверка похожих имен не атомарна со вставкой: гонка дает лишь двух похожих пользователей, а точные
+	// дубликаты по-прежнему отсекает уникальный индекс
+	lookalike, err := uc.Repo.GetLookalikeUser(u.Username)
+	switch {
+	case err == nil && model.UsernameKey(lookalike.Username) == model.UsernameKey(u.Username):
 		log.Warn().Str("username", u.Username).Msg("User already exists")
-		return nil, usecase.ErrUserAlreadyExists // TODO: добавить работу с ошибками
+		return nil, usernameTakenError()
+	case err == nil:
+		log.Warn().Str("username", u.Username).Str("existing", lookalike.Username).Msg("Confusable username refused")
+		return nil, model.NewValidationError(usecase.ErrUsernameConfusable, "username", model.CodeConfusable)
+	case !errors.Is(err, repository.ErrNotFound):
+		log.Error().Err(err).Msg("Failed to look up similar usernames")
+		return nil, err
 	}
-	hashed, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	if err := checkPasswordPolicy(uc.PasswordPolicy, u.Password, u.Username, "password"); err != nil {
+		return nil, err
+	}
+	hashed, err := uc.Hasher.Hash(u.Password)
 	if err != nil {
 		log.Error().Err(err).Msg("Password hashing failed")
 		return nil, err
 	}
-	u.Password = string(hashed)
+	u.Password = hashed
 
+	// Уникальность имени гарантирует ограничение в БД, а не предварительная проверка: так нет гонки между проверкой и вставкой
 	if err := uc.Repo.CreateUser(u); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			log.Warn().Str("username", u.Username).Msg("User already exists")
+			return nil, usernameTakenError()
+		}
 		log.Error().Err(err).Msg("Failed to create user")
 		return nil, err
 	}
 	log.Info().Int("userID", u.ID).Str("username", u.Username).Msg("User registered")
+
+	// Регистрация не зависит от доставки письма: его можно запросить повторно
+	if uc.Verification != nil && u.Email != "" {
+		if err := uc.Verification.SendVerification(u.ID); err != nil {
+			log.Error().Err(err).Int("userID", u.ID).Msg("Failed to send verification email")
+		}
+	}
 	return u, nil
 }
 
-func (uc *AuthUseCaseImpl) Login(req dto.LoginRequest) (*model.User, string, string, error) {
+func (uc *AuthUseCaseImpl) Login(req dto.LoginRequest) (*usecase.LoginResult, error) {
 	log.Debug().Str("username", req.Username).Msg("Login attempt")
 
+	// Попытка учитывается до поиска пользователя: ответ не зависит от того, существует ли аккаунт
+	if uc.Lockout != nil {
+		if err := uc.Lockout.Attempt(req.Username, req.IP); err != nil {
+			return nil, err
+		}
+	}
 	user, err := uc.Repo.GetUserByUsername(req.Username)
-	if err != nil || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		log.Error().Err(err).Str("username", req.Username).Msg("Failed to get user")
+		return nil, err
+	}
+	// Для несуществующего имени пароль проверяется только по фиктивным хешам: время ответа не выдает,
+	// есть ли аккаунт и каким алгоритмом получен его хеш, см. password.VerifyUniform
+	var hash string
+	if err == nil {
+		hash = user.Password
+	}
+	ok, needsRehash, verifyErr := password.VerifyUniform(uc.Hasher, hash, req.Password)
+	if verifyErr != nil {
+		log.Error().Err(verifyErr).Msg("Failed to verify password hash")
+		ok = false
+	}
+	if !ok || err != nil {
 		log.Warn().Str("username", req.Username).Msg("Invalid credentials")
-		return nil, "", "", usecase.ErrInvalidCredentials
+		return nil, usecase.ErrInvalidCredentials
+	}
+	if uc.Lockout != nil {
+		if err := uc.Lockout.Success(req.Username, req.IP); err != nil {
+			return nil, err
+		}
 	}
+	if needsRehash {
+		uc.rehashPassword(user, req.Password)
+	}
+	return uc.completeLogin(user)
+}
 
-	access, err := utils.GenerateAccessToken(user.ID, user.Username, user.Role)
+// rehashPassword пересчитывает устаревший хеш текущим алгоритмом. Вход от этого не зависит:
+// при ошибке хеш пересчитается при следующем входе
+func (uc *AuthUseCaseImpl) rehashPassword(user *model.User, plain string) {
+	hashed, err := uc.Hasher.Hash(plain)
+	if err != nil {
+		log.Error().Err(err).Int("userID", user.ID).Msg("Password rehashing failed")
+		return
+	}
+	// Хеш заменяется, только если пароль не сменили с момента проверки
+	err = uc.Repo.RehashPassword(user.ID, user.Password, hashed)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Warn().Int("userID", user.ID).Msg("Password changed during login, rehash skipped")
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Int("userID", user.ID).Msg("Failed to save rehashed password")
+		return
+	}
+	user.Password = hashed
+	log.Info().Int("userID", user.ID).Msg("Password hash upgraded")
+}
+
+func usernameTakenError() error {
+	return model.NewValidationError(usecase.ErrUserAlreadyExists, "username", model.CodeTaken)
+}
+
+// checkPasswordPolicy превращает нарушения политики в *model.ValidationError поля field с кодами правил;
+// errors.Is(err, usecase.ErrInvalidNewPassword) для нее верно
+func checkPasswordPolicy(policy *password.Policy, plain, username, field string) error {
+	err := policy.Check(plain, username)
+	var policyErr *password.PolicyError
+	if errors.As(err, &policyErr) {
+		log.Warn().Err(err).Str("username", username).Msg("Password rejected by policy")
+		validationErr := &model.ValidationError{Err: usecase.ErrInvalidNewPassword}
+		for _, v := range policyErr.Violations {
+			validationErr.Fields = append(validationErr.Fields, model.FieldError{Field: field, Code: v.Rule, Key: "password." + v.Rule, Args: v.Args})
+		}
+		return validationErr
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to check password policy")
+		return err
+	}
+	return nil
+}
+
+// verifyPassword сравнивает пароль с хешем; хеш неизвестного формата не совпадает ни с чем
+func verifyPassword(h password.PasswordHasher, hash, plain string) (ok, needsRehash bool) {
+	ok, needsRehash, err := h.Verify(hash, plain)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to verify password hash")
+		return false, false
+	}
+	return ok, needsRehash
+}
+
+func (uc *AuthUseCaseImpl) UnlockLogin(username string) error {
+	if uc.Lockout == nil {
+		return nil
+	}
+	if err := uc.Lockout.Unlock(username); err != nil {
+		return err
+	}
+	log.Info().Str("username", username).Msg("Login unlocked by admin")
+	return nil
+}
+
+func (uc *AuthUseCaseImpl) ChangeLocale(userID int, locale string) error {
+	if locale != "" {
+		parsed, ok := i18n.Parse(locale)
+		if !ok {
+			log.Warn().Str("locale", locale).Int("userID", userID).Msg("Unsupported locale refused")
+			return &model.ValidationError{Fields: []model.FieldError{*model.ValidateLocale(locale)}}
+		}
+		locale = parsed
+	}
+	if err := uc.Repo.UpdateLocale(userID, locale); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			log.Warn().Int("userID", userID).Msg("Locale change for unknown user")
+			return usecase.ErrInvalidCredentials
+		}
+		log.Error().Err(err).Int("userID", userID).Msg("Failed to update locale")
+		return err
+	}
+	log.Info().Int("userID", userID).Str("locale", locale).Msg("Locale changed")
+	return nil
+}
+
+func (uc *AuthUseCaseImpl) LoginMagicLink(req dto.MagicLinkLoginRequest) (*usecase.LoginResult, error) {
+	if uc.MagicLink == nil {
+		log.Warn().Msg("Magic link login while magic links are not configured")
+		return nil, usecase.ErrInvalidMagicLink
+	}
+	user, err := uc.MagicLink.Consume(req)
+	if err != nil {
+		return nil, err
+	}
+	return uc.completeLogin(user)
+}
+
+// completeLogin завершает первый фактор: выдает пару токенов или MFA токен, если нужен второй фактор
+func (uc *AuthUseCaseImpl) completeLogin(user *model.User) (*usecase.LoginResult, error) {
+	if uc.RequireVerifiedEmail && !user.EmailVerified {
+		log.Warn().Int("userID", user.ID).Msg("Login refused: email not verified")
+		return nil, usecase.ErrEmailNotVerified
+	}
+
+	enabled, required, err := uc.MFA.Status(user)
+	if err != nil {
+		return nil, err
+	}
+	if enabled || required {
+		mfaToken, err := utils.GenerateMFAToken(user.ID)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to generate mfa token")
+			return nil, err
+		}
+		log.Info().Int("userID", user.ID).Bool("enrollmentRequired", !enabled).Msg("Password accepted, second factor required")
+		return &usecase.LoginResult{User: user, MFAToken: mfaToken, MFAEnrollmentRequired: !enabled}, nil
+	}
+
+	access, refresh, err := uc.issueSession(user)
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Int("userID", user.ID).Str("username", user.Username).Msg("User logged in")
+	return &usecase.LoginResult{User: user, AccessToken: access, RefreshToken: refresh}, nil
+}
+
+func (uc *AuthUseCaseImpl) LoginMFA(req dto.MFALoginRequest) (*model.User, string, string, error) {
+	log.Debug().Msg("MFA login attempt")
+
+	userID, err := uc.VerifyMFAToken(req.MFAToken)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to generate access token")
 		return nil, "", "", err
 	}
-	refresh, exp, err := utils.GenerateRefreshToken(user.ID, user.Username, user.Role)
+	user, err := uc.Repo.GetUserByID(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Warn().Int("userID", userID).Msg("MFA token of unknown user")
+		return nil, "", "", usecase.ErrInvalidMFAToken
+	}
+	if err != nil {
+		log.Error().Err(err).Int("userID", userID).Msg("Failed to get user")
+		return nil, "", "", err
+	}
+	enabled, _, err := uc.MFA.Status(user)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to generate refresh token")
 		return nil, "", "", err
 	}
-	if err := uc.Repo.DeleteRefreshTokensByUserID(user.ID); err != nil {
-		log.Error().Err(err).Msg("Failed to delete old refresh tokens")
+	switch {
+	case enabled && len(req.WebAuthnCredential) > 0:
+		err = uc.finishWebAuthn(userID, dto.WebAuthnLoginRequest{SessionToken: req.WebAuthnSession, Credential: req.WebAuthnCredential})
+	case enabled && req.RecoveryCode != "":
+		err = uc.MFA.UseRecoveryCode(userID, req.RecoveryCode)
+	case enabled:
+		err = uc.MFA.VerifyTOTP(userID, req.Code)
+	default:
+		// Привязка во время входа: первый верный код подключает второй фактор
+		err = uc.MFA.ConfirmTOTP(userID, dto.TOTPCodeRequest{Code: req.Code})
+	}
+	if err != nil {
 		return nil, "", "", err
 	}
-	if err := uc.Repo.SaveRefreshToken(&model.RefreshToken{
-		UserID:    user.ID,
-		Token:     refresh,
-		ExpiresAt: exp,
-	}); err != nil {
-		log.Error().Err(err).Msg("Failed to save refresh token")
+
+	access, refresh, err := uc.issueSession(user)
+	if err != nil {
 		return nil, "", "", err
 	}
-	log.Info().Int("userID", user.ID).Str("username", user.Username).Msg("User logged in")
+	log.Info().Int("userID", user.ID).Str("username", user.Username).Msg("User logged in with second factor")
+	return user, access, refresh, nil
+}
+
+func (uc *AuthUseCaseImpl) LoginWebAuthn(req dto.WebAuthnLoginRequest) (*model.User, string, string, error) {
+	log.Debug().Msg("Passkey login attempt")
+
+	if uc.WebAuthn == nil {
+		log.Warn().Msg("Passkey login while webauthn is not configured")
+		return nil, "", "", usecase.ErrInvalidWebAuthnResponse
+	}
+	user, err := uc.WebAuthn.FinishLogin(0, req)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if uc.RequireVerifiedEmail && !user.EmailVerified {
+		log.Warn().Int("userID", user.ID).Msg("Login refused: email not verified")
+		return nil, "", "", usecase.ErrEmailNotVerified
+	}
+
+	access, refresh, err := uc.issueSession(user)
+	if err != nil {
+		return nil, "", "", err
+	}
+	log.Info().Int("userID", user.ID).Str("username", user.Username).Msg("User logged in with passkey")
 	return user, access, refresh, nil
 }
 
+func (uc *AuthUseCaseImpl) finishWebAuthn(userID int, req dto.WebAuthnLoginRequest) error {
+	if uc.WebAuthn == nil {
+		log.Warn().Msg("WebAuthn second factor while webauthn is not configured")
+		return usecase.ErrInvalidWebAuthnResponse
+	}
+	_, err := uc.WebAuthn.FinishLogin(userID, req)
+	return err
+}
+
+func (uc *AuthUseCaseImpl) VerifyMFAToken(token string) (int, error) {
+	claims, err := utils.VerifyToken(token)
+	if err != nil {
+		log.Warn().Err(err).Msg("MFA token verification failed")
+		return 0, usecase.ErrInvalidMFAToken
+	}
+	uid, ok := claims["user_id"].(float64)
+	if typ, _ := claims["typ"].(string); typ != utils.TokenTypeMFA || !ok {
+		log.Warn().Msg("Token is not an mfa token")
+		return 0, usecase.ErrInvalidMFAToken
+	}
+	return int(uid), nil
+}
+
+// issueSession выдает пару токенов и заменяет ими прежние действующие refresh токены пользователя;
+// использованные токены остаются до истечения для обнаружения повторного использования
+func (uc *AuthUseCaseImpl) issueSession(user *model.User) (string, string, error) {
+	access, err := utils.GenerateAccessToken(user.ID, user.Username, user.Role, user.EmailVerified, user.Locale)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate access token")
+		return "", "", err
+	}
+	refresh, exp, err := utils.GenerateRefreshToken(user.ID, user.Username, user.Role)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate refresh token")
+		return "", "", err
+	}
+	err = uc.Repo.WithTx(func(repo repository.AuthRepository) error {
+		if err := repo.DeleteActiveRefreshTokensByUserID(user.ID); err != nil {
+			log.Error().Err(err).Msg("Failed to delete active refresh tokens")
+			return err
+		}
+		if err := repo.SaveRefreshToken(&model.RefreshToken{
+			UserID:    user.ID,
+			Token:     refresh,
+			ExpiresAt: exp,
+		}); err != nil {
+			log.Error().Err(err).Msg("Failed to save refresh token")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return access, refresh, nil
+}
+
 func (uc *AuthUseCaseImpl) RefreshToken(req dto.RefreshRequest) (*model.User, string, string, error) {
 	log.Debug().Msg("Token refresh attempt")
 
 	claims, err := utils.VerifyToken(req.RefreshToken)
 	if err != nil {
 		log.Warn().Err(err).Msg("Refresh token verification failed")
-		return nil, "", "", err
+		return nil, "", "", usecase.ErrInvalidRefreshToken
 	}
 	uid, ok := claims["user_id"].(float64)
-	username, ok1 := claims["username"].(string)
-	role, ok2 := claims["role"].(string)
-	if !ok || !ok1 || !ok2 {
+	if !ok {
 		log.Warn().Msg("Invalid token data")
 		return nil, "", "", usecase.ErrInvalidTokenData
 	}
 	userID := int(uid)
 
-	rt, err := uc.Repo.GetRefreshToken(req.RefreshToken)
-	if err != nil || rt.UserID != userID || time.Now().After(rt.ExpiresAt) {
-		log.Warn().Err(err).Msg("Invalid or expired refresh token")
+	// Claims нового access токена берутся из БД: роль и подтверждение почты могли измениться
+	user, err := uc.Repo.GetUserByID(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Warn().Int("userID", userID).Msg("Refresh token of unknown user")
 		return nil, "", "", usecase.ErrInvalidRefreshToken
 	}
-	if err := uc.Repo.DeleteRefreshToken(req.RefreshToken); err != nil {
-		log.Error().Err(err).Msg("Failed to delete used refresh token")
+	if err != nil {
+		log.Error().Err(err).Int("userID", userID).Msg("Failed to get user")
 		return nil, "", "", err
 	}
+	if uc.RequireVerifiedEmail && !user.EmailVerified {
+		log.Warn().Int("userID", userID).Msg("Refresh refused: email not verified")
+		return nil, "", "", usecase.ErrEmailNotVerified
+	}
 
-	newAccess, err := utils.GenerateAccessToken(userID, username, role)
+	newAccess, err := utils.GenerateAccessToken(user.ID, user.Username, user.Role, user.EmailVerified, user.Locale)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to generate new access token")
 		return nil, "", "", err
 	}
-	newRefresh, newExp, err := utils.GenerateRefreshToken(userID, username, role)
+	newRefresh, newExp, err := utils.GenerateRefreshToken(user.ID, user.Username, user.Role)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to generate new refresh token")
 		return nil, "", "", err
 	}
-	if err := uc.Repo.SaveRefreshToken(&model.RefreshToken{
-		UserID:    userID,
-		Token:     newRefresh,
-		ExpiresAt: newExp,
-	}); err != nil {
-		log.Error().Err(err).Msg("Failed to save new refresh token")
+
+	reused := false
+	err = uc.Repo.WithTx(func(repo repository.AuthRepository) error {
+		rt, err := repo.ConsumeRefreshToken(req.RefreshToken)
+		if errors.Is(err, repository.ErrConflict) && rt.UserID == userID {
+			// Токен мог быть украден: отзываются все сессии, и вору, и владельцу придется войти заново.
+			// Транзакция фиксируется, чтобы отзыв не разошелся с проверкой токена
+			log.Warn().Int("userID", userID).Msg("Refresh token reused, revoking all refresh tokens of the user")
+			if err := repo.DeleteActiveRefreshTokensByUserID(userID); err != nil {
+				log.Error().Err(err).Int("userID", userID).Msg("Failed to revoke refresh tokens")
+				return err
+			}
+			reused = true
+			return nil
+		}
+		if err != nil && !errors.Is(err, repository.ErrNotFound) && !errors.Is(err, repository.ErrConflict) {
+			log.Error().Err(err).Msg("Failed to consume refresh token")
+			return err
+		}
+		if err != nil || rt.UserID != userID || time.Now().After(rt.ExpiresAt) {
+			log.Warn().Err(err).Msg("Invalid or expired refresh token")
+			return usecase.ErrInvalidRefreshToken
+		}
+		if err := repo.SaveRefreshToken(&model.RefreshToken{
+			UserID:    userID,
+			Token:     newRefresh,
+			ExpiresAt: newExp,
+		}); err != nil {
+			log.Error().Err(err).Msg("Failed to save new refresh token")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, "", "", err
 	}
+	if reused {
+		return nil, "", "", usecase.ErrRefreshTokenReused
+	}
 
 	log.Info().Int("userID", userID).Msg("Refresh token successful")
-	return &model.User{ID: userID, Username: username, Role: role}, newAccess, newRefresh, nil
+	return user, newAccess, newRefresh, nil
+}
+
+func (uc *AuthUseCaseImpl) ChangePassword(userID int, req dto.ChangePasswordRequest) (*model.User, string, string, error) {
+	log.Debug().Int("userID", userID).Msg("Password change attempt")
+
+	user, err := uc.Repo.GetUserByID(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Warn().Int("userID", userID).Msg("Password change for unknown user")
+		return nil, "", "", usecase.ErrInvalidCredentials
+	}
+	if err != nil {
+		log.Error().Err(err).Int("userID", userID).Msg("Failed to get user")
+		return nil, "", "", err
+	}
+	if ok, _ := verifyPassword(uc.Hasher, user.Password, req.CurrentPassword); !ok {
+		log.Warn().Int("userID", userID).Msg("Invalid current password")
+		return nil, "", "", usecase.ErrInvalidCredentials
+	}
+	if err := checkPasswordPolicy(uc.PasswordPolicy, req.NewPassword, user.Username, "new_password"); err != nil {
+		return nil, "", "", err
+	}
+	hashed, err := uc.Hasher.Hash(req.NewPassword)
+	if err != nil {
+		log.Error().Err(err).Msg("Password hashing failed")
+		return nil, "", "", err
+	}
+
+	// Токены, выпущенные раньше этого момента, считаются отозванными; новая пара выпускается позже и остается валидной
+	revokedBefore := time.Now().Truncate(time.Millisecond)
+	access, err := utils.GenerateAccessToken(user.ID, user.Username, user.Role, user.EmailVerified, user.Locale)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate access token")
+		return nil, "", "", err
+	}
+	refresh, exp, err := utils.GenerateRefreshToken(user.ID, user.Username, user.Role)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate refresh token")
+		return nil, "", "", err
+	}
+
+	err = uc.Repo.WithTx(func(repo repository.AuthRepository) error {
+		if err := repo.UpdatePassword(user.ID, hashed); err != nil {
+			log.Error().Err(err).Msg("Failed to update password")
+			return err
+		}
+		if err := revokeSessions(repo, user.ID, revokedBefore); err != nil {
+			log.Error().Err(err).Msg("Failed to revoke sessions")
+			return err
+		}
+		if err := repo.SaveRefreshToken(&model.RefreshToken{
+			UserID:    user.ID,
+			Token:     refresh,
+			ExpiresAt: exp,
+		}); err != nil {
+			log.Error().Err(err).Msg("Failed to save refresh token")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	log.Info().Int("userID", user.ID).Msg("Password changed, other sessions revoked")
+	return user, access, refresh, nil
+}
+
+func (uc *AuthUseCaseImpl) VerifyAccessToken(token string) (jwt.MapClaims, error) {
+	claims, err := utils.VerifyToken(token)
+	if err != nil {
+		log.Warn().Err(err).Msg("Access token verification failed")
+		return nil, usecase.ErrInvalidAccessToken
+	}
+	// Токены, выпущенные до появления claim "typ", принимаются как access
+	if typ, ok := claims["typ"].(string); ok && typ != utils.TokenTypeAccess {
+		log.Warn().Str("typ", typ).Msg("Token is not an access token")
+		return nil, usecase.ErrInvalidAccessToken
+	}
+	uid, ok := claims["user_id"].(float64)
+	iat, ok1 := claims["iat"].(float64)
+	if !ok || !ok1 {
+		log.Warn().Msg("Invalid token data")
+		return nil, usecase.ErrInvalidTokenData
+	}
+
+	entry, err := uc.Repo.GetDenylistEntry(int(uid))
+	if errors.Is(err, repository.ErrNotFound) {
+		return claims, nil
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to check access token denylist")
+		return nil, err
+	}
+	if iat < float64(entry.RevokedBefore.UnixMilli())/1000 {
+		log.Warn().Int("userID", int(uid)).Msg("Access token revoked")
+		return nil, usecase.ErrTokenRevoked
+	}
+	return claims, nil
 }
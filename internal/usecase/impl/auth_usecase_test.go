@@ -3,24 +3,37 @@ package usecase
 import (
 	"errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"golang.org/x/crypto/bcrypt"
 	"sstu-go-forum-auth-service/internal/dto"
 	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/password"
+	"sstu-go-forum-auth-service/internal/repository"
+	"sstu-go-forum-auth-service/internal/repository/memory"
 	"sstu-go-forum-auth-service/internal/repository/mocks"
+	"sstu-go-forum-auth-service/internal/timingtest"
 	"sstu-go-forum-auth-service/internal/usecase"
 	"sstu-go-forum-auth-service/internal/utils"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
+func expectTx(mockRepo *mocks.MockAuthRepository) *gomock.Call {
+	return mockRepo.EXPECT().WithTx(gomock.Any()).DoAndReturn(func(fn func(repository.AuthRepository) error) error {
+		return fn(mockRepo)
+	})
+}
+
 func TestRegister_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockRepo := mocks.NewMockAuthRepository(ctrl)
 	uc := NewAuthUseCase(mockRepo)
 	user := &model.User{Username: "user", Password: "password", Role: "USER"}
-	mockRepo.EXPECT().GetUserByUsername("user").Return(nil, nil)
+	mockRepo.EXPECT().GetLookalikeUser("user").Return(nil, repository.ErrNotFound)
 	mockRepo.EXPECT().CreateUser(user).DoAndReturn(func(u *model.User) error { u.ID = 1; return nil })
 
 	created, err := uc.Register(user)
@@ -35,10 +48,46 @@ func TestRegister_ExistingUser(t *testing.T) {
 	mockRepo := mocks.NewMockAuthRepository(ctrl)
 	uc := NewAuthUseCase(mockRepo)
 	user := &model.User{Username: "user", Password: "password", Role: "USER"}
-	mockRepo.EXPECT().GetUserByUsername("user").Return(&model.User{}, nil)
+	mockRepo.EXPECT().GetLookalikeUser("user").Return(nil, repository.ErrNotFound)
+	mockRepo.EXPECT().CreateUser(user).Return(repository.ErrConflict)
+
+	_, err := uc.Register(user)
+
+	assert.ErrorIs(t, err, usecase.ErrUserAlreadyExists)
+}
+
+func TestRegister_RepositoryError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := mocks.NewMockAuthRepository(ctrl)
+	uc := NewAuthUseCase(mockRepo)
+	user := &model.User{Username: "user", Password: "password", Role: "USER"}
+	dbErr := errors.New("connection refused")
+	mockRepo.EXPECT().GetLookalikeUser("user").Return(nil, repository.ErrNotFound)
+	mockRepo.EXPECT().CreateUser(user).Return(dbErr)
 
 	_, err := uc.Register(user)
 
+	assert.ErrorIs(t, err, dbErr)
+	assert.NotErrorIs(t, err, usecase.ErrUserAlreadyExists)
+}
+
+func TestRegister_ReservedAndConfusableUsernames(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := mocks.NewMockAuthRepository(ctrl)
+	uc := NewAuthUseCase(mockRepo)
+
+	// "Аdmin" с кириллической "А"
+	_, err := uc.Register(&model.User{Username: " \u0410dmin ", Password: "password", Role: "USER"})
+	assert.ErrorIs(t, err, usecase.ErrUsernameReserved)
+
+	mockRepo.EXPECT().GetLookalikeUser("forum.user").Return(&model.User{ID: 1, Username: "forum_user"}, nil)
+	_, err = uc.Register(&model.User{Username: "forum.user", Password: "password", Role: "USER"})
+	assert.ErrorIs(t, err, usecase.ErrUsernameConfusable)
+
+	mockRepo.EXPECT().GetLookalikeUser("Forum_User").Return(&model.User{ID: 1, Username: "forum_user"}, nil)
+	_, err = uc.Register(&model.User{Username: "Forum_User", Password: "password", Role: "USER"})
 	assert.ErrorIs(t, err, usecase.ErrUserAlreadyExists)
 }
 
@@ -46,40 +95,92 @@ func TestLogin_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockRepo := mocks.NewMockAuthRepository(ctrl)
-	hash, _ := bcrypt.GenerateFromPassword([]byte("p"), bcrypt.DefaultCost)
-	mockRepo.EXPECT().GetUserByUsername("u").Return(&model.User{ID: 1, Username: "u", Password: string(hash), Role: "r"}, nil)
-	mockRepo.EXPECT().DeleteRefreshTokensByUserID(1).Return(nil)
+	hash, _ := password.Default().Hash("p")
+	mockRepo.EXPECT().GetUserByUsername("u").Return(&model.User{ID: 1, Username: "u", Password: hash, Role: "r"}, nil)
+	mockRepo.EXPECT().GetTOTP(1).Return(nil, repository.ErrNotFound)
+	mockRepo.EXPECT().ListWebAuthnCredentials(1).Return(nil, nil)
+	expectTx(mockRepo)
+	mockRepo.EXPECT().DeleteActiveRefreshTokensByUserID(1).Return(nil)
 	mockRepo.EXPECT().SaveRefreshToken(gomock.Any()).Return(nil)
 
 	uc := NewAuthUseCase(mockRepo)
-	user, access, refresh, err := uc.Login(dto.LoginRequest{Username: "u", Password: "p"})
+	result, err := uc.Login(dto.LoginRequest{Username: "u", Password: "p"})
 
 	assert.NoError(t, err)
-	assert.Equal(t, 1, user.ID)
-	assert.NotEmpty(t, access)
-	assert.NotEmpty(t, refresh)
+	assert.Equal(t, 1, result.User.ID)
+	assert.NotEmpty(t, result.AccessToken)
+	assert.NotEmpty(t, result.RefreshToken)
+	assert.Empty(t, result.MFAToken)
+}
+
+func TestLogin_SaveFailsInsideTx(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := mocks.NewMockAuthRepository(ctrl)
+	hash, _ := password.Default().Hash("p")
+	saveErr := errors.New("insert failed")
+	mockRepo.EXPECT().GetUserByUsername("u").Return(&model.User{ID: 1, Username: "u", Password: hash, Role: "r"}, nil)
+	mockRepo.EXPECT().GetTOTP(1).Return(nil, repository.ErrNotFound)
+	mockRepo.EXPECT().ListWebAuthnCredentials(1).Return(nil, nil)
+	expectTx(mockRepo)
+	mockRepo.EXPECT().DeleteActiveRefreshTokensByUserID(1).Return(nil)
+	mockRepo.EXPECT().SaveRefreshToken(gomock.Any()).Return(saveErr)
+
+	uc := NewAuthUseCase(mockRepo)
+	_, err := uc.Login(dto.LoginRequest{Username: "u", Password: "p"})
+
+	assert.ErrorIs(t, err, saveErr)
+}
+
+func TestLogin_UnverifiedEmailRefused(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := mocks.NewMockAuthRepository(ctrl)
+	hash, _ := password.Default().Hash("p")
+	mockRepo.EXPECT().GetUserByUsername("u").Return(&model.User{ID: 1, Username: "u", Password: hash, Role: "USER", Email: "u@example.com"}, nil)
+
+	uc := NewAuthUseCase(mockRepo)
+	uc.RequireVerifiedEmail = true
+	_, err := uc.Login(dto.LoginRequest{Username: "u", Password: "p"})
+
+	assert.ErrorIs(t, err, usecase.ErrEmailNotVerified)
 }
 
 func TestLogin_InvalidCredentials(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockRepo := mocks.NewMockAuthRepository(ctrl)
-	mockRepo.EXPECT().GetUserByUsername("u").Return(nil, errors.New("not found"))
+	mockRepo.EXPECT().GetUserByUsername("u").Return(nil, repository.ErrNotFound)
 
 	uc := NewAuthUseCase(mockRepo)
-	_, _, _, err := uc.Login(dto.LoginRequest{Username: "u", Password: "p"})
+	_, err := uc.Login(dto.LoginRequest{Username: "u", Password: "p"})
 
 	assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
 }
 
+func TestLogin_RepositoryError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := mocks.NewMockAuthRepository(ctrl)
+	dbErr := errors.New("connection refused")
+	mockRepo.EXPECT().GetUserByUsername("u").Return(nil, dbErr)
+
+	uc := NewAuthUseCase(mockRepo)
+	_, err := uc.Login(dto.LoginRequest{Username: "u", Password: "p"})
+
+	assert.ErrorIs(t, err, dbErr)
+	assert.NotErrorIs(t, err, usecase.ErrInvalidCredentials)
+}
+
 func TestRefreshToken_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockRepo := mocks.NewMockAuthRepository(ctrl)
 
 	token, exp, _ := utils.GenerateRefreshToken(1, "u", "r")
-	mockRepo.EXPECT().GetRefreshToken(token).Return(&model.RefreshToken{UserID: 1, Token: token, ExpiresAt: exp}, nil)
-	mockRepo.EXPECT().DeleteRefreshToken(token).Return(nil)
+	mockRepo.EXPECT().GetUserByID(1).Return(&model.User{ID: 1, Username: "u", Role: "r"}, nil)
+	expectTx(mockRepo)
+	mockRepo.EXPECT().ConsumeRefreshToken(token).Return(&model.RefreshToken{UserID: 1, Token: token, ExpiresAt: exp}, nil)
 	mockRepo.EXPECT().SaveRefreshToken(gomock.Any()).Return(nil)
 
 	uc := NewAuthUseCase(mockRepo)
@@ -97,7 +198,9 @@ func TestRefreshToken_Expired(t *testing.T) {
 	mockRepo := mocks.NewMockAuthRepository(ctrl)
 
 	token, _, _ := utils.GenerateRefreshToken(1, "u", "r")
-	mockRepo.EXPECT().GetRefreshToken(token).Return(&model.RefreshToken{
+	mockRepo.EXPECT().GetUserByID(1).Return(&model.User{ID: 1, Username: "u", Role: "r"}, nil)
+	expectTx(mockRepo)
+	mockRepo.EXPECT().ConsumeRefreshToken(token).Return(&model.RefreshToken{
 		UserID:    1,
 		Token:     token,
 		ExpiresAt: time.Now().Add(-time.Hour),
@@ -108,3 +211,301 @@ func TestRefreshToken_Expired(t *testing.T) {
 
 	assert.ErrorIs(t, err, usecase.ErrInvalidRefreshToken)
 }
+
+func TestRefreshToken_AlreadyUsed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := mocks.NewMockAuthRepository(ctrl)
+
+	token, _, _ := utils.GenerateRefreshToken(1, "u", "r")
+	mockRepo.EXPECT().GetUserByID(1).Return(&model.User{ID: 1, Username: "u", Role: "r"}, nil)
+	expectTx(mockRepo)
+	mockRepo.EXPECT().ConsumeRefreshToken(token).Return(nil, repository.ErrNotFound)
+
+	uc := NewAuthUseCase(mockRepo)
+	_, _, _, err := uc.RefreshToken(dto.RefreshRequest{RefreshToken: token})
+
+	assert.ErrorIs(t, err, usecase.ErrInvalidRefreshToken)
+}
+
+func TestRefreshToken_UsesCurrentUserData(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := mocks.NewMockAuthRepository(ctrl)
+
+	token, exp, _ := utils.GenerateRefreshToken(1, "u", "USER")
+	mockRepo.EXPECT().GetUserByID(1).Return(&model.User{ID: 1, Username: "u", Role: "ADMIN", EmailVerified: true}, nil)
+	expectTx(mockRepo)
+	mockRepo.EXPECT().ConsumeRefreshToken(token).Return(&model.RefreshToken{UserID: 1, Token: token, ExpiresAt: exp}, nil)
+	mockRepo.EXPECT().SaveRefreshToken(gomock.Any()).Return(nil)
+
+	uc := NewAuthUseCase(mockRepo)
+	_, access, _, err := uc.RefreshToken(dto.RefreshRequest{RefreshToken: token})
+
+	assert.NoError(t, err)
+	claims, err := utils.VerifyToken(access)
+	assert.NoError(t, err)
+	assert.Equal(t, "ADMIN", claims["role"])
+	assert.Equal(t, true, claims["email_verified"])
+}
+
+func TestRefreshToken_UnverifiedEmailRefused(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := mocks.NewMockAuthRepository(ctrl)
+
+	token, _, _ := utils.GenerateRefreshToken(1, "u", "USER")
+	mockRepo.EXPECT().GetUserByID(1).Return(&model.User{ID: 1, Username: "u", Role: "USER", Email: "u@example.com"}, nil)
+
+	uc := NewAuthUseCase(mockRepo)
+	uc.RequireVerifiedEmail = true
+	_, _, _, err := uc.RefreshToken(dto.RefreshRequest{RefreshToken: token})
+
+	assert.ErrorIs(t, err, usecase.ErrEmailNotVerified)
+}
+
+func TestRefreshToken_ConcurrentRotationSucceedsOnce(t *testing.T) {
+	repo := memory.NewRepository()
+	user := &model.User{Username: "u", Password: "hash", Role: "USER"}
+	assert.NoError(t, repo.CreateUser(user))
+	token, exp, _ := utils.GenerateRefreshToken(user.ID, user.Username, user.Role)
+	assert.NoError(t, repo.SaveRefreshToken(&model.RefreshToken{UserID: user.ID, Token: token, ExpiresAt: exp}))
+	uc := NewAuthUseCase(repo)
+
+	const attempts = 20
+	var wg sync.WaitGroup
+	type result struct {
+		refresh string
+		err     error
+	}
+	results := make(chan result, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, refresh, err := uc.RefreshToken(dto.RefreshRequest{RefreshToken: token})
+			results <- result{refresh, err}
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	var rotated []string
+	for r := range results {
+		if r.err == nil {
+			rotated = append(rotated, r.refresh)
+			continue
+		}
+		assert.ErrorIs(t, r.err, usecase.ErrRefreshTokenReused)
+	}
+	require.Len(t, rotated, 1)
+	// Повторное предъявление отозвало и токен победителя, а использованный токен остался для обнаружения
+	_, err := repo.ConsumeRefreshToken(rotated[0])
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.ConsumeRefreshToken(token)
+	assert.ErrorIs(t, err, repository.ErrConflict)
+}
+
+func TestRefreshToken_ReuseDetectedAfterNewLogin(t *testing.T) {
+	repo := memory.NewRepository()
+	uc := NewAuthUseCase(repo)
+	_, err := uc.Register(&model.User{Username: "user", Password: "password"})
+	require.NoError(t, err)
+	first, err := uc.Login(dto.LoginRequest{Username: "user", Password: "password"})
+	require.NoError(t, err)
+	_, _, _, err = uc.RefreshToken(dto.RefreshRequest{RefreshToken: first.RefreshToken})
+	require.NoError(t, err)
+
+	// Вход на другом устройстве заменяет действующие токены, но не стирает использованные
+	_, err = uc.Login(dto.LoginRequest{Username: "user", Password: "password"})
+	require.NoError(t, err)
+	_, _, _, err = uc.RefreshToken(dto.RefreshRequest{RefreshToken: first.RefreshToken})
+	assert.ErrorIs(t, err, usecase.ErrRefreshTokenReused)
+}
+
+func TestChangePassword_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := mocks.NewMockAuthRepository(ctrl)
+	hash, _ := password.Default().Hash("old-password")
+	mockRepo.EXPECT().GetUserByID(1).Return(&model.User{ID: 1, Username: "u", Password: hash, Role: "USER"}, nil)
+	expectTx(mockRepo)
+	mockRepo.EXPECT().UpdatePassword(1, gomock.Any()).DoAndReturn(func(_ int, newHash string) error {
+		ok, _, err := password.Default().Verify(newHash, "new-password")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		return nil
+	})
+	mockRepo.EXPECT().DeleteActiveRefreshTokensByUserID(1).Return(nil)
+	mockRepo.EXPECT().SaveDenylistEntry(gomock.Any()).DoAndReturn(func(e *model.DenylistEntry) error {
+		assert.Equal(t, 1, e.UserID)
+		assert.Equal(t, utils.AccessTokenTTL, e.ExpiresAt.Sub(e.RevokedBefore))
+		return nil
+	})
+	mockRepo.EXPECT().SaveRefreshToken(gomock.Any()).Return(nil)
+
+	uc := NewAuthUseCase(mockRepo)
+	user, access, refresh, err := uc.ChangePassword(1, dto.ChangePasswordRequest{CurrentPassword: "old-password", NewPassword: "new-password"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+	assert.NotEmpty(t, access)
+	assert.NotEmpty(t, refresh)
+}
+
+func TestChangePassword_WrongCurrentPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := mocks.NewMockAuthRepository(ctrl)
+	hash, _ := password.Default().Hash("old-password")
+	mockRepo.EXPECT().GetUserByID(1).Return(&model.User{ID: 1, Username: "u", Password: hash, Role: "USER"}, nil)
+
+	uc := NewAuthUseCase(mockRepo)
+	_, _, _, err := uc.ChangePassword(1, dto.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "new-password"})
+
+	assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+}
+
+func TestChangePassword_WeakNewPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := mocks.NewMockAuthRepository(ctrl)
+	hash, _ := password.Default().Hash("old-password")
+	mockRepo.EXPECT().GetUserByID(1).Return(&model.User{ID: 1, Username: "u", Password: hash, Role: "USER"}, nil)
+
+	uc := NewAuthUseCase(mockRepo)
+	_, _, _, err := uc.ChangePassword(1, dto.ChangePasswordRequest{CurrentPassword: "old-password", NewPassword: "123"})
+
+	assert.ErrorIs(t, err, usecase.ErrInvalidNewPassword)
+}
+
+func TestVerifyAccessToken_Revoked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := mocks.NewMockAuthRepository(ctrl)
+	token, _ := utils.GenerateAccessToken(1, "u", "USER", true, "")
+	mockRepo.EXPECT().GetDenylistEntry(1).Return(&model.DenylistEntry{UserID: 1, RevokedBefore: time.Now().Add(time.Second)}, nil)
+
+	uc := NewAuthUseCase(mockRepo)
+	_, err := uc.VerifyAccessToken(token)
+
+	assert.ErrorIs(t, err, usecase.ErrTokenRevoked)
+}
+
+func TestVerifyAccessToken_IssuedAfterRevocation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := mocks.NewMockAuthRepository(ctrl)
+	revokedBefore := time.Now().Truncate(time.Millisecond)
+	token, _ := utils.GenerateAccessToken(1, "u", "USER", true, "")
+	mockRepo.EXPECT().GetDenylistEntry(1).Return(&model.DenylistEntry{UserID: 1, RevokedBefore: revokedBefore}, nil)
+
+	uc := NewAuthUseCase(mockRepo)
+	claims, err := uc.VerifyAccessToken(token)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "u", claims["username"])
+}
+
+func TestVerifyAccessToken_RejectsRefreshToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := mocks.NewMockAuthRepository(ctrl)
+	token, _, _ := utils.GenerateRefreshToken(1, "u", "USER")
+
+	uc := NewAuthUseCase(mockRepo)
+	_, err := uc.VerifyAccessToken(token)
+
+	assert.ErrorIs(t, err, usecase.ErrInvalidAccessToken)
+}
+
+func TestLogin_UnknownUserTakesAsLongAsWrongPassword(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing test")
+	}
+	repo := memory.NewRepository()
+	legacy, err := bcrypt.GenerateFromPassword([]byte("secret1"), bcrypt.DefaultCost)
+	require.NoError(t, err)
+	current, err := password.Default().Hash("secret1")
+	require.NoError(t, err)
+	// Старые аккаунты хранят bcrypt-хеши, новые — argon2id: ни тот, ни другой не должен отличаться от отсутствия аккаунта
+	require.NoError(t, repo.CreateUser(&model.User{Username: "legacy", Password: string(legacy), Role: "USER"}))
+	require.NoError(t, repo.CreateUser(&model.User{Username: "current", Password: current, Role: "USER"}))
+	uc := NewAuthUseCase(repo)
+
+	for _, username := range []string{"legacy", "current"} {
+		known, unknown := timingtest.Sample(30,
+			func() { _, _ = uc.Login(dto.LoginRequest{Username: username, Password: "wrong"}) },
+			func() { _, _ = uc.Login(dto.LoginRequest{Username: "ghost", Password: "wrong"}) },
+		)
+		timingtest.AssertIndistinguishable(t, known, unknown)
+	}
+}
+
+// С перцем аккаунты с хешами без перца проверяются другой веткой и пересчитываются при входе,
+// но время неверного пароля у них тоже не должно отличаться от отсутствия аккаунта
+func TestLogin_UnknownUserTimingWithPepper(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing test")
+	}
+	repo := memory.NewRepository()
+	uc := NewAuthUseCase(repo)
+	var err error
+	uc.Hasher, err = password.WithPepper(password.Default(), map[int][]byte{1: []byte("first-pepper-secret")}, 1)
+	require.NoError(t, err)
+	unpeppered, err := bcrypt.GenerateFromPassword([]byte("secret1"), bcrypt.DefaultCost)
+	require.NoError(t, err)
+	peppered, err := uc.Hasher.Hash("secret1")
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateUser(&model.User{Username: "unpeppered", Password: string(unpeppered), Role: "USER"}))
+	require.NoError(t, repo.CreateUser(&model.User{Username: "peppered", Password: peppered, Role: "USER"}))
+
+	for _, username := range []string{"unpeppered", "peppered"} {
+		known, unknown := timingtest.Sample(30,
+			func() { _, _ = uc.Login(dto.LoginRequest{Username: username, Password: "wrong"}) },
+			func() { _, _ = uc.Login(dto.LoginRequest{Username: "ghost", Password: "wrong"}) },
+		)
+		timingtest.AssertIndistinguishable(t, known, unknown)
+	}
+}
+
+func TestLogin_UpgradesLegacyHash(t *testing.T) {
+	repo := memory.NewRepository()
+	legacy, err := bcrypt.GenerateFromPassword([]byte("secret1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &model.User{Username: "user", Password: string(legacy), Role: "USER"}
+	require.NoError(t, repo.CreateUser(user))
+	uc := NewAuthUseCase(repo)
+
+	_, err = uc.Login(dto.LoginRequest{Username: "user", Password: "wrong"})
+	require.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+	stored, err := repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(legacy), stored.Password, "a wrong password does not rehash")
+
+	_, err = uc.Login(dto.LoginRequest{Username: "user", Password: "secret1"})
+	require.NoError(t, err)
+	stored, err = repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored.Password, "$argon2id$"), stored.Password)
+	_, err = uc.Login(dto.LoginRequest{Username: "user", Password: "secret1"})
+	assert.NoError(t, err, "the upgraded hash still matches")
+}
+
+func TestLogin_MigratesToNewPepper(t *testing.T) {
+	repo := memory.NewRepository()
+	uc := NewAuthUseCase(repo)
+	uc.Hasher, _ = password.WithPepper(password.Default(), map[int][]byte{1: []byte("first-pepper-secret")}, 1)
+	user, err := uc.Register(&model.User{Username: "user", Password: "secret1", Role: "USER"})
+	require.NoError(t, err)
+
+	uc.Hasher, _ = password.WithPepper(password.Default(), map[int][]byte{
+		1: []byte("first-pepper-secret"),
+		2: []byte("second-pepper-secret"),
+	}, 2)
+	_, err = uc.Login(dto.LoginRequest{Username: "user", Password: "secret1"})
+	require.NoError(t, err)
+	stored, err := repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored.Password, "$pepper$v=2$"), stored.Password)
+}
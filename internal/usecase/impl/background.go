@@ -0,0 +1,8 @@
+package usecase
+
+// runInBackground — Background по умолчанию. Запросы писем по имени пользователя ищут аккаунт и сразу отвечают,
+// а токен и письмо готовятся в фоне: так ответ для существующего аккаунта не дольше, чем для несуществующего,
+// даже если запись в БД заняла больше UniformResponseTime, и ошибка отправки не выдает, что аккаунт есть
+func runInBackground(task func()) {
+	go task()
+}
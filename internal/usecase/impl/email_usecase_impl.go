@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/i18n"
+	"sstu-go-forum-auth-service/internal/mailer"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/password"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository"
+	"sstu-go-forum-auth-service/internal/usecase"
+	"sstu-go-forum-auth-service/internal/utils"
+)
+
+type EmailUseCaseImpl struct {
+	Repo   repository.AuthRepository
+	Mailer mailer.Mailer
+	// AccountLimiter ограничивает повторные письма на один аккаунт
+	AccountLimiter ratelimit.Limiter
+	// VerifyURL — страница фронтенда, к которой добавляется параметр token
+	VerifyURL string
+	TokenTTL  time.Duration
+	Hasher    password.PasswordHasher
+	// Background выполняет работу с найденным аккаунтом после ответа, см. runInBackground
+	Background func(task func())
+}
+
+func NewEmailUseCase(repo repository.AuthRepository, m mailer.Mailer, accountLimiter ratelimit.Limiter, verifyURL string, tokenTTL time.Duration) *EmailUseCaseImpl {
+	log.Info().Msg("EmailUseCaseImpl initialized")
+	return &EmailUseCaseImpl{
+		Repo:           repo,
+		Mailer:         m,
+		AccountLimiter: accountLimiter,
+		VerifyURL:      verifyURL,
+		TokenTTL:       tokenTTL,
+		Hasher:         password.Default(),
+		Background:     runInBackground,
+	}
+}
+
+func (uc *EmailUseCaseImpl) SendVerification(userID int) error {
+	user, err := uc.Repo.GetUserByID(userID)
+	if err != nil {
+		log.Error().Err(err).Int("userID", userID).Msg("Failed to get user")
+		return err
+	}
+	return uc.sendVerification(user)
+}
+
+func (uc *EmailUseCaseImpl) ResendVerification(req dto.ResendVerificationRequest) error {
+	log.Debug().Str("username", req.Username).Msg("Verification email resend requested")
+
+	user, err := uc.Repo.GetUserByUsername(req.Username)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Info().Str("username", req.Username).Msg("Verification resend for unknown user ignored")
+		return nil
+	}
+	if err != nil {
+		log.Error().Err(err).Str("username", req.Username).Msg("Failed to get user")
+		return err
+	}
+	// Ошибки отправки уже залогированы в sendVerification, а ответ к этому времени отдан
+	uc.Background(func() {
+		if ok, _ := uc.AccountLimiter.Allow(strconv.Itoa(user.ID)); !ok {
+			log.Warn().Int("userID", user.ID).Msg("Verification resend throttled for account")
+			return
+		}
+		_ = uc.sendVerification(user)
+	})
+	return nil
+}
+
+func (uc *EmailUseCaseImpl) VerifyEmail(req dto.VerifyEmailRequest) error {
+	log.Debug().Msg("Email verification attempt")
+
+	var userID int
+	err := uc.Repo.WithTx(func(repo repository.AuthRepository) error {
+		t, err := repo.ConsumeEmailVerificationToken(utils.HashToken(req.Token))
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			log.Error().Err(err).Msg("Failed to consume verification token")
+			return err
+		}
+		if err != nil || time.Now().After(t.ExpiresAt) {
+			log.Warn().Msg("Invalid, expired or already used verification token")
+			return usecase.ErrInvalidVerificationToken
+		}
+		userID = t.UserID
+		// Токен подтверждает адрес, на который был отправлен: после смены почты он не действует
+		err = repo.MarkEmailVerified(t.UserID, t.Email)
+		if errors.Is(err, repository.ErrNotFound) {
+			log.Warn().Int("userID", t.UserID).Msg("Verification token issued for a previous email")
+			return usecase.ErrInvalidVerificationToken
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to mark email verified")
+			return err
+		}
+		if err := repo.DeleteEmailVerificationTokensByUserID(t.UserID); err != nil {
+			log.Error().Err(err).Msg("Failed to delete verification tokens")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Info().Int("userID", userID).Msg("Email verified")
+	return nil
+}
+
+func (uc *EmailUseCaseImpl) ChangeEmail(userID int, req dto.ChangeEmailRequest) error {
+	log.Debug().Int("userID", userID).Msg("Email change attempt")
+
+	user, err := uc.Repo.GetUserByID(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Warn().Int("userID", userID).Msg("Email change for unknown user")
+		return usecase.ErrInvalidCredentials
+	}
+	if err != nil {
+		log.Error().Err(err).Int("userID", userID).Msg("Failed to get user")
+		return err
+	}
+	if ok, _ := verifyPassword(uc.Hasher, user.Password, req.CurrentPassword); !ok {
+		log.Warn().Int("userID", userID).Msg("Invalid current password")
+		return usecase.ErrInvalidCredentials
+	}
+	email := model.NormalizeEmail(req.Email)
+	if err := model.ValidateEmail(email); err != nil {
+		log.Warn().Str("code", err.Code).Int("userID", userID).Msg("New email validation failed")
+		return &model.ValidationError{Err: usecase.ErrInvalidEmail, Fields: []model.FieldError{*err}}
+	}
+	if email == user.Email {
+		return uc.sendVerification(user)
+	}
+
+	err = uc.Repo.WithTx(func(repo repository.AuthRepository) error {
+		err := repo.UpdateEmail(user.ID, email)
+		if errors.Is(err, repository.ErrConflict) {
+			log.Warn().Int("userID", userID).Msg("Email already in use")
+			return usecase.ErrEmailTaken
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to update email")
+			return err
+		}
+		if err := repo.DeleteEmailVerificationTokensByUserID(user.ID); err != nil {
+			log.Error().Err(err).Msg("Failed to delete verification tokens")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Info().Int("userID", user.ID).Msg("Email changed, verification required")
+
+	if user.Email != "" {
+		if err := uc.Mailer.Send(mailer.Message{
+			To:      user.Email,
+			Subject: i18n.T(user.Locale, "mail.email_changed.subject"),
+			Body:    i18n.T(user.Locale, "mail.email_changed.body", user.Username, email),
+		}); err != nil {
+			log.Error().Err(err).Int("userID", user.ID).Msg("Failed to notify previous email")
+		}
+	}
+	user.Email = email
+	user.EmailVerified = false
+	return uc.sendVerification(user)
+}
+
+func (uc *EmailUseCaseImpl) sendVerification(user *model.User) error {
+	if user.Email == "" || user.EmailVerified {
+		log.Info().Int("userID", user.ID).Msg("Verification email not needed")
+		return nil
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate verification token")
+		return err
+	}
+	if err := uc.Repo.SaveEmailVerificationToken(&model.EmailVerificationToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(uc.TokenTTL),
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to save verification token")
+		return err
+	}
+
+	link := uc.VerifyURL + "?" + url.Values{"token": {token}}.Encode()
+	if err := uc.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: i18n.T(user.Locale, "mail.verify_email.subject"),
+		Body:    i18n.T(user.Locale, "mail.verify_email.body", user.Username, link, int(uc.TokenTTL.Hours())),
+	}); err != nil {
+		log.Error().Err(err).Int("userID", user.ID).Msg("Failed to send verification email")
+		return err
+	}
+	log.Info().Int("userID", user.ID).Msg("Verification email sent")
+	return nil
+}
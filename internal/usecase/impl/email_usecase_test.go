@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	"sstu-go-forum-auth-service/internal/usecase"
+)
+
+func newEmailFixture(t *testing.T) (*EmailUseCaseImpl, *memory.AuthRepository, *capturingMailer, *model.User) {
+	repo := memory.NewRepository()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &model.User{Username: "user", Password: string(hash), Role: "USER", Email: "user@example.com"}
+	require.NoError(t, repo.CreateUser(user))
+	m := &capturingMailer{}
+	uc := NewEmailUseCase(repo, m, ratelimit.NewSlidingWindow(2, time.Hour), "https://forum.example/verify", 24*time.Hour)
+	uc.Background = runNow
+	return uc, repo, m, user
+}
+
+func TestVerifyEmail_Success(t *testing.T) {
+	uc, repo, m, user := newEmailFixture(t)
+
+	require.NoError(t, uc.SendVerification(user.ID))
+	require.Len(t, m.sent, 1)
+	assert.Equal(t, "user@example.com", m.sent[0].To)
+	token := resetTokenFrom(t, m.sent[0])
+
+	require.NoError(t, uc.VerifyEmail(dto.VerifyEmailRequest{Token: token}))
+	got, err := repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.True(t, got.EmailVerified)
+
+	assert.ErrorIs(t, uc.VerifyEmail(dto.VerifyEmailRequest{Token: token}), usecase.ErrInvalidVerificationToken)
+
+	// Подтвержденную почту повторно не подтверждают
+	require.NoError(t, uc.SendVerification(user.ID))
+	assert.Len(t, m.sent, 1)
+}
+
+func TestRegister_SendsVerificationEmail(t *testing.T) {
+	emailUC, repo, m, _ := newEmailFixture(t)
+	uc := NewAuthUseCase(repo)
+	uc.Verification = emailUC
+
+	created, err := uc.Register(&model.User{Username: "newbie", Password: "secret1", Role: "USER", Email: " Newbie@Example.com ", EmailVerified: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, "newbie@example.com", created.Email)
+	assert.False(t, created.EmailVerified, "clients must not be able to register a verified email")
+	require.Len(t, m.sent, 1)
+	assert.Equal(t, "newbie@example.com", m.sent[0].To)
+}
+
+func TestChangeEmail_RequiresReverification(t *testing.T) {
+	uc, repo, m, user := newEmailFixture(t)
+	require.NoError(t, uc.SendVerification(user.ID))
+	staleToken := resetTokenFrom(t, m.sent[0])
+	m.sent = nil
+
+	err := uc.ChangeEmail(user.ID, dto.ChangeEmailRequest{CurrentPassword: "wrong", Email: "new@example.com"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+	err = uc.ChangeEmail(user.ID, dto.ChangeEmailRequest{CurrentPassword: "secret1", Email: "not-an-email"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidEmail)
+
+	require.NoError(t, uc.ChangeEmail(user.ID, dto.ChangeEmailRequest{CurrentPassword: "secret1", Email: "New@Example.com"}))
+	got, err := repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", got.Email)
+	assert.False(t, got.EmailVerified)
+	require.Len(t, m.sent, 2)
+	assert.Equal(t, "user@example.com", m.sent[0].To, "previous address is notified")
+	assert.Equal(t, "new@example.com", m.sent[1].To)
+
+	assert.ErrorIs(t, uc.VerifyEmail(dto.VerifyEmailRequest{Token: staleToken}), usecase.ErrInvalidVerificationToken)
+	require.NoError(t, uc.VerifyEmail(dto.VerifyEmailRequest{Token: resetTokenFrom(t, m.sent[1])}))
+	got, err = repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.True(t, got.EmailVerified)
+}
+
+func TestChangeEmail_Taken(t *testing.T) {
+	uc, repo, _, user := newEmailFixture(t)
+	require.NoError(t, repo.CreateUser(&model.User{Username: "other", Password: "hash", Role: "USER", Email: "other@example.com"}))
+
+	err := uc.ChangeEmail(user.ID, dto.ChangeEmailRequest{CurrentPassword: "secret1", Email: "OTHER@example.com"})
+
+	assert.ErrorIs(t, err, usecase.ErrEmailTaken)
+}
+
+func TestVerifyEmail_TokenForPreviousAddress(t *testing.T) {
+	uc, repo, m, user := newEmailFixture(t)
+	require.NoError(t, uc.SendVerification(user.ID))
+	token := resetTokenFrom(t, m.sent[0])
+	require.NoError(t, repo.UpdateEmail(user.ID, "new@example.com"))
+
+	assert.ErrorIs(t, uc.VerifyEmail(dto.VerifyEmailRequest{Token: token}), usecase.ErrInvalidVerificationToken)
+	got, err := repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.False(t, got.EmailVerified)
+}
+
+func TestResendVerification_SilentAndThrottled(t *testing.T) {
+	uc, _, m, _ := newEmailFixture(t)
+
+	assert.NoError(t, uc.ResendVerification(dto.ResendVerificationRequest{Username: "ghost"}))
+	assert.Empty(t, m.sent)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, uc.ResendVerification(dto.ResendVerificationRequest{Username: "user"}))
+	}
+	assert.Len(t, m.sent, 2)
+}
@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/repository"
+	"sstu-go-forum-auth-service/internal/usecase"
+)
+
+// LoginLockout считает неудачные входы по имени пользователя и по IP и откладывает следующие попытки.
+// Счетчик ведется по имени, а не по ID: несуществующие аккаунты блокируются так же, как существующие
+type LoginLockout struct {
+	Repo repository.AuthRepository
+	// Первые FreeAttempts ошибок по имени не задерживают вход, дальше задержка BaseDelay удваивается с каждой ошибкой
+	FreeAttempts int
+	BaseDelay    time.Duration
+	// После AccountThreshold ошибок по имени или IPThreshold ошибок с IP вход блокируется на Lockout;
+	// каждая следующая ошибка удваивает блокировку, но не дольше MaxLockout
+	AccountThreshold int
+	IPThreshold      int
+	Lockout          time.Duration
+	MaxLockout       time.Duration
+	// Window — через сколько после последней ошибки или конца блокировки счетчик сбрасывается
+	Window time.Duration
+
+	now func() time.Time
+}
+
+func NewLoginLockout(repo repository.AuthRepository, freeAttempts int, baseDelay time.Duration, accountThreshold, ipThreshold int, lockout, maxLockout, window time.Duration) *LoginLockout {
+	log.Info().Msg("LoginLockout initialized")
+	return &LoginLockout{
+		Repo:             repo,
+		FreeAttempts:     freeAttempts,
+		BaseDelay:        baseDelay,
+		AccountThreshold: accountThreshold,
+		IPThreshold:      ipThreshold,
+		Lockout:          lockout,
+		MaxLockout:       maxLockout,
+		Window:           window,
+		now:              time.Now,
+	}
+}
+
+func accountSubject(username string) string { return "account:" + model.UsernameKey(username) }
+
+func ipSubject(ip string) string { return "ip:" + ip }
+
+// lockedError — блокировка одного из счетчиков; откатывает учет попытки по остальным
+type lockedError struct{ retryAfter time.Duration }
+
+func (e *lockedError) Error() string { return "login locked" }
+
+// Attempt учитывает попытку входа до проверки пароля и возвращает LoginLockedError, если вход по имени
+// или с IP сейчас запрещен. Увеличение счетчика и сравнение с порогом — один атомарный шаг: параллельные
+// попытки получают разные номера, и та, что проходит порог, сразу блокирует следующие. Попытка считается
+// неудачной, пока успешный вход не отменит ее через Success
+func (l *LoginLockout) Attempt(username, ip string) error {
+	now := l.now()
+	err := l.Repo.WithTx(func(repo repository.AuthRepository) error {
+		locked := &lockedError{}
+		for _, subject := range l.subjects(username, ip) {
+			f, err := repo.RecordLoginAttempt(subject, now, now.Add(l.Window))
+			if err != nil {
+				log.Error().Err(err).Str("subject", subject).Msg("Failed to record login attempt")
+				return err
+			}
+			if wait := f.LockedUntil.Sub(now); wait > 0 {
+				locked.retryAfter = max(locked.retryAfter, wait)
+				continue
+			}
+			free, threshold := l.FreeAttempts, l.AccountThreshold
+			if subject == ipSubject(ip) {
+				// С одного IP могут входить многие пользователи, поэтому для него только блокировка
+				free, threshold = l.IPThreshold, l.IPThreshold
+			}
+			delay := l.delay(f.Failures, free, threshold)
+			if delay <= 0 {
+				continue
+			}
+			until := now.Add(delay)
+			if err := repo.LockLogin(subject, until, until.Add(l.Window)); err != nil {
+				log.Error().Err(err).Str("subject", subject).Msg("Failed to lock login")
+				return err
+			}
+			log.Warn().Str("subject", subject).Int("failures", f.Failures).Dur("delay", delay).Msg("Login delayed after failures")
+		}
+		if locked.retryAfter > 0 {
+			return locked
+		}
+		return nil
+	})
+	var locked *lockedError
+	if errors.As(err, &locked) {
+		log.Warn().Str("username", username).Str("ip", ip).Dur("retryAfter", locked.retryAfter).Msg("Login refused: locked out")
+		return &usecase.LoginLockedError{RetryAfter: locked.retryAfter}
+	}
+	return err
+}
+
+// Success сбрасывает счетчик имени, а с IP только отменяет учет этой попытки: иначе свой аккаунт позволял бы
+// перебирать чужие
+func (l *LoginLockout) Success(username, ip string) error {
+	if err := l.Unlock(username); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	if err := l.Repo.ReleaseLoginAttempt(ipSubject(ip)); err != nil {
+		log.Error().Err(err).Str("ip", ip).Msg("Failed to release login attempt")
+		return err
+	}
+	return nil
+}
+
+// Unlock снимает блокировку с имени пользователя
+func (l *LoginLockout) Unlock(username string) error {
+	if err := l.Repo.DeleteLoginFailure(accountSubject(username)); err != nil {
+		log.Error().Err(err).Str("username", username).Msg("Failed to reset login failures")
+		return err
+	}
+	return nil
+}
+
+func (l *LoginLockout) subjects(username, ip string) []string {
+	if ip == "" {
+		return []string{accountSubject(username)}
+	}
+	return []string{accountSubject(username), ipSubject(ip)}
+}
+
+// delay — сколько ждать после failures ошибок: ноль до free, затем экспоненциальная задержка,
+// а с threshold — экспоненциальная блокировка
+func (l *LoginLockout) delay(failures, free, threshold int) time.Duration {
+	switch {
+	case failures >= threshold:
+		return doubled(l.Lockout, failures-threshold, l.MaxLockout)
+	case failures > free:
+		return doubled(l.BaseDelay, failures-free-1, l.Lockout)
+	default:
+		return 0
+	}
+}
+
+// doubled удваивает d n раз, не превышая max
+func doubled(d time.Duration, n int, max time.Duration) time.Duration {
+	for ; n > 0 && d < max; n-- {
+		d *= 2
+	}
+	return min(d, max)
+}
@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	"sstu-go-forum-auth-service/internal/usecase"
+)
+
+type lockoutClock struct{ now time.Time }
+
+func (c *lockoutClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newLockoutFixture(t *testing.T) (*AuthUseCaseImpl, *LoginLockout, *lockoutClock) {
+	repo := memory.NewRepository()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateUser(&model.User{Username: "user", Password: string(hash), Role: "USER"}))
+
+	clock := &lockoutClock{now: time.Now()}
+	lockout := NewLoginLockout(repo, 2, time.Second, 5, 8, time.Minute, 10*time.Minute, time.Hour)
+	lockout.now = func() time.Time { return clock.now }
+	auth := NewAuthUseCase(repo)
+	auth.Lockout = lockout
+	return auth, lockout, clock
+}
+
+func retryAfter(t *testing.T, err error) time.Duration {
+	var locked *usecase.LoginLockedError
+	require.True(t, errors.As(err, &locked), "expected lockout, got %v", err)
+	assert.ErrorIs(t, err, usecase.ErrLoginLocked)
+	return locked.RetryAfter
+}
+
+func TestLoginLockout_ExponentialBackoffThenLockout(t *testing.T) {
+	auth, _, clock := newLockoutFixture(t)
+	wrong := dto.LoginRequest{Username: "user", Password: "wrong", IP: "10.0.0.1"}
+
+	for i := 0; i < 2; i++ {
+		_, err := auth.Login(wrong)
+		assert.ErrorIs(t, err, usecase.ErrInvalidCredentials, "free attempts")
+	}
+	// Третья и четвертая ошибки задерживают вход на 1 и 2 секунды
+	for _, want := range []time.Duration{time.Second, 2 * time.Second} {
+		_, err := auth.Login(wrong)
+		require.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+		_, err = auth.Login(dto.LoginRequest{Username: "user", Password: "secret1", IP: "10.0.0.1"})
+		assert.Equal(t, want, retryAfter(t, err), "correct password is refused while delayed")
+		clock.advance(want)
+	}
+
+	_, err := auth.Login(wrong)
+	require.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+	_, err = auth.Login(wrong)
+	assert.Equal(t, time.Minute, retryAfter(t, err), "threshold reached")
+	clock.advance(time.Minute)
+	_, err = auth.Login(wrong)
+	require.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+	_, err = auth.Login(wrong)
+	assert.Equal(t, 2*time.Minute, retryAfter(t, err), "each failure doubles the lockout")
+	clock.advance(2 * time.Minute)
+
+	result, err := auth.Login(dto.LoginRequest{Username: "user", Password: "secret1", IP: "10.0.0.1"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.AccessToken)
+	for i := 0; i < 2; i++ {
+		_, err = auth.Login(wrong)
+		assert.ErrorIs(t, err, usecase.ErrInvalidCredentials, "success resets the account counter")
+	}
+}
+
+func TestLoginLockout_ConcurrentAttemptsCannotPassTheLock(t *testing.T) {
+	auth, _, _ := newLockoutFixture(t)
+
+	const attempts = 30
+	var wg sync.WaitGroup
+	results := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := auth.Login(dto.LoginRequest{Username: "user", Password: "wrong", IP: "10.0.0.1"})
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	checked := 0
+	for err := range results {
+		if errors.Is(err, usecase.ErrInvalidCredentials) {
+			checked++
+			continue
+		}
+		assert.ErrorIs(t, err, usecase.ErrLoginLocked)
+	}
+	assert.Equal(t, 3, checked, "two free attempts and the one that set the delay")
+}
+
+func TestLoginLockout_UnknownAccountLooksTheSame(t *testing.T) {
+	auth, _, _ := newLockoutFixture(t)
+
+	var known, unknown []error
+	for i := 0; i < 6; i++ {
+		_, err := auth.Login(dto.LoginRequest{Username: "user", Password: "wrong"})
+		known = append(known, err)
+		_, err = auth.Login(dto.LoginRequest{Username: "ghost", Password: "wrong"})
+		unknown = append(unknown, err)
+	}
+	assert.Equal(t, known, unknown)
+}
+
+func TestLoginLockout_PerIP(t *testing.T) {
+	auth, _, _ := newLockoutFixture(t)
+
+	for i := 0; i < 8; i++ {
+		_, err := auth.Login(dto.LoginRequest{Username: "guess" + string(rune('a'+i)), Password: "wrong", IP: "10.0.0.1"})
+		require.ErrorIs(t, err, usecase.ErrInvalidCredentials, "different names are not delayed individually")
+	}
+	_, err := auth.Login(dto.LoginRequest{Username: "user", Password: "secret1", IP: "10.0.0.1"})
+	assert.Equal(t, time.Minute, retryAfter(t, err))
+	_, err = auth.Login(dto.LoginRequest{Username: "user", Password: "secret1", IP: "10.0.0.2"})
+	assert.NoError(t, err, "other addresses are not affected")
+}
+
+func TestLoginLockout_AdminUnlock(t *testing.T) {
+	auth, _, _ := newLockoutFixture(t)
+	for i := 0; i < 5; i++ {
+		_, _ = auth.Login(dto.LoginRequest{Username: "user", Password: "wrong"})
+	}
+	_, err := auth.Login(dto.LoginRequest{Username: "user", Password: "secret1"})
+	retryAfter(t, err)
+
+	require.NoError(t, auth.UnlockLogin("user"))
+	_, err = auth.Login(dto.LoginRequest{Username: "user", Password: "secret1"})
+	assert.NoError(t, err)
+}
+
+func TestDoubled_CapsAtMax(t *testing.T) {
+	assert.Equal(t, time.Second, doubled(time.Second, 0, time.Hour))
+	assert.Equal(t, 8*time.Second, doubled(time.Second, 3, time.Hour))
+	assert.Equal(t, time.Hour, doubled(time.Minute, 1000, time.Hour))
+}
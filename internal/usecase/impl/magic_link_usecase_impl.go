@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/i18n"
+	"sstu-go-forum-auth-service/internal/mailer"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository"
+	"sstu-go-forum-auth-service/internal/usecase"
+	"sstu-go-forum-auth-service/internal/utils"
+)
+
+type MagicLinkUseCaseImpl struct {
+	Repo   repository.AuthRepository
+	Mailer mailer.Mailer
+	// AccountLimiter ограничивает число писем на один аккаунт
+	AccountLimiter ratelimit.Limiter
+	// LoginURL — страница фронтенда, к которой добавляется параметр token
+	LoginURL string
+	TokenTTL time.Duration
+}
+
+func NewMagicLinkUseCase(repo repository.AuthRepository, m mailer.Mailer, accountLimiter ratelimit.Limiter, loginURL string, tokenTTL time.Duration) *MagicLinkUseCaseImpl {
+	log.Info().Msg("MagicLinkUseCaseImpl initialized")
+	return &MagicLinkUseCaseImpl{
+		Repo:           repo,
+		Mailer:         m,
+		AccountLimiter: accountLimiter,
+		LoginURL:       loginURL,
+		TokenTTL:       tokenTTL,
+	}
+}
+
+func (uc *MagicLinkUseCaseImpl) SendLink(req dto.MagicLinkRequest) (string, error) {
+	log.Debug().Str("username", req.Username).Msg("Magic link requested")
+
+	// Nonce выдается и для несуществующих аккаунтов, чтобы ответ их не различал
+	nonce, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate magic link nonce")
+		return "", err
+	}
+
+	user, err := uc.Repo.GetUserByUsername(req.Username)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Info().Str("username", req.Username).Msg("Magic link for unknown user ignored")
+		return nonce, nil
+	}
+	if err != nil {
+		log.Error().Err(err).Str("username", req.Username).Msg("Failed to get user")
+		return "", err
+	}
+	if user.Email == "" {
+		log.Info().Int("userID", user.ID).Msg("Magic link ignored: user has no email")
+		return nonce, nil
+	}
+	if ok, _ := uc.AccountLimiter.Allow(strconv.Itoa(user.ID)); !ok {
+		log.Warn().Int("userID", user.ID).Msg("Magic link throttled for account")
+		return nonce, nil
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate magic link token")
+		return "", err
+	}
+	if err := uc.Repo.SaveMagicLinkToken(&model.MagicLinkToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		NonceHash: utils.HashToken(nonce),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(uc.TokenTTL),
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to save magic link token")
+		return "", err
+	}
+
+	link := uc.LoginURL + "?" + url.Values{"token": {token}}.Encode()
+	if err := uc.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: i18n.T(user.Locale, "mail.magic_link.subject"),
+		Body:    i18n.T(user.Locale, "mail.magic_link.body", user.Username, link, int(uc.TokenTTL.Minutes())),
+	}); err != nil {
+		log.Error().Err(err).Int("userID", user.ID).Msg("Failed to send magic link email")
+		return "", err
+	}
+	log.Info().Int("userID", user.ID).Msg("Magic link email sent")
+	return nonce, nil
+}
+
+func (uc *MagicLinkUseCaseImpl) Consume(req dto.MagicLinkLoginRequest) (*model.User, error) {
+	log.Debug().Msg("Magic link login attempt")
+
+	// Без nonce токен не тратится: так его не погасит превью ссылки в почтовом клиенте
+	if req.Token == "" || req.Nonce == "" {
+		log.Warn().Msg("Magic link login without token or nonce")
+		return nil, usecase.ErrInvalidMagicLink
+	}
+	t, err := uc.Repo.ConsumeMagicLinkToken(utils.HashToken(req.Token))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		log.Error().Err(err).Msg("Failed to consume magic link token")
+		return nil, err
+	}
+	if err != nil || time.Now().After(t.ExpiresAt) {
+		log.Warn().Msg("Invalid, expired or already used magic link token")
+		return nil, usecase.ErrInvalidMagicLink
+	}
+	// Токен уже погашен: ссылка, открытая в чужом браузере, больше не сработает и в своем
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(req.Nonce)), []byte(t.NonceHash)) != 1 {
+		log.Warn().Int("userID", t.UserID).Msg("Magic link opened in another browser")
+		return nil, usecase.ErrInvalidMagicLink
+	}
+
+	var user *model.User
+	err = uc.Repo.WithTx(func(repo repository.AuthRepository) error {
+		// Переход по ссылке доказывает владение адресом, на который она отправлена; после смены почты она не действует
+		err := repo.MarkEmailVerified(t.UserID, t.Email)
+		if errors.Is(err, repository.ErrNotFound) {
+			log.Warn().Int("userID", t.UserID).Msg("Magic link issued for a previous email")
+			return usecase.ErrInvalidMagicLink
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to mark email verified")
+			return err
+		}
+		if err := repo.DeleteMagicLinkTokensByUserID(t.UserID); err != nil {
+			log.Error().Err(err).Msg("Failed to delete magic link tokens")
+			return err
+		}
+		user, err = repo.GetUserByID(t.UserID)
+		if err != nil {
+			log.Error().Err(err).Int("userID", t.UserID).Msg("Failed to get user")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Int("userID", user.ID).Msg("Magic link accepted")
+	return user, nil
+}
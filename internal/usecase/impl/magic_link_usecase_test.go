@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	"sstu-go-forum-auth-service/internal/usecase"
+	"sstu-go-forum-auth-service/internal/utils"
+)
+
+func newMagicLinkFixture(t *testing.T) (*AuthUseCaseImpl, *MagicLinkUseCaseImpl, *memory.AuthRepository, *capturingMailer, *model.User) {
+	repo := memory.NewRepository()
+	user := &model.User{Username: "user", Password: "hash", Role: "USER", Email: "user@example.com"}
+	require.NoError(t, repo.CreateUser(user))
+	m := &capturingMailer{}
+	uc := NewMagicLinkUseCase(repo, m, ratelimit.NewSlidingWindow(2, time.Hour), "https://forum.example/login/magic-link", 15*time.Minute)
+	auth := NewAuthUseCase(repo)
+	auth.MagicLink = uc
+	auth.RequireVerifiedEmail = true
+	return auth, uc, repo, m, user
+}
+
+func TestMagicLink_LoginIsSingleUseAndVerifiesEmail(t *testing.T) {
+	auth, uc, repo, m, user := newMagicLinkFixture(t)
+
+	nonce, err := uc.SendLink(dto.MagicLinkRequest{Username: "user"})
+	require.NoError(t, err)
+	require.NotEmpty(t, nonce)
+	require.Len(t, m.sent, 1)
+	assert.Equal(t, "user@example.com", m.sent[0].To)
+	token := resetTokenFrom(t, m.sent[0])
+	assert.NotContains(t, m.sent[0].Body, nonce, "nonce stays in the browser")
+
+	result, err := auth.LoginMagicLink(dto.MagicLinkLoginRequest{Token: token, Nonce: nonce})
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.AccessToken)
+	assert.NotEmpty(t, result.RefreshToken)
+	stored, err := repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.EmailVerified, "following the link proves the mailbox")
+
+	_, err = auth.LoginMagicLink(dto.MagicLinkLoginRequest{Token: token, Nonce: nonce})
+	assert.ErrorIs(t, err, usecase.ErrInvalidMagicLink)
+}
+
+func TestMagicLink_BoundToRequestingBrowser(t *testing.T) {
+	auth, uc, _, m, _ := newMagicLinkFixture(t)
+	nonce, err := uc.SendLink(dto.MagicLinkRequest{Username: "user"})
+	require.NoError(t, err)
+	token := resetTokenFrom(t, m.sent[0])
+
+	_, err = auth.LoginMagicLink(dto.MagicLinkLoginRequest{Token: token})
+	assert.ErrorIs(t, err, usecase.ErrInvalidMagicLink)
+	_, err = auth.LoginMagicLink(dto.MagicLinkLoginRequest{Token: token, Nonce: "other-browser"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidMagicLink)
+	// Попытка из чужого браузера гасит ссылку
+	_, err = auth.LoginMagicLink(dto.MagicLinkLoginRequest{Token: token, Nonce: nonce})
+	assert.ErrorIs(t, err, usecase.ErrInvalidMagicLink)
+}
+
+func TestMagicLink_ExpiredOrPreviousEmail(t *testing.T) {
+	auth, uc, repo, m, user := newMagicLinkFixture(t)
+	require.NoError(t, repo.SaveMagicLinkToken(&model.MagicLinkToken{
+		UserID: user.ID, TokenHash: utils.HashToken("expired"), NonceHash: utils.HashToken("nonce"),
+		Email: user.Email, ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+	_, err := auth.LoginMagicLink(dto.MagicLinkLoginRequest{Token: "expired", Nonce: "nonce"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidMagicLink)
+
+	nonce, err := uc.SendLink(dto.MagicLinkRequest{Username: "user"})
+	require.NoError(t, err)
+	token := resetTokenFrom(t, m.sent[0])
+	require.NoError(t, repo.UpdateEmail(user.ID, "new@example.com"))
+	_, err = auth.LoginMagicLink(dto.MagicLinkLoginRequest{Token: token, Nonce: nonce})
+	assert.ErrorIs(t, err, usecase.ErrInvalidMagicLink)
+}
+
+func TestMagicLink_SecondFactorStillRequired(t *testing.T) {
+	auth, uc, repo, m, user := newMagicLinkFixture(t)
+	require.NoError(t, repo.SaveTOTP(&model.TOTP{UserID: user.ID, Secret: "JBSWY3DPEHPK3PXP"}))
+	require.NoError(t, repo.ConfirmTOTP(user.ID))
+
+	nonce, err := uc.SendLink(dto.MagicLinkRequest{Username: "user"})
+	require.NoError(t, err)
+	result, err := auth.LoginMagicLink(dto.MagicLinkLoginRequest{Token: resetTokenFrom(t, m.sent[0]), Nonce: nonce})
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.MFAToken)
+	assert.Empty(t, result.AccessToken)
+}
+
+func TestMagicLink_UnknownUserAndThrottling(t *testing.T) {
+	_, uc, _, m, _ := newMagicLinkFixture(t)
+
+	nonce, err := uc.SendLink(dto.MagicLinkRequest{Username: "ghost"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, nonce, "response does not reveal unknown accounts")
+	assert.Empty(t, m.sent)
+
+	for i := 0; i < 5; i++ {
+		_, err := uc.SendLink(dto.MagicLinkRequest{Username: "user"})
+		assert.NoError(t, err)
+	}
+	assert.Len(t, m.sent, 2)
+}
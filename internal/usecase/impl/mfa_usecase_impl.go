@@ -0,0 +1,359 @@
+package usecase
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/i18n"
+	"sstu-go-forum-auth-service/internal/mailer"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/password"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository"
+	"sstu-go-forum-auth-service/internal/totp"
+	"sstu-go-forum-auth-service/internal/usecase"
+	"sstu-go-forum-auth-service/internal/utils"
+)
+
+const (
+	DefaultMFAIssuer = "SSTU Forum"
+	// DefaultMFAAttempts — проверок кода на пользователя за время жизни MFA токена
+	DefaultMFAAttempts = 5
+	RecoveryCodeCount  = 10
+)
+
+type MFAUseCaseImpl struct {
+	Repo repository.AuthRepository
+	// Issuer отображается в приложении-аутентификаторе рядом с именем пользователя
+	Issuer string
+	// RequiredRoles — роли, которым нельзя входить без второго фактора
+	RequiredRoles []string
+	// AttemptLimiter ограничивает число проверок кода на пользователя: шесть цифр иначе перебираются
+	AttemptLimiter ratelimit.Limiter
+	// Mailer, если задан, уведомляет пользователя о входе по коду восстановления
+	Mailer mailer.Mailer
+	Hasher password.PasswordHasher
+}
+
+func NewMFAUseCase(repo repository.AuthRepository, issuer string, requiredRoles []string, attemptLimiter ratelimit.Limiter) *MFAUseCaseImpl {
+	log.Info().Strs("requiredRoles", requiredRoles).Msg("MFAUseCaseImpl initialized")
+	return &MFAUseCaseImpl{
+		Repo:           repo,
+		Issuer:         issuer,
+		RequiredRoles:  requiredRoles,
+		AttemptLimiter: attemptLimiter,
+		Hasher:         password.Default(),
+	}
+}
+
+func (uc *MFAUseCaseImpl) Status(user *model.User) (bool, bool, error) {
+	required := false
+	for _, role := range uc.RequiredRoles {
+		if role == user.Role {
+			required = true
+		}
+	}
+	enabled, err := uc.totpEnabled(user.ID)
+	if err != nil || enabled {
+		return enabled, required, err
+	}
+	// Зарегистрированный ключ доступа тоже считается вторым фактором
+	credentials, err := uc.Repo.ListWebAuthnCredentials(user.ID)
+	if err != nil {
+		log.Error().Err(err).Int("userID", user.ID).Msg("Failed to list webauthn credentials")
+		return false, false, err
+	}
+	return len(credentials) > 0, required, nil
+}
+
+func (uc *MFAUseCaseImpl) totpEnabled(userID int) (bool, error) {
+	t, err := uc.Repo.GetTOTP(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		log.Error().Err(err).Int("userID", userID).Msg("Failed to get totp")
+		return false, err
+	}
+	return t.Confirmed, nil
+}
+
+func (uc *MFAUseCaseImpl) EnrollTOTP(userID int, req dto.TOTPEnrollRequest) (*dto.TOTPEnrollResponse, error) {
+	log.Debug().Int("userID", userID).Msg("TOTP enrollment")
+
+	user, err := uc.checkPassword(userID, req.CurrentPassword)
+	if err != nil {
+		return nil, err
+	}
+	return uc.enrollTOTP(user)
+}
+
+func (uc *MFAUseCaseImpl) EnrollTOTPDuringLogin(userID int) (*dto.TOTPEnrollResponse, error) {
+	log.Debug().Int("userID", userID).Msg("TOTP enrollment during login")
+
+	user, err := uc.Repo.GetUserByID(userID)
+	if err != nil {
+		log.Error().Err(err).Int("userID", userID).Msg("Failed to get user")
+		return nil, err
+	}
+	return uc.enrollTOTP(user)
+}
+
+func (uc *MFAUseCaseImpl) enrollTOTP(user *model.User) (*dto.TOTPEnrollResponse, error) {
+	userID := user.ID
+	enabled, err := uc.totpEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		log.Warn().Int("userID", userID).Msg("TOTP already enabled")
+		return nil, usecase.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate totp secret")
+		return nil, err
+	}
+	var codes []string
+	err = uc.Repo.WithTx(func(repo repository.AuthRepository) error {
+		if err := repo.SaveTOTP(&model.TOTP{UserID: userID, Secret: secret}); err != nil {
+			log.Error().Err(err).Msg("Failed to save totp secret")
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(repo, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Int("userID", userID).Msg("TOTP enrollment started")
+	return &dto.TOTPEnrollResponse{
+		Secret:        secret,
+		OTPAuthURI:    totp.URI(uc.Issuer, user.Username, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+func (uc *MFAUseCaseImpl) ConfirmTOTP(userID int, req dto.TOTPCodeRequest) error {
+	log.Debug().Int("userID", userID).Msg("TOTP confirmation")
+
+	return uc.Repo.WithTx(func(repo repository.AuthRepository) error {
+		t, err := uc.checkCode(repo, userID, req.Code)
+		if err != nil {
+			return err
+		}
+		if t.Confirmed {
+			log.Warn().Int("userID", userID).Msg("TOTP already enabled")
+			return usecase.ErrMFAAlreadyEnabled
+		}
+		if err := repo.ConfirmTOTP(userID); err != nil {
+			log.Error().Err(err).Msg("Failed to confirm totp")
+			return err
+		}
+		log.Info().Int("userID", userID).Msg("TOTP enabled")
+		return nil
+	})
+}
+
+func (uc *MFAUseCaseImpl) VerifyTOTP(userID int, code string) error {
+	t, err := uc.checkCode(uc.Repo, userID, code)
+	if err != nil {
+		return err
+	}
+	if !t.Confirmed {
+		log.Warn().Int("userID", userID).Msg("TOTP not confirmed")
+		return usecase.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (uc *MFAUseCaseImpl) DisableTOTP(userID int, req dto.DisableTOTPRequest) error {
+	log.Debug().Int("userID", userID).Msg("TOTP disable attempt")
+
+	user, err := uc.checkPassword(userID, req.CurrentPassword)
+	if err != nil {
+		return err
+	}
+	if _, required, err := uc.Status(user); err != nil {
+		return err
+	} else if required {
+		log.Warn().Int("userID", userID).Str("role", user.Role).Msg("TOTP is mandatory for role")
+		return usecase.ErrMFARequired
+	}
+	if err := uc.VerifyTOTP(userID, req.Code); err != nil {
+		return err
+	}
+	err = uc.Repo.WithTx(func(repo repository.AuthRepository) error {
+		if err := repo.DeleteTOTP(userID); err != nil {
+			log.Error().Err(err).Msg("Failed to delete totp")
+			return err
+		}
+		if err := repo.DeleteRecoveryCodesByUserID(userID); err != nil {
+			log.Error().Err(err).Msg("Failed to delete recovery codes")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Info().Int("userID", userID).Msg("TOTP disabled")
+	return nil
+}
+
+func (uc *MFAUseCaseImpl) UseRecoveryCode(userID int, code string) error {
+	log.Debug().Int("userID", userID).Msg("Recovery code attempt")
+
+	if ok, _ := uc.AttemptLimiter.Allow(strconv.Itoa(userID)); !ok {
+		log.Warn().Int("userID", userID).Msg("Too many mfa attempts")
+		return usecase.ErrTooManyMFAAttempts
+	}
+	user, err := uc.Repo.GetUserByID(userID)
+	if err != nil {
+		log.Error().Err(err).Int("userID", userID).Msg("Failed to get user")
+		return err
+	}
+	// Коды, выданные при неподтвержденной привязке, не действуют. Проверяется сам TOTP, а не Status:
+	// ключ доступа не делает действительными коды неподтвержденной привязки
+	if enabled, err := uc.totpEnabled(userID); err != nil {
+		return err
+	} else if !enabled {
+		log.Warn().Int("userID", userID).Msg("Recovery code without enabled TOTP")
+		return usecase.ErrInvalidMFACode
+	}
+	err = uc.Repo.UseRecoveryCode(userID, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Warn().Int("userID", userID).Msg("Invalid or already used recovery code")
+		return usecase.ErrInvalidMFACode
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to use recovery code")
+		return err
+	}
+	remaining, err := uc.Repo.CountRecoveryCodes(userID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to count recovery codes")
+		return err
+	}
+	log.Info().Int("userID", userID).Int("remaining", remaining).Msg("Recovery code used")
+
+	if uc.Mailer != nil && user.Email != "" {
+		if err := uc.Mailer.Send(mailer.Message{
+			To:      user.Email,
+			Subject: i18n.T(user.Locale, "mail.recovery_code_used.subject"),
+			Body:    i18n.T(user.Locale, "mail.recovery_code_used.body", user.Username, remaining),
+		}); err != nil {
+			log.Error().Err(err).Int("userID", userID).Msg("Failed to send recovery code notification")
+		}
+	}
+	return nil
+}
+
+func (uc *MFAUseCaseImpl) RegenerateRecoveryCodes(userID int, req dto.RegenerateRecoveryCodesRequest) ([]string, error) {
+	log.Debug().Int("userID", userID).Msg("Recovery codes regeneration")
+
+	if _, err := uc.checkPassword(userID, req.CurrentPassword); err != nil {
+		return nil, err
+	}
+	// Коды действуют только при подтвержденном TOTP, см. UseRecoveryCode
+	if enabled, err := uc.totpEnabled(userID); err != nil {
+		return nil, err
+	} else if !enabled {
+		log.Warn().Int("userID", userID).Msg("Recovery codes without enabled TOTP")
+		return nil, usecase.ErrMFANotEnrolled
+	}
+
+	var codes []string
+	err := uc.Repo.WithTx(func(repo repository.AuthRepository) error {
+		var err error
+		codes, err = replaceRecoveryCodes(repo, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Int("userID", userID).Msg("Recovery codes regenerated")
+	return codes, nil
+}
+
+func (uc *MFAUseCaseImpl) checkPassword(userID int, plain string) (*model.User, error) {
+	return checkCurrentPassword(uc.Repo, uc.Hasher, userID, plain)
+}
+
+// checkCurrentPassword загружает пользователя и сверяет его текущий пароль перед изменением факторов входа
+func checkCurrentPassword(repo repository.AuthRepository, hasher password.PasswordHasher, userID int, plain string) (*model.User, error) {
+	user, err := repo.GetUserByID(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, usecase.ErrInvalidCredentials
+	}
+	if err != nil {
+		log.Error().Err(err).Int("userID", userID).Msg("Failed to get user")
+		return nil, err
+	}
+	if ok, _ := verifyPassword(hasher, user.Password, plain); !ok {
+		log.Warn().Int("userID", userID).Msg("Invalid current password")
+		return nil, usecase.ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// replaceRecoveryCodes выдает новый набор кодов взамен прежнего; в БД хранятся только хеши
+func replaceRecoveryCodes(repo repository.AuthRepository, userID int) ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to generate recovery code")
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = utils.HashToken(utils.NormalizeRecoveryCode(code))
+	}
+	if err := repo.DeleteRecoveryCodesByUserID(userID); err != nil {
+		log.Error().Err(err).Msg("Failed to delete recovery codes")
+		return nil, err
+	}
+	if err := repo.SaveRecoveryCodes(userID, hashes); err != nil {
+		log.Error().Err(err).Msg("Failed to save recovery codes")
+		return nil, err
+	}
+	return codes, nil
+}
+
+// checkCode проверяет код и помечает его шаг использованным, чтобы перехваченный код нельзя было повторить
+func (uc *MFAUseCaseImpl) checkCode(repo repository.AuthRepository, userID int, code string) (*model.TOTP, error) {
+	if ok, _ := uc.AttemptLimiter.Allow(strconv.Itoa(userID)); !ok {
+		log.Warn().Int("userID", userID).Msg("Too many mfa attempts")
+		return nil, usecase.ErrTooManyMFAAttempts
+	}
+	t, err := repo.GetTOTP(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Warn().Int("userID", userID).Msg("TOTP not enrolled")
+		return nil, usecase.ErrMFANotEnrolled
+	}
+	if err != nil {
+		log.Error().Err(err).Int("userID", userID).Msg("Failed to get totp")
+		return nil, err
+	}
+	step, ok := totp.Validate(t.Secret, code, time.Now())
+	if !ok {
+		log.Warn().Int("userID", userID).Msg("Invalid totp code")
+		return nil, usecase.ErrInvalidMFACode
+	}
+	err = repo.UseTOTPStep(userID, step)
+	if errors.Is(err, repository.ErrConflict) {
+		log.Warn().Int("userID", userID).Msg("TOTP code already used")
+		return nil, usecase.ErrInvalidMFACode
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to save totp step")
+		return nil, err
+	}
+	return t, nil
+}
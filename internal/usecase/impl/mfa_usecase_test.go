@@ -0,0 +1,182 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	"sstu-go-forum-auth-service/internal/totp"
+	"sstu-go-forum-auth-service/internal/usecase"
+)
+
+// totpCode возвращает код для шага, сдвинутого на offset от текущего: разные offset дают разные шаги,
+// поэтому коды в одном тесте не отклоняются как повторные
+func totpCode(t *testing.T, secret string, offset int64) string {
+	code, err := totp.Code(secret, totp.Step(time.Now())+offset)
+	require.NoError(t, err)
+	return code
+}
+
+func newMFAFixture(t *testing.T, role string, requiredRoles ...string) (*AuthUseCaseImpl, *MFAUseCaseImpl, *model.User) {
+	repo := memory.NewRepository()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &model.User{Username: "user", Password: string(hash), Role: role, Email: "user@example.com"}
+	require.NoError(t, repo.CreateUser(user))
+	mfa := NewMFAUseCase(repo, "SSTU Forum", requiredRoles, ratelimit.NewSlidingWindow(5, time.Minute))
+	mfa.Mailer = &capturingMailer{}
+	auth := NewAuthUseCase(repo)
+	auth.MFA = mfa
+	return auth, mfa, user
+}
+
+func TestTOTP_EnrollConfirmAndLogin(t *testing.T) {
+	auth, mfa, user := newMFAFixture(t, "USER")
+
+	_, err := mfa.EnrollTOTP(user.ID, dto.TOTPEnrollRequest{CurrentPassword: "wrong"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+	enrollment, err := mfa.EnrollTOTP(user.ID, dto.TOTPEnrollRequest{CurrentPassword: "secret1"})
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.OTPAuthURI, "secret="+enrollment.Secret)
+
+	// Пока привязка не подтверждена, вход остается однофакторным
+	result, err := auth.Login(dto.LoginRequest{Username: "user", Password: "secret1"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.AccessToken)
+
+	assert.ErrorIs(t, mfa.ConfirmTOTP(user.ID, dto.TOTPCodeRequest{Code: "000000"}), usecase.ErrInvalidMFACode)
+	require.NoError(t, mfa.ConfirmTOTP(user.ID, dto.TOTPCodeRequest{Code: totpCode(t, enrollment.Secret, -1)}))
+	_, err = mfa.EnrollTOTP(user.ID, dto.TOTPEnrollRequest{CurrentPassword: "secret1"})
+	assert.ErrorIs(t, err, usecase.ErrMFAAlreadyEnabled)
+
+	result, err = auth.Login(dto.LoginRequest{Username: "user", Password: "secret1"})
+	require.NoError(t, err)
+	assert.Empty(t, result.AccessToken)
+	require.NotEmpty(t, result.MFAToken)
+	assert.False(t, result.MFAEnrollmentRequired)
+	_, err = auth.VerifyAccessToken(result.MFAToken)
+	assert.ErrorIs(t, err, usecase.ErrInvalidAccessToken, "mfa token must not grant API access")
+
+	code := totpCode(t, enrollment.Secret, 0)
+	_, access, refresh, err := auth.LoginMFA(dto.MFALoginRequest{MFAToken: result.MFAToken, Code: code})
+	require.NoError(t, err)
+	assert.NotEmpty(t, access)
+	assert.NotEmpty(t, refresh)
+
+	_, _, _, err = auth.LoginMFA(dto.MFALoginRequest{MFAToken: result.MFAToken, Code: code})
+	assert.ErrorIs(t, err, usecase.ErrInvalidMFACode, "a code is accepted only once")
+	_, _, _, err = auth.LoginMFA(dto.MFALoginRequest{MFAToken: access, Code: totpCode(t, enrollment.Secret, 1)})
+	assert.ErrorIs(t, err, usecase.ErrInvalidMFAToken)
+}
+
+func TestTOTP_RequiredRoleEnrollsDuringLogin(t *testing.T) {
+	auth, mfa, user := newMFAFixture(t, "ADMIN", "ADMIN")
+
+	result, err := auth.Login(dto.LoginRequest{Username: "user", Password: "secret1"})
+	require.NoError(t, err)
+	assert.Empty(t, result.AccessToken)
+	assert.True(t, result.MFAEnrollmentRequired)
+
+	_, _, _, err = auth.LoginMFA(dto.MFALoginRequest{MFAToken: result.MFAToken, Code: "123456"})
+	assert.ErrorIs(t, err, usecase.ErrMFANotEnrolled)
+
+	userID, err := auth.VerifyMFAToken(result.MFAToken)
+	require.NoError(t, err)
+	enrollment, err := mfa.EnrollTOTPDuringLogin(userID)
+	require.NoError(t, err)
+	_, access, _, err := auth.LoginMFA(dto.MFALoginRequest{MFAToken: result.MFAToken, Code: totpCode(t, enrollment.Secret, 0)})
+	require.NoError(t, err)
+	assert.NotEmpty(t, access)
+
+	enabled, required, err := mfa.Status(user)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+	assert.True(t, required)
+	err = mfa.DisableTOTP(user.ID, dto.DisableTOTPRequest{CurrentPassword: "secret1", Code: totpCode(t, enrollment.Secret, 1)})
+	assert.ErrorIs(t, err, usecase.ErrMFARequired)
+}
+
+func TestTOTP_Disable(t *testing.T) {
+	auth, mfa, user := newMFAFixture(t, "USER")
+	enrollment, err := mfa.EnrollTOTP(user.ID, dto.TOTPEnrollRequest{CurrentPassword: "secret1"})
+	require.NoError(t, err)
+	require.NoError(t, mfa.ConfirmTOTP(user.ID, dto.TOTPCodeRequest{Code: totpCode(t, enrollment.Secret, -1)}))
+
+	err = mfa.DisableTOTP(user.ID, dto.DisableTOTPRequest{CurrentPassword: "wrong", Code: totpCode(t, enrollment.Secret, 0)})
+	assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+	require.NoError(t, mfa.DisableTOTP(user.ID, dto.DisableTOTPRequest{CurrentPassword: "secret1", Code: totpCode(t, enrollment.Secret, 0)}))
+
+	result, err := auth.Login(dto.LoginRequest{Username: "user", Password: "secret1"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.AccessToken)
+}
+
+func TestTOTP_AttemptsAreLimited(t *testing.T) {
+	_, mfa, user := newMFAFixture(t, "USER")
+	enrollment, err := mfa.EnrollTOTP(user.ID, dto.TOTPEnrollRequest{CurrentPassword: "secret1"})
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		assert.ErrorIs(t, mfa.ConfirmTOTP(user.ID, dto.TOTPCodeRequest{Code: "000000"}), usecase.ErrInvalidMFACode)
+	}
+	err = mfa.ConfirmTOTP(user.ID, dto.TOTPCodeRequest{Code: totpCode(t, enrollment.Secret, 0)})
+	assert.ErrorIs(t, err, usecase.ErrTooManyMFAAttempts)
+}
+
+func TestRecoveryCodes_LoginAndRegenerate(t *testing.T) {
+	auth, mfa, user := newMFAFixture(t, "MODERATOR")
+	mail := mfa.Mailer.(*capturingMailer)
+	mfa.AttemptLimiter = ratelimit.NewSlidingWindow(100, time.Minute)
+	enrollment, err := mfa.EnrollTOTP(user.ID, dto.TOTPEnrollRequest{CurrentPassword: "secret1"})
+	require.NoError(t, err)
+	require.Len(t, enrollment.RecoveryCodes, RecoveryCodeCount)
+
+	// До подтверждения привязки коды восстановления не действуют
+	assert.ErrorIs(t, mfa.UseRecoveryCode(user.ID, enrollment.RecoveryCodes[0]), usecase.ErrInvalidMFACode)
+	require.NoError(t, mfa.ConfirmTOTP(user.ID, dto.TOTPCodeRequest{Code: totpCode(t, enrollment.Secret, 0)}))
+
+	challenge, err := auth.Login(dto.LoginRequest{Username: "user", Password: "secret1"})
+	require.NoError(t, err)
+	// Код принимается без дефисов и в верхнем регистре
+	code := strings.ToUpper(strings.ReplaceAll(enrollment.RecoveryCodes[0], "-", ""))
+	_, access, _, err := auth.LoginMFA(dto.MFALoginRequest{MFAToken: challenge.MFAToken, RecoveryCode: code})
+	require.NoError(t, err)
+	assert.NotEmpty(t, access)
+	require.Len(t, mail.sent, 1)
+	assert.Equal(t, "user@example.com", mail.sent[0].To)
+	assert.Contains(t, mail.sent[0].Body, "Осталось кодов: 9")
+
+	_, _, _, err = auth.LoginMFA(dto.MFALoginRequest{MFAToken: challenge.MFAToken, RecoveryCode: enrollment.RecoveryCodes[0]})
+	assert.ErrorIs(t, err, usecase.ErrInvalidMFACode, "a recovery code is accepted only once")
+
+	_, err = mfa.RegenerateRecoveryCodes(user.ID, dto.RegenerateRecoveryCodesRequest{CurrentPassword: "wrong"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+	codes, err := mfa.RegenerateRecoveryCodes(user.ID, dto.RegenerateRecoveryCodesRequest{CurrentPassword: "secret1"})
+	require.NoError(t, err)
+	require.Len(t, codes, RecoveryCodeCount)
+	assert.ErrorIs(t, mfa.UseRecoveryCode(user.ID, enrollment.RecoveryCodes[1]), usecase.ErrInvalidMFACode)
+	assert.NoError(t, mfa.UseRecoveryCode(user.ID, codes[1]))
+}
+
+func TestRecoveryCodes_PasskeyDoesNotEnableUnconfirmedCodes(t *testing.T) {
+	_, mfa, user := newMFAFixture(t, "USER")
+	mfa.AttemptLimiter = ratelimit.NewSlidingWindow(100, time.Minute)
+	enrollment, err := mfa.EnrollTOTP(user.ID, dto.TOTPEnrollRequest{CurrentPassword: "secret1"})
+	require.NoError(t, err)
+	require.NoError(t, mfa.Repo.SaveWebAuthnCredential(&model.WebAuthnCredential{UserID: user.ID, Name: "Телефон", CredentialID: []byte("key")}))
+	enabled, _, err := mfa.Status(user)
+	require.NoError(t, err)
+	require.True(t, enabled, "the passkey is a second factor")
+
+	assert.ErrorIs(t, mfa.UseRecoveryCode(user.ID, enrollment.RecoveryCodes[0]), usecase.ErrInvalidMFACode,
+		"codes of an unconfirmed TOTP enrollment stay invalid")
+	_, err = mfa.RegenerateRecoveryCodes(user.ID, dto.RegenerateRecoveryCodesRequest{CurrentPassword: "secret1"})
+	assert.ErrorIs(t, err, usecase.ErrMFANotEnrolled)
+}
@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/i18n"
+	"sstu-go-forum-auth-service/internal/mailer"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/password"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository"
+	"sstu-go-forum-auth-service/internal/usecase"
+	"sstu-go-forum-auth-service/internal/utils"
+)
+
+type PasswordResetUseCaseImpl struct {
+	Repo   repository.AuthRepository
+	Mailer mailer.Mailer
+	// AccountLimiter ограничивает число писем на один аккаунт
+	AccountLimiter ratelimit.Limiter
+	// ResetURL — страница фронтенда, к которой добавляется параметр token
+	ResetURL string
+	TokenTTL time.Duration
+	Hasher   password.PasswordHasher
+	// PasswordPolicy проверяет новый пароль
+	PasswordPolicy *password.Policy
+	// Background выполняет работу с найденным аккаунтом после ответа, см. runInBackground
+	Background func(task func())
+}
+
+func NewPasswordResetUseCase(repo repository.AuthRepository, m mailer.Mailer, accountLimiter ratelimit.Limiter, resetURL string, tokenTTL time.Duration) *PasswordResetUseCaseImpl {
+	log.Info().Msg("PasswordResetUseCaseImpl initialized")
+	return &PasswordResetUseCaseImpl{
+		Repo:           repo,
+		Mailer:         m,
+		AccountLimiter: accountLimiter,
+		ResetURL:       resetURL,
+		TokenTTL:       tokenTTL,
+		Hasher:         password.Default(),
+		PasswordPolicy: password.DefaultPolicy,
+		Background:     runInBackground,
+	}
+}
+
+func (uc *PasswordResetUseCaseImpl) ForgotPassword(req dto.ForgotPasswordRequest) error {
+	log.Debug().Str("username", req.Username).Msg("Password reset requested")
+
+	user, err := uc.Repo.GetUserByUsername(req.Username)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Info().Str("username", req.Username).Msg("Password reset for unknown user ignored")
+		return nil
+	}
+	if err != nil {
+		log.Error().Err(err).Str("username", req.Username).Msg("Failed to get user")
+		return err
+	}
+	uc.Background(func() { uc.sendReset(user) })
+	return nil
+}
+
+// sendReset выдает токен сброса и отправляет письмо; ошибки только логируются, потому что ответ уже отдан
+func (uc *PasswordResetUseCaseImpl) sendReset(user *model.User) {
+	if user.Email == "" {
+		log.Info().Int("userID", user.ID).Msg("Password reset ignored: user has no email")
+		return
+	}
+	if ok, _ := uc.AccountLimiter.Allow(strconv.Itoa(user.ID)); !ok {
+		log.Warn().Int("userID", user.ID).Msg("Password reset throttled for account")
+		return
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate reset token")
+		return
+	}
+	if err := uc.Repo.SavePasswordResetToken(&model.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(uc.TokenTTL),
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to save reset token")
+		return
+	}
+
+	link := uc.ResetURL + "?" + url.Values{"token": {token}}.Encode()
+	if err := uc.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: i18n.T(user.Locale, "mail.password_reset.subject"),
+		Body:    i18n.T(user.Locale, "mail.password_reset.body", user.Username, link, int(uc.TokenTTL.Minutes())),
+	}); err != nil {
+		log.Error().Err(err).Int("userID", user.ID).Msg("Failed to send reset email")
+		return
+	}
+	log.Info().Int("userID", user.ID).Msg("Password reset email sent")
+}
+
+func (uc *PasswordResetUseCaseImpl) ResetPassword(req dto.ResetPasswordRequest) error {
+	log.Debug().Msg("Password reset attempt")
+
+	var userID int
+	err := uc.Repo.WithTx(func(repo repository.AuthRepository) error {
+		t, err := repo.ConsumePasswordResetToken(utils.HashToken(req.Token))
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			log.Error().Err(err).Msg("Failed to consume reset token")
+			return err
+		}
+		if err != nil || time.Now().After(t.ExpiresAt) {
+			log.Warn().Msg("Invalid, expired or already used reset token")
+			return usecase.ErrInvalidResetToken
+		}
+		userID = t.UserID
+		// Ошибка откатывает транзакцию, и ссылкой можно воспользоваться снова с другим паролем
+		user, err := repo.GetUserByID(t.UserID)
+		if err != nil {
+			log.Error().Err(err).Int("userID", t.UserID).Msg("Failed to get user")
+			return err
+		}
+		if err := checkPasswordPolicy(uc.PasswordPolicy, req.NewPassword, user.Username, "new_password"); err != nil {
+			return err
+		}
+		hashed, err := uc.Hasher.Hash(req.NewPassword)
+		if err != nil {
+			log.Error().Err(err).Msg("Password hashing failed")
+			return err
+		}
+		if err := repo.UpdatePassword(t.UserID, hashed); err != nil {
+			log.Error().Err(err).Msg("Failed to update password")
+			return err
+		}
+		if err := repo.DeletePasswordResetTokensByUserID(t.UserID); err != nil {
+			log.Error().Err(err).Msg("Failed to delete reset tokens")
+			return err
+		}
+		if err := revokeSessions(repo, t.UserID, time.Now().Truncate(time.Millisecond)); err != nil {
+			log.Error().Err(err).Msg("Failed to revoke sessions")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Info().Int("userID", userID).Msg("Password reset, all sessions revoked")
+	return nil
+}
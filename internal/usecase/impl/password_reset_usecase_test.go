@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/mailer"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/ratelimit"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	"sstu-go-forum-auth-service/internal/usecase"
+	"sstu-go-forum-auth-service/internal/utils"
+)
+
+type capturingMailer struct {
+	sent []mailer.Message
+}
+
+func (m *capturingMailer) Send(msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var resetTokenPattern = regexp.MustCompile(`\?token=(\S+)`)
+
+func resetTokenFrom(t *testing.T, msg mailer.Message) string {
+	match := resetTokenPattern.FindStringSubmatch(msg.Body)
+	require.NotNil(t, match, "reset link not found in mail body")
+	token, err := url.QueryUnescape(match[1])
+	require.NoError(t, err)
+	return token
+}
+
+func newResetFixture(t *testing.T) (*PasswordResetUseCaseImpl, *memory.AuthRepository, *capturingMailer, *model.User) {
+	repo := memory.NewRepository()
+	user := &model.User{Username: "user", Password: "hash", Role: "USER", Email: "user@example.com"}
+	require.NoError(t, repo.CreateUser(user))
+	m := &capturingMailer{}
+	uc := NewPasswordResetUseCase(repo, m, ratelimit.NewSlidingWindow(2, time.Hour), "https://forum.example/reset", 30*time.Minute)
+	uc.Background = runNow
+	return uc, repo, m, user
+}
+
+// runNow выполняет фоновую работу сразу, чтобы тесты видели письма без ожидания
+func runNow(task func()) { task() }
+
+type blockingMailer struct {
+	release chan struct{}
+}
+
+func (m *blockingMailer) Send(mailer.Message) error {
+	<-m.release
+	return nil
+}
+
+// Ответ не ждет записи токена и письма: существующий аккаунт отвечает так же быстро, как несуществующий
+func TestForgotPassword_DoesNotWaitForMail(t *testing.T) {
+	repo := memory.NewRepository()
+	require.NoError(t, repo.CreateUser(&model.User{Username: "user", Password: "hash", Role: "USER", Email: "user@example.com"}))
+	m := &blockingMailer{release: make(chan struct{})}
+	defer close(m.release)
+	uc := NewPasswordResetUseCase(repo, m, ratelimit.NewSlidingWindow(2, time.Hour), "https://forum.example/reset", 30*time.Minute)
+
+	done := make(chan error, 1)
+	go func() { done <- uc.ForgotPassword(dto.ForgotPasswordRequest{Username: "user"}) }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("ForgotPassword waited for the mailer")
+	}
+}
+
+func TestForgotPassword_SendsSingleUseToken(t *testing.T) {
+	uc, repo, m, user := newResetFixture(t)
+	require.NoError(t, repo.SaveRefreshToken(&model.RefreshToken{UserID: user.ID, Token: "session", ExpiresAt: time.Now().Add(time.Hour)}))
+
+	require.NoError(t, uc.ForgotPassword(dto.ForgotPasswordRequest{Username: "user"}))
+	require.Len(t, m.sent, 1)
+	assert.Equal(t, "user@example.com", m.sent[0].To)
+	token := resetTokenFrom(t, m.sent[0])
+
+	require.NoError(t, uc.ResetPassword(dto.ResetPasswordRequest{Token: token, NewPassword: "new-secret"}))
+	stored, err := repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, "hash", stored.Password)
+	_, err = repo.ConsumeRefreshToken("session")
+	assert.Error(t, err, "reset must revoke existing sessions")
+	_, err = repo.GetDenylistEntry(user.ID)
+	assert.NoError(t, err)
+
+	err = uc.ResetPassword(dto.ResetPasswordRequest{Token: token, NewPassword: "other-secret"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidResetToken)
+}
+
+func TestForgotPassword_MailInUserLocale(t *testing.T) {
+	uc, repo, m, user := newResetFixture(t)
+
+	require.NoError(t, uc.ForgotPassword(dto.ForgotPasswordRequest{Username: "user"}))
+	assert.Equal(t, "Сброс пароля", m.sent[0].Subject, "without a preference the default locale is used")
+	assert.Contains(t, m.sent[0].Body, "Ссылка действует 30 минут")
+
+	require.NoError(t, repo.UpdateLocale(user.ID, "en"))
+	require.NoError(t, uc.ForgotPassword(dto.ForgotPasswordRequest{Username: "user"}))
+	assert.Equal(t, "Password reset", m.sent[1].Subject)
+	assert.Contains(t, m.sent[1].Body, "Hello, user!")
+	assert.Contains(t, m.sent[1].Body, "valid for 30 minutes")
+	resetTokenFrom(t, m.sent[1])
+}
+
+func TestForgotPassword_StoresOnlyHash(t *testing.T) {
+	uc, repo, m, _ := newResetFixture(t)
+
+	require.NoError(t, uc.ForgotPassword(dto.ForgotPasswordRequest{Username: "user"}))
+	token := resetTokenFrom(t, m.sent[0])
+
+	_, err := repo.ConsumePasswordResetToken(token)
+	assert.Error(t, err)
+	_, err = repo.ConsumePasswordResetToken(utils.HashToken(token))
+	assert.NoError(t, err)
+}
+
+func TestForgotPassword_UnknownUserOrNoEmailIsSilent(t *testing.T) {
+	uc, repo, m, _ := newResetFixture(t)
+	require.NoError(t, repo.CreateUser(&model.User{Username: "noemail", Password: "hash", Role: "USER"}))
+
+	assert.NoError(t, uc.ForgotPassword(dto.ForgotPasswordRequest{Username: "ghost"}))
+	assert.NoError(t, uc.ForgotPassword(dto.ForgotPasswordRequest{Username: "noemail"}))
+	assert.Empty(t, m.sent)
+}
+
+func TestForgotPassword_ThrottledPerAccount(t *testing.T) {
+	uc, _, m, _ := newResetFixture(t)
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, uc.ForgotPassword(dto.ForgotPasswordRequest{Username: "user"}))
+	}
+	assert.Len(t, m.sent, 2)
+}
+
+func TestResetPassword_ExpiredToken(t *testing.T) {
+	uc, repo, _, user := newResetFixture(t)
+	require.NoError(t, repo.SavePasswordResetToken(&model.PasswordResetToken{
+		UserID: user.ID, TokenHash: utils.HashToken("expired"), ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+
+	err := uc.ResetPassword(dto.ResetPasswordRequest{Token: "expired", NewPassword: "new-secret"})
+
+	assert.ErrorIs(t, err, usecase.ErrInvalidResetToken)
+	stored, _ := repo.GetUserByID(user.ID)
+	assert.Equal(t, "hash", stored.Password)
+}
+
+func TestResetPassword_WeakPasswordKeepsToken(t *testing.T) {
+	uc, _, m, _ := newResetFixture(t)
+	require.NoError(t, uc.ForgotPassword(dto.ForgotPasswordRequest{Username: "user"}))
+	token := resetTokenFrom(t, m.sent[0])
+
+	err := uc.ResetPassword(dto.ResetPasswordRequest{Token: token, NewPassword: "123"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidNewPassword)
+
+	assert.NoError(t, uc.ResetPassword(dto.ResetPasswordRequest{Token: token, NewPassword: "new-secret"}))
+}
@@ -0,0 +1,21 @@
+package usecase
+
+import (
+	"time"
+
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/repository"
+	"sstu-go-forum-auth-service/internal/utils"
+)
+
+// revokeSessions удаляет действующие refresh токены пользователя и отзывает его access токены, выпущенные до revokedBefore
+func revokeSessions(repo repository.AuthRepository, userID int, revokedBefore time.Time) error {
+	if err := repo.DeleteActiveRefreshTokensByUserID(userID); err != nil {
+		return err
+	}
+	return repo.SaveDenylistEntry(&model.DenylistEntry{
+		UserID:        userID,
+		RevokedBefore: revokedBefore,
+		ExpiresAt:     revokedBefore.Add(utils.AccessTokenTTL),
+	})
+}
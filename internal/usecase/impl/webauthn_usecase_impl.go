@@ -0,0 +1,389 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/rs/zerolog/log"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/password"
+	"sstu-go-forum-auth-service/internal/repository"
+	"sstu-go-forum-auth-service/internal/usecase"
+	"sstu-go-forum-auth-service/internal/utils"
+)
+
+const (
+	DefaultWebAuthnCredentialName = "Ключ доступа"
+	maxWebAuthnCredentialName     = 64
+)
+
+type WebAuthnUseCaseImpl struct {
+	Repo     repository.AuthRepository
+	WebAuthn *webauthn.WebAuthn
+	// SessionTTL — время на завершение церемонии после begin
+	SessionTTL time.Duration
+	// Hasher проверяет текущий пароль при добавлении и удалении ключей
+	Hasher password.PasswordHasher
+	// RequiredRoles — роли, у которых нельзя удалить последний второй фактор, см. MFAUseCaseImpl.RequiredRoles
+	RequiredRoles []string
+}
+
+func NewWebAuthnUseCase(repo repository.AuthRepository, wa *webauthn.WebAuthn, sessionTTL time.Duration) *WebAuthnUseCaseImpl {
+	log.Info().Str("rpID", wa.Config.RPID).Msg("WebAuthnUseCaseImpl initialized")
+	return &WebAuthnUseCaseImpl{Repo: repo, WebAuthn: wa, SessionTTL: sessionTTL, Hasher: password.Default()}
+}
+
+func (uc *WebAuthnUseCaseImpl) BeginRegistration(userID int, req dto.WebAuthnBeginRegistrationRequest) (*dto.WebAuthnBeginResponse, error) {
+	log.Debug().Int("userID", userID).Msg("WebAuthn registration started")
+
+	if _, err := checkCurrentPassword(uc.Repo, uc.Hasher, userID, req.CurrentPassword); err != nil {
+		return nil, err
+	}
+	user, err := uc.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	// Уже зарегистрированные ключи исключаются, чтобы один аутентификатор не добавлялся дважды
+	creation, session, err := uc.WebAuthn.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to begin webauthn registration")
+		return nil, err
+	}
+	return uc.saveSession(session, creation)
+}
+
+func (uc *WebAuthnUseCaseImpl) FinishRegistration(userID int, req dto.WebAuthnRegisterRequest) (*model.WebAuthnCredential, error) {
+	log.Debug().Int("userID", userID).Msg("WebAuthn registration finish")
+
+	session, err := uc.consumeSession(req.SessionToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(session.UserID, user.WebAuthnID()) {
+		log.Warn().Int("userID", userID).Msg("WebAuthn registration session of another user")
+		return nil, usecase.ErrInvalidWebAuthnResponse
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to parse webauthn registration response")
+		return nil, usecase.ErrInvalidWebAuthnResponse
+	}
+	credential, err := uc.WebAuthn.CreateCredential(user, *session, parsed)
+	if err != nil {
+		log.Warn().Err(err).Int("userID", userID).Msg("WebAuthn registration response rejected")
+		return nil, usecase.ErrInvalidWebAuthnResponse
+	}
+
+	c := credentialToModel(userID, credential)
+	c.Name = credentialName(req.Name)
+	c.CreatedAt = time.Now()
+	err = uc.Repo.SaveWebAuthnCredential(c)
+	if errors.Is(err, repository.ErrConflict) {
+		log.Warn().Int("userID", userID).Msg("WebAuthn credential already registered")
+		return nil, usecase.ErrInvalidWebAuthnResponse
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to save webauthn credential")
+		return nil, err
+	}
+	log.Info().Int("userID", userID).Int("credentialID", c.ID).Msg("WebAuthn credential registered")
+	return c, nil
+}
+
+func (uc *WebAuthnUseCaseImpl) BeginLogin(userID int) (*dto.WebAuthnBeginResponse, error) {
+	if userID == 0 {
+		log.Debug().Msg("Passkey login started")
+		// Вход без пароля: ключ должен сам проверить пользователя (PIN или биометрия)
+		assertion, session, err := uc.WebAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to begin passkey login")
+			return nil, err
+		}
+		return uc.saveSession(session, assertion)
+	}
+
+	log.Debug().Int("userID", userID).Msg("WebAuthn second factor started")
+	user, err := uc.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(user.credentials) == 0 {
+		log.Warn().Int("userID", userID).Msg("No webauthn credentials registered")
+		return nil, usecase.ErrWebAuthnNotRegistered
+	}
+	assertion, session, err := uc.WebAuthn.BeginLogin(user)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to begin webauthn login")
+		return nil, err
+	}
+	return uc.saveSession(session, assertion)
+}
+
+func (uc *WebAuthnUseCaseImpl) FinishLogin(userID int, req dto.WebAuthnLoginRequest) (*model.User, error) {
+	log.Debug().Int("userID", userID).Msg("WebAuthn login finish")
+
+	session, err := uc.consumeSession(req.SessionToken)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to parse webauthn login response")
+		return nil, usecase.ErrInvalidWebAuthnResponse
+	}
+
+	var user *webAuthnUser
+	var credential *webauthn.Credential
+	if userID == 0 {
+		// Сессия второго фактора привязана к пользователю и не годится для входа без пароля
+		if len(session.UserID) != 0 {
+			log.Warn().Msg("Passkey login with a second factor session")
+			return nil, usecase.ErrInvalidWebAuthnResponse
+		}
+		var owner webauthn.User
+		owner, credential, err = uc.WebAuthn.ValidatePasskeyLogin(uc.loadUserByHandle, *session, parsed)
+		if err == nil {
+			user = owner.(*webAuthnUser)
+		}
+	} else {
+		if user, err = uc.loadUser(userID); err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(session.UserID, user.WebAuthnID()) {
+			log.Warn().Int("userID", userID).Msg("WebAuthn login session of another user")
+			return nil, usecase.ErrInvalidWebAuthnResponse
+		}
+		credential, err = uc.WebAuthn.ValidateLogin(user, *session, parsed)
+	}
+	if err != nil {
+		log.Warn().Err(err).Int("userID", userID).Msg("WebAuthn login response rejected")
+		return nil, usecase.ErrInvalidWebAuthnResponse
+	}
+
+	if credential.Authenticator.CloneWarning {
+		log.Warn().Int("userID", user.ID).Msg("WebAuthn sign counter did not increase, credential may be cloned")
+		return nil, usecase.ErrInvalidWebAuthnResponse
+	}
+	err = uc.Repo.UseWebAuthnCredential(credential.ID, credential.Authenticator.SignCount, credential.Flags.BackupState)
+	if errors.Is(err, repository.ErrConflict) || errors.Is(err, repository.ErrNotFound) {
+		log.Warn().Int("userID", user.ID).Msg("WebAuthn credential used concurrently or removed")
+		return nil, usecase.ErrInvalidWebAuthnResponse
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update webauthn sign count")
+		return nil, err
+	}
+	log.Info().Int("userID", user.ID).Msg("WebAuthn assertion accepted")
+	return user.User, nil
+}
+
+func (uc *WebAuthnUseCaseImpl) ListCredentials(userID int) ([]model.WebAuthnCredential, error) {
+	credentials, err := uc.Repo.ListWebAuthnCredentials(userID)
+	if err != nil {
+		log.Error().Err(err).Int("userID", userID).Msg("Failed to list webauthn credentials")
+		return nil, err
+	}
+	return credentials, nil
+}
+
+func (uc *WebAuthnUseCaseImpl) DeleteCredential(userID int, req dto.DeleteWebAuthnCredentialRequest) error {
+	user, err := checkCurrentPassword(uc.Repo, uc.Hasher, userID, req.CurrentPassword)
+	if err != nil {
+		return err
+	}
+	required := slices.Contains(uc.RequiredRoles, user.Role)
+	// Остаток факторов проверяется в той же транзакции, что и удаление, чтобы параллельные удаления
+	// не оставили обязательную роль без второго фактора
+	err = uc.Repo.WithTx(func(repo repository.AuthRepository) error {
+		if err := repo.DeleteWebAuthnCredential(userID, req.ID); err != nil {
+			return err
+		}
+		if !required {
+			return nil
+		}
+		if remaining, err := hasSecondFactor(repo, userID); err != nil {
+			return err
+		} else if !remaining {
+			log.Warn().Int("userID", userID).Str("role", user.Role).Msg("Last second factor is mandatory for role")
+			return usecase.ErrMFARequired
+		}
+		return nil
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Warn().Int("userID", userID).Int("credentialID", req.ID).Msg("WebAuthn credential not found")
+		return usecase.ErrWebAuthnCredentialNotFound
+	}
+	if errors.Is(err, usecase.ErrMFARequired) {
+		return err
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete webauthn credential")
+		return err
+	}
+	log.Info().Int("userID", userID).Int("credentialID", req.ID).Msg("WebAuthn credential deleted")
+	return nil
+}
+
+// hasSecondFactor сообщает, остался ли у пользователя подтвержденный TOTP или ключ доступа
+func hasSecondFactor(repo repository.AuthRepository, userID int) (bool, error) {
+	t, err := repo.GetTOTP(userID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return false, err
+	}
+	if err == nil && t.Confirmed {
+		return true, nil
+	}
+	credentials, err := repo.ListWebAuthnCredentials(userID)
+	if err != nil {
+		return false, err
+	}
+	return len(credentials) > 0, nil
+}
+
+func (uc *WebAuthnUseCaseImpl) saveSession(session *webauthn.SessionData, options any) (*dto.WebAuthnBeginResponse, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode webauthn session")
+		return nil, err
+	}
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate webauthn session token")
+		return nil, err
+	}
+	if err := uc.Repo.SaveWebAuthnSession(&model.WebAuthnSession{
+		TokenHash: utils.HashToken(token),
+		Data:      data,
+		ExpiresAt: time.Now().Add(uc.SessionTTL),
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to save webauthn session")
+		return nil, err
+	}
+	return &dto.WebAuthnBeginResponse{SessionToken: token, Options: options}, nil
+}
+
+// consumeSession достает сессию церемонии один раз: перехваченный ответ ключа нельзя отправить повторно
+func (uc *WebAuthnUseCaseImpl) consumeSession(token string) (*webauthn.SessionData, error) {
+	s, err := uc.Repo.ConsumeWebAuthnSession(utils.HashToken(token))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		log.Error().Err(err).Msg("Failed to consume webauthn session")
+		return nil, err
+	}
+	if err != nil || time.Now().After(s.ExpiresAt) {
+		log.Warn().Msg("Invalid, expired or already used webauthn session")
+		return nil, usecase.ErrInvalidWebAuthnResponse
+	}
+	var session webauthn.SessionData
+	if err := json.Unmarshal(s.Data, &session); err != nil {
+		log.Error().Err(err).Msg("Failed to decode webauthn session")
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (uc *WebAuthnUseCaseImpl) loadUser(userID int) (*webAuthnUser, error) {
+	user, err := uc.Repo.GetUserByID(userID)
+	if err != nil {
+		log.Error().Err(err).Int("userID", userID).Msg("Failed to get user")
+		return nil, err
+	}
+	credentials, err := uc.Repo.ListWebAuthnCredentials(userID)
+	if err != nil {
+		log.Error().Err(err).Int("userID", userID).Msg("Failed to list webauthn credentials")
+		return nil, err
+	}
+	return &webAuthnUser{User: user, credentials: credentials}, nil
+}
+
+func (uc *WebAuthnUseCaseImpl) loadUserByHandle(_, userHandle []byte) (webauthn.User, error) {
+	if len(userHandle) != 8 {
+		return nil, errors.New("unknown user handle")
+	}
+	return uc.loadUser(int(binary.BigEndian.Uint64(userHandle)))
+}
+
+// webAuthnUser связывает пользователя с его ключами для библиотеки webauthn
+type webAuthnUser struct {
+	*model.User
+	credentials []model.WebAuthnCredential
+}
+
+// WebAuthnID — user handle ключа. ID пользователя не содержит персональных данных, поэтому
+// отдельный случайный идентификатор не хранится
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(u.ID))
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.Username
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.Username
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.credentials))
+	for i, c := range u.credentials {
+		transports := make([]protocol.AuthenticatorTransport, len(c.Transports))
+		for j, t := range c.Transports {
+			transports[j] = protocol.AuthenticatorTransport(t)
+		}
+		credentials[i] = webauthn.Credential{
+			ID:              c.CredentialID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: c.BackupEligible,
+				BackupState:    c.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{AAGUID: c.AAGUID, SignCount: c.SignCount},
+		}
+	}
+	return credentials
+}
+
+func credentialToModel(userID int, c *webauthn.Credential) *model.WebAuthnCredential {
+	transports := make([]string, len(c.Transport))
+	for i, t := range c.Transport {
+		transports[i] = string(t)
+	}
+	return &model.WebAuthnCredential{
+		UserID:          userID,
+		CredentialID:    c.ID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transports:      transports,
+		AAGUID:          c.Authenticator.AAGUID,
+		SignCount:       c.Authenticator.SignCount,
+		BackupEligible:  c.Flags.BackupEligible,
+		BackupState:     c.Flags.BackupState,
+	}
+}
+
+func credentialName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return DefaultWebAuthnCredentialName
+	}
+	if r := []rune(name); len(r) > maxWebAuthnCredentialName {
+		name = string(r[:maxWebAuthnCredentialName])
+	}
+	return name
+}
@@ -0,0 +1,194 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/model"
+	"sstu-go-forum-auth-service/internal/repository/memory"
+	"sstu-go-forum-auth-service/internal/usecase"
+	"sstu-go-forum-auth-service/internal/webauthntest"
+)
+
+const webAuthnOrigin = "https://forum.example"
+
+func newWebAuthnFixture(t *testing.T) (*AuthUseCaseImpl, *WebAuthnUseCaseImpl, *memory.AuthRepository) {
+	repo := memory.NewRepository()
+	wa, err := webauthn.New(&webauthn.Config{RPID: "forum.example", RPDisplayName: "SSTU Forum", RPOrigins: []string{webAuthnOrigin}})
+	require.NoError(t, err)
+	webAuthnUC := NewWebAuthnUseCase(repo, wa, time.Minute)
+	auth := NewAuthUseCase(repo)
+	auth.WebAuthn = webAuthnUC
+	return auth, webAuthnUC, repo
+}
+
+func createWebAuthnUser(t *testing.T, repo *memory.AuthRepository, username string) *model.User {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &model.User{Username: username, Password: string(hash), Role: "USER"}
+	require.NoError(t, repo.CreateUser(user))
+	return user
+}
+
+func registerKey(t *testing.T, uc *WebAuthnUseCaseImpl, userID int, key *webauthntest.Authenticator, name string) *model.WebAuthnCredential {
+	begin, err := uc.BeginRegistration(userID, dto.WebAuthnBeginRegistrationRequest{CurrentPassword: "secret1"})
+	require.NoError(t, err)
+	response, err := key.Create(begin.Options)
+	require.NoError(t, err)
+	credential, err := uc.FinishRegistration(userID, dto.WebAuthnRegisterRequest{SessionToken: begin.SessionToken, Name: name, Credential: response})
+	require.NoError(t, err)
+	return credential
+}
+
+// assert подписывает вызов из BeginLogin ключом key
+func assertWith(t *testing.T, uc *WebAuthnUseCaseImpl, userID int, key *webauthntest.Authenticator) dto.WebAuthnLoginRequest {
+	begin, err := uc.BeginLogin(userID)
+	require.NoError(t, err)
+	response, err := key.Get(begin.Options)
+	require.NoError(t, err)
+	return dto.WebAuthnLoginRequest{SessionToken: begin.SessionToken, Credential: response}
+}
+
+func TestWebAuthn_RegisterAndPasskeyLogin(t *testing.T) {
+	auth, uc, repo := newWebAuthnFixture(t)
+	user := createWebAuthnUser(t, repo, "user")
+	key := webauthntest.New(webAuthnOrigin)
+
+	credential := registerKey(t, uc, user.ID, key, "  Ноутбук  ")
+	assert.Equal(t, "Ноутбук", credential.Name)
+	assert.Equal(t, []string{"internal", "hybrid"}, credential.Transports)
+
+	req := assertWith(t, uc, 0, key)
+	loggedIn, access, refresh, err := auth.LoginWebAuthn(req)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, loggedIn.ID)
+	assert.NotEmpty(t, access)
+	assert.NotEmpty(t, refresh)
+
+	_, _, _, err = auth.LoginWebAuthn(req)
+	assert.ErrorIs(t, err, usecase.ErrInvalidWebAuthnResponse, "a ceremony completes only once")
+
+	credentials, err := uc.ListCredentials(user.ID)
+	require.NoError(t, err)
+	require.Len(t, credentials, 1)
+	assert.Equal(t, uint32(1), credentials[0].SignCount)
+}
+
+func TestWebAuthn_RejectsForeignOriginAndMissingUserVerification(t *testing.T) {
+	auth, uc, repo := newWebAuthnFixture(t)
+	user := createWebAuthnUser(t, repo, "user")
+	key := webauthntest.New(webAuthnOrigin)
+	registerKey(t, uc, user.ID, key, "")
+
+	phishing := key.Clone()
+	phishing.Origin = "https://forum.example.evil"
+	_, _, _, err := auth.LoginWebAuthn(assertWith(t, uc, 0, phishing))
+	assert.ErrorIs(t, err, usecase.ErrInvalidWebAuthnResponse)
+
+	key.SkipUserVerification = true
+	_, _, _, err = auth.LoginWebAuthn(assertWith(t, uc, 0, key))
+	assert.ErrorIs(t, err, usecase.ErrInvalidWebAuthnResponse, "passwordless login requires user verification")
+}
+
+func TestWebAuthn_ClonedKeyIsRejected(t *testing.T) {
+	auth, uc, repo := newWebAuthnFixture(t)
+	user := createWebAuthnUser(t, repo, "user")
+	key := webauthntest.New(webAuthnOrigin)
+	registerKey(t, uc, user.ID, key, "")
+	clone := key.Clone()
+
+	_, _, _, err := auth.LoginWebAuthn(assertWith(t, uc, 0, key))
+	require.NoError(t, err)
+	_, _, _, err = auth.LoginWebAuthn(assertWith(t, uc, 0, clone))
+	assert.ErrorIs(t, err, usecase.ErrInvalidWebAuthnResponse, "sign counter must grow")
+}
+
+func TestWebAuthn_SecondFactor(t *testing.T) {
+	auth, uc, repo := newWebAuthnFixture(t)
+	user := createWebAuthnUser(t, repo, "user")
+	other := createWebAuthnUser(t, repo, "other")
+	key := webauthntest.New(webAuthnOrigin)
+	otherKey := webauthntest.New(webAuthnOrigin)
+
+	_, err := uc.BeginLogin(user.ID)
+	assert.ErrorIs(t, err, usecase.ErrWebAuthnNotRegistered)
+	registerKey(t, uc, user.ID, key, "")
+	registerKey(t, uc, other.ID, otherKey, "")
+
+	result, err := auth.Login(dto.LoginRequest{Username: "user", Password: "secret1"})
+	require.NoError(t, err)
+	require.NotEmpty(t, result.MFAToken, "a registered key enables the second factor")
+
+	begin, err := uc.BeginLogin(user.ID)
+	require.NoError(t, err)
+	_, err = otherKey.Get(begin.Options)
+	assert.ErrorIs(t, err, webauthntest.ErrNoCredential, "only the user's keys are allowed")
+
+	// Ответ passkey входа без пароля не принимается как второй фактор
+	req := assertWith(t, uc, 0, key)
+	_, _, _, err = auth.LoginMFA(dto.MFALoginRequest{MFAToken: result.MFAToken, WebAuthnSession: req.SessionToken, WebAuthnCredential: req.Credential})
+	assert.ErrorIs(t, err, usecase.ErrInvalidWebAuthnResponse)
+
+	req = assertWith(t, uc, user.ID, key)
+	_, access, _, err := auth.LoginMFA(dto.MFALoginRequest{MFAToken: result.MFAToken, WebAuthnSession: req.SessionToken, WebAuthnCredential: req.Credential})
+	require.NoError(t, err)
+	assert.NotEmpty(t, access)
+}
+
+func TestWebAuthn_MultipleAuthenticators(t *testing.T) {
+	auth, uc, repo := newWebAuthnFixture(t)
+	user := createWebAuthnUser(t, repo, "user")
+	other := createWebAuthnUser(t, repo, "other")
+	phone := webauthntest.New(webAuthnOrigin)
+	laptop := webauthntest.New(webAuthnOrigin)
+
+	first := registerKey(t, uc, user.ID, phone, "Телефон")
+	begin, err := uc.BeginRegistration(user.ID, dto.WebAuthnBeginRegistrationRequest{CurrentPassword: "secret1"})
+	require.NoError(t, err)
+	_, err = phone.Create(begin.Options)
+	assert.Error(t, err, "registered keys are excluded")
+	second := registerKey(t, uc, user.ID, laptop, "Ноутбук")
+
+	for _, key := range []*webauthntest.Authenticator{phone, laptop} {
+		loggedIn, _, _, err := auth.LoginWebAuthn(assertWith(t, uc, 0, key))
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, loggedIn.ID)
+	}
+
+	assert.ErrorIs(t, uc.DeleteCredential(other.ID, dto.DeleteWebAuthnCredentialRequest{ID: first.ID, CurrentPassword: "secret1"}), usecase.ErrWebAuthnCredentialNotFound)
+	require.NoError(t, uc.DeleteCredential(user.ID, dto.DeleteWebAuthnCredentialRequest{ID: first.ID, CurrentPassword: "secret1"}))
+	_, _, _, err = auth.LoginWebAuthn(assertWith(t, uc, 0, phone))
+	assert.ErrorIs(t, err, usecase.ErrInvalidWebAuthnResponse)
+	credentials, err := uc.ListCredentials(user.ID)
+	require.NoError(t, err)
+	require.Len(t, credentials, 1)
+	assert.Equal(t, second.ID, credentials[0].ID)
+}
+
+func TestWebAuthn_DeleteKeepsMandatorySecondFactor(t *testing.T) {
+	_, uc, repo := newWebAuthnFixture(t)
+	uc.RequiredRoles = []string{"ADMIN"}
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	admin := &model.User{Username: "admin", Password: string(hash), Role: "ADMIN"}
+	require.NoError(t, repo.CreateUser(admin))
+	phone := registerKey(t, uc, admin.ID, webauthntest.New(webAuthnOrigin), "Телефон")
+	laptop := registerKey(t, uc, admin.ID, webauthntest.New(webAuthnOrigin), "Ноутбук")
+
+	_, err = uc.BeginRegistration(admin.ID, dto.WebAuthnBeginRegistrationRequest{CurrentPassword: "wrong"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+	err = uc.DeleteCredential(admin.ID, dto.DeleteWebAuthnCredentialRequest{ID: phone.ID, CurrentPassword: "wrong"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+
+	require.NoError(t, uc.DeleteCredential(admin.ID, dto.DeleteWebAuthnCredentialRequest{ID: phone.ID, CurrentPassword: "secret1"}))
+	err = uc.DeleteCredential(admin.ID, dto.DeleteWebAuthnCredentialRequest{ID: laptop.ID, CurrentPassword: "secret1"})
+	assert.ErrorIs(t, err, usecase.ErrMFARequired, "the last factor of a mandatory role stays")
+	credentials, err := repo.ListWebAuthnCredentials(admin.ID)
+	require.NoError(t, err)
+	assert.Len(t, credentials, 1, "the refused deletion is rolled back")
+}
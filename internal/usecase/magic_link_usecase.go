@@ -0,0 +1,14 @@
+package usecase
+
+import (
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/model"
+)
+
+type MagicLinkUseCase interface {
+	// SendLink отправляет ссылку для входа на почту пользователя и возвращает nonce для браузера.
+	// Результат одинаков для существующих и несуществующих аккаунтов
+	SendLink(req dto.MagicLinkRequest) (string, error)
+	// Consume погашает токен из ссылки, если nonce совпадает с выданным браузеру, и подтверждает почту
+	Consume(req dto.MagicLinkLoginRequest) (*model.User, error)
+}
@@ -0,0 +1,23 @@
+package usecase
+
+import (
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/model"
+)
+
+type MFAUseCase interface {
+	// Status сообщает, подключен ли у пользователя второй фактор и обязателен ли он для его роли
+	Status(user *model.User) (enabled, required bool, err error)
+	// EnrollTOTP проверяет текущий пароль и создает новый секрет; второй фактор включается только после ConfirmTOTP
+	EnrollTOTP(userID int, req dto.TOTPEnrollRequest) (*dto.TOTPEnrollResponse, error)
+	// EnrollTOTPDuringLogin создает секрет по MFA токену: пароль уже проверен на первом шаге входа
+	EnrollTOTPDuringLogin(userID int) (*dto.TOTPEnrollResponse, error)
+	ConfirmTOTP(userID int, req dto.TOTPCodeRequest) error
+	// VerifyTOTP проверяет код подключенного второго фактора; каждый код принимается один раз
+	VerifyTOTP(userID int, code string) error
+	DisableTOTP(userID int, req dto.DisableTOTPRequest) error
+	// UseRecoveryCode принимает код восстановления вместо кода TOTP и уведомляет пользователя письмом
+	UseRecoveryCode(userID int, code string) error
+	// RegenerateRecoveryCodes заменяет коды восстановления; прежние коды перестают действовать
+	RegenerateRecoveryCodes(userID int, req dto.RegenerateRecoveryCodesRequest) ([]string, error)
+}
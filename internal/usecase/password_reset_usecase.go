@@ -0,0 +1,11 @@
+package usecase
+
+import "sstu-go-forum-auth-service/internal/dto"
+
+type PasswordResetUseCase interface {
+	// ForgotPassword отправляет письмо со ссылкой для сброса, если аккаунт существует.
+	// Результат одинаков для существующих и несуществующих аккаунтов
+	ForgotPassword(req dto.ForgotPasswordRequest) error
+	// ResetPassword устанавливает новый пароль по одноразовому токену и завершает все сессии
+	ResetPassword(req dto.ResetPasswordRequest) error
+}
@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"sstu-go-forum-auth-service/internal/dto"
+	"sstu-go-forum-auth-service/internal/model"
+)
+
+type WebAuthnUseCase interface {
+	// BeginRegistration требует текущий пароль: один access токен не должен позволять добавить ключ,
+	// который останется у злоумышленника постоянным вторым фактором. FinishRegistration завершает только
+	// одноразовую сессию, начатую с паролем
+	BeginRegistration(userID int, req dto.WebAuthnBeginRegistrationRequest) (*dto.WebAuthnBeginResponse, error)
+	FinishRegistration(userID int, req dto.WebAuthnRegisterRequest) (*model.WebAuthnCredential, error)
+	// BeginLogin начинает проверку ключа: при userID == 0 — вход без имени пользователя по passkey,
+	// иначе второй фактор по ключам этого пользователя
+	BeginLogin(userID int) (*dto.WebAuthnBeginResponse, error)
+	// FinishLogin проверяет подпись ключа и возвращает его владельца. userID должен совпадать с переданным в BeginLogin
+	FinishLogin(userID int, req dto.WebAuthnLoginRequest) (*model.User, error)
+	ListCredentials(userID int) ([]model.WebAuthnCredential, error)
+	// DeleteCredential требует текущий пароль и не удаляет последний второй фактор у ролей, которым он обязателен
+	DeleteCredential(userID int, req dto.DeleteWebAuthnCredentialRequest) error
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"os"
 	"time"
@@ -8,31 +10,66 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
-func GenerateAccessToken(userID int, username, role string) (string, error) {
-	exp := time.Now().Add(15 * time.Minute)
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+	// MFATokenTTL — время на ввод кода второго фактора после проверки пароля
+	MFATokenTTL = 5 * time.Minute
+)
+
+// Значения claim "typ", по которому access токен нельзя подменить refresh токеном
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	TokenTypeMFA     = "mfa"
+)
+
+// GenerateAccessToken выдает access токен; locale — выбранный пользователем язык, пустой, если он не выбран
+func GenerateAccessToken(userID int, username, role string, emailVerified bool, locale string) (string, error) {
+	exp := time.Now().Add(AccessTokenTTL)
 	claims := jwt.MapClaims{
-		"user_id":  userID,
-		"username": username,
-		"role":     role,
-		"exp":      exp.Unix(),
-		"iat":      time.Now().Unix(),
+		"user_id":        userID,
+		"username":       username,
+		"role":           role,
+		"email_verified": emailVerified,
+		"locale":         locale,
+		"typ":            TokenTypeAccess,
+		"exp":            exp.Unix(),
+		"iat":            numericDate(time.Now()),
 	}
 	return signToken(claims)
 }
 
 func GenerateRefreshToken(userID int, username, role string) (string, time.Time, error) {
-	exp := time.Now().Add(30 * 24 * time.Hour)
+	exp := time.Now().Add(RefreshTokenTTL)
+	jti, err := randomID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
 	claims := jwt.MapClaims{
 		"user_id":  userID,
 		"username": username,
 		"role":     role,
+		"typ":      TokenTypeRefresh,
 		"exp":      exp.Unix(),
-		"iat":      time.Now().Unix(),
+		"iat":      numericDate(time.Now()),
+		"jti":      jti, // без jti токены, выпущенные в одну секунду, совпадали бы и ротация не была бы однократной
 	}
 	token, err := signToken(claims)
 	return token, exp, err
 }
 
+// GenerateMFAToken выдает токен первого шага входа: он подтверждает только пароль и не дает доступа к API
+func GenerateMFAToken(userID int) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"typ":     TokenTypeMFA,
+		"exp":     time.Now().Add(MFATokenTTL).Unix(),
+		"iat":     numericDate(time.Now()),
+	}
+	return signToken(claims)
+}
+
 func VerifyToken(tokenString string) (jwt.MapClaims, error) {
 	secret := getSecret()
 	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
@@ -48,6 +85,20 @@ func VerifyToken(tokenString string) (jwt.MapClaims, error) {
 	return claims, nil
 }
 
+// numericDate возвращает время с точностью до миллисекунд (RFC 7519 допускает дробный NumericDate):
+// по iat определяется, выпущен ли токен до отзыва сессий
+func numericDate(t time.Time) float64 {
+	return float64(t.UnixMilli()) / 1000
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 func signToken(claims jwt.MapClaims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(getSecret()))
@@ -0,0 +1,242 @@
+// Package webauthntest содержит программный аутентификатор WebAuthn для тестов церемоний без браузера
+package webauthntest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+var encoding = base64.RawURLEncoding
+
+// ErrNoCredential — у аутентификатора нет подходящего ключа (в браузере это NotAllowedError)
+var ErrNoCredential = errors.New("no matching credential")
+
+// Authenticator эмулирует браузер и платформенный аутентификатор с passkey: принимает options
+// из begin и возвращает PublicKeyCredential в том виде, в каком его отправляет клиент
+type Authenticator struct {
+	Origin string
+	// SkipUserVerification имитирует ключ без PIN и биометрии
+	SkipUserVerification bool
+	credentials          []*credential
+}
+
+type credential struct {
+	id         []byte
+	key        *ecdsa.PrivateKey
+	rpID       string
+	userHandle []byte
+	signCount  uint32
+}
+
+func New(origin string) *Authenticator {
+	return &Authenticator{Origin: origin}
+}
+
+// Clone копирует аутентификатор вместе с ключами и счетчиками, как при клонировании ключа
+func (a *Authenticator) Clone() *Authenticator {
+	c := *a
+	c.credentials = make([]*credential, len(a.credentials))
+	for i, cred := range a.credentials {
+		copied := *cred
+		c.credentials[i] = &copied
+	}
+	return &c
+}
+
+type descriptor struct {
+	ID string `json:"id"`
+}
+
+type creationOptions struct {
+	PublicKey struct {
+		Challenge string `json:"challenge"`
+		RP        struct {
+			ID string `json:"id"`
+		} `json:"rp"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		ExcludeCredentials []descriptor `json:"excludeCredentials"`
+	} `json:"publicKey"`
+}
+
+type requestOptions struct {
+	PublicKey struct {
+		Challenge        string       `json:"challenge"`
+		RPID             string       `json:"rpId"`
+		AllowCredentials []descriptor `json:"allowCredentials"`
+	} `json:"publicKey"`
+}
+
+// Create отвечает на PublicKeyCredentialCreationOptions как navigator.credentials.create с attestation none
+func (a *Authenticator) Create(options any) (json.RawMessage, error) {
+	var opts creationOptions
+	if err := convert(options, &opts); err != nil {
+		return nil, err
+	}
+	rpID := opts.PublicKey.RP.ID
+	for _, excluded := range opts.PublicKey.ExcludeCredentials {
+		if a.find(rpID, excluded.ID) != nil {
+			return nil, errors.New("credential already registered")
+		}
+	}
+	userHandle, err := encoding.DecodeString(opts.PublicKey.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	cred := &credential{id: id, key: key, rpID: rpID, userHandle: userHandle}
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: key.PublicKey.X.FillBytes(make([]byte, 32)),
+		YCoord: key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	authData := a.authData(cred, flagAttestedData)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(id)))
+	authData = append(authData, id...)
+	authData = append(authData, publicKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	if err != nil {
+		return nil, err
+	}
+	clientData, err := a.clientData("webauthn.create", opts.PublicKey.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	a.credentials = append(a.credentials, cred)
+
+	return json.Marshal(map[string]any{
+		"id":                      encoding.EncodeToString(id),
+		"rawId":                   encoding.EncodeToString(id),
+		"type":                    "public-key",
+		"authenticatorAttachment": "platform",
+		"response": map[string]any{
+			"clientDataJSON":    encoding.EncodeToString(clientData),
+			"attestationObject": encoding.EncodeToString(attestation),
+			"transports":        []string{"internal", "hybrid"},
+		},
+	})
+}
+
+// Get отвечает на PublicKeyCredentialRequestOptions как navigator.credentials.get. Без allowCredentials
+// выбирается первый passkey для RP, как при входе без имени пользователя
+func (a *Authenticator) Get(options any) (json.RawMessage, error) {
+	var opts requestOptions
+	if err := convert(options, &opts); err != nil {
+		return nil, err
+	}
+	rpID := opts.PublicKey.RPID
+	var cred *credential
+	if len(opts.PublicKey.AllowCredentials) == 0 {
+		cred = a.find(rpID, "")
+	}
+	for _, allowed := range opts.PublicKey.AllowCredentials {
+		if cred = a.find(rpID, allowed.ID); cred != nil {
+			break
+		}
+	}
+	if cred == nil {
+		return nil, ErrNoCredential
+	}
+
+	cred.signCount++
+	authData := a.authData(cred, 0)
+	clientData, err := a.clientData("webauthn.get", opts.PublicKey.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(bytes.Clone(authData), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, cred.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]any{
+		"id":    encoding.EncodeToString(cred.id),
+		"rawId": encoding.EncodeToString(cred.id),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    encoding.EncodeToString(clientData),
+			"authenticatorData": encoding.EncodeToString(authData),
+			"signature":         encoding.EncodeToString(signature),
+			"userHandle":        encoding.EncodeToString(cred.userHandle),
+		},
+	})
+}
+
+func (a *Authenticator) find(rpID, id string) *credential {
+	for _, cred := range a.credentials {
+		if cred.rpID == rpID && (id == "" || encoding.EncodeToString(cred.id) == id) {
+			return cred
+		}
+	}
+	return nil
+}
+
+// authData собирает rpIdHash, флаги и счетчик подписей
+func (a *Authenticator) authData(cred *credential, flags byte) []byte {
+	flags |= flagUserPresent
+	if !a.SkipUserVerification {
+		flags |= flagUserVerified
+	}
+	rpIDHash := sha256.Sum256([]byte(cred.rpID))
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, cred.signCount)
+}
+
+func (a *Authenticator) clientData(typ, challenge string) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"type":        typ,
+		"challenge":   challenge,
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+}
+
+// convert принимает options как структуру библиотеки или как разобранный JSON ответа API
+func convert(options any, dst any) error {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}